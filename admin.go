@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/e-minguez/eib-mcp/mcp"
+)
+
+// runtimeStats is the document served by /debug/runtime.
+type runtimeStats struct {
+	// Uptime is how long the process has been running.
+	Uptime string `json:"uptime"`
+	// Goroutines is the number of goroutines.
+	Goroutines int `json:"goroutines"`
+	// HeapAlloc is the number of bytes of allocated heap objects.
+	HeapAlloc uint64 `json:"heapAllocBytes"`
+	// HeapInuse is the number of bytes in in-use heap spans.
+	HeapInuse uint64 `json:"heapInuseBytes"`
+	// Sys is the number of bytes obtained from the operating system.
+	Sys uint64 `json:"sysBytes"`
+	// NumGC is the number of completed garbage collection cycles.
+	NumGC uint32 `json:"numGC"`
+	// GOMAXPROCS is the number of CPUs the process may use.
+	GOMAXPROCS int `json:"gomaxprocs"`
+}
+
+// startAdmin serves the admin endpoints in the background:
+//   - /healthz answers "ok" while the process runs;
+//   - /metrics serves the tool call latencies in the Prometheus format;
+//   - /debug/runtime reports memory and goroutine statistics as JSON;
+//   - /debug/pprof/ serves the net/http/pprof profiles, if enabled.
+//
+// The endpoints have no authentication, so the address should be a loopback
+// one; a warning is printed otherwise.
+//
+// Parameters:
+//   - addr: The host:port to listen on.
+//   - enablePprof: Whether to serve the profiling endpoints.
+//   - metrics: The tool call metrics of the sessions.
+//
+// Returns:
+//   - error: An error if the address cannot be listened on.
+func startAdmin(addr string, enablePprof bool, metrics *mcp.Metrics) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on admin address: %w", err)
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			fmt.Fprintf(os.Stderr, "Warning: admin endpoints on %s are reachable from other hosts and have no authentication\n", l.Addr())
+		}
+	}
+
+	started := time.Now()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metrics.WritePrometheus(w)
+	})
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, r *http.Request) {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(runtimeStats{
+			Uptime:     time.Since(started).Round(time.Second).String(),
+			Goroutines: runtime.NumGoroutine(),
+			HeapAlloc:  m.HeapAlloc,
+			HeapInuse:  m.HeapInuse,
+			Sys:        m.Sys,
+			NumGC:      m.NumGC,
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+		})
+	})
+	if enablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(l); err != nil {
+			fmt.Fprintf(os.Stderr, "Admin server error: %v\n", err)
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "Admin endpoints listening on %s\n", l.Addr())
+	return nil
+}
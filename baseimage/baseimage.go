@@ -0,0 +1,201 @@
+// Package baseimage inspects SL Micro base images without mounting them.
+//
+// It reads the ISO9660 file system of ISO images (volume label, file tree,
+// embedded os-release) and the GPT partition table of raw images, so that the
+// arch and baseImage of a configuration can be checked against the actual
+// image before building.
+package baseimage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Format is the detected image format.
+type Format string
+
+const (
+	// FormatISO is an ISO9660 image.
+	FormatISO Format = "iso"
+	// FormatRaw is a raw disk image with a GPT or MBR partition table.
+	FormatRaw Format = "raw"
+)
+
+// archPattern finds an architecture name in image labels and file names.
+var archPattern = regexp.MustCompile(`(?i)(x86_64|aarch64|amd64|arm64)`)
+
+// versionPattern finds an OS version in image labels and file names, e.g.
+// the "6.0" of "SL-Micro.x86_64-6.0-Base-GM.raw".
+var versionPattern = regexp.MustCompile(`[-_.]([0-9]+\.[0-9]+)(?:[-_.]|$)`)
+
+// efiBootArchs maps the names of removable-media EFI boot loaders to the
+// architecture they boot.
+var efiBootArchs = map[string]string{
+	"bootx64.efi":  "x86_64",
+	"bootaa64.efi": "aarch64",
+}
+
+// Info describes an inspected base image.
+type Info struct {
+	// Path is the inspected file.
+	Path string `json:"path"`
+	// Size is the file size in bytes.
+	Size int64 `json:"size"`
+	// Format is the detected image format.
+	Format Format `json:"format"`
+	// VolumeLabel is the ISO9660 volume identifier of ISO images.
+	VolumeLabel string `json:"volumeLabel,omitempty"`
+	// Partitions lists the GPT partition names of raw images.
+	Partitions []string `json:"partitions,omitempty"`
+	// Arch is the detected architecture, "x86_64" or "aarch64", if known.
+	Arch string `json:"arch,omitempty"`
+	// ArchSource explains how Arch was detected.
+	ArchSource string `json:"archSource,omitempty"`
+	// OSRelease holds the fields of an os-release file found in the image.
+	OSRelease map[string]string `json:"osRelease,omitempty"`
+	// OSVersion is the detected OS version, e.g. "6.0", if known.
+	OSVersion string `json:"osVersion,omitempty"`
+	// OSVersionSource explains how OSVersion was detected.
+	OSVersionSource string `json:"osVersionSource,omitempty"`
+}
+
+// Inspect reads a base image and reports its format, architecture and OS version.
+//
+// Details that cannot be read from the image itself are inferred from the
+// volume label and file names, and the *Source fields say so.
+//
+// Parameters:
+//   - path: The image file.
+//
+// Returns:
+//   - *Info: The inspection report.
+//   - error: An error if the file cannot be read or is neither an ISO nor a partitioned disk image.
+func Inspect(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	info := &Info{Path: path, Size: st.Size()}
+	var names []string
+	if iso, err := readISO(f); err == nil {
+		info.Format = FormatISO
+		info.VolumeLabel = iso.label
+		info.OSRelease = iso.osRelease
+		names = iso.files
+	} else if parts, err := readPartitions(f); err == nil {
+		info.Format = FormatRaw
+		info.Partitions = parts
+	} else {
+		return nil, fmt.Errorf("%s is neither an ISO9660 image nor a partitioned disk image", path)
+	}
+
+	detectArch(info, filepath.Base(path), names)
+	detectVersion(info, filepath.Base(path))
+	return info, nil
+}
+
+// detectArch fills in the architecture from EFI boot loaders, the volume
+// label or the file name, in that order of reliability.
+func detectArch(info *Info, fileName string, names []string) {
+	for _, name := range names {
+		if arch, ok := efiBootArchs[strings.ToLower(filepath.Base(name))]; ok {
+			info.Arch, info.ArchSource = arch, "EFI boot loader "+name
+			return
+		}
+	}
+	for _, candidate := range []struct{ value, source string }{
+		{info.VolumeLabel, "volume label"},
+		{fileName, "file name"},
+	} {
+		if m := archPattern.FindString(candidate.value); m != "" {
+			info.Arch, info.ArchSource = normalizeArch(m), candidate.source
+			return
+		}
+	}
+}
+
+// normalizeArch maps architecture aliases to the names used by EIB.
+func normalizeArch(arch string) string {
+	switch strings.ToLower(arch) {
+	case "amd64", "x86_64":
+		return "x86_64"
+	default:
+		return "aarch64"
+	}
+}
+
+// detectVersion fills in the OS version from os-release, the volume label
+// or the file name, in that order of reliability.
+func detectVersion(info *Info, fileName string) {
+	if v := info.OSRelease["VERSION_ID"]; v != "" {
+		info.OSVersion, info.OSVersionSource = v, "os-release"
+		return
+	}
+	for _, candidate := range []struct{ value, source string }{
+		{info.VolumeLabel, "volume label"},
+		{fileName, "file name"},
+	} {
+		if m := versionPattern.FindStringSubmatch(candidate.value); m != nil {
+			info.OSVersion, info.OSVersionSource = m[1], candidate.source
+			return
+		}
+	}
+}
+
+// Compare checks the image section of a configuration against an inspected image.
+//
+// Parameters:
+//   - info: The inspection report.
+//   - config: The configuration.
+//
+// Returns:
+//   - []string: The mismatches found; empty if the configuration fits the image.
+func Compare(info *Info, config map[string]interface{}) []string {
+	image, _ := config["image"].(map[string]interface{})
+	var mismatches []string
+	if t, _ := image["imageType"].(string); t != "" && t != string(info.Format) {
+		mismatches = append(mismatches, fmt.Sprintf("image.imageType is %q but the base image is a %s image", t, info.Format))
+	}
+	if a, _ := image["arch"].(string); a != "" && info.Arch != "" && a != info.Arch {
+		mismatches = append(mismatches, fmt.Sprintf("image.arch is %q but the base image is %s (from the %s)", a, info.Arch, info.ArchSource))
+	}
+	if b, _ := image["baseImage"].(string); b != "" && b != filepath.Base(info.Path) {
+		mismatches = append(mismatches, fmt.Sprintf("image.baseImage is %q but the inspected file is %q", b, filepath.Base(info.Path)))
+	}
+	return mismatches
+}
+
+// parseOSRelease parses the KEY=value lines of an os-release file.
+//
+// Parameters:
+//   - r: The file content.
+//
+// Returns:
+//   - map[string]string: The fields, with quotes removed.
+func parseOSRelease(r io.Reader) map[string]string {
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields[key] = strings.Trim(value, `"'`)
+	}
+	return fields
+}
@@ -0,0 +1,137 @@
+package baseimage
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// digestAlgorithms maps the hexadecimal length of supported digests to their
+// algorithm name.
+var digestAlgorithms = map[int]string{
+	64:  "sha256",
+	128: "sha512",
+}
+
+// bsdChecksumLine matches the BSD format of checksum files, e.g.
+// "SHA256 (image.iso) = <digest>".
+var bsdChecksumLine = regexp.MustCompile(`^(?i:SHA256|SHA512) \((.+)\) = ([0-9a-fA-F]+)$`)
+
+// Verification is the outcome of comparing a base image with its published
+// checksum.
+type Verification struct {
+	// Algorithm is the digest algorithm, "sha256" or "sha512".
+	Algorithm string `json:"algorithm"`
+	// Expected is the published digest.
+	Expected string `json:"expected"`
+	// Actual is the digest of the image.
+	Actual string `json:"actual"`
+	// Source is where the expected digest comes from: "argument" or a URL.
+	Source string `json:"source"`
+	// Match is true if the digests are equal.
+	Match bool `json:"match"`
+}
+
+// Verify computes the digest of an image and compares it with an expected
+// value. The algorithm is chosen from the length of the expected digest.
+//
+// Parameters:
+//   - path: The image file.
+//   - expected: The expected SHA-256 or SHA-512 digest, in hexadecimal.
+//   - source: Where the expected digest comes from, reported in the result.
+//
+// Returns:
+//   - *Verification: The comparison.
+//   - error: An error if the expected digest is malformed or the image cannot be read.
+func Verify(path, expected, source string) (*Verification, error) {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	algorithm, ok := digestAlgorithms[len(expected)]
+	if _, err := hex.DecodeString(expected); err != nil || !ok {
+		return nil, fmt.Errorf("expected checksum %q is not a SHA-256 or SHA-512 hexadecimal digest", expected)
+	}
+	actual, err := FileDigest(path, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &Verification{Algorithm: algorithm, Expected: expected, Actual: actual, Source: source, Match: actual == expected}, nil
+}
+
+// FileDigest computes the digest of a file.
+//
+// Parameters:
+//   - path: The file.
+//   - algorithm: "sha256" or "sha512".
+//
+// Returns:
+//   - string: The hexadecimal digest.
+//   - error: An error if the algorithm is unknown or the file cannot be read.
+func FileDigest(path, algorithm string) (string, error) {
+	var h hash.Hash
+	switch algorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// PublishedDigest finds the digest of an image in a published checksum file,
+// in the format of sha256sum ("<digest>  <name>") or BSD ("SHA256 (<name>) = <digest>").
+// A file holding a single digest without a name, as the .sha256 files next
+// to SUSE downloads do, applies to any image.
+//
+// Parameters:
+//   - data: The checksum file.
+//   - fileName: The base name of the image.
+//
+// Returns:
+//   - string: The digest.
+//   - error: An error if the file does not list the image.
+func PublishedDigest(data []byte, fileName string) (string, error) {
+	var digests []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := bsdChecksumLine.FindStringSubmatch(line); m != nil {
+			if filepath.Base(m[1]) == fileName {
+				return m[2], nil
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 1 {
+			digests = append(digests, fields[0])
+			continue
+		}
+		if filepath.Base(strings.TrimPrefix(fields[1], "*")) == fileName {
+			return fields[0], nil
+		}
+	}
+	if len(digests) == 1 {
+		return digests[0], nil
+	}
+	return "", fmt.Errorf("the checksum file does not list %s", fileName)
+}
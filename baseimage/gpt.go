@@ -0,0 +1,82 @@
+package baseimage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// diskSectorSize is the logical sector size assumed for raw images.
+	diskSectorSize = 512
+	// gptMaxEntries bounds the number of partition entries read.
+	gptMaxEntries = 256
+)
+
+// readPartitions reads the partition table of a raw disk image.
+//
+// Parameters:
+//   - r: The image.
+//
+// Returns:
+//   - []string: The GPT partition names, or nil for an MBR-only disk.
+//   - error: An error if the image has neither a GPT nor an MBR signature.
+func readPartitions(r io.ReaderAt) ([]string, error) {
+	mbr := make([]byte, diskSectorSize)
+	if _, err := r.ReadAt(mbr, 0); err != nil {
+		return nil, err
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xAA {
+		return nil, fmt.Errorf("no partition table")
+	}
+
+	header := make([]byte, 92)
+	if _, err := r.ReadAt(header, diskSectorSize); err != nil || string(header[0:8]) != "EFI PART" {
+		return nil, nil
+	}
+	entriesLBA := binary.LittleEndian.Uint64(header[72:80])
+	count := binary.LittleEndian.Uint32(header[80:84])
+	entrySize := binary.LittleEndian.Uint32(header[84:88])
+	if entrySize < 128 || entrySize > 4096 || count > gptMaxEntries {
+		return nil, fmt.Errorf("malformed GPT header")
+	}
+
+	var names []string
+	entry := make([]byte, entrySize)
+	for i := uint32(0); i < count; i++ {
+		offset := int64(entriesLBA)*diskSectorSize + int64(i)*int64(entrySize)
+		if _, err := r.ReadAt(entry, offset); err != nil {
+			break
+		}
+		if isZero(entry[0:16]) {
+			continue
+		}
+		names = append(names, utf16Name(entry[56:128]))
+	}
+	return names, nil
+}
+
+// isZero reports whether every byte is zero.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// utf16Name decodes a NUL-padded UTF-16LE partition name.
+func utf16Name(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return strings.TrimSpace(string(utf16.Decode(units)))
+}
@@ -0,0 +1,200 @@
+package baseimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	// isoSectorSize is the ISO9660 logical sector size.
+	isoSectorSize = 2048
+	// isoMaxEntries bounds the number of directory entries read.
+	isoMaxEntries = 10000
+	// isoMaxDepth bounds the directory depth walked.
+	isoMaxDepth = 8
+	// osReleaseMaxSize bounds the size of an os-release file read.
+	osReleaseMaxSize = 64 * 1024
+)
+
+// isoImage holds what was read from an ISO9660 file system.
+type isoImage struct {
+	// label is the volume identifier.
+	label string
+	// files lists the file paths found.
+	files []string
+	// osRelease holds the fields of the first os-release file found.
+	osRelease map[string]string
+}
+
+// isoEntry is a directory record.
+type isoEntry struct {
+	name   string
+	extent uint32
+	size   uint32
+	dir    bool
+}
+
+// readISO reads the primary volume descriptor and walks the file tree.
+//
+// Parameters:
+//   - r: The image.
+//
+// Returns:
+//   - *isoImage: What was read.
+//   - error: An error if the image has no ISO9660 primary volume descriptor.
+func readISO(r io.ReaderAt) (*isoImage, error) {
+	pvd := make([]byte, isoSectorSize)
+	if _, err := r.ReadAt(pvd, 16*isoSectorSize); err != nil {
+		return nil, err
+	}
+	if pvd[0] != 1 || string(pvd[1:6]) != "CD001" {
+		return nil, fmt.Errorf("no ISO9660 primary volume descriptor")
+	}
+
+	img := &isoImage{label: strings.TrimSpace(string(pvd[40:72]))}
+	root, ok := parseISORecord(pvd[156:190])
+	if !ok {
+		return img, nil
+	}
+
+	type pending struct {
+		entry isoEntry
+		path  string
+		depth int
+	}
+	queue := []pending{{root, "", 0}}
+	count := 0
+	for len(queue) > 0 && count < isoMaxEntries {
+		dir := queue[0]
+		queue = queue[1:]
+		entries, err := readISODir(r, dir.entry)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			count++
+			p := dir.path + "/" + e.name
+			if e.dir {
+				if dir.depth+1 < isoMaxDepth {
+					queue = append(queue, pending{e, p, dir.depth + 1})
+				}
+				continue
+			}
+			img.files = append(img.files, p)
+			if img.osRelease == nil && isOSReleasePath(p) && e.size <= osReleaseMaxSize {
+				content := make([]byte, e.size)
+				if _, err := r.ReadAt(content, int64(e.extent)*isoSectorSize); err == nil {
+					img.osRelease = parseOSRelease(bytes.NewReader(content))
+				}
+			}
+		}
+	}
+	return img, nil
+}
+
+// isOSReleasePath reports whether a path names an os-release file.
+func isOSReleasePath(p string) bool {
+	p = strings.ToLower(p)
+	return strings.HasSuffix(p, "/etc/os-release") || strings.HasSuffix(p, "/usr/lib/os-release")
+}
+
+// readISODir reads the records of a directory.
+//
+// Parameters:
+//   - r: The image.
+//   - dir: The directory record.
+//
+// Returns:
+//   - []isoEntry: The entries, excluding "." and "..".
+//   - error: An error if the directory cannot be read.
+func readISODir(r io.ReaderAt, dir isoEntry) ([]isoEntry, error) {
+	if dir.size > 16*1024*1024 {
+		return nil, fmt.Errorf("directory too large")
+	}
+	data := make([]byte, dir.size)
+	if _, err := r.ReadAt(data, int64(dir.extent)*isoSectorSize); err != nil {
+		return nil, err
+	}
+
+	var entries []isoEntry
+	for offset := 0; offset < len(data); {
+		length := int(data[offset])
+		if length == 0 {
+			// Records do not cross sector boundaries; skip the padding.
+			offset = (offset/isoSectorSize + 1) * isoSectorSize
+			continue
+		}
+		if offset+length > len(data) {
+			break
+		}
+		if e, ok := parseISORecord(data[offset : offset+length]); ok && e.name != "" {
+			entries = append(entries, e)
+		}
+		offset += length
+	}
+	return entries, nil
+}
+
+// parseISORecord decodes a directory record, preferring its Rock Ridge name.
+//
+// Parameters:
+//   - rec: The record bytes.
+//
+// Returns:
+//   - isoEntry: The entry; its name is empty for "." and "..".
+//   - bool: False if the record is malformed.
+func parseISORecord(rec []byte) (isoEntry, bool) {
+	if len(rec) < 34 {
+		return isoEntry{}, false
+	}
+	nameLen := int(rec[32])
+	if 33+nameLen > len(rec) {
+		return isoEntry{}, false
+	}
+	e := isoEntry{
+		extent: binary.LittleEndian.Uint32(rec[2:6]),
+		size:   binary.LittleEndian.Uint32(rec[10:14]),
+		dir:    rec[25]&0x02 != 0,
+	}
+	raw := rec[33 : 33+nameLen]
+	if nameLen == 1 && (raw[0] == 0 || raw[0] == 1) {
+		return e, true
+	}
+
+	e.name = strings.ToLower(strings.TrimSuffix(strings.SplitN(string(raw), ";", 2)[0], "."))
+	suStart := 33 + nameLen
+	if nameLen%2 == 0 {
+		suStart++
+	}
+	if rr := rockRidgeName(rec[min(suStart, len(rec)):]); rr != "" {
+		e.name = rr
+	}
+	e.name = path.Base(e.name)
+	return e, true
+}
+
+// rockRidgeName extracts the alternate name from a System Use area.
+//
+// Parameters:
+//   - su: The System Use bytes of a directory record.
+//
+// Returns:
+//   - string: The Rock Ridge name, or empty if there is none.
+func rockRidgeName(su []byte) string {
+	var name string
+	for len(su) >= 4 {
+		length := int(su[2])
+		if length < 4 || length > len(su) {
+			break
+		}
+		if string(su[0:2]) == "NM" && length > 5 {
+			name += string(su[5:length])
+		}
+		su = su[length:]
+	}
+	return name
+}
@@ -0,0 +1,120 @@
+package baseimage
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ignoredSuffixes lists the extensions of files that sit next to base
+// images but are not images themselves.
+var ignoredSuffixes = []string{".part", ".sha256", ".sha512", ".asc", ".sig", ".txt", ".md"}
+
+// Entry is a base image found in a directory.
+type Entry struct {
+	// Name is the file name, the value of image.baseImage that refers to it.
+	Name string `json:"name"`
+	// Size is the file size in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the SHA-256 digest of the file.
+	SHA256 string `json:"sha256,omitempty"`
+	// Format is the detected image format, "iso" or "raw".
+	Format Format `json:"format,omitempty"`
+	// Arch is the detected architecture, if known.
+	Arch string `json:"arch,omitempty"`
+	// OSVersion is the detected OS version, if known.
+	OSVersion string `json:"osVersion,omitempty"`
+	// Error explains why the file could not be inspected.
+	Error string `json:"error,omitempty"`
+}
+
+// digestKey identifies a version of a file, so its digest is only
+// recomputed when it changes.
+type digestKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// digestCache holds the SHA-256 digests of listed images, since hashing
+// multi-gigabyte images on every listing would be slow.
+var digestCache = struct {
+	sync.Mutex
+	digests map[digestKey]string
+}{digests: map[digestKey]string{}}
+
+// List finds the base images in a directory and inspects them.
+//
+// Checksum files, signatures and partial downloads are skipped. Files that
+// are neither ISO nor raw images are listed with an Error.
+//
+// Parameters:
+//   - dir: The directory, such as the base-images directory of an image
+//     configuration directory. Subdirectories are not searched.
+//
+// Returns:
+//   - []Entry: The images, sorted by name.
+//   - error: An error if the directory cannot be read.
+func List(dir string) ([]Entry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	for _, f := range files {
+		if !f.Type().IsRegular() || strings.HasPrefix(f.Name(), ".") || hasIgnoredSuffix(f.Name()) {
+			continue
+		}
+		entries = append(entries, listEntry(filepath.Join(dir, f.Name())))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// listEntry inspects one file of a listing.
+func listEntry(path string) Entry {
+	entry := Entry{Name: filepath.Base(path)}
+	st, err := os.Stat(path)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Size = st.Size()
+
+	info, err := Inspect(path)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Format, entry.Arch, entry.OSVersion = info.Format, info.Arch, info.OSVersion
+
+	key := digestKey{path: path, size: st.Size(), modTime: st.ModTime()}
+	digestCache.Lock()
+	digest, ok := digestCache.digests[key]
+	digestCache.Unlock()
+	if !ok {
+		if digest, err = FileDigest(path, "sha256"); err != nil {
+			entry.Error = err.Error()
+			return entry
+		}
+		digestCache.Lock()
+		digestCache.digests[key] = digest
+		digestCache.Unlock()
+	}
+	entry.SHA256 = digest
+	return entry
+}
+
+// hasIgnoredSuffix tells whether a file name has one of ignoredSuffixes.
+func hasIgnoredSuffix(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range ignoredSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+// Package definition provides typed Go structs for the EIB configuration.
+//
+// The structs are generated from the newest embedded schema by structgen and
+// mirror its "$defs", so Go programs embedding this module can build and
+// inspect configurations with compile-time checks instead of nested maps.
+package definition
+
+//go:generate go run ../schema/structgen -schema-dir ../schema/versions -package definition -out types_gen.go
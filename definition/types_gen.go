@@ -0,0 +1,231 @@
+// Code generated by structgen from schema apiVersion 1.3; DO NOT EDIT.
+
+package definition
+
+// AddRepo mirrors the "AddRepo" schema definition.
+type AddRepo struct {
+	Priority *int   `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Unsigned bool   `json:"unsigned,omitempty" yaml:"unsigned,omitempty"`
+	URL      string `json:"url" yaml:"url"`
+}
+
+// ContainerImage mirrors the "ContainerImage" schema definition.
+type ContainerImage struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// Definition mirrors the "Definition" schema definition.
+//
+// Edge Image Builder Configuration.
+type Definition struct {
+	APIVersion               string                    `json:"apiVersion" yaml:"apiVersion"`
+	EmbeddedArtifactRegistry *EmbeddedArtifactRegistry `json:"embeddedArtifactRegistry,omitempty" yaml:"embeddedArtifactRegistry,omitempty"`
+	Image                    Image                     `json:"image" yaml:"image"`
+	Kubernetes               *Kubernetes               `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
+	OperatingSystem          OperatingSystem           `json:"operatingSystem" yaml:"operatingSystem"`
+}
+
+// EmbeddedArtifactRegistry mirrors the "EmbeddedArtifactRegistry" schema definition.
+type EmbeddedArtifactRegistry struct {
+	Images     []ContainerImage `json:"images,omitempty" yaml:"images,omitempty"`
+	Registries []Registry       `json:"registries,omitempty" yaml:"registries,omitempty"`
+}
+
+// Helm mirrors the "Helm" schema definition.
+type Helm struct {
+	Charts       []HelmChart      `json:"charts,omitempty" yaml:"charts,omitempty"`
+	Repositories []HelmRepository `json:"repositories,omitempty" yaml:"repositories,omitempty"`
+}
+
+// HelmAuthentication mirrors the "HelmAuthentication" schema definition.
+type HelmAuthentication struct {
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+}
+
+// HelmChart mirrors the "HelmChart" schema definition.
+//
+// Helm chart configuration.
+type HelmChart struct {
+	APIVersions           []string `json:"apiVersions,omitempty" yaml:"apiVersions,omitempty"`
+	CreateNamespace       bool     `json:"createNamespace,omitempty" yaml:"createNamespace,omitempty"`
+	InstallationNamespace string   `json:"installationNamespace,omitempty" yaml:"installationNamespace,omitempty"`
+	Name                  string   `json:"name" yaml:"name"`
+	ReleaseName           string   `json:"releaseName,omitempty" yaml:"releaseName,omitempty"`
+	// Name of the repository to use. Must match a repository defined in 'repositories'. Required. DO NOT use 'repoUrl'.
+	RepositoryName  string `json:"repositoryName" yaml:"repositoryName"`
+	TargetNamespace string `json:"targetNamespace,omitempty" yaml:"targetNamespace,omitempty"`
+	ValuesFile      string `json:"valuesFile,omitempty" yaml:"valuesFile,omitempty"`
+	Version         string `json:"version" yaml:"version"`
+}
+
+// HelmRepository mirrors the "HelmRepository" schema definition.
+//
+// Helm repository configuration.
+type HelmRepository struct {
+	Authentication *HelmAuthentication `json:"authentication,omitempty" yaml:"authentication,omitempty"`
+	CAFile         string              `json:"caFile,omitempty" yaml:"caFile,omitempty"`
+	Name           string              `json:"name" yaml:"name"`
+	PlainHTTP      bool                `json:"plainHTTP,omitempty" yaml:"plainHTTP,omitempty"`
+	SkipTLSVerify  bool                `json:"skipTLSVerify,omitempty" yaml:"skipTLSVerify,omitempty"`
+	URL            string              `json:"url" yaml:"url"`
+}
+
+// Image mirrors the "Image" schema definition.
+type Image struct {
+	// Target architecture. Must be 'x86_64' or 'aarch64'.
+	Arch string `json:"arch" yaml:"arch"`
+	// Name of the base image to use (e.g., 'sles15sp5-x86_64'). Required.
+	BaseImage string `json:"baseImage" yaml:"baseImage"`
+	// Type of image to build. Must be 'iso' or 'raw'.
+	ImageType string `json:"imageType" yaml:"imageType"`
+	// Name of the output image file. Required.
+	OutputImageName string `json:"outputImageName" yaml:"outputImageName"`
+}
+
+// IsoConfiguration mirrors the "IsoConfiguration" schema definition.
+type IsoConfiguration struct {
+	InstallDevice string `json:"installDevice,omitempty" yaml:"installDevice,omitempty"`
+}
+
+// Kubernetes mirrors the "Kubernetes" schema definition.
+//
+// Kubernetes configuration.
+type Kubernetes struct {
+	Helm      *Helm      `json:"helm,omitempty" yaml:"helm,omitempty"`
+	Manifests *Manifests `json:"manifests,omitempty" yaml:"manifests,omitempty"`
+	// Network config. Example: { 'apiVIP': '1.2.3.4' }
+	Network *Network `json:"network,omitempty" yaml:"network,omitempty"`
+	// List of nodes. Required for multi-node. Example: [{ 'hostname': 'n1', 'type': 'server' }]
+	Nodes   []Node `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// Manifests mirrors the "Manifests" schema definition.
+type Manifests struct {
+	URLs []string `json:"urls,omitempty" yaml:"urls,omitempty"`
+}
+
+// Network mirrors the "Network" schema definition.
+type Network struct {
+	APIHost string `json:"apiHost,omitempty" yaml:"apiHost,omitempty"`
+	APIVIP  string `json:"apiVIP,omitempty" yaml:"apiVIP,omitempty"`
+	APIVIP6 string `json:"apiVIP6,omitempty" yaml:"apiVIP6,omitempty"`
+}
+
+// Node mirrors the "Node" schema definition.
+//
+// Node configuration. DO NOT include IP addresses here (they are not supported).
+type Node struct {
+	Hostname    string `json:"hostname" yaml:"hostname"`
+	Initializer bool   `json:"initializer,omitempty" yaml:"initializer,omitempty"`
+	Type        string `json:"type" yaml:"type"`
+}
+
+// NtpConfiguration mirrors the "NtpConfiguration" schema definition.
+type NtpConfiguration struct {
+	ForceWait bool `json:"forceWait,omitempty" yaml:"forceWait,omitempty"`
+	// List of NTP pool addresses. Must be a list of strings.
+	Pools []string `json:"pools,omitempty" yaml:"pools,omitempty"`
+	// List of NTP server addresses (e.g., ['pool.ntp.org']). Must be a list of strings.
+	Servers []string `json:"servers,omitempty" yaml:"servers,omitempty"`
+}
+
+// OperatingSystem mirrors the "OperatingSystem" schema definition.
+//
+// Operating System configuration.
+type OperatingSystem struct {
+	EnableFIPS bool                   `json:"enableFIPS,omitempty" yaml:"enableFIPS,omitempty"`
+	Groups     []OperatingSystemGroup `json:"groups,omitempty" yaml:"groups,omitempty"`
+	// ISO configuration object. MUST be nested here. Example: { 'installDevice': '/dev/sda' }
+	ISOConfiguration *IsoConfiguration `json:"isoConfiguration,omitempty" yaml:"isoConfiguration,omitempty"`
+	KernelArgs       []string          `json:"kernelArgs,omitempty" yaml:"kernelArgs,omitempty"`
+	Keymap           string            `json:"keymap,omitempty" yaml:"keymap,omitempty"`
+	Packages         *Packages         `json:"packages,omitempty" yaml:"packages,omitempty"`
+	Proxy            *Proxy            `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// RAW configuration object. MUST be nested here. Example: { 'diskSize': '10G' }
+	RawConfiguration *RawConfiguration `json:"rawConfiguration,omitempty" yaml:"rawConfiguration,omitempty"`
+	Suma             *Suma             `json:"suma,omitempty" yaml:"suma,omitempty"`
+	Systemd          *Systemd          `json:"systemd,omitempty" yaml:"systemd,omitempty"`
+	// Time configuration. Example: { 'timezone': 'UTC', 'ntp': { 'servers': ['pool.ntp.org'] } }
+	Time *Time `json:"time,omitempty" yaml:"time,omitempty"`
+	// List of users. Example: [{ 'username': 'user', 'password': '...' }]
+	Users []OperatingSystemUser `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+// OperatingSystemGroup mirrors the "OperatingSystemGroup" schema definition.
+type OperatingSystemGroup struct {
+	GID  *int   `json:"gid,omitempty" yaml:"gid,omitempty"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// OperatingSystemUser mirrors the "OperatingSystemUser" schema definition.
+//
+// User configuration.
+type OperatingSystemUser struct {
+	CreateHomeDir bool `json:"createHomeDir,omitempty" yaml:"createHomeDir,omitempty"`
+	// Encrypted password for the user. Required if sshKey is not provided.
+	EncryptedPassword string   `json:"encryptedPassword,omitempty" yaml:"encryptedPassword,omitempty"`
+	PrimaryGroup      string   `json:"primaryGroup,omitempty" yaml:"primaryGroup,omitempty"`
+	SecondaryGroups   []string `json:"secondaryGroups,omitempty" yaml:"secondaryGroups,omitempty"`
+	// List of SSH keys for the user. Required if encryptedPassword is not provided.
+	SSHKeys []string `json:"sshKeys,omitempty" yaml:"sshKeys,omitempty"`
+	UID     *int     `json:"uid,omitempty" yaml:"uid,omitempty"`
+	// Username for the user. Required.
+	Username string `json:"username" yaml:"username"`
+}
+
+// Packages mirrors the "Packages" schema definition.
+type Packages struct {
+	AdditionalRepos     []AddRepo `json:"additionalRepos,omitempty" yaml:"additionalRepos,omitempty"`
+	EnableExtras        bool      `json:"enableExtras,omitempty" yaml:"enableExtras,omitempty"`
+	NoGPGCheck          bool      `json:"noGPGCheck,omitempty" yaml:"noGPGCheck,omitempty"`
+	PackageList         []string  `json:"packageList,omitempty" yaml:"packageList,omitempty"`
+	SCCRegistrationCode string    `json:"sccRegistrationCode,omitempty" yaml:"sccRegistrationCode,omitempty"`
+}
+
+// Proxy mirrors the "Proxy" schema definition.
+type Proxy struct {
+	HTTPProxy  string   `json:"httpProxy,omitempty" yaml:"httpProxy,omitempty"`
+	HTTPSProxy string   `json:"httpsProxy,omitempty" yaml:"httpsProxy,omitempty"`
+	NoProxy    []string `json:"noProxy,omitempty" yaml:"noProxy,omitempty"`
+}
+
+// RawConfiguration mirrors the "RawConfiguration" schema definition.
+type RawConfiguration struct {
+	DiskSize                 string `json:"diskSize,omitempty" yaml:"diskSize,omitempty"`
+	ExpandEncryptedPartition bool   `json:"expandEncryptedPartition,omitempty" yaml:"expandEncryptedPartition,omitempty"`
+	LUKSKey                  string `json:"luksKey,omitempty" yaml:"luksKey,omitempty"`
+}
+
+// Registry mirrors the "Registry" schema definition.
+type Registry struct {
+	Authentication *RegistryAuthentication `json:"authentication,omitempty" yaml:"authentication,omitempty"`
+	URI            string                  `json:"uri,omitempty" yaml:"uri,omitempty"`
+}
+
+// RegistryAuthentication mirrors the "RegistryAuthentication" schema definition.
+type RegistryAuthentication struct {
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+}
+
+// Suma mirrors the "Suma" schema definition.
+type Suma struct {
+	ActivationKey string `json:"activationKey" yaml:"activationKey"`
+	Host          string `json:"host" yaml:"host"`
+}
+
+// Systemd mirrors the "Systemd" schema definition.
+type Systemd struct {
+	Disable []string `json:"disable,omitempty" yaml:"disable,omitempty"`
+	Enable  []string `json:"enable,omitempty" yaml:"enable,omitempty"`
+}
+
+// Time mirrors the "Time" schema definition.
+type Time struct {
+	// NTP config. 'servers' and 'pools' are lists of STRINGS. Example: { 'servers': ['1.2.3.4'] }
+	NTP *NtpConfiguration `json:"ntp,omitempty" yaml:"ntp,omitempty"`
+	// Timezone (e.g., 'UTC'). Note: field name is lowercase 'timezone'.
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
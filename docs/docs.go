@@ -0,0 +1,120 @@
+// Package docs provides curated Edge Image Builder documentation excerpts.
+//
+// Each excerpt covers one configuration section and is exposed by the MCP
+// server as a resource, so that clients can ground their answers in the
+// real EIB semantics of each field.
+package docs
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed sections/*.md
+var sectionFiles embed.FS
+
+// URIPrefix is the prefix of the resource URI of every excerpt.
+const URIPrefix = "eib://docs/"
+
+// Doc is the documentation excerpt of a configuration section.
+type Doc struct {
+	// Section is the dotted path of the documented section, e.g. "kubernetes.helm".
+	Section string
+	// Title is the excerpt title.
+	Title string
+	// Description is the first paragraph of the excerpt.
+	Description string
+	// Content is the full Markdown excerpt.
+	Content string
+}
+
+// URI returns the resource URI of the excerpt.
+//
+// Returns:
+//   - string: The URI, e.g. "eib://docs/kubernetes.helm".
+func (d Doc) URI() string {
+	return URIPrefix + d.Section
+}
+
+// docs holds the excerpts keyed by section.
+var docs = mustLoad()
+
+// mustLoad parses the embedded excerpts.
+//
+// It panics if the embedded directory cannot be read, which can only happen
+// if the binary was built incorrectly.
+//
+// Returns:
+//   - map[string]Doc: The excerpts keyed by section.
+func mustLoad() map[string]Doc {
+	entries, err := sectionFiles.ReadDir("sections")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read embedded docs: %v", err))
+	}
+	result := map[string]Doc{}
+	for _, entry := range entries {
+		raw, err := sectionFiles.ReadFile("sections/" + entry.Name())
+		if err != nil {
+			panic(fmt.Sprintf("failed to read embedded doc %s: %v", entry.Name(), err))
+		}
+		doc := parse(strings.TrimSuffix(entry.Name(), ".md"), string(raw))
+		result[doc.Section] = doc
+	}
+	return result
+}
+
+// parse extracts the title and description of an excerpt.
+//
+// Parameters:
+//   - section: The documented section.
+//   - content: The Markdown content, starting with a "# Title" line.
+//
+// Returns:
+//   - Doc: The parsed excerpt.
+func parse(section, content string) Doc {
+	doc := Doc{Section: section, Title: section, Content: content}
+	paragraphs := strings.Split(strings.TrimSpace(content), "\n\n")
+	if len(paragraphs) > 0 && strings.HasPrefix(paragraphs[0], "# ") {
+		doc.Title = strings.TrimPrefix(paragraphs[0], "# ")
+		paragraphs = paragraphs[1:]
+	}
+	if len(paragraphs) > 0 {
+		doc.Description = strings.Join(strings.Fields(paragraphs[0]), " ")
+	}
+	return doc
+}
+
+// List returns all excerpts.
+//
+// Returns:
+//   - []Doc: The excerpts ordered by section.
+func List() []Doc {
+	sections := make([]string, 0, len(docs))
+	for s := range docs {
+		sections = append(sections, s)
+	}
+	sort.Strings(sections)
+	result := make([]Doc, 0, len(sections))
+	for _, s := range sections {
+		result = append(result, docs[s])
+	}
+	return result
+}
+
+// Lookup finds an excerpt by its resource URI.
+//
+// Parameters:
+//   - uri: The resource URI, e.g. "eib://docs/kubernetes.helm".
+//
+// Returns:
+//   - Doc: The excerpt, if found.
+//   - bool: True if the excerpt exists.
+func Lookup(uri string) (Doc, bool) {
+	if !strings.HasPrefix(uri, URIPrefix) {
+		return Doc{}, false
+	}
+	doc, ok := docs[strings.TrimPrefix(uri, URIPrefix)]
+	return doc, ok
+}
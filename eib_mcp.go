@@ -5,21 +5,377 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/e-minguez/eib-mcp/httpcache"
+	"github.com/e-minguez/eib-mcp/i18n"
 	"github.com/e-minguez/eib-mcp/mcp"
+	"github.com/e-minguez/eib-mcp/otlp"
+	"github.com/e-minguez/eib-mcp/policy"
+	"github.com/e-minguez/eib-mcp/preset"
+	"github.com/e-minguez/eib-mcp/schema"
+	"github.com/e-minguez/eib-mcp/tool"
 )
 
 // main initializes and runs the EIB MCP server.
 //
-// It creates a new Server instance connected to os.Stdin and os.Stdout,
-// and starts the server loop. If the server encounters a fatal error,
-// it prints the error to os.Stderr and exits with status code 1.
+// It parses the command line flags, optionally refreshes the schemas from a
+// remote location, then creates a new Server instance connected to os.Stdin
+// and os.Stdout and starts the server loop. The process exits with status 0
+// when the client closes standard input, or once the requests in progress
+// have drained after SIGTERM or SIGINT. Startup failures exit with status 1
+// and invalid flags with 2; stream failures use the codes of mcp.ExitCode.
 func main() {
-	server := mcp.NewServer(os.Stdin, os.Stdout)
-	if err := server.Serve(); err != nil {
+	schemaURL := flag.String("schema-url", "", "URL of a JSON schema to fetch at startup (enables remote schema refresh)")
+	schemaSHA256 := flag.String("schema-sha256", "", "expected SHA-256 of the remote schema (-schema-url requires it or -schema-sha256-url)")
+	schemaSHA256URL := flag.String("schema-sha256-url", "", "URL of the SHA-256 of the remote schema, used without -schema-sha256; serve it from another origin than -schema-url")
+	schemaCacheDir := flag.String("schema-cache-dir", "", "directory where verified remote schemas are cached (defaults to the user cache directory)")
+	schemaOverlay := flag.String("schema-overlay", "", "path to a JSON schema overlay merged into every schema (e.g. company policies)")
+	validationMode := flag.String("validation-mode", string(tool.ValidationStrict), "how unknown fields are handled: strict (reject) or permissive (pass through with warnings)")
+	presetsDir := flag.String("presets-dir", "", "directory of additional configuration presets (*.yaml), overriding embedded presets with the same name")
+	targetRelease := flag.String("target-release", "", "default EIB release configurations must be compatible with, e.g. 1.1 (empty disables the check)")
+	eibBinary := flag.String("eib-binary", "", "path of a local eib binary used to run `eib validate` on generated configurations (opt-in)")
+	eibImage := flag.String("eib-image", "", "EIB container image used to run `eib validate` on generated configurations (opt-in, takes precedence over -eib-binary)")
+	eibRuntime := flag.String("eib-runtime", "podman", "container runtime used with -eib-image")
+	trivyBinary := flag.String("trivy-binary", "", "path of a trivy binary used to look up the known vulnerabilities of embedded images (opt-in)")
+	trivyServer := flag.String("trivy-server", "", "URL of a Trivy server holding the vulnerability database, e.g. http://trivy:4954 (opt-in, uses the trivy binary on PATH unless -trivy-binary is set)")
+	helmBinary := flag.String("helm-binary", "helm", "path of the helm binary render_helm_chart runs `helm template` with")
+	httpCacheDir := flag.String("http-cache-dir", "", "directory where downloads of network validations are cached (defaults to the user cache directory)")
+	httpCacheTTL := flag.Duration("http-cache-ttl", httpcache.DefaultTTL, "how long downloads of network validations are reused (0 disables caching)")
+	offline := flag.Bool("offline", false, "disable every network-dependent validation and the remote schema refresh, for air-gapped hosts")
+	allowedDirs := flag.String("allowed-dirs", "", "list of directories, separated by the OS path list separator, that tools may read or write (empty allows every path)")
+	disableFeatures := flag.String("disable-features", os.Getenv("EIB_MCP_DISABLE_FEATURES"), "comma separated features whose tools are hidden and rejected: "+strings.Join(mcp.Features(), ", ")+" (defaults to $EIB_MCP_DISABLE_FEATURES)")
+	serverName := flag.String("server-name", mcp.DefaultServerInfo.Name, "server name advertised to clients")
+	serverTitle := flag.String("server-title", "", "human readable server name advertised to clients")
+	serverVersion := flag.String("server-version", mcp.DefaultServerInfo.Version, "server version advertised to clients")
+	instructionsFile := flag.String("instructions-file", "", "path to a text file of usage instructions advertised to clients")
+	secretEnvPrefix := flag.String("secret-env-prefix", tool.DefaultSecretEnvPrefix, "prefix of environment variables readable by env:// secret references (empty disables them)")
+	baseImagesDir := flag.String("base-images-dir", "", "directory list_base_images lists when the client names none")
+	signingKey := flag.String("signing-key", "", "path to an armored OpenPGP private key generated definitions can be signed with (passphrase from $EIB_MCP_SIGNING_PASSPHRASE)")
+	envPrefix := flag.String("env-var-prefix", tool.DefaultEnvPrefix, "prefix of environment variables usable as ${NAME} values in configurations (empty disables)")
+	passwordPolicy := flag.String("password-policy", string(tool.PasswordPolicyOff), "how plaintext passwords violating the password policy are handled: off, warn or enforce")
+	passwordMinLength := flag.Int("password-min-length", 12, "minimum length of plaintext passwords under -password-policy")
+	passwordMinClasses := flag.Int("password-min-classes", 3, "minimum number of character classes (lower, upper, digits, symbols) of plaintext passwords under -password-policy")
+	passwordDenyList := flag.String("password-deny-list", "", "file of passwords, one per line, denied under -password-policy in addition to built-in common defaults")
+	sourcePolicy := flag.String("source-policy", "", "path to a YAML file of the approved Helm repositories, container registries and RPM repositories")
+	orgPolicy := flag.String("org-policy", "", "path to a YAML file of organization policies, CEL expressions every configuration must satisfy")
+	rulePolicy := flag.String("rule-policy", "", "path to a YAML file changing the severity of validation rules (error, warning, info or off), by rule ID")
+	drainTimeout := flag.Duration("drain-timeout", 30*time.Second, "on SIGTERM or SIGINT, how long to wait for requests in progress before terminating them")
+	idleTimeout := flag.Duration("idle-timeout", 0, "under systemd socket activation, exit after this long without connections (0 serves until stopped)")
+	adminAddr := flag.String("admin-addr", "", "host:port of the admin endpoints (/healthz, /metrics, /debug/runtime), e.g. 127.0.0.1:7601 (empty disables)")
+	otlpEndpoint := flag.String("otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP/HTTP collector URL traces are exported to, e.g. http://collector:4318 (defaults to OTEL_EXPORTER_OTLP_ENDPOINT; empty disables tracing)")
+	otlpHeaders := flag.String("otlp-headers", os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), "comma-separated key=value HTTP headers sent to the OTLP collector (defaults to OTEL_EXPORTER_OTLP_HEADERS)")
+	language := flag.String("language", i18n.English, fmt.Sprintf("language of tool descriptions, error messages and warnings (%s); clients can override it with the \"language\" initialization option", strings.Join(i18n.Languages(), ", ")))
+	slowCallThreshold := flag.Duration("slow-call-threshold", 0, "log a warning with the slowest steps of tool calls taking longer than this, e.g. 5s (0 disables)")
+	enablePprof := flag.Bool("pprof", false, "serve the net/http/pprof profiles under /debug/pprof/ on -admin-addr")
+	maxRequestSize := flag.Int64("max-request-size", mcp.DefaultMaxRequestSize, "largest request in bytes; larger requests are rejected with an error (raise it for configurations with thousands of images or manifests)")
+	flag.Parse()
+
+	mode, err := tool.ParseValidationMode(*validationMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid flag: %v\n", err)
+		os.Exit(2)
+	}
+	disabled, err := mcp.ParseFeatures(*disableFeatures)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid flag: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *enablePprof && *adminAddr == "" {
+		fmt.Fprintln(os.Stderr, "Invalid flag: -pprof requires -admin-addr")
+		os.Exit(2)
+	}
+	metrics := mcp.NewMetrics()
+	if *adminAddr != "" {
+		if err := startAdmin(*adminAddr, *enablePprof, metrics); err != nil {
+			fmt.Fprintf(os.Stderr, "Admin error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *schemaURL != "" && *schemaSHA256 == "" && *schemaSHA256URL == "" {
+		fmt.Fprintln(os.Stderr, "Invalid flag: -schema-url requires -schema-sha256 or -schema-sha256-url")
+		os.Exit(2)
+	}
+	if *offline && *schemaURL != "" {
+		fmt.Fprintln(os.Stderr, "Schema refresh skipped: offline mode")
+	}
+	loadSchemas(schema.RemoteOptions{URL: *schemaURL, SHA256: *schemaSHA256, ChecksumURL: *schemaSHA256URL, CacheDir: *schemaCacheDir}, !*offline)
+	if *schemaOverlay != "" {
+		if err := loadOverlay(*schemaOverlay); err != nil {
+			fmt.Fprintf(os.Stderr, "Schema overlay error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *presetsDir != "" {
+		if _, err := preset.LoadDir(*presetsDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Preset error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var sandbox *tool.Sandbox
+	if *allowedDirs != "" {
+		if sandbox, err = tool.NewSandbox(filepath.SplitList(*allowedDirs)); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid flag: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	info := mcp.ServerInfo{Name: *serverName, Title: *serverTitle, Version: *serverVersion}
+	if *instructionsFile != "" {
+		raw, err := os.ReadFile(*instructionsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Instructions error: %v\n", err)
+			os.Exit(1)
+		}
+		info.Instructions = strings.TrimSpace(string(raw))
+	}
+
+	if *targetRelease != "" && !schema.IsSupportedVersion(*targetRelease) {
+		fmt.Fprintf(os.Stderr, "Invalid flag: unknown EIB release %q\n", *targetRelease)
+		os.Exit(2)
+	}
+
+	opts := tool.Options{Mode: mode, EnvPrefix: *envPrefix, TargetRelease: *targetRelease, HTTP: newHTTPCache(*httpCacheDir, *httpCacheTTL), Offline: *offline, Sandbox: sandbox}
+	opts.SecretEnvPrefix = *secretEnvPrefix
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" {
+		opts.Vault = &tool.VaultClient{Addr: addr, Token: os.Getenv("VAULT_TOKEN"), Namespace: os.Getenv("VAULT_NAMESPACE")}
+	}
+	if *signingKey != "" {
+		raw, err := os.ReadFile(*signingKey)
+		if err == nil {
+			opts.Signer, err = tool.NewSigner(raw, os.Getenv("EIB_MCP_SIGNING_PASSPHRASE"))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Signing key error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if mode := tool.PasswordPolicyMode(*passwordPolicy); mode != tool.PasswordPolicyOff {
+		if opts.PasswordPolicy, err = tool.NewPasswordPolicy(mode, *passwordMinLength, *passwordMinClasses, *passwordDenyList); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid flag: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	opts.Rules = tool.DefaultRules()
+	if *sourcePolicy != "" {
+		sources, err := tool.LoadSourcePolicy(*sourcePolicy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Source policy error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Rules = append(opts.Rules, sources.Rules()...)
+	}
+	if *orgPolicy != "" {
+		policies, err := policy.Load(*orgPolicy, opts.Rules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Policy error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Rules = append(opts.Rules, policies...)
+	}
+	if *rulePolicy != "" {
+		if opts.RulePolicy, err = tool.LoadRulePolicy(*rulePolicy, opts.Rules); err != nil {
+			fmt.Fprintf(os.Stderr, "Rule policy error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *eibBinary != "" || *eibImage != "" {
+		opts.EIB = &tool.EIBValidator{Binary: *eibBinary, Image: *eibImage, Runtime: *eibRuntime}
+	}
+	opts.Helm = &tool.HelmRenderer{Binary: *helmBinary}
+	if *trivyBinary != "" || *trivyServer != "" {
+		opts.Advisories = &tool.AdvisoryScanner{Binary: *trivyBinary, Server: *trivyServer}
+	}
+
+	serverOpts := []mcp.Option{mcp.WithToolOptions(opts), mcp.WithDisabledFeatures(disabled...), mcp.WithServerInfo(info), mcp.WithBaseImagesDir(*baseImagesDir), mcp.WithMaxRequestSize(*maxRequestSize), mcp.WithMetrics(metrics), mcp.WithSlowCallThreshold(*slowCallThreshold)}
+	catalog, err := i18n.Lookup(*language)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid flag: %v\n", err)
+		os.Exit(2)
+	}
+	serverOpts = append(serverOpts, mcp.WithLanguage(catalog))
+	var exporter *otlp.Exporter
+	if *otlpEndpoint != "" {
+		headers, err := otlp.ParseHeaders(*otlpHeaders)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid flag: %v\n", err)
+			os.Exit(2)
+		}
+		if exporter, err = otlp.NewExporter(*otlpEndpoint, info.Name, headers); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid flag: %v\n", err)
+			os.Exit(2)
+		}
+		serverOpts = append(serverOpts, mcp.WithTraceExporter(exporter))
+	}
+	listeners, err := mcp.ActivationListeners()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
 		os.Exit(1)
 	}
+
+	err = serve(listeners, serverOpts, *idleTimeout, *drainTimeout)
+	if exporter != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if exporter.Shutdown(ctx) != nil {
+			fmt.Fprintln(os.Stderr, "OTLP export: the last spans could not be sent in time")
+		}
+		cancel()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		os.Exit(mcp.ExitCode(err))
+	}
+}
+
+// serve runs the server on the sockets passed by systemd or, without any, on
+// the standard streams, until the input ends or the requests in progress
+// have drained after a shutdown signal.
+//
+// Parameters:
+//   - listeners: The socket activation listeners, or nil.
+//   - serverOpts: The options of every session.
+//   - idle: The idle timeout of socket activation.
+//   - drain: How long to wait for requests in progress on shutdown.
+//
+// Returns:
+//   - error: The error to report, mapped to an exit code by mcp.ExitCode.
+func serve(listeners []net.Listener, serverOpts []mcp.Option, idle, drain time.Duration) error {
+	shutdown := shutdownSignals()
+	if listeners != nil {
+		newServer := func(in io.Reader, out io.Writer) *mcp.Server {
+			return mcp.NewServer(in, out, serverOpts...)
+		}
+		return mcp.ServeListeners(shutdown, listeners, idle, drain, newServer)
+	}
+
+	server := mcp.NewServer(os.Stdin, os.Stdout, serverOpts...)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve() }()
+	select {
+	case err := <-served:
+		return err
+	case <-shutdown.Done():
+		ctx, cancel := context.WithTimeout(context.Background(), drain)
+		defer cancel()
+		return server.Drain(ctx)
+	}
+}
+
+// shutdownSignals returns a context that ends on the first SIGTERM or
+// SIGINT, which starts draining the requests in progress. A second signal
+// terminates the process at once with mcp.ExitDrainTimeout.
+//
+// Returns:
+//   - context.Context: The context ending on shutdown.
+func shutdownSignals() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-signals
+		fmt.Fprintf(os.Stderr, "Received %v, draining requests in progress (send it again to terminate at once)\n", sig)
+		cancel()
+		<-signals
+		fmt.Fprintln(os.Stderr, "Terminating requests in progress")
+		os.Exit(mcp.ExitDrainTimeout)
+	}()
+	return ctx
+}
+
+// loadSchemas registers previously cached remote schemas and refreshes the
+// schema from its URL.
+//
+// Nothing is loaded without a URL, so that schemas cached for a remote
+// source no longer configured are not used. Failures are reported on
+// os.Stderr but are not fatal: the server falls back to the cached or
+// embedded schemas.
+//
+// Parameters:
+//   - opts: The remote source; an empty CacheDir uses the default.
+//   - refresh: Whether to download the schema, false in offline mode.
+func loadSchemas(opts schema.RemoteOptions, refresh bool) {
+	if opts.URL == "" {
+		return
+	}
+
+	if opts.CacheDir == "" {
+		dir, err := schema.DefaultCacheDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Schema cache disabled: %v\n", err)
+		}
+		opts.CacheDir = dir
+	}
+
+	if opts.CacheDir != "" {
+		if _, err := schema.LoadCache(opts.CacheDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load cached schemas: %v\n", err)
+		}
+	}
+
+	if !refresh {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	version, err := schema.Refresh(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Schema refresh failed, using cached or embedded schemas: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Loaded remote schema for apiVersion %s\n", version)
+}
+
+// newHTTPCache creates the cache shared by network validations.
+//
+// If the on-disk cache directory cannot be determined, downloads are only
+// cached in memory.
+//
+// Parameters:
+//   - dir: The cache directory, or empty to use the default.
+//   - ttl: How long downloads are reused.
+//
+// Returns:
+//   - *httpcache.Cache: The cache.
+func newHTTPCache(dir string, ttl time.Duration) *httpcache.Cache {
+	if dir == "" && ttl > 0 {
+		d, err := httpcache.DefaultDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "HTTP cache kept in memory only: %v\n", err)
+		}
+		dir = d
+	}
+	return httpcache.New(dir, ttl)
+}
+
+// loadOverlay reads an overlay schema from disk and installs it.
+//
+// Unlike remote refresh failures, overlay failures are fatal: running without
+// the operator's policies would silently accept configurations they forbid.
+//
+// Parameters:
+//   - path: The path to the overlay JSON file.
+//
+// Returns:
+//   - error: An error if the file cannot be read or the overlay is invalid.
+func loadOverlay(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return schema.SetOverlay(raw)
 }
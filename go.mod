@@ -3,12 +3,15 @@ module github.com/e-minguez/eib-mcp
 go 1.25.5
 
 require (
+	github.com/ProtonMail/go-crypto v1.5.2
 	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/crypto v0.45.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/cloudflare/circl v1.6.3 // indirect
 	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
-	golang.org/x/crypto v0.45.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
 )
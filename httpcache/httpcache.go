@@ -0,0 +1,208 @@
+// Package httpcache provides the shared HTTP cache used by network-dependent
+// validations.
+//
+// Agents typically regenerate a configuration many times while iterating on
+// it. Caching downloads such as Helm repository indexes or Kubernetes release
+// lists for a configurable time keeps these iterations fast and avoids
+// hammering the upstream services.
+package httpcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long downloads are reused when no TTL is configured.
+const DefaultTTL = 15 * time.Minute
+
+// maxResponseSize caps the size of a cached response body.
+const maxResponseSize = 32 << 20
+
+// entry is a cached response body.
+type entry struct {
+	body    []byte
+	fetched time.Time
+}
+
+// Cache is an HTTP GET cache kept in memory and, optionally, on disk.
+//
+// Entries are reused for TTL after they were downloaded. A Cache is safe for
+// concurrent use.
+type Cache struct {
+	// Dir is the directory where responses are persisted across restarts.
+	// If empty, responses are only cached in memory.
+	Dir string
+	// TTL is how long a response is reused. Zero or negative disables caching.
+	TTL time.Duration
+	// Client is the HTTP client to use. If nil, a client with a 30s timeout is used.
+	Client *http.Client
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+// New creates a cache.
+//
+// Parameters:
+//   - dir: The on-disk cache directory, or empty for an in-memory cache.
+//   - ttl: How long responses are reused.
+//
+// Returns:
+//   - *Cache: The cache.
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{Dir: dir, TTL: ttl}
+}
+
+// DefaultDir returns the default on-disk cache directory.
+//
+// Returns:
+//   - string: The cache directory path.
+//   - error: An error if the user cache directory cannot be determined.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "eib-mcp", "http"), nil
+}
+
+// Get returns the body of a URL, downloading it only if no fresh copy is cached.
+//
+// Only 200 OK responses are cached. Failures to persist a response on disk are
+// ignored: the in-memory copy is still used.
+//
+// Parameters:
+//   - ctx: The context controlling the download.
+//   - url: The URL to fetch.
+//
+// Returns:
+//   - []byte: The response body.
+//   - error: An error if the download fails or does not return 200 OK.
+func (c *Cache) Get(ctx context.Context, url string) ([]byte, error) {
+	if body, ok := c.lookup(url); ok {
+		return body, nil
+	}
+
+	body, err := c.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	c.store(url, body)
+	return body, nil
+}
+
+// lookup finds a fresh cached response, in memory first, then on disk.
+//
+// Parameters:
+//   - url: The URL.
+//
+// Returns:
+//   - []byte: The cached body.
+//   - bool: False if no fresh copy is cached.
+func (c *Cache) lookup(url string) ([]byte, bool) {
+	if c.TTL <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[url]; ok && time.Since(e.fetched) < c.TTL {
+		return e.body, true
+	}
+	if c.Dir == "" {
+		return nil, false
+	}
+	path := c.path(url)
+	st, err := os.Stat(path)
+	if err != nil || time.Since(st.ModTime()) >= c.TTL {
+		return nil, false
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	c.remember(url, entry{body: body, fetched: st.ModTime()})
+	return body, true
+}
+
+// store caches a downloaded response.
+//
+// Parameters:
+//   - url: The URL.
+//   - body: The response body.
+func (c *Cache) store(url string, body []byte) {
+	if c.TTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remember(url, entry{body: body, fetched: time.Now()})
+	if c.Dir == "" {
+		return
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".download-")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(body)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), c.path(url)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// remember stores an entry in memory; the caller holds c.mu.
+func (c *Cache) remember(url string, e entry) {
+	if c.entries == nil {
+		c.entries = map[string]entry{}
+	}
+	c.entries[url] = e
+}
+
+// path returns the on-disk location of a cached URL.
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// fetch performs a size-limited HTTP GET.
+//
+// Parameters:
+//   - ctx: The context controlling the request.
+//   - url: The URL to fetch.
+//
+// Returns:
+//   - []byte: The response body.
+//   - error: An error if the request fails or does not return 200 OK.
+func (c *Cache) fetch(ctx context.Context, url string) ([]byte, error) {
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+}
@@ -0,0 +1,234 @@
+// Package i18n translates the messages the server shows to people: tool
+// descriptions, error messages, hints and validation warnings.
+//
+// English is the language of the source code and needs no catalog. Other
+// languages have a catalog embedded from locales/<language>.yaml, which
+// holds the tool descriptions and hints by name and the messages as
+// regular expressions over the English text. Messages without a translation
+// are left in English, so a catalog can grow over time.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// English is the language of the untranslated messages.
+const English = "en"
+
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// Catalog holds the translations of one language. A nil *Catalog stands for
+// English and returns every text unchanged.
+type Catalog struct {
+	// Language is the language code, e.g. "es".
+	Language string `yaml:"-"`
+	// Tools are the tool descriptions, by tool name.
+	Tools map[string]string `yaml:"tools"`
+	// Hints are the recovery hints of tool failures, by error code.
+	Hints map[int]string `yaml:"hints"`
+	// Labels are short fixed words, such as "Hint" and "Warnings".
+	Labels map[string]string `yaml:"labels"`
+	// Messages translate message lines, tried in order.
+	Messages []message `yaml:"messages"`
+}
+
+// message translates the lines matching a pattern.
+type message struct {
+	// Pattern is a regular expression matching a whole English line.
+	Pattern string `yaml:"match"`
+	// Text is the translation, where ${1} and so on are the groups of Pattern.
+	Text string `yaml:"text"`
+
+	re *regexp.Regexp
+}
+
+var (
+	catalogsMu sync.Mutex
+	catalogs   = map[string]*Catalog{}
+)
+
+// Languages returns the supported language codes, English first.
+//
+// Returns:
+//   - []string: The language codes, e.g. ["en", "es"].
+func Languages() []string {
+	entries, _ := locales.ReadDir("locales")
+	var langs []string
+	for _, e := range entries {
+		langs = append(langs, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(langs)
+	return append([]string{English}, langs...)
+}
+
+// Lookup returns the catalog of a language.
+//
+// Parameters:
+//   - lang: The language code, e.g. "es"; a region such as "es-ES" is ignored.
+//
+// Returns:
+//   - *Catalog: The catalog, or nil for English.
+//   - error: An error if the language is not supported.
+func Lookup(lang string) (*Catalog, error) {
+	lang, _, _ = strings.Cut(strings.ToLower(strings.ReplaceAll(lang, "_", "-")), "-")
+	if lang == "" || lang == English {
+		return nil, nil
+	}
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	if c, ok := catalogs[lang]; ok {
+		return c, nil
+	}
+
+	data, err := locales.ReadFile("locales/" + lang + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("unsupported language %q (supported: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	c := &Catalog{Language: lang}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid catalog for language %q: %w", lang, err)
+	}
+	for i := range c.Messages {
+		if c.Messages[i].re, err = regexp.Compile("^" + c.Messages[i].Pattern + "$"); err != nil {
+			return nil, fmt.Errorf("invalid catalog for language %q: message %d: %w", lang, i, err)
+		}
+	}
+	catalogs[lang] = c
+	return c, nil
+}
+
+// Tool returns the description of a tool.
+//
+// Parameters:
+//   - name: The tool name.
+//   - english: The English description.
+//
+// Returns:
+//   - string: The translated description, or english if there is none.
+func (c *Catalog) Tool(name, english string) string {
+	if c == nil || c.Tools[name] == "" {
+		return english
+	}
+	return strings.TrimRight(c.Tools[name], "\n")
+}
+
+// Hint returns the recovery hint of an error code.
+//
+// Parameters:
+//   - code: The error code.
+//   - english: The English hint.
+//
+// Returns:
+//   - string: The translated hint, or english if there is none.
+func (c *Catalog) Hint(code int, english string) string {
+	if c == nil || c.Hints[code] == "" {
+		return english
+	}
+	return c.Hints[code]
+}
+
+// Label returns a short fixed word, such as "Hint".
+//
+// Parameters:
+//   - english: The English word.
+//
+// Returns:
+//   - string: The translated word, or english if there is none.
+func (c *Catalog) Label(english string) string {
+	if c == nil || c.Labels[english] == "" {
+		return english
+	}
+	return c.Labels[english]
+}
+
+// Text translates a message, line by line.
+//
+// The bullet ("- ") and rule ID ("[rule-id] ") prefixes of a line are kept.
+// A line of the form "<field>: <message>" that has no translation of its own
+// keeps the field and has the message translated.
+//
+// Parameters:
+//   - text: The English message.
+//
+// Returns:
+//   - string: The translated message; untranslated lines stay in English.
+func (c *Catalog) Text(text string) string {
+	if c == nil || text == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = c.line(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ruleIDPattern matches the rule ID prefix of a violation.
+var ruleIDPattern = regexp.MustCompile(`^\[[a-z0-9-]+\] `)
+
+// fieldPattern matches a field path prefix, e.g. "operatingSystem.users.0: "
+// or "kubernetes/manifests/web.yaml: ".
+var fieldPattern = regexp.MustCompile(`^(\(root\)|[A-Za-z0-9_./\[\]-]+): `)
+
+// objectPattern matches the prefix locating a Kubernetes object in a
+// manifest, e.g. `document 2 (Deployment "web"): `.
+var objectPattern = regexp.MustCompile(`^document [0-9]+( item [0-9]+)? \([^)]*\): `)
+
+// line translates one line of a message.
+//
+// Parameters:
+//   - line: The English line.
+//
+// Returns:
+//   - string: The translated line.
+func (c *Catalog) line(line string) string {
+	rest := strings.TrimLeft(line, " ")
+	prefix := line[:len(line)-len(rest)]
+	if after, ok := strings.CutPrefix(rest, "- "); ok {
+		prefix, rest = prefix+"- ", after
+	}
+	if id := ruleIDPattern.FindString(rest); id != "" {
+		prefix, rest = prefix+id, rest[len(id):]
+	}
+	field := fieldPattern.FindString(rest)
+	if object := objectPattern.FindString(rest[len(field):]); object != "" {
+		locator, _ := c.message(strings.TrimSuffix(object, ": "))
+		if translated, ok := c.message(rest[len(field)+len(object):]); ok {
+			return prefix + field + locator + ": " + translated
+		}
+	}
+	if translated, ok := c.message(rest); ok {
+		return prefix + translated
+	}
+	if field != "" {
+		if translated, ok := c.message(rest[len(field):]); ok {
+			return prefix + field + translated
+		}
+	}
+	return line
+}
+
+// message translates a message without prefixes.
+//
+// Parameters:
+//   - text: The English message.
+//
+// Returns:
+//   - string: The translation.
+//   - bool: false if no pattern matches.
+func (c *Catalog) message(text string) (string, bool) {
+	for _, m := range c.Messages {
+		if idx := m.re.FindStringSubmatchIndex(text); idx != nil {
+			return string(m.re.ExpandString(nil, m.Text, text, idx)), true
+		}
+	}
+	return text, false
+}
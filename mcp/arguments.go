@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"fmt"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// argumentError is a field-level problem with tool arguments.
+type argumentError struct {
+	// Field is the path of the offending argument, "(root)" for the arguments object.
+	Field string `json:"field"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// validateArguments checks tool arguments against the schema the tool
+// advertises, before the tool runs.
+//
+// Parameters:
+//   - t: The tool being called.
+//   - schemaMap: The schema to check against.
+//   - args: The call arguments.
+//
+// Returns:
+//   - []argumentError: The problems found; empty if the arguments are acceptable.
+//   - error: An error if the schema cannot be compiled.
+func validateArguments(t toolDefinition, schemaMap map[string]interface{}, args map[string]interface{}) ([]argumentError, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schemaMap), gojsonschema.NewGoLoader(args))
+	if err != nil {
+		return nil, fmt.Errorf("input schema of %s: %w", t.name, err)
+	}
+	var problems []argumentError
+	for _, desc := range result.Errors() {
+		problems = append(problems, argumentError{Field: desc.Field(), Message: desc.Description()})
+	}
+	return problems, nil
+}
+
+// argumentSchema returns the schema the arguments of a tool are checked
+// against before it runs: its argsSchema if set, else its advertised input
+// schema without the arguments of disabled features.
+//
+// Parameters:
+//   - t: The tool.
+//
+// Returns:
+//   - map[string]interface{}: The schema.
+func (s *Server) argumentSchema(t toolDefinition) map[string]interface{} {
+	if t.argsSchema != nil {
+		return t.argsSchema()
+	}
+	return s.toolSchema(t)
+}
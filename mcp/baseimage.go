@@ -0,0 +1,190 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/e-minguez/eib-mcp/baseimage"
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// inspectionReport is the result of the inspect_base_image tool.
+type inspectionReport struct {
+	*baseimage.Info
+	// Checksum is the comparison with the expected checksum, if one was given.
+	Checksum *baseimage.Verification `json:"checksum,omitempty"`
+	// Mismatches lists differences between the image and the given
+	// configuration or expected checksum.
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// handleInspectBaseImage implements the inspect_base_image tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The image "path", an optional "config" to compare with and an
+//     optional "expectedChecksum" or "checksumUrl" to verify the image against.
+//
+// Returns:
+//   - []map[string]interface{}: The inspection report as a JSON document.
+//   - error: An error if the image cannot be read, the configuration is
+//     malformed or the expected checksum cannot be obtained.
+func handleInspectBaseImage(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	path, _ := args["path"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("argument \"path\" is required")
+	}
+
+	if _, err := s.toolOptions.Sandbox.Check(path); err != nil {
+		return nil, err
+	}
+	info, err := baseimage.Inspect(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect base image: %w", err)
+	}
+	report := inspectionReport{Info: info}
+	if v, ok := args["config"]; ok {
+		config, err := tool.ParseConfig(v)
+		if err != nil {
+			return nil, err
+		}
+		report.Mismatches = baseimage.Compare(info, config)
+	}
+
+	expected, _ := args["expectedChecksum"].(string)
+	url, _ := args["checksumUrl"].(string)
+	if expected != "" && url != "" {
+		return nil, fmt.Errorf("arguments \"expectedChecksum\" and \"checksumUrl\" cannot be combined")
+	}
+	source := "argument"
+	if url != "" {
+		if expected, err = tool.PublishedChecksum(context.Background(), url, filepath.Base(path), s.toolOptions); err != nil {
+			return nil, err
+		}
+		source = url
+	}
+	if expected != "" {
+		if report.Checksum, err = baseimage.Verify(path, expected, source); err != nil {
+			return nil, err
+		}
+		if !report.Checksum.Match {
+			report.Mismatches = append(report.Mismatches, fmt.Sprintf("the %s checksum of the base image is %s but %s was expected (from %s); the image is corrupted or was tampered with", report.Checksum.Algorithm, report.Checksum.Actual, report.Checksum.Expected, source))
+		}
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(out))}, nil
+}
+
+// handleDownloadBaseImage implements the download_base_image tool.
+//
+// Progress is reported with notifications/progress when the client passes a
+// progressToken.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The image "url", the image configuration directory "outputDir",
+//     an optional "fileName", the "expectedChecksum" or "checksumUrl" and
+//     "overwrite".
+//
+// Returns:
+//   - []map[string]interface{}: The download report as a JSON document.
+//   - error: An error if an argument is missing, a path is not allowed, or
+//     the download or its verification fails.
+func handleDownloadBaseImage(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	req := tool.DownloadRequest{}
+	req.URL, _ = args["url"].(string)
+	req.Dir, _ = args["outputDir"].(string)
+	req.FileName, _ = args["fileName"].(string)
+	req.Expected, _ = args["expectedChecksum"].(string)
+	req.ChecksumURL, _ = args["checksumUrl"].(string)
+	if req.URL == "" || req.Dir == "" {
+		return nil, fmt.Errorf("arguments \"url\" and \"outputDir\" are required")
+	}
+	if (req.Expected == "") == (req.ChecksumURL == "") {
+		return nil, fmt.Errorf("exactly one of the arguments \"expectedChecksum\" and \"checksumUrl\" is required")
+	}
+	if v, ok := args["overwrite"]; ok {
+		if err := decodeArgument(v, "overwrite", &req.Overwrite); err != nil {
+			return nil, err
+		}
+	}
+
+	name, err := tool.DownloadFileName(req)
+	if err != nil {
+		return nil, err
+	}
+	rel := filepath.Join(tool.BaseImagesDir, name)
+	if err := s.checkOutputPaths(req.Dir, []string{rel, rel + ".part"}); err != nil {
+		return nil, err
+	}
+	req.Progress = func(done, total int64) {
+		message := fmt.Sprintf("Downloaded %d MiB of %s", done>>20, name)
+		if total > 0 {
+			message = fmt.Sprintf("Downloaded %d of %d MiB of %s", done>>20, total>>20, name)
+		}
+		s.notifyProgress(float64(done), float64(total), message)
+	}
+
+	download, err := tool.DownloadBaseImage(context.Background(), req, s.toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(download, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(out))}, nil
+}
+
+// WithBaseImagesDir sets the directory list_base_images lists when the
+// client does not name one.
+//
+// Parameters:
+//   - dir: The directory holding the base images.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithBaseImagesDir(dir string) Option {
+	return func(s *Server) {
+		s.baseImagesDir = dir
+	}
+}
+
+// handleListBaseImages implements the list_base_images tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: An optional "dir" to list instead of the configured one.
+//
+// Returns:
+//   - []map[string]interface{}: The images as a JSON document.
+//   - error: An error if no directory is given or configured, the directory
+//     is not allowed or cannot be read.
+func handleListBaseImages(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	dir, _ := args["dir"].(string)
+	if dir == "" {
+		dir = s.baseImagesDir
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("argument \"dir\" is required, since no base images directory is configured on this server")
+	}
+	if _, err := s.toolOptions.Sandbox.Check(dir); err != nil {
+		return nil, err
+	}
+	entries, err := baseimage.List(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list base images: %w", err)
+	}
+
+	out, err := json.MarshalIndent(map[string]interface{}{"dir": dir, "images": entries}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode listing: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(out))}, nil
+}
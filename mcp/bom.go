@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// handleBillOfMaterials implements the bill_of_materials tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "config" to inventory.
+//
+// Returns:
+//   - []map[string]interface{}: The bill of materials as a JSON document.
+//   - error: An error if the configuration is malformed.
+func handleBillOfMaterials(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	config, err := tool.ParseConfig(args["config"])
+	if err != nil {
+		return nil, err
+	}
+
+	bom := tool.BillOfMaterials(context.Background(), config, s.toolOptions)
+	out, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bill of materials: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(out))}, nil
+}
@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/i18n"
+	"github.com/e-minguez/eib-mcp/preset"
+	"github.com/e-minguez/eib-mcp/schema"
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// capabilities describes what this server build supports, as reported by
+// the list_capabilities tool.
+type capabilities struct {
+	// Tools lists the names of the available tools.
+	Tools []string `json:"tools"`
+	// DisabledFeatures lists the features disabled on this server.
+	DisabledFeatures []string `json:"disabledFeatures,omitempty"`
+	// UnsupportedFeatures lists the features hidden because the client lacks a capability they need.
+	UnsupportedFeatures []string `json:"unsupportedFeatures,omitempty"`
+	// APIVersions lists the supported EIB definition apiVersions.
+	APIVersions []string `json:"apiVersions"`
+	// LatestAPIVersion is the newest supported apiVersion.
+	LatestAPIVersion string `json:"latestApiVersion"`
+	// Sections lists the configuration sections supported by each apiVersion.
+	Sections map[string][]string `json:"sections"`
+	// Presets lists the available preset names.
+	Presets []string `json:"presets"`
+	// Validation describes the validations applied to every configuration.
+	Validation validationCapabilities `json:"validation"`
+	// NetworkChecks lists the enabled checks that contact external services.
+	NetworkChecks []string `json:"networkChecks"`
+	// Offline is true if network-dependent checks are disabled.
+	Offline bool `json:"offline"`
+	// AllowedDirs lists the directories tools may read or write, if restricted.
+	AllowedDirs []string `json:"allowedDirs,omitempty"`
+	// HTTPCacheTTL is how long downloads of network checks are reused, if cached.
+	HTTPCacheTTL string `json:"httpCacheTTL,omitempty"`
+	// SigningKey is the fingerprint of the key generated definitions are signed with, if configured.
+	SigningKey string `json:"signingKey,omitempty"`
+	// Language is the language of messages in this session.
+	Language string `json:"language"`
+	// Languages lists the languages the "language" initialization option accepts.
+	Languages []string `json:"languages"`
+}
+
+// validationCapabilities describes the validation settings of the server.
+type validationCapabilities struct {
+	// Mode is the validation mode, "strict" or "permissive".
+	Mode string `json:"mode"`
+	// Formats lists the string formats checked by the schemas.
+	Formats []string `json:"formats"`
+	// Rules lists the cross-field rules, keyed by ID, with their description.
+	Rules map[string]string `json:"rules"`
+	// FindingRules lists every rule ID findings can name, with its severity;
+	// warning and info rules can be suppressed.
+	FindingRules []tool.RuleInfo `json:"findingRules"`
+	// TargetRelease is the default EIB release configurations must be compatible with, if any.
+	TargetRelease string `json:"targetRelease,omitempty"`
+	// EIBValidate is true if generated configurations are also checked with `eib validate`.
+	EIBValidate bool `json:"eibValidate"`
+	// PasswordPolicy is the policy plaintext passwords are checked against, if any.
+	PasswordPolicy *tool.PasswordPolicy `json:"passwordPolicy,omitempty"`
+}
+
+// handleListCapabilities implements the list_capabilities tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: Unused.
+//
+// Returns:
+//   - []map[string]interface{}: The capabilities as a JSON document.
+//   - error: An error if a schema cannot be read.
+func handleListCapabilities(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	caps, err := s.capabilities()
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(caps, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode capabilities: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(out))}, nil
+}
+
+// capabilities collects what this server supports with its current options.
+//
+// Returns:
+//   - capabilities: The capabilities.
+//   - error: An error if a schema cannot be read.
+func (s *Server) capabilities() (capabilities, error) {
+	caps := capabilities{
+		APIVersions:      schema.SupportedVersions(),
+		LatestAPIVersion: schema.LatestVersion(),
+		Sections:         map[string][]string{},
+		Presets:          preset.Names(),
+		NetworkChecks:    []string{},
+		Offline:          s.toolOptions.Offline,
+		AllowedDirs:      s.toolOptions.Sandbox.Roots(),
+		Language:         i18n.English,
+		Languages:        i18n.Languages(),
+	}
+	if s.catalog != nil {
+		caps.Language = s.catalog.Language
+	}
+	if s.toolOptions.Advisories != nil && !s.toolOptions.Offline {
+		caps.NetworkChecks = append(caps.NetworkChecks, "image vulnerability lookup")
+	}
+	for _, t := range s.enabledTools() {
+		caps.Tools = append(caps.Tools, t.name)
+	}
+	for _, f := range Features() {
+		if s.disabled[Feature(f)] {
+			caps.DisabledFeatures = append(caps.DisabledFeatures, f)
+		} else if s.unsupported[Feature(f)] {
+			caps.UnsupportedFeatures = append(caps.UnsupportedFeatures, f)
+		}
+	}
+	for _, v := range caps.APIVersions {
+		sections, err := schema.Sections(v)
+		if err != nil {
+			return caps, err
+		}
+		caps.Sections[v] = sections
+	}
+
+	mode := s.toolOptions.Mode
+	if mode == "" {
+		mode = tool.ValidationStrict
+	}
+	rules := s.toolOptions.Rules
+	if rules == nil {
+		rules = tool.DefaultRules()
+	}
+	caps.Validation = validationCapabilities{
+		Mode:           string(mode),
+		Formats:        schema.Formats(),
+		Rules:          map[string]string{},
+		FindingRules:   tool.RuleCatalog(rules, s.toolOptions.RulePolicy),
+		TargetRelease:  s.toolOptions.TargetRelease,
+		EIBValidate:    s.toolOptions.EIB != nil,
+		PasswordPolicy: s.toolOptions.PasswordPolicy,
+	}
+	for _, r := range rules {
+		caps.Validation.Rules[r.ID] = r.Description
+	}
+	if c := s.toolOptions.HTTP; c != nil && c.TTL > 0 {
+		caps.HTTPCacheTTL = c.TTL.String()
+	}
+	if s.toolOptions.Signer != nil {
+		caps.SigningKey = s.toolOptions.Signer.Fingerprint()
+	}
+	return caps, nil
+}
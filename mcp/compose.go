@@ -0,0 +1,48 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// handleComposeConfig implements the compose_config tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "fragments" to compose, lowest precedence first.
+//
+// Returns:
+//   - []map[string]interface{}: The generated YAML, followed by warnings if
+//     any, and the fragments setting each section as a JSON document.
+//   - error: An error if a fragment is malformed, fragments set sections
+//     another one owns, or the composed configuration is invalid.
+func handleComposeConfig(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	var fragments []tool.Fragment
+	if err := decodeArgument(args["fragments"], "fragments", &fragments); err != nil {
+		return nil, err
+	}
+	composition, err := tool.ComposeConfig(fragments)
+	if err != nil {
+		return nil, err
+	}
+	opts := s.toolOptions
+	notes, err := opts.RulePolicy.Check(composition.Findings)
+	if err != nil {
+		return nil, err
+	}
+	notes, suppressed := tool.SuppressFindings(notes, opts.Suppress)
+
+	result, err := tool.Generate(composition.Config, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.addNotes(result, notes, suppressed)
+
+	out, err := json.MarshalIndent(map[string]interface{}{"sources": composition.Sources}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sources: %w", err)
+	}
+	return append(s.resultContent(result), textContent(string(out))), nil
+}
@@ -0,0 +1,52 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+	"gopkg.in/yaml.v3"
+)
+
+// handleConvertConfig implements the convert_config tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "document" to convert and its optional "format".
+//
+// Returns:
+//   - []map[string]interface{}: The converted YAML, the format and files as
+//     a JSON document, and the validation verdict as a JSON document.
+//   - error: An error if the format is unknown or the document cannot be
+//     parsed.
+func handleConvertConfig(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	document, _ := args["document"].(string)
+	format, _ := args["format"].(string)
+	conv, err := tool.ConvertProvisioning(document, tool.ProvisioningFormat(format))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := yaml.Marshal(conv.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	files, err := json.MarshalIndent(map[string]interface{}{"format": conv.Format, "files": conv.Files}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode files: %w", err)
+	}
+
+	opts := s.toolOptions
+	opts.ValidateOnly = true
+	opts.Files = conv.Files
+	notes, err := opts.RulePolicy.Check(conv.Findings)
+	if err != nil {
+		return nil, err
+	}
+	notes, suppressed := tool.SuppressFindings(notes, opts.Suppress)
+	result, err := tool.Generate(conv.Config, opts)
+	verdict, err := s.validationVerdict(result, notes, suppressed, err)
+	if err != nil {
+		return nil, err
+	}
+	return append([]map[string]interface{}{textContent(string(raw)), textContent(string(files))}, verdict...), nil
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// newCorrelationID returns a random identifier for a request, used to trace
+// it across log lines and the error reported to the client.
+//
+// Returns:
+//   - string: A 16 character hexadecimal identifier.
+func newCorrelationID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "0000000000000000"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// logf writes a log line on os.Stderr, prefixed with a correlation ID.
+//
+// Parameters:
+//   - correlationID: The ID of the request the line is about.
+//   - format: The message format.
+//   - args: The format arguments.
+func logf(correlationID, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "[%s] %s\n", correlationID, fmt.Sprintf(format, args...))
+}
+
+// withCorrelationID records a correlation ID in a response: in the data of
+// an error, or in the _meta of a tool result with isError set.
+//
+// Parameters:
+//   - resp: The response; it is modified in place.
+//   - correlationID: The ID of the request.
+func withCorrelationID(resp *JSONRPCResponse, correlationID string) {
+	if resp.Error != nil {
+		switch data := resp.Error.Data.(type) {
+		case nil:
+			resp.Error.Data = map[string]interface{}{"correlationId": correlationID}
+		case map[string]interface{}:
+			data["correlationId"] = correlationID
+		default:
+			resp.Error.Data = map[string]interface{}{"correlationId": correlationID, "details": data}
+		}
+		return
+	}
+	result, _ := resp.Result.(map[string]interface{})
+	if isError, _ := result["isError"].(bool); isError {
+		if meta, ok := result["_meta"].(map[string]interface{}); ok {
+			meta["correlationId"] = correlationID
+		}
+	}
+}
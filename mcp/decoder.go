@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// DefaultMaxRequestSize is the largest request, in bytes, the server accepts
+// unless WithMaxRequestSize sets another limit. The decoder holds a whole
+// request in memory, so the limit bounds the memory a client can make the
+// server use.
+const DefaultMaxRequestSize = 64 * 1024
+
+// requestReader splits the input stream into JSON values.
+//
+// Requests are not required to fit on one line: a json.Decoder finds where
+// each value ends, so pretty-printed requests and requests with newlines
+// between tokens are accepted. After a syntax error, the reader skips to the
+// end of the line to resynchronize, as the stream would otherwise be
+// unreadable.
+type requestReader struct {
+	src     io.Reader
+	err     error
+	pending []byte
+	dec     *json.Decoder
+	read    int64
+	// limit is the largest request, in bytes.
+	limit int64
+}
+
+// newRequestReader creates a requestReader reading from r.
+//
+// Parameters:
+//   - r: The input stream.
+//   - limit: The largest request, in bytes.
+//
+// Returns:
+//   - *requestReader: The reader.
+func newRequestReader(r io.Reader, limit int64) *requestReader {
+	rr := &requestReader{src: r, limit: limit}
+	rr.dec = json.NewDecoder(rr)
+	return rr
+}
+
+// Read feeds the decoder: first the bytes left over by a resynchronization,
+// then the input stream. The decoder only reads while its current value is
+// unfinished, so reads are capped to keep that value within the limit, and
+// fail with ErrLineTooLong once it is reached.
+func (r *requestReader) Read(p []byte) (int, error) {
+	room := r.limit - (r.read - r.dec.InputOffset())
+	if room <= 0 {
+		return 0, ErrLineTooLong
+	}
+	if int64(len(p)) > room {
+		p = p[:room]
+	}
+	if len(r.pending) > 0 {
+		n := copy(p, r.pending)
+		r.pending = r.pending[n:]
+		r.read += int64(n)
+		return n, nil
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.src.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// next returns the next JSON value of the stream.
+//
+// Returns:
+//   - json.RawMessage: The value or, on a syntax error, the rest of the line
+//     holding it.
+//   - error: io.EOF at the end of the input, a *json.SyntaxError or
+//     io.ErrUnexpectedEOF for undecodable input, ErrLineTooLong for a request
+//     over the limit, or the error of the input stream.
+func (r *requestReader) next() (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := r.dec.Decode(&raw)
+	if err == nil {
+		return raw, nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || err == io.ErrUnexpectedEOF || errors.Is(err, ErrLineTooLong) {
+		// The rest of an oversized request is discarded as it is read, so
+		// that it never has to fit in memory.
+		return r.skipLine(), err
+	}
+	return nil, err
+}
+
+// skipLine discards the undecoded input up to the end of the current line
+// and starts a new decoder after it.
+//
+// Returns:
+//   - []byte: The discarded text, truncated to the limit.
+func (r *requestReader) skipLine() []byte {
+	rest, _ := io.ReadAll(r.dec.Buffered())
+	rest = bytes.TrimLeft(append(rest, r.pending...), " \t\r\n")
+	r.pending = nil
+
+	line := rest
+	if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+		line, r.pending = rest[:i], rest[i+1:]
+	} else if r.err == nil {
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.src.Read(buf)
+			chunk := buf[:n]
+			if i := bytes.IndexByte(chunk, '\n'); i >= 0 {
+				line = r.appendLimited(line, chunk[:i])
+				r.pending = append([]byte(nil), chunk[i+1:]...)
+				break
+			}
+			line = r.appendLimited(line, chunk)
+			if err != nil {
+				r.err = err
+				break
+			}
+		}
+	}
+
+	r.dec = json.NewDecoder(r)
+	r.read = 0
+	if int64(len(line)) > r.limit {
+		line = line[:r.limit]
+	}
+	return bytes.TrimSpace(line)
+}
+
+// appendLimited appends data to line without growing it past the limit.
+//
+// Parameters:
+//   - line: The text read so far.
+//   - data: The text to append.
+//
+// Returns:
+//   - []byte: The extended text.
+func (r *requestReader) appendLimited(line, data []byte) []byte {
+	if room := int(r.limit) - len(line); room < len(data) {
+		if room <= 0 {
+			return line
+		}
+		data = data[:room]
+	}
+	return append(line, data...)
+}
@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+	"gopkg.in/yaml.v3"
+)
+
+// handleDraftFromSystem implements the draft_from_system tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The description of the node: "hostname", "arch", "packages",
+//     "users", "services", "kernelArgs", "timezone", "ntpServers", "keymap"
+//     and "kubernetes", all optional.
+//
+// Returns:
+//   - []map[string]interface{}: The drafted YAML, followed by its
+//     validation verdict as a JSON document.
+//   - error: An error if the description is malformed or names an
+//     unsupported architecture or Kubernetes version.
+func handleDraftFromSystem(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	var desc tool.SystemDescription
+	if err := decodeArgument(args, "description", &desc); err != nil {
+		return nil, err
+	}
+	draft, err := tool.DraftFromSystem(desc)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := yaml.Marshal(draft.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode draft: %w", err)
+	}
+
+	opts := s.toolOptions
+	opts.ValidateOnly = true
+	notes, err := opts.RulePolicy.Check(draft.Findings)
+	if err != nil {
+		return nil, err
+	}
+	notes, suppressed := tool.SuppressFindings(notes, opts.Suppress)
+	result, err := tool.Generate(draft.Config, opts)
+	verdict, err := s.validationVerdict(result, notes, suppressed, err)
+	if err != nil {
+		return nil, err
+	}
+	return append([]map[string]interface{}{textContent(string(raw))}, verdict...), nil
+}
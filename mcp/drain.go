@@ -0,0 +1,230 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// drainNoticeInterval is how often clients are reminded of the requests
+// still draining during a shutdown.
+const drainNoticeInterval = 5 * time.Second
+
+// ErrDrainTimeout is returned by Drain and ServeListeners when requests were
+// still running at the drain deadline.
+var ErrDrainTimeout = errors.New("drain deadline exceeded")
+
+// logLevels orders the levels of notifications/message, as defined by MCP
+// after syslog.
+var logLevels = map[string]int{
+	"debug":     0,
+	"info":      1,
+	"notice":    2,
+	"warning":   3,
+	"error":     4,
+	"critical":  5,
+	"alert":     6,
+	"emergency": 7,
+}
+
+// inflightRequest describes the request a session is handling.
+type inflightRequest struct {
+	// ID is the JSON-RPC id of the request.
+	ID interface{} `json:"id"`
+	// Method is the JSON-RPC method.
+	Method string `json:"method"`
+	// Tool is the name of the tool, for tools/call requests.
+	Tool string `json:"tool,omitempty"`
+	// CorrelationID is the ID of the request in the server logs.
+	CorrelationID string `json:"correlationId"`
+	// ElapsedSeconds is how long the request has been running.
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+
+	// started is when the request was read.
+	started time.Time
+	// done is closed when the response has been written.
+	done chan struct{}
+}
+
+// beginRequest records the request the session is about to handle.
+//
+// Parameters:
+//   - req: The request.
+//
+// Returns:
+//   - bool: false if the server is draining and must not start new requests.
+func (s *Server) beginRequest(req *JSONRPCRequest) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return false
+	}
+	r := &inflightRequest{ID: req.ID, Method: req.Method, CorrelationID: req.correlationID, started: time.Now(), done: make(chan struct{})}
+	if req.Method == "tools/call" {
+		var params struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(req.Params, &params) == nil {
+			r.Tool = params.Name
+		}
+	}
+	s.inflight = r
+	return true
+}
+
+// endRequest records that the response to the current request has been
+// written.
+//
+// Returns:
+//   - bool: true if the server is draining, so Serve must stop.
+func (s *Server) endRequest() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inflight != nil {
+		close(s.inflight.done)
+		s.inflight = nil
+	}
+	return s.draining
+}
+
+// shuttingDownResponse builds the error response to a request read after a
+// shutdown was requested.
+//
+// Parameters:
+//   - req: The rejected request.
+//
+// Returns:
+//   - *JSONRPCResponse: The error response, or nil for a notification.
+func shuttingDownResponse(req *JSONRPCRequest) *JSONRPCResponse {
+	if req.ID == nil {
+		return nil
+	}
+	return &JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Error: &JSONRPCError{
+		Code:    CodeInternalError,
+		Message: "Server is shutting down",
+		Data:    map[string]interface{}{"hint": "Reconnect and send the request again once the server has restarted."},
+	}}
+}
+
+// Drain stops the session gracefully: new requests are rejected, Serve
+// returns after the response to the request in progress, and the client is
+// told which request is still draining with a notifications/message every
+// few seconds until it finishes or the context ends.
+//
+// Parameters:
+//   - ctx: Bounds the wait; its deadline is reported to the client.
+//
+// Returns:
+//   - error: nil once no request is running, or ErrDrainTimeout if one was
+//     still running when ctx ended. The caller then terminates it by
+//     exiting or closing the connection.
+func (s *Server) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+	r := s.inflight
+	s.mu.Unlock()
+	if r == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(drainNoticeInterval)
+	defer ticker.Stop()
+	for {
+		s.notifyDraining(ctx, r)
+		select {
+		case <-r.done:
+			return nil
+		case <-ticker.C:
+		case <-ctx.Done():
+			select {
+			case <-r.done:
+				return nil
+			default:
+			}
+			logf(r.CorrelationID, "Drain deadline exceeded, terminating %s", r.Method)
+			s.logMessage("error", map[string]interface{}{
+				"message":  "Server is shutting down; the request below did not finish before the drain deadline and is terminated",
+				"draining": []*inflightRequest{r.snapshot()},
+			})
+			return ErrDrainTimeout
+		}
+	}
+}
+
+// notifyDraining tells the client which request is still draining and when
+// it will be terminated.
+//
+// Parameters:
+//   - ctx: The drain context, whose deadline is reported.
+//   - r: The request in progress.
+func (s *Server) notifyDraining(ctx context.Context, r *inflightRequest) {
+	data := map[string]interface{}{
+		"message":  "Server is shutting down; waiting for the request below to finish",
+		"draining": []*inflightRequest{r.snapshot()},
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		data["deadline"] = deadline.UTC().Format(time.RFC3339)
+		data["remainingSeconds"] = time.Until(deadline).Round(time.Second).Seconds()
+	}
+	s.logMessage("warning", data)
+}
+
+// snapshot returns a copy of the request with its elapsed time filled in.
+//
+// Returns:
+//   - *inflightRequest: The copy.
+func (r *inflightRequest) snapshot() *inflightRequest {
+	c := *r
+	c.ElapsedSeconds = time.Since(r.started).Round(time.Second).Seconds()
+	return &c
+}
+
+// logMessage sends a notifications/message to the client, unless the client
+// asked for a higher level with logging/setLevel. A failure to write is
+// logged.
+//
+// Parameters:
+//   - level: The level of the message, e.g. "warning".
+//   - data: The content of the message.
+func (s *Server) logMessage(level string, data interface{}) {
+	s.mu.Lock()
+	minLevel := s.logLevel
+	s.mu.Unlock()
+	if minLevel != "" && logLevels[level] < logLevels[minLevel] {
+		return
+	}
+	params := map[string]interface{}{"level": level, "logger": s.info.Name, "data": data}
+	if err := s.writeMessage(notification{JSONRPC: "2.0", Method: "notifications/message", Params: params}, "notification"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write notification: %v\n", err)
+	}
+}
+
+// handleSetLevel handles the "logging/setLevel" method, which sets the
+// lowest level of the notifications/message sent to the client.
+//
+// Parameters:
+//   - req: The request containing the level.
+//
+// Returns:
+//   - *JSONRPCResponse: An empty result, or an Invalid Params error for an unknown level.
+func (s *Server) handleSetLevel(req *JSONRPCRequest) *JSONRPCResponse {
+	var params struct {
+		Level string `json:"level"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return &JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Error: &JSONRPCError{Code: CodeParseError, Message: "Parse error"}}
+	}
+	if _, ok := logLevels[params.Level]; !ok {
+		return &JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Error: &JSONRPCError{
+			Code:    CodeInvalidParams,
+			Message: fmt.Sprintf("Invalid params: unknown log level %q", params.Level),
+		}}
+	}
+	s.mu.Lock()
+	s.logLevel = params.Level
+	s.mu.Unlock()
+	return &JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}}
+}
@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// Error codes used by the server.
+//
+// Codes from -32700 to -32600 are defined by JSON-RPC 2.0 and -32002 by MCP;
+// they are returned as protocol errors. Tool failures are returned as tool
+// results with isError set, carrying -32000 or one of the server-specific
+// codes from -32010 to -32029 in their metadata, so that clients can branch
+// on the class of failure.
+const (
+	// CodeParseError means the request parameters are not valid JSON.
+	CodeParseError = -32700
+	// CodeInvalidRequest means a line is valid JSON but not a request object.
+	CodeInvalidRequest = -32600
+	// CodeMethodNotFound means the method or tool does not exist or is disabled.
+	CodeMethodNotFound = -32601
+	// CodeInvalidParams means the tool arguments are not acceptable.
+	CodeInvalidParams = -32602
+	// CodeInternalError means the server failed unexpectedly, e.g. a tool panicked.
+	CodeInternalError = -32603
+	// CodeResourceNotFound means resources/read was called with an unknown URI.
+	CodeResourceNotFound = -32002
+	// CodeToolError is any tool failure without a more specific code.
+	CodeToolError = -32000
+
+	// CodeSandboxViolation means a path is outside the allowed directories.
+	CodeSandboxViolation = -32010
+	// CodeSchemaValidation means the configuration does not match the EIB schema.
+	CodeSchemaValidation = -32011
+	// CodeRuleViolation means the configuration violates a cross-field rule.
+	CodeRuleViolation = -32012
+	// CodeIncompatibleRelease means the configuration is too new for the target EIB release.
+	CodeIncompatibleRelease = -32013
+	// CodeEncryptionFailure means a plaintext password could not be encrypted.
+	CodeEncryptionFailure = -32014
+	// CodeNetworkCheckFailure means a network check could not be completed.
+	CodeNetworkCheckFailure = -32015
+	// CodeOutputConflict means writing output would replace existing files.
+	CodeOutputConflict = -32016
+	// CodeUndefinedVariable means a ${NAME} reference has no value.
+	CodeUndefinedVariable = -32017
+	// CodeInvalidArtifact means a custom file, certificate, GPG key, script, network configuration or PEM block is invalid.
+	CodeInvalidArtifact = -32018
+	// CodeSecretReference means a vault:// or env:// secret reference could not be resolved.
+	CodeSecretReference = -32019
+	// CodeChecksumMismatch means a downloaded file does not match its expected checksum.
+	CodeChecksumMismatch = -32020
+	// CodePasswordPolicy means a plaintext password violates the password policy.
+	CodePasswordPolicy = -32021
+)
+
+// kindCodes maps generation failure kinds to their error codes.
+var kindCodes = map[tool.ErrorKind]int{
+	tool.KindSchema:            CodeSchemaValidation,
+	tool.KindRule:              CodeRuleViolation,
+	tool.KindCompatibility:     CodeIncompatibleRelease,
+	tool.KindEncryption:        CodeEncryptionFailure,
+	tool.KindNetwork:           CodeNetworkCheckFailure,
+	tool.KindUndefinedVariable: CodeUndefinedVariable,
+	tool.KindArtifact:          CodeInvalidArtifact,
+	tool.KindSecret:            CodeSecretReference,
+	tool.KindChecksum:          CodeChecksumMismatch,
+	tool.KindPasswordPolicy:    CodePasswordPolicy,
+}
+
+// codeHints tells the client how to recover from each class of tool failure.
+var codeHints = map[int]string{
+	CodeSandboxViolation:    "Use a path inside one of the allowed directories (see list_capabilities).",
+	CodeSchemaValidation:    "Correct the listed fields to match the EIB schema (see the tool input schema and the eib://docs/ resources) and call the tool again.",
+	CodeRuleViolation:       "Adjust the configuration so that the listed cross-field rules hold and call the tool again.",
+	CodeIncompatibleRelease: "Remove the listed fields, or target a newer EIB release with targetRelease, and call the tool again.",
+	CodeEncryptionFailure:   "Provide encryptedPassword instead of password and call the tool again.",
+	CodeNetworkCheckFailure: "Retry later; network checks are skipped when the server runs offline.",
+	CodeOutputConflict:      "Choose another outputDir, or pass overwrite: true to replace the listed files.",
+	CodeUndefinedVariable:   "Pass the missing values in variables and call the tool again.",
+	CodeInvalidArtifact:     "Fix the listed file, certificate, GPG key, script, manifest or network configuration and call the tool again.",
+	CodeSecretReference:     "Ask the operator to provide the listed secrets on the server; never ask the user for the secret values.",
+	CodePasswordPolicy:      "Ask the user for a password that satisfies the listed requirements, or use sshKeys; never invent one.",
+	CodeChecksumMismatch:    "Check the URL and the expected checksum; if both are right, the mirror serves a corrupted or tampered file, so download from another one.",
+}
+
+// toolErrorResult converts a tool failure into a tool result with isError
+// set, as required by MCP for errors the client LLM should see and correct.
+//
+// The content holds the error message and a hint on how to recover, in the
+// language of the session, and the structured error data; the _meta object
+// holds the error code and data for programmatic use, which are never
+// translated.
+//
+// Parameters:
+//   - err: The error returned by the tool handler.
+//
+// Returns:
+//   - map[string]interface{}: The tool result.
+func (s *Server) toolErrorResult(err error) map[string]interface{} {
+	rpcErr := toolError(err)
+	content := []map[string]interface{}{textContent(s.catalog.Text(rpcErr.Message))}
+	if hint, ok := codeHints[rpcErr.Code]; ok {
+		content = append(content, textContent(s.catalog.Label("Hint")+": "+s.catalog.Hint(rpcErr.Code, hint)))
+	}
+	meta := map[string]interface{}{"errorCode": rpcErr.Code}
+	if rpcErr.Data != nil {
+		meta["errorData"] = rpcErr.Data
+		// A bare error kind adds nothing to the message and hint.
+		if _, isKind := rpcErr.Data.(map[string]interface{}); !isKind {
+			if details, err := json.MarshalIndent(rpcErr.Data, "", "  "); err == nil {
+				content = append(content, textContent(string(details)))
+			}
+		}
+	}
+	return map[string]interface{}{
+		"content": content,
+		"isError": true,
+		"_meta":   meta,
+	}
+}
+
+// toolError classifies a tool failure.
+//
+// Parameters:
+//   - err: The error returned by the tool handler.
+//
+// Returns:
+//   - *JSONRPCError: The error, with the code of its class and structured data where available.
+func toolError(err error) *JSONRPCError {
+	rpcErr := &JSONRPCError{Code: CodeToolError, Message: err.Error()}
+
+	var conflict *tool.ConflictError
+	var sandbox *tool.SandboxError
+	var classified *tool.Error
+	var rules *tool.RuleError
+	switch {
+	case errors.As(err, &sandbox):
+		rpcErr.Code = CodeSandboxViolation
+		rpcErr.Data = sandbox
+	case errors.As(err, &conflict):
+		rpcErr.Code = CodeOutputConflict
+		rpcErr.Data = conflict
+	case errors.As(err, &classified):
+		if code, ok := kindCodes[classified.Kind]; ok {
+			rpcErr.Code = code
+			data := map[string]interface{}{"kind": classified.Kind}
+			if errors.As(err, &rules) {
+				data["violations"] = rules.Violations
+			}
+			if findings := tool.ErrorFindings(err); findings != nil {
+				data["findings"] = findings
+			}
+			rpcErr.Data = data
+		}
+	}
+	return rpcErr
+}
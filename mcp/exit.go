@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"errors"
+)
+
+// Exit codes of the server process, so supervisors can tell why it stopped.
+//
+// Codes 1 and 2 are used by the command for startup failures and invalid
+// flags respectively.
+const (
+	// ExitOK means the client closed the input stream.
+	ExitOK = 0
+	// ExitTransportError means reading requests or writing responses failed.
+	ExitTransportError = 3
+	// ExitLineTooLong means a request exceeded the maximum size.
+	//
+	// Deprecated: Serve answers requests over the limit with an error and
+	// keeps serving, so it no longer stops for them.
+	ExitLineTooLong = 4
+	// ExitDrainTimeout means requests were still running at the drain
+	// deadline of a shutdown and were terminated.
+	ExitDrainTimeout = 5
+)
+
+// ErrLineTooLong is the error of reading a request over the maximum size.
+var ErrLineTooLong = errors.New("request too long")
+
+// ErrTransport is returned by Serve, wrapping the cause, when the input or
+// output stream fails.
+var ErrTransport = errors.New("transport error")
+
+// ExitCode returns the process exit code matching the error returned by
+// Serve.
+//
+// Parameters:
+//   - err: The error returned by Serve, Drain or ServeListeners, or nil
+//     after a clean end of input or shutdown.
+//
+// Returns:
+//   - int: ExitOK, ExitLineTooLong, ExitDrainTimeout or ExitTransportError.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrLineTooLong):
+		return ExitLineTooLong
+	case errors.Is(err, ErrDrainTimeout):
+		return ExitDrainTimeout
+	default:
+		return ExitTransportError
+	}
+}
@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Feature names a group of tools, or of tool arguments, that can be disabled,
+// e.g. in shared hosted deployments.
+type Feature string
+
+const (
+	// FeatureGenerate covers the configuration tools generate_config,
+	// generate_many, patch_config, compose_config, generate_layered,
+	// plan_config, apply_config, rancher_registration, bill_of_materials,
+	// render_helm_chart, check_install_order, explain_nodes, advise_sizing,
+	// validate_fragment, draft_from_system and convert_config.
+	FeatureGenerate Feature = "generate"
+	// FeatureFleet covers generate_fleet.
+	FeatureFleet Feature = "fleet"
+	// FeatureBaseImage covers inspect_base_image and list_base_images, which
+	// read files on the server, and download_base_image, which also needs
+	// FeatureWriteFiles.
+	FeatureBaseImage Feature = "base-image"
+	// FeatureDiscovery covers list_capabilities and schema_diff.
+	FeatureDiscovery Feature = "discovery"
+	// FeatureWriteFiles covers the arguments that make tools write to the
+	// server's file system, such as outputDir.
+	FeatureWriteFiles Feature = "write-files"
+)
+
+// Features returns every feature name, sorted.
+//
+// Returns:
+//   - []string: The feature names.
+func Features() []string {
+	names := []string{
+		string(FeatureGenerate),
+		string(FeatureFleet),
+		string(FeatureBaseImage),
+		string(FeatureDiscovery),
+		string(FeatureWriteFiles),
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFeatures parses a comma separated list of feature names.
+//
+// Parameters:
+//   - list: The list, e.g. "fleet,write-files". Blank entries are ignored.
+//
+// Returns:
+//   - []Feature: The features.
+//   - error: An error naming the first unknown feature.
+func ParseFeatures(list string) ([]Feature, error) {
+	known := map[string]bool{}
+	for _, name := range Features() {
+		known[name] = true
+	}
+	var features []Feature
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown feature %q (available: %s)", name, strings.Join(Features(), ", "))
+		}
+		features = append(features, Feature(name))
+	}
+	return features, nil
+}
+
+// WithDisabledFeatures hides the tools and tool arguments of the given
+// features from tools/list and rejects them in tools/call.
+//
+// Parameters:
+//   - features: The features to disable.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithDisabledFeatures(features ...Feature) Option {
+	return func(s *Server) {
+		if s.disabled == nil {
+			s.disabled = map[Feature]bool{}
+		}
+		for _, f := range features {
+			s.disabled[f] = true
+		}
+	}
+}
+
+// clientCapabilityFeatures maps the features that only work with a client
+// capability, declared in the initialize request, to that capability.
+// Writing files is only offered to clients that expose roots, since others
+// cannot tell the user where output went.
+var clientCapabilityFeatures = map[Feature]string{
+	FeatureWriteFiles: "roots",
+}
+
+// unsupportedFeatures returns the features that need a client capability
+// missing from the declared ones.
+//
+// Parameters:
+//   - declared: The capabilities of the initialize request, by name.
+//
+// Returns:
+//   - map[Feature]bool: The unsupported features.
+func unsupportedFeatures(declared map[string]json.RawMessage) map[Feature]bool {
+	unsupported := map[Feature]bool{}
+	for feature, capability := range clientCapabilityFeatures {
+		if _, ok := declared[capability]; !ok {
+			unsupported[feature] = true
+		}
+	}
+	return unsupported
+}
+
+// unavailable tells why a feature cannot be used in this session.
+//
+// Parameters:
+//   - f: The feature.
+//
+// Returns:
+//   - string: The reason, or empty if the feature is available.
+func (s *Server) unavailable(f Feature) string {
+	switch {
+	case s.disabled[f]:
+		return fmt.Sprintf("feature %q is disabled on this server", f)
+	case s.unsupported[f]:
+		return fmt.Sprintf("feature %q requires the %q client capability", f, clientCapabilityFeatures[f])
+	}
+	return ""
+}
+
+// toolUnavailable tells why a tool cannot be used in this session.
+//
+// Parameters:
+//   - t: The tool.
+//
+// Returns:
+//   - Feature: The first unavailable feature among the tool's feature and
+//     the ones it requires.
+//   - string: The reason, or empty if the tool is available.
+func (s *Server) toolUnavailable(t toolDefinition) (Feature, string) {
+	for _, f := range append([]Feature{t.feature}, t.requires...) {
+		if reason := s.unavailable(f); reason != "" {
+			return f, reason
+		}
+	}
+	return "", ""
+}
+
+// enabledTools returns the tools whose features are available, in listing order.
+//
+// Returns:
+//   - []toolDefinition: The enabled tools.
+func (s *Server) enabledTools() []toolDefinition {
+	var enabled []toolDefinition
+	for _, t := range s.tools() {
+		if _, reason := s.toolUnavailable(t); reason == "" {
+			enabled = append(enabled, t)
+		}
+	}
+	return enabled
+}
+
+// toolSchema returns the input schema of a tool without the arguments of
+// unavailable features.
+//
+// Parameters:
+//   - t: The tool.
+//
+// Returns:
+//   - map[string]interface{}: The input schema.
+func (s *Server) toolSchema(t toolDefinition) map[string]interface{} {
+	schemaMap := t.inputSchema()
+	properties := argumentProperties(schemaMap)
+	for feature, names := range t.featureArgs {
+		if s.unavailable(feature) == "" {
+			continue
+		}
+		for _, name := range names {
+			delete(properties, name)
+		}
+	}
+	return schemaMap
+}
+
+// checkFeatureArgs rejects arguments that belong to unavailable features.
+//
+// Parameters:
+//   - t: The tool being called.
+//   - args: The call arguments.
+//
+// Returns:
+//   - error: An error naming the first disabled argument given.
+func (s *Server) checkFeatureArgs(t toolDefinition, args map[string]interface{}) error {
+	for feature, names := range t.featureArgs {
+		reason := s.unavailable(feature)
+		if reason == "" {
+			continue
+		}
+		for _, name := range names {
+			if _, ok := args[name]; ok {
+				return fmt.Errorf("argument %q is not available: %s", name, reason)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+	"gopkg.in/yaml.v3"
+)
+
+// fragmentVerdict is the result of validate_fragment, shaped like the
+// verdict of generate_config with validateOnly set.
+type fragmentVerdict struct {
+	// Path is the dotted path of the fragment.
+	Path string `json:"path"`
+	// APIVersion is the apiVersion whose schema was used.
+	APIVersion string `json:"apiVersion"`
+	// Valid is true if the fragment has no errors.
+	Valid bool `json:"valid"`
+	// Errors lists the error findings, in the language of the session.
+	Errors []string `json:"errors,omitempty"`
+	// Warnings lists the other findings, in the language of the session.
+	Warnings []string `json:"warnings,omitempty"`
+	// Findings lists the findings with their rule ID, path and suggestion.
+	// They are not translated.
+	Findings []tool.Finding `json:"findings"`
+}
+
+// handleValidateFragment implements the validate_fragment tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "path" and "fragment", and the optional "apiVersion".
+//
+// Returns:
+//   - []map[string]interface{}: The verdict as a JSON document.
+//   - error: An error if the fragment is not valid YAML, the apiVersion is
+//     unsupported or the path is not a section of its schema.
+func handleValidateFragment(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	path, _ := args["path"].(string)
+	apiVersion, _ := args["apiVersion"].(string)
+	fragment := args["fragment"]
+	if text, ok := fragment.(string); ok {
+		if err := yaml.Unmarshal([]byte(text), &fragment); err != nil {
+			return nil, fmt.Errorf("fragment is not valid YAML: %w", err)
+		}
+	}
+
+	report, err := tool.ValidateFragment(path, fragment, apiVersion, s.toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	v := fragmentVerdict{Path: report.Path, APIVersion: report.APIVersion, Valid: report.Valid, Findings: report.Findings}
+	for _, f := range report.Findings {
+		if f.Severity == tool.SeverityError {
+			v.Errors = append(v.Errors, s.catalog.Text(f.Text()))
+		} else {
+			v.Warnings = append(v.Warnings, s.catalog.Text(f.String()))
+		}
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode verdict: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(out))}, nil
+}
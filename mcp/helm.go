@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+	"gopkg.in/yaml.v3"
+)
+
+// handleRenderHelmChart implements the render_helm_chart tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "config", and the optional "chart" and "values".
+//
+// Returns:
+//   - []map[string]interface{}: The summary of the rendered chart as a JSON
+//     document, followed by the manifests.
+//   - error: An error if the chart cannot be rendered.
+func handleRenderHelmChart(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	config, err := tool.ParseConfig(args["config"])
+	if err != nil {
+		return nil, err
+	}
+	req := tool.ChartRender{}
+	req.Chart, _ = args["chart"].(string)
+	if req.Values, err = chartValues(args["values"]); err != nil {
+		return nil, err
+	}
+
+	rendered, err := tool.RenderChart(context.Background(), config, req, s.toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := json.MarshalIndent(rendered, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rendered chart: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(summary)), textContent(rendered.Manifests)}, nil
+}
+
+// handleCheckInstallOrder implements the check_install_order tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "config", and the optional "values" and "manifests".
+//
+// Returns:
+//   - []map[string]interface{}: The report as a JSON document, followed by
+//     the findings.
+//   - error: An error if the configuration, values or manifests are
+//     malformed.
+func handleCheckInstallOrder(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	config, err := tool.ParseConfig(args["config"])
+	if err != nil {
+		return nil, err
+	}
+	req := tool.InstallOrderRequest{Values: map[string]string{}, Manifests: map[string]string{}}
+	values, _ := args["values"].(map[string]interface{})
+	for chart, v := range values {
+		if req.Values[chart], err = chartValues(v); err != nil {
+			return nil, fmt.Errorf("chart %q: %w", chart, err)
+		}
+	}
+	manifests, _ := args["manifests"].(map[string]interface{})
+	for name, v := range manifests {
+		req.Manifests[name], _ = v.(string)
+	}
+
+	report, err := tool.CheckInstallOrder(context.Background(), config, req, s.toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode install order report: %w", err)
+	}
+	content := []map[string]interface{}{textContent(string(out))}
+	if len(report.Findings) > 0 {
+		warnings := make([]string, len(report.Findings))
+		for i, f := range report.Findings {
+			warnings[i] = f.String()
+		}
+		content = append(content, textContent(s.formatWarnings(warnings)))
+	}
+	return content, nil
+}
+
+// chartValues converts chart values given as an object or as a YAML string
+// to YAML.
+//
+// Parameters:
+//   - v: The values, or nil.
+//
+// Returns:
+//   - string: The values as YAML, or empty.
+//   - error: An error if the object cannot be encoded.
+func chartValues(v interface{}) (string, error) {
+	switch values := v.(type) {
+	case string:
+		return values, nil
+	case map[string]interface{}:
+		out, err := yaml.Marshal(values)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode values: %w", err)
+		}
+		return string(out), nil
+	}
+	return "", nil
+}
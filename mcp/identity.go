@@ -0,0 +1,75 @@
+package mcp
+
+// ServerInfo is the identity the server advertises in the initialize result.
+type ServerInfo struct {
+	// Name is the programmatic name of the server.
+	Name string
+	// Title is the human readable name shown by clients, if any.
+	Title string
+	// Version is the server version.
+	Version string
+	// Instructions is guidance on how to use the server, passed to the client
+	// LLM. If empty, instructions are generated from the capabilities.
+	Instructions string
+}
+
+// DefaultServerInfo is the identity advertised unless overridden with
+// WithServerInfo.
+var DefaultServerInfo = ServerInfo{Name: "eib-mcp", Version: "0.1.0"}
+
+// WithServerInfo overrides the advertised identity, e.g. to brand the server
+// when it is embedded in another product. Empty fields keep their default.
+//
+// Parameters:
+//   - info: The identity fields to override.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithServerInfo(info ServerInfo) Option {
+	return func(s *Server) {
+		if info.Name != "" {
+			s.info.Name = info.Name
+		}
+		if info.Title != "" {
+			s.info.Title = info.Title
+		}
+		if info.Version != "" {
+			s.info.Version = info.Version
+		}
+		if info.Instructions != "" {
+			s.info.Instructions = info.Instructions
+		}
+	}
+}
+
+// initializeResult builds the result of the initialize method from the
+// server identity.
+//
+// Returns:
+//   - map[string]interface{}: The protocol version, capabilities, server
+//     information and instructions.
+func (s *Server) initializeResult() map[string]interface{} {
+	serverInfo := map[string]interface{}{
+		"name":    s.info.Name,
+		"version": s.info.Version,
+	}
+	if s.info.Title != "" {
+		serverInfo["title"] = s.info.Title
+	}
+
+	result := map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"capabilities": map[string]interface{}{
+			"tools":     map[string]interface{}{},
+			"resources": map[string]interface{}{},
+			"logging":   map[string]interface{}{},
+		},
+		"serverInfo": serverInfo,
+	}
+	if s.info.Instructions != "" {
+		result["instructions"] = s.info.Instructions
+	} else if caps, err := s.capabilities(); err == nil {
+		result["instructions"] = generatedInstructions(caps)
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// generatedInstructions describes how to use this server build, for the
+// instructions field of the initialize result.
+//
+// The text is derived from the capabilities, so it always matches the
+// supported apiVersions, the enabled validations and offline mode.
+//
+// Parameters:
+//   - caps: The capabilities of the server.
+//
+// Returns:
+//   - string: The instructions.
+func generatedInstructions(caps capabilities) string {
+	var b strings.Builder
+	b.WriteString("This server generates and validates SUSE Edge Image Builder (EIB) definition files.\n")
+	fmt.Fprintf(&b, "- Supported apiVersions: %s. Use %s unless the user targets an older EIB release.\n",
+		strings.Join(caps.APIVersions, ", "), caps.LatestAPIVersion)
+	if caps.Validation.TargetRelease != "" {
+		fmt.Fprintf(&b, "- Configurations must be compatible with EIB %s; newer fields are rejected.\n", caps.Validation.TargetRelease)
+	}
+
+	if caps.Validation.Mode == string(tool.ValidationPermissive) {
+		b.WriteString("- Validation is permissive: unknown fields are kept and reported as warnings.\n")
+	} else {
+		b.WriteString("- Validation is strict: unknown fields are rejected.\n")
+	}
+	fmt.Fprintf(&b, "- Every configuration is checked against the EIB schema and %d cross-field rules.\n", len(caps.Validation.Rules))
+	if p := caps.Validation.PasswordPolicy; p != nil {
+		fmt.Fprintf(&b, "- Plaintext passwords need at least %d characters and %d character classes and must not be common defaults (%s). Never invent passwords; ask the user.\n", p.MinLength, p.MinClasses, p.Mode)
+	}
+	if caps.Validation.EIBValidate {
+		b.WriteString("- Generated configurations are also checked with `eib validate`.\n")
+	}
+
+	switch {
+	case caps.Offline:
+		b.WriteString("- Offline mode is active: checks that contact external services are skipped.\n")
+	case len(caps.NetworkChecks) > 0:
+		fmt.Fprintf(&b, "- Network checks: %s.\n", strings.Join(caps.NetworkChecks, ", "))
+	}
+	if len(caps.AllowedDirs) > 0 {
+		fmt.Fprintf(&b, "- Files can only be read and written under: %s.\n", strings.Join(caps.AllowedDirs, ", "))
+	}
+
+	fmt.Fprintf(&b, "- Tools: %s. Call list_capabilities for details.", strings.Join(caps.Tools, ", "))
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// handleGenerateLayered implements the generate_layered tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "base" template and the optional "environment" and "site"
+//     overlays.
+//
+// Returns:
+//   - []map[string]interface{}: The generated YAML, followed by warnings if
+//     any, and the layer of each field as a JSON document.
+//   - error: An error if a layer is malformed or the merged configuration is
+//     invalid.
+func handleGenerateLayered(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	layers := make([]map[string]interface{}, len(tool.LayerNames))
+	for i, name := range tool.LayerNames {
+		v, ok := args[name]
+		if !ok {
+			continue
+		}
+		layer, err := tool.ParseConfig(v)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", name, err)
+		}
+		layers[i] = layer
+	}
+	layering := tool.LayerConfig(layers[0], layers[1], layers[2])
+	opts := s.toolOptions
+	notes, err := opts.RulePolicy.Check(layering.Findings)
+	if err != nil {
+		return nil, err
+	}
+	notes, suppressed := tool.SuppressFindings(notes, opts.Suppress)
+
+	result, err := tool.Generate(layering.Config, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.addNotes(result, notes, suppressed)
+
+	out, err := json.MarshalIndent(map[string]interface{}{"origins": layering.Origins}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode origins: %w", err)
+	}
+	return append(s.resultContent(result), textContent(string(out))), nil
+}
@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation; 0 to 2 are the standard streams.
+const listenFDsStart = 3
+
+// ActivationListeners returns the sockets passed by systemd socket
+// activation, following sd_listen_fds(3): LISTEN_PID names this process and
+// LISTEN_FDS counts the descriptors starting at 3. The variables are removed
+// from the environment so that child processes, such as `eib validate`, do
+// not take the sockets for theirs.
+//
+// Returns:
+//   - []net.Listener: The listening sockets, or nil if the process was not
+//     socket activated.
+//   - error: An error if a passed descriptor is not a listening socket.
+func ActivationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		// FileListener duplicates the descriptor.
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("socket activation: descriptor %d is not a listening socket (use Accept=no): %w", fd, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// ServeListeners serves MCP sessions on listening sockets: every accepted
+// connection is a session of its own, with requests and responses exchanged
+// as on the standard streams.
+//
+// With an idle timeout, it returns once no connection has been open for that
+// long. Under socket activation, systemd keeps the sockets open and starts
+// the server again on the next connection, so it uses no resources while
+// unused.
+//
+// When ctx ends, it stops accepting connections and drains every session as
+// Drain does, waiting at most drainTimeout for the requests in progress.
+//
+// Parameters:
+//   - ctx: Ends to request a shutdown.
+//   - listeners: The listening sockets; they are closed on return.
+//   - idle: How long to wait without connections before returning, or 0 to
+//     serve until a listener fails.
+//   - drainTimeout: How long to wait for requests in progress on shutdown.
+//   - newServer: Creates the server of a session from its streams.
+//
+// Returns:
+//   - error: nil after the idle timeout or a complete drain, ErrDrainTimeout
+//     if requests were terminated, or an ErrTransport error if accepting
+//     connections fails.
+func ServeListeners(ctx context.Context, listeners []net.Listener, idle, drainTimeout time.Duration, newServer func(in io.Reader, out io.Writer) *Server) error {
+	conns := make(chan net.Conn)
+	failures := make(chan error, len(listeners))
+	stop := make(chan struct{})
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					failures <- err
+					return
+				}
+				select {
+				case conns <- conn:
+				case <-stop:
+					conn.Close()
+					return
+				}
+			}
+		}(l)
+	}
+	closeAll := func() {
+		close(stop)
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+
+	var timeout <-chan time.Time
+	var timer *time.Timer
+	if idle > 0 {
+		timer = time.NewTimer(idle)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	done := make(chan *Server)
+	sessions := make(map[*Server]net.Conn)
+	for {
+		select {
+		case conn := <-conns:
+			server := newServer(conn, conn)
+			sessions[server] = conn
+			go func() {
+				// Once ServeListeners returned, nothing receives from done;
+				// stop is closed on every return.
+				defer func() {
+					select {
+					case done <- server:
+					case <-stop:
+					}
+				}()
+				defer conn.Close()
+				if err := server.Serve(); err != nil {
+					fmt.Fprintf(os.Stderr, "Session %s ended: %v\n", conn.RemoteAddr(), err)
+				}
+			}()
+		case server := <-done:
+			delete(sessions, server)
+			if len(sessions) == 0 && timer != nil {
+				timer.Reset(idle)
+			}
+		case <-ctx.Done():
+			closeAll()
+			return drainSessions(sessions, drainTimeout)
+		case <-timeout:
+			if len(sessions) > 0 {
+				continue
+			}
+			closeAll()
+			return nil
+		case err := <-failures:
+			closeAll()
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("%w: accepting connections: %v", ErrTransport, err)
+		}
+	}
+}
+
+// drainSessions drains the sessions in parallel, then closes their
+// connections, which ends the requests still running.
+//
+// Parameters:
+//   - sessions: The open sessions and their connections.
+//   - timeout: How long to wait for the requests in progress.
+//
+// Returns:
+//   - error: nil if every session drained, or ErrDrainTimeout.
+func drainSessions(sessions map[*Server]net.Conn, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	results := make(chan error, len(sessions))
+	for server := range sessions {
+		go func() { results <- server.Drain(ctx) }()
+	}
+	var err error
+	for range sessions {
+		if e := <-results; e != nil {
+			err = e
+		}
+	}
+	for _, conn := range sessions {
+		conn.Close()
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// maxManyItems caps the number of configurations one generate_many call
+// generates.
+const maxManyItems = 100
+
+// maxParallelism caps the number of configurations generated at once.
+const maxParallelism = 16
+
+// manyItem is the outcome of one configuration of generate_many: the
+// result generate_config would have returned for it, with its index.
+type manyItem struct {
+	// Index is the position of the arguments in "items".
+	Index int `json:"index"`
+	// Content holds the content blocks of the result.
+	Content []map[string]interface{} `json:"content"`
+	// IsError is true if the configuration could not be generated.
+	IsError bool `json:"isError,omitempty"`
+	// Meta holds the error code and data of a failure.
+	Meta interface{} `json:"_meta,omitempty"`
+}
+
+// handleGenerateMany implements the generate_many tool.
+//
+// Every item is checked and generated exactly as a generate_config call
+// with the same arguments, several at a time. A failing item does not stop
+// the others. Progress is reported with notifications/progress as items
+// complete, when the client passes a progressToken.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "items", each a generate_config argument object, and an
+//     optional "parallelism".
+//
+// Returns:
+//   - []map[string]interface{}: A summary, then the results of the items in
+//     their order as a JSON document.
+//   - error: An error if the items are missing or malformed.
+func handleGenerateMany(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	var items []map[string]interface{}
+	if err := decodeArgument(args["items"], "items", &items); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 || len(items) > maxManyItems {
+		return nil, fmt.Errorf("argument \"items\" must hold between 1 and %d argument objects", maxManyItems)
+	}
+	parallelism := runtime.GOMAXPROCS(0)
+	if v, ok := args["parallelism"]; ok {
+		if err := decodeArgument(v, "parallelism", &parallelism); err != nil {
+			return nil, err
+		}
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	if parallelism > maxParallelism {
+		parallelism = maxParallelism
+	}
+
+	generate, _ := s.lookupTool("generate_config")
+	results := make([]manyItem, len(items))
+	done := make(chan int)
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item map[string]interface{}) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = s.generateItem(generate, i, item)
+			done <- i
+		}(i, item)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Notifications are only written from this goroutine, so they never
+	// interleave on the output stream.
+	completed, failed := 0, 0
+	for i := range done {
+		completed++
+		if results[i].IsError {
+			failed++
+		}
+		s.notifyProgress(float64(completed), float64(len(items)), fmt.Sprintf("Generated %d of %d configurations", completed, len(items)))
+	}
+
+	out, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode results: %w", err)
+	}
+	summary := fmt.Sprintf("Generated %d of %d configurations; %d failed.", len(items)-failed, len(items), failed)
+	return []map[string]interface{}{textContent(summary), textContent(string(out))}, nil
+}
+
+// generateItem runs generate_config for one item of generate_many.
+//
+// Parameters:
+//   - generate: The generate_config tool.
+//   - index: The position of the item.
+//   - args: The generate_config arguments.
+//
+// Returns:
+//   - manyItem: The result, with IsError set on failure. A panic is
+//     reported as an internal error of the item.
+func (s *Server) generateItem(generate toolDefinition, index int, args map[string]interface{}) (item manyItem) {
+	item.Index = index
+	fail := func(code int, message string, data interface{}) manyItem {
+		item.IsError = true
+		item.Content = []map[string]interface{}{textContent(message)}
+		meta := map[string]interface{}{"errorCode": code}
+		if data != nil {
+			meta["errorData"] = data
+		}
+		item.Meta = meta
+		return item
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logf(s.callID, "Panic while generating item %d: %v\n%s", index, r, debug.Stack())
+			item = fail(CodeInternalError, fmt.Sprintf("Internal error: %v", r), nil)
+		}
+	}()
+
+	if err := s.checkFeatureArgs(generate, args); err != nil {
+		return fail(CodeInvalidParams, err.Error(), nil)
+	}
+	problems, err := validateArguments(generate, s.argumentSchema(generate), args)
+	if err != nil {
+		return fail(CodeInternalError, err.Error(), nil)
+	}
+	if len(problems) > 0 {
+		return fail(CodeInvalidParams, fmt.Sprintf("Invalid params: %s: %s", problems[0].Field, problems[0].Message), map[string]interface{}{"errors": problems})
+	}
+
+	content, err := generate.handler(s, args)
+	if err != nil {
+		logf(s.callID, "Item %d of generate_many failed: %v", index, err)
+		result := s.toolErrorResult(err)
+		item.IsError = true
+		item.Content, _ = result["content"].([]map[string]interface{})
+		item.Meta = result["_meta"]
+		return item
+	}
+	item.Content = content
+	return item
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the tool call latency
+// histogram buckets: from fast in-memory validations to base image downloads.
+var latencyBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// toolStats is the latency histogram of a tool.
+type toolStats struct {
+	// counts holds the number of calls per bucket of latencyBuckets, not
+	// cumulated; the last element counts the calls above every bound.
+	counts []uint64
+	// sum is the total duration of the calls, in seconds.
+	sum float64
+	// errors is the number of calls that failed.
+	errors uint64
+}
+
+// Metrics records the latency of tool calls per tool. One Metrics is usually
+// shared by every session of the process. A Metrics is safe for concurrent
+// use.
+type Metrics struct {
+	mu    sync.Mutex
+	tools map[string]*toolStats
+}
+
+// NewMetrics creates an empty set of metrics.
+//
+// Returns:
+//   - *Metrics: The metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{tools: map[string]*toolStats{}}
+}
+
+// observe records a tool call.
+//
+// Parameters:
+//   - tool: The tool name.
+//   - d: How long the call took.
+//   - failed: Whether the call returned an error.
+func (m *Metrics) observe(tool string, d time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.tools[tool]
+	if !ok {
+		stats = &toolStats{counts: make([]uint64, len(latencyBuckets)+1)}
+		m.tools[tool] = stats
+	}
+	seconds := d.Seconds()
+	stats.counts[sort.SearchFloat64s(latencyBuckets, seconds)]++
+	stats.sum += seconds
+	if failed {
+		stats.errors++
+	}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format:
+//   - eib_mcp_tool_call_duration_seconds, a histogram per tool;
+//   - eib_mcp_tool_call_errors_total, the failed calls per tool.
+//
+// Parameters:
+//   - w: The writer.
+//
+// Returns:
+//   - error: An error if writing fails.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.tools))
+	for name := range m.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b []byte
+	b = append(b, "# HELP eib_mcp_tool_call_duration_seconds Duration of tool calls.\n"...)
+	b = append(b, "# TYPE eib_mcp_tool_call_duration_seconds histogram\n"...)
+	for _, name := range names {
+		stats := m.tools[name]
+		var total uint64
+		for i, bound := range latencyBuckets {
+			total += stats.counts[i]
+			b = fmt.Appendf(b, "eib_mcp_tool_call_duration_seconds_bucket{tool=%q,le=%q} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), total)
+		}
+		total += stats.counts[len(latencyBuckets)]
+		b = fmt.Appendf(b, "eib_mcp_tool_call_duration_seconds_bucket{tool=%q,le=\"+Inf\"} %d\n", name, total)
+		b = fmt.Appendf(b, "eib_mcp_tool_call_duration_seconds_sum{tool=%q} %g\n", name, stats.sum)
+		b = fmt.Appendf(b, "eib_mcp_tool_call_duration_seconds_count{tool=%q} %d\n", name, total)
+	}
+	b = append(b, "# HELP eib_mcp_tool_call_errors_total Tool calls that returned an error.\n"...)
+	b = append(b, "# TYPE eib_mcp_tool_call_errors_total counter\n"...)
+	for _, name := range names {
+		b = fmt.Appendf(b, "eib_mcp_tool_call_errors_total{tool=%q} %d\n", name, m.tools[name].errors)
+	}
+	_, err := w.Write(b)
+	return err
+}
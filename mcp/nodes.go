@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// kubernetesConfigSchema describes the server.yaml and agent.yaml argument
+// of generate_config and explain_nodes.
+//
+// Returns:
+//   - map[string]interface{}: The argument schema.
+func kubernetesConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"server": map[string]interface{}{"type": "string", "description": "Content of kubernetes/config/server.yaml, the RKE2 or K3s configuration of the server nodes."},
+			"agent":  map[string]interface{}{"type": "string", "description": "Content of kubernetes/config/agent.yaml, the RKE2 or K3s configuration of the agent nodes."},
+		},
+		"additionalProperties": false,
+		"description":          "The RKE2 or K3s configuration files of the nodes. Server-only options, such as etcd or control plane settings, are rejected in agent.yaml when the cluster has agents. The files are returned as kubernetes/config/ artifacts, not in the generated YAML.",
+	}
+}
+
+// handleExplainNodes implements the explain_nodes tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "config" and its "kubernetesConfig" files.
+//
+// Returns:
+//   - []map[string]interface{}: The node report as a JSON document, followed
+//     by its findings if any.
+//   - error: An error if the configuration is malformed or has no kubernetes section.
+func handleExplainNodes(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	config, err := tool.ParseConfig(args["config"])
+	if err != nil {
+		return nil, err
+	}
+	var kc tool.KubernetesConfig
+	if v, ok := args["kubernetesConfig"]; ok {
+		if err := decodeArgument(v, "kubernetesConfig", &kc); err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := tool.ExplainNodes(config, kc)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode node report: %w", err)
+	}
+	content := []map[string]interface{}{textContent(string(out))}
+	if len(report.Findings) > 0 {
+		warnings := make([]string, len(report.Findings))
+		for i, f := range report.Findings {
+			warnings[i] = f.String()
+		}
+		content = append(content, textContent(s.formatWarnings(warnings)))
+	}
+	return content, nil
+}
@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// planArgumentsSchema returns the properties shared by plan_config and
+// apply_config.
+//
+// Returns:
+//   - map[string]interface{}: The config, patch and patchType properties.
+func planArgumentsSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"config": map[string]interface{}{
+			"type":        []string{"object", "string"},
+			"description": "The current configuration, as an object or as a YAML string.",
+		},
+		"patch": map[string]interface{}{
+			"type":        []string{"object", "array"},
+			"description": "The change set: a partial configuration for the merge types, a list of operations for json-patch.",
+		},
+		"patchType": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{string(tool.PatchDeepMerge), string(tool.PatchMergePatch), string(tool.PatchJSONPatch)},
+			"description": "How to apply the patch, as for patch_config. Defaults to \"merge\".",
+		},
+	}
+}
+
+// handlePlanConfig implements the plan_config tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The current "config", the "patch" and its "patchType".
+//
+// Returns:
+//   - []map[string]interface{}: The plan as a JSON document.
+//   - error: An error if the patch cannot be applied or the result is invalid.
+func handlePlanConfig(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	config, err := tool.ParseConfig(args["config"])
+	if err != nil {
+		return nil, err
+	}
+	patchType, _ := args["patchType"].(string)
+
+	plan, err := tool.PlanPatch(config, args["patch"], tool.PatchType(patchType), s.toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plan: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(out))}, nil
+}
+
+// handleApplyConfig implements the apply_config tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The planned "config", "patch" and "patchType", the "planId" and
+//     an optional "outputDir" and "overwrite".
+//
+// Returns:
+//   - []map[string]interface{}: The final YAML, warnings, artifacts and
+//     checksums, followed by the written paths if any.
+//   - error: An error if the arguments differ from the plan or the result is invalid.
+func handleApplyConfig(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	config, err := tool.ParseConfig(args["config"])
+	if err != nil {
+		return nil, err
+	}
+	patchType, _ := args["patchType"].(string)
+	planID, _ := args["planId"].(string)
+
+	result, err := tool.ApplyPlan(config, args["patch"], tool.PatchType(patchType), planID, s.toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	content := s.resultContent(result)
+	if dir, _ := args["outputDir"].(string); dir != "" {
+		overwrite, _ := args["overwrite"].(bool)
+		written, err := s.writeResult(dir, result, overwrite)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, textContent(fmt.Sprintf("Wrote %d files:\n%s\n", len(written), strings.Join(written, "\n"))))
+	}
+	return content, nil
+}
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+)
+
+// notification is a JSON-RPC 2.0 notification, a request without an id
+// that expects no response.
+type notification struct {
+	// JSONRPC specifies the version of the JSON-RPC protocol. Must be "2.0".
+	JSONRPC string `json:"jsonrpc"`
+	// Method is the notification name.
+	Method string `json:"method"`
+	// Params contains the notification parameters.
+	Params interface{} `json:"params,omitempty"`
+}
+
+// notifyProgress reports the progress of the running tool call with a
+// notifications/progress message, if the client asked for it by passing a
+// progressToken in the _meta of the call.
+//
+// Requests are handled one at a time and writes are serialized, so the
+// notification cannot interleave with a response. A failure to write is logged; the response that follows
+// reports it.
+//
+// Parameters:
+//   - progress: The work done so far, increasing with every call.
+//   - total: The total amount of work, or 0 if unknown.
+//   - message: A human readable description of the progress.
+func (s *Server) notifyProgress(progress, total float64, message string) {
+	if s.progressToken == nil {
+		return
+	}
+	params := map[string]interface{}{
+		"progressToken": s.progressToken,
+		"progress":      progress,
+		"message":       message,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	if err := s.writeMessage(notification{JSONRPC: "2.0", Method: "notifications/progress", Params: params}, "notification"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write notification: %v\n", err)
+	}
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// handleRancherRegistration implements the rancher_registration tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "rancherUrl", "token" and optional "clusterId", plus an
+//     optional "outputDir" and "overwrite".
+//
+// Returns:
+//   - []map[string]interface{}: The manifest artifact and its checksum, or
+//     the written path.
+//   - error: An error if the manifest cannot be downloaded or written.
+func handleRancherRegistration(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	var reg tool.RancherRegistration
+	reg.URL, _ = args["rancherUrl"].(string)
+	reg.Token, _ = args["token"].(string)
+	reg.ClusterID, _ = args["clusterId"].(string)
+
+	artifact, err := tool.RancherManifest(context.Background(), reg, s.toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	artifacts := []tool.Artifact{artifact}
+	checksums, err := tool.ResultChecksums("", artifacts)
+	if err != nil {
+		return nil, err
+	}
+	delete(checksums, tool.DefinitionFile)
+	notes := "The manifest embeds the registration token: keep the image configuration directory private. EIB applies it once the cluster is up, so the configuration needs a kubernetes section."
+
+	if dir, _ := args["outputDir"].(string); dir != "" {
+		overwrite, _ := args["overwrite"].(bool)
+		if err := s.checkOutputPaths(dir, []string{artifact.Path}); err != nil {
+			return nil, err
+		}
+		written, err := tool.WriteArtifacts(dir, artifacts, overwrite)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]interface{}{
+			textContent(fmt.Sprintf("Wrote %d files:\n%s\n", len(written), strings.Join(written, "\n"))),
+			textContent("SHA-256 checksums:\n" + tool.FormatChecksums(checksums)),
+			textContent(notes),
+		}, nil
+	}
+
+	out, err := json.MarshalIndent(artifacts, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode artifacts: %w", err)
+	}
+	return []map[string]interface{}{
+		textContent("Artifacts (paths relative to the image configuration directory):\n" + string(out)),
+		textContent("SHA-256 checksums:\n" + tool.FormatChecksums(checksums)),
+		textContent(notes),
+	}, nil
+}
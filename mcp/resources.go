@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"encoding/json"
+
+	"github.com/e-minguez/eib-mcp/docs"
+)
+
+// docsMimeType is the MIME type of the documentation resources.
+const docsMimeType = "text/markdown"
+
+// handleResourcesList handles the "resources/list" method.
+//
+// It lists the embedded EIB documentation excerpts, one per configuration section.
+//
+// Parameters:
+//   - req: The resources/list request.
+//
+// Returns:
+//   - *JSONRPCResponse: The response containing the list of resources.
+func (s *Server) handleResourcesList(req *JSONRPCRequest) *JSONRPCResponse {
+	list := []map[string]interface{}{}
+	for _, d := range docs.List() {
+		list = append(list, map[string]interface{}{
+			"uri":         d.URI(),
+			"name":        "EIB docs: " + d.Title,
+			"description": d.Description,
+			"mimeType":    docsMimeType,
+		})
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"resources": list,
+		},
+	}
+}
+
+// handleResourcesRead handles the "resources/read" method.
+//
+// Parameters:
+//   - req: The resources/read request containing the resource URI.
+//
+// Returns:
+//   - *JSONRPCResponse: The response containing the resource content or an error.
+func (s *Server) handleResourcesRead(req *JSONRPCRequest) *JSONRPCResponse {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &JSONRPCError{Code: CodeParseError, Message: "Parse error"},
+		}
+	}
+
+	d, ok := docs.Lookup(params.URI)
+	if !ok {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &JSONRPCError{Code: CodeResourceNotFound, Message: "Resource not found", Data: map[string]interface{}{"uri": params.URI}},
+		}
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"contents": []map[string]interface{}{
+				{
+					"uri":      d.URI(),
+					"mimeType": docsMimeType,
+					"text":     d.Content,
+				},
+			},
+		},
+	}
+}
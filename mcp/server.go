@@ -5,13 +5,18 @@
 package mcp
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"runtime/debug"
+	"sync"
+	"time"
 
-	"github.com/e-minguez/eib-mcp/schema"
+	"github.com/e-minguez/eib-mcp/i18n"
+	"github.com/e-minguez/eib-mcp/otlp"
 	"github.com/e-minguez/eib-mcp/tool"
 )
 
@@ -27,6 +32,9 @@ type JSONRPCRequest struct {
 	Params json.RawMessage `json:"params,omitempty"`
 	// ID is a unique identifier established by the client.
 	ID interface{} `json:"id"`
+
+	// correlationID is assigned by the server to trace the request in logs and errors.
+	correlationID string
 }
 
 // JSONRPCResponse represents a JSON-RPC 2.0 response.
@@ -62,57 +70,366 @@ type JSONRPCError struct {
 type Server struct {
 	in  io.Reader
 	out io.Writer
+	// toolOptions configures how tools validate and generate configurations.
+	toolOptions tool.Options
+	// disabled holds the features whose tools and arguments are unavailable.
+	disabled map[Feature]bool
+	// unsupported holds the features the connected client cannot use.
+	unsupported map[Feature]bool
+	// info is the identity advertised in the initialize result.
+	info ServerInfo
+	// baseImagesDir is the directory list_base_images lists by default.
+	baseImagesDir string
+	// progressToken is the token of the running tool call's progress
+	// notifications, or nil if the client did not ask for them.
+	progressToken interface{}
+	// callID is the correlation ID of the running tool call.
+	callID string
+	// maxRequestSize is the largest request, in bytes; 0 selects DefaultMaxRequestSize.
+	maxRequestSize int64
+	// metrics records the latency of tool calls, if set.
+	metrics *Metrics
+	// slowCall is the duration above which a tool call is logged with the
+	// trace of its steps; 0 disables the log.
+	slowCall time.Duration
+	// exporter receives the spans of every request, if set.
+	exporter *otlp.Exporter
+	// catalog translates messages into the language of the session; nil
+	// is English.
+	catalog *i18n.Catalog
+
+	// outMu serializes writes to out, which Drain shares with Serve.
+	outMu sync.Mutex
+	// mu guards the fields below, which Drain reads from another goroutine.
+	mu sync.Mutex
+	// inflight is the request being handled, or nil between requests.
+	inflight *inflightRequest
+	// draining is set by Drain; new requests are then rejected.
+	draining bool
+	// logLevel is the lowest level of notifications/message set by the
+	// client, or empty for all.
+	logLevel string
+}
+
+// Option configures optional Server behavior.
+type Option func(*Server)
+
+// WithToolOptions sets the options used when running configuration tools.
+//
+// Parameters:
+//   - opts: The tool options, e.g. the validation mode.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithToolOptions(opts tool.Options) Option {
+	return func(s *Server) {
+		s.toolOptions = opts
+	}
+}
+
+// WithMaxRequestSize sets the largest request the server accepts. Larger
+// requests are answered with an Invalid Request error.
+//
+// Parameters:
+//   - n: The limit in bytes; 0 or less keeps DefaultMaxRequestSize.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithMaxRequestSize(n int64) Option {
+	return func(s *Server) {
+		s.maxRequestSize = n
+	}
+}
+
+// WithMetrics records the latency of every tool call in m, which may be
+// shared with other servers.
+//
+// Parameters:
+//   - m: The metrics.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithMetrics(m *Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// WithSlowCallThreshold logs a warning for tool calls that take longer than
+// d, naming their slowest steps, such as downloads and schema validation.
+//
+// Parameters:
+//   - d: The threshold; 0 disables the warning.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithSlowCallThreshold(d time.Duration) Option {
+	return func(s *Server) {
+		s.slowCall = d
+	}
+}
+
+// WithTraceExporter exports a trace of every request to e: a span for the
+// request and one for each of its steps, such as schema validation, password
+// hashing and downloads.
+//
+// Parameters:
+//   - e: The exporter, which may be shared with other servers.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithTraceExporter(e *otlp.Exporter) Option {
+	return func(s *Server) {
+		s.exporter = e
+	}
+}
+
+// WithLanguage sets the language of tool descriptions, error messages,
+// hints and warnings. Clients can choose another one with the "language"
+// initialization option.
+//
+// Parameters:
+//   - c: The catalog of the language, from i18n.Lookup; nil is English.
+//
+// Returns:
+//   - Option: The option to pass to NewServer.
+func WithLanguage(c *i18n.Catalog) Option {
+	return func(s *Server) {
+		s.catalog = c
+	}
 }
 
 // NewServer creates a new MCP server.
 //
-// It takes an input reader and an output writer for communication.
+// It takes an input reader and an output writer for communication, plus any
+// number of options customizing its behavior.
 //
 // Parameters:
 //   - in: The io.Reader to read requests from.
 //   - out: The io.Writer to write responses to.
+//   - opts: Optional settings applied in order.
 //
 // Returns:
 //   - *Server: A pointer to the newly created Server instance.
-func NewServer(in io.Reader, out io.Writer) *Server {
-	return &Server{in: in, out: out}
+func NewServer(in io.Reader, out io.Writer, opts ...Option) *Server {
+	s := &Server{in: in, out: out, info: DefaultServerInfo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Serve starts the server loop.
 //
-// It continuously reads from the input stream, processes requests,
+// It continuously reads requests from the input stream, processes them,
 // and writes responses to the output stream until the input is closed
-// or an error occurs.
+// or an error occurs. Requests are JSON values and may span several lines;
+// responses are always written one per line. Once Drain is called, Serve
+// returns after the response to the request in progress. Every request is
+// assigned a correlation ID, which prefixes the log lines about it and is
+// returned with any error.
 //
 // Returns:
-//   - error: An error if reading from the input fails, or nil on clean exit.
+//   - error: nil when the input is closed, or an ErrTransport error when
+//     reading or writing fails. ExitCode maps it to a process exit code.
 func (s *Server) Serve() error {
-	scanner := bufio.NewScanner(s.in)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
+	limit := s.maxRequestSize
+	if limit <= 0 {
+		limit = DefaultMaxRequestSize
+	}
+	reader := newRequestReader(s.in, limit)
+	for {
+		raw, err := reader.next()
+		var syntaxErr *json.SyntaxError
+		switch {
+		case err == io.EOF:
+			return nil
+		case errors.Is(err, ErrLineTooLong):
+			correlationID := newCorrelationID()
+			logf(correlationID, "Request too large: exceeds %d bytes", limit)
+			resp := tooLargeResponse(raw, limit)
+			withCorrelationID(resp, correlationID)
+			if err := s.writeResponse(resp); err != nil {
+				return err
+			}
 			continue
+		case errors.As(err, &syntaxErr), err == io.ErrUnexpectedEOF:
+		case err != nil:
+			return fmt.Errorf("%w: reading requests: %v", ErrTransport, err)
 		}
 
+		correlationID := newCorrelationID()
 		var req JSONRPCRequest
-		if err := json.Unmarshal(line, &req); err != nil {
-			// Ignore invalid JSON or log it?
-			// For now, just continue or send parse error if we can identify it's a request.
+		if err == nil {
+			err = json.Unmarshal(raw, &req)
+		}
+		if err != nil {
+			logf(correlationID, "Invalid request: %v", err)
+			resp := malformedRequestResponse(raw, err)
+			withCorrelationID(resp, correlationID)
+			if err := s.writeResponse(resp); err != nil {
+				return err
+			}
 			continue
 		}
 
-		resp := s.handleRequest(&req)
-		if resp != nil {
-			bytes, err := json.Marshal(resp)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to marshal response: %v\n", err)
-				continue
+		req.correlationID = correlationID
+		if !s.beginRequest(&req) {
+			logf(correlationID, "Rejected %s: server is shutting down", req.Method)
+			if resp := shuttingDownResponse(&req); resp != nil {
+				withCorrelationID(resp, correlationID)
+				if err := s.writeResponse(resp); err != nil {
+					return err
+				}
 			}
-			s.out.Write(bytes)
-			s.out.Write([]byte("\n"))
+			continue
+		}
+		resp := s.tracedHandleRequest(&req)
+		if resp != nil {
+			withCorrelationID(resp, correlationID)
+			err = s.writeResponse(resp)
+		}
+		if s.endRequest() && err == nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeResponse writes a response as a single line, with the error message
+// in the language of the session.
+//
+// A response that cannot be marshalled is logged and dropped; a failure to
+// write is returned, since the client can no longer be reached.
+//
+// Parameters:
+//   - resp: The response to write.
+//
+// Returns:
+//   - error: An ErrTransport error if the output stream fails.
+func (s *Server) writeResponse(resp *JSONRPCResponse) error {
+	if resp.Error != nil {
+		resp.Error.Message = s.catalog.Text(resp.Error.Message)
+	}
+	return s.writeMessage(resp, "response")
+}
+
+// writeMessage encodes a JSON-RPC message straight into the output stream,
+// followed by a newline, without building a copy of it first.
+//
+// Parameters:
+//   - msg: The message.
+//   - kind: What the message is, for errors, e.g. "response".
+//
+// Returns:
+//   - error: An ErrTransport error if the output stream fails; a message
+//     that cannot be marshalled is logged and dropped.
+func (s *Server) writeMessage(msg interface{}, kind string) error {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	if err := json.NewEncoder(s.out).Encode(msg); err != nil {
+		var unsupported *json.UnsupportedValueError
+		var unsupportedType *json.UnsupportedTypeError
+		var marshaler *json.MarshalerError
+		if errors.As(err, &unsupported) || errors.As(err, &unsupportedType) || errors.As(err, &marshaler) {
+			fmt.Fprintf(os.Stderr, "Failed to marshal %s: %v\n", kind, err)
+			return nil
+		}
+		return fmt.Errorf("%w: writing %s: %v", ErrTransport, kind, err)
+	}
+	return nil
+}
+
+// tooLargeResponse builds the error response to a request over the size
+// limit, recovering its id from the first bytes when possible.
+//
+// Parameters:
+//   - prefix: The beginning of the request.
+//   - limit: The size limit, in bytes.
+//
+// Returns:
+//   - *JSONRPCResponse: The error response.
+func tooLargeResponse(prefix []byte, limit int64) *JSONRPCResponse {
+	var id interface{}
+	if m := idPattern.FindSubmatch(prefix); m != nil {
+		if json.Unmarshal(m[1], &id) != nil {
+			id = nil
 		}
 	}
-	return scanner.Err()
+	return &JSONRPCResponse{JSONRPC: "2.0", ID: id, Error: &JSONRPCError{
+		Code:    CodeInvalidRequest,
+		Message: fmt.Sprintf("Request too large: exceeds %d bytes", limit),
+		Data: map[string]interface{}{
+			"limit": limit,
+			"hint":  "Split the work into smaller calls, e.g. one configuration per generate_config call, or ask the operator to raise -max-request-size.",
+		},
+	}}
+}
+
+// idPattern finds the id member of a request that is not valid JSON.
+var idPattern = regexp.MustCompile(`"id"\s*:\s*(-?[0-9]+|"(?:[^"\\]|\\.)*")`)
+
+// malformedRequestResponse builds the error response to a line that could
+// not be decoded as a request.
+//
+// Invalid JSON is a Parse error and valid JSON that is not a request object
+// is an Invalid Request. The request id is recovered when the line still
+// shows one, so the client can match the error to its request; otherwise the
+// id is null, as JSON-RPC 2.0 requires.
+//
+// Parameters:
+//   - line: The undecodable line.
+//   - err: The decoding error.
+//
+// Returns:
+//   - *JSONRPCResponse: The error response.
+func malformedRequestResponse(line []byte, err error) *JSONRPCResponse {
+	rpcErr := &JSONRPCError{Code: CodeParseError, Message: "Parse error", Data: err.Error()}
+	if json.Valid(line) {
+		rpcErr.Code, rpcErr.Message = CodeInvalidRequest, "Invalid Request"
+	}
+
+	var id interface{}
+	if m := idPattern.FindSubmatch(line); m != nil {
+		if json.Unmarshal(m[1], &id) != nil {
+			id = nil
+		}
+	}
+	return &JSONRPCResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+}
+
+// safeHandleRequest processes a request like handleRequest, converting a
+// panic into an internal error response.
+//
+// The panic and its stack are logged on os.Stderr, so a single malformed
+// input cannot terminate the session of the client.
+//
+// Parameters:
+//   - req: The incoming JSON-RPC request.
+//
+// Returns:
+//   - *JSONRPCResponse: The response, or nil if no response is needed.
+func (s *Server) safeHandleRequest(req *JSONRPCRequest) (resp *JSONRPCResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			logf(req.correlationID, "Panic while handling %q: %v\n%s", req.Method, r, debug.Stack())
+			resp = nil
+			if req.ID != nil {
+				resp = &JSONRPCResponse{
+					JSONRPC: "2.0",
+					ID:      req.ID,
+					Error: &JSONRPCError{
+						Code:    CodeInternalError,
+						Message: "Internal error",
+						Data:    map[string]interface{}{"method": req.Method, "panic": fmt.Sprint(r)},
+					},
+				}
+			}
+		}
+	}()
+	return s.handleRequest(req)
 }
 
 // handleRequest processes a single JSON-RPC request and returns a response.
@@ -132,6 +449,12 @@ func (s *Server) handleRequest(req *JSONRPCRequest) *JSONRPCResponse {
 		return s.handleToolsList(req)
 	case "tools/call":
 		return s.handleToolsCall(req)
+	case "resources/list":
+		return s.handleResourcesList(req)
+	case "resources/read":
+		return s.handleResourcesRead(req)
+	case "logging/setLevel":
+		return s.handleSetLevel(req)
 	default:
 		// Ignore notifications or unknown methods
 		if req.ID != nil {
@@ -139,7 +462,7 @@ func (s *Server) handleRequest(req *JSONRPCRequest) *JSONRPCResponse {
 				JSONRPC: "2.0",
 				ID:      req.ID,
 				Error: &JSONRPCError{
-					Code:    -32601,
+					Code:    CodeMethodNotFound,
 					Message: "Method not found",
 				},
 			}
@@ -150,7 +473,13 @@ func (s *Server) handleRequest(req *JSONRPCRequest) *JSONRPCResponse {
 
 // handleInitialize handles the "initialize" method.
 //
-// It returns the server's protocol version, capabilities, and information.
+// It returns the server's protocol version, capabilities, and identity, as
+// set with WithServerInfo.
+// Clients can enable offline mode with the "offline" initialization option;
+// a server started offline cannot be switched back online. Features that
+// need a client capability the client did not declare are hidden for the
+// rest of the session. The "language" option, e.g. "es", selects the
+// language of messages for the session.
 //
 // Parameters:
 //   - req: The initialize request.
@@ -158,26 +487,53 @@ func (s *Server) handleRequest(req *JSONRPCRequest) *JSONRPCResponse {
 // Returns:
 //   - *JSONRPCResponse: The response containing server details.
 func (s *Server) handleInitialize(req *JSONRPCRequest) *JSONRPCResponse {
+	var params struct {
+		Capabilities          map[string]json.RawMessage `json:"capabilities"`
+		InitializationOptions struct {
+			Offline  bool   `json:"offline"`
+			Language string `json:"language"`
+		} `json:"initializationOptions"`
+	}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return &JSONRPCResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   &JSONRPCError{Code: CodeParseError, Message: "Parse error"},
+			}
+		}
+	}
+	if lang := params.InitializationOptions.Language; lang != "" {
+		catalog, err := i18n.Lookup(lang)
+		if err != nil {
+			return &JSONRPCResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error: &JSONRPCError{
+					Code:    CodeInvalidParams,
+					Message: fmt.Sprintf("Invalid params: %v", err),
+					Data:    map[string]interface{}{"languages": i18n.Languages()},
+				},
+			}
+		}
+		s.catalog = catalog
+	}
+	s.unsupported = unsupportedFeatures(params.Capabilities)
+	if params.InitializationOptions.Offline {
+		s.toolOptions.Offline = true
+	}
+
 	return &JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      req.ID,
-		Result: map[string]interface{}{
-			"protocolVersion": "2024-11-05",
-			"capabilities": map[string]interface{}{
-				"tools": map[string]interface{}{},
-			},
-			"serverInfo": map[string]interface{}{
-				"name":    "eib-mcp",
-				"version": "0.1.0",
-			},
-		},
+		Result:  s.initializeResult(),
 	}
 }
 
 // handleToolsList handles the "tools/list" method.
 //
-// It returns a list of available tools, including "generate_config",
-// along with their descriptions and input schemas.
+// It returns the list of available tools, along with their descriptions
+// and input schemas.
 //
 // Parameters:
 //   - req: The tools/list request.
@@ -185,72 +541,27 @@ func (s *Server) handleInitialize(req *JSONRPCRequest) *JSONRPCResponse {
 // Returns:
 //   - *JSONRPCResponse: The response containing the list of tools.
 func (s *Server) handleToolsList(req *JSONRPCRequest) *JSONRPCResponse {
-	// Load schema to embed in tool definition
-	schemaBytes := schema.GetRawSchema()
-	var schemaMap map[string]interface{}
-	if err := json.Unmarshal(schemaBytes, &schemaMap); err != nil {
-		// Should not happen with embedded valid JSON
-		schemaMap = map[string]interface{}{"type": "object", "error": "failed to parse schema"}
+	var list []map[string]interface{}
+	for _, t := range s.enabledTools() {
+		list = append(list, map[string]interface{}{
+			"name":        t.name,
+			"description": s.catalog.Tool(t.name, t.description),
+			"inputSchema": s.toolSchema(t),
+		})
 	}
 
 	return &JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      req.ID,
 		Result: map[string]interface{}{
-			"tools": []map[string]interface{}{
-				{
-					"name": "generate_config",
-					"description": `Generates a valid edge-image-builder YAML configuration file.
-IMPORTANT GUIDELINES:
-1. "kubernetes.helm.charts.repositoryName" MUST match a "name" in "kubernetes.helm.repositories".
-2. "kubernetes.nodes" MUST NOT contain IP addresses (only hostname, type, initializer).
-3. "operatingSystem.time" MUST use "timezone" (lowercase), NOT "timeZone".
-4. Passwords: You can put plaintext in "encryptedPassword" or "password". The tool will automatically encrypt it.
-
-Example Structure:
-apiVersion: "1.0"
-image:
-  imageType: "iso"
-  arch: "x86_64"
-  baseImage: "sles15.iso"
-  outputImageName: "output"
-operatingSystem:
-  users:
-    - username: "root"
-      encryptedPassword: "..."
-  isoConfiguration:
-    installDevice: "/dev/sda"
-  time:
-    timezone: "UTC"
-    ntp:
-      servers:
-        - "pool.ntp.org"
-kubernetes:
-  version: "1.29.0"
-  network:
-    apiVIP: "1.2.3.4"
-  nodes:
-    - hostname: "node1"
-      type: "server"
-  helm:
-    charts:
-      - name: "chart"
-        repositoryName: "repo"
-        version: "1.0.0"
-    repositories:
-      - name: "repo"
-        url: "https://charts.example.com"`,
-					"inputSchema": schemaMap,
-				},
-			},
+			"tools": list,
 		},
 	}
 }
 
 // handleToolsCall handles the "tools/call" method.
 //
-// It executes the requested tool (currently only "generate_config")
-// with the provided arguments.
+// It looks up the requested tool and executes it with the provided arguments.
 //
 // Parameters:
 //   - req: The tools/call request containing the tool name and arguments.
@@ -261,29 +572,77 @@ func (s *Server) handleToolsCall(req *JSONRPCRequest) *JSONRPCResponse {
 	var params struct {
 		Name      string                 `json:"name"`
 		Arguments map[string]interface{} `json:"arguments"`
+		Meta      struct {
+			ProgressToken interface{} `json:"progressToken"`
+		} `json:"_meta"`
 	}
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return &JSONRPCResponse{
 			JSONRPC: "2.0",
 			ID:      req.ID,
-			Error:   &JSONRPCError{Code: -32700, Message: "Parse error"},
+			Error:   &JSONRPCError{Code: CodeParseError, Message: "Parse error"},
+		}
+	}
+
+	t, ok := s.lookupTool(params.Name)
+	if !ok {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &JSONRPCError{Code: CodeMethodNotFound, Message: "Tool not found"},
+		}
+	}
+
+	if feature, reason := s.toolUnavailable(t); reason != "" {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &JSONRPCError{
+				Code:    CodeMethodNotFound,
+				Message: fmt.Sprintf("Tool %q is not available: %s", t.name, reason),
+				Data:    map[string]interface{}{"feature": feature},
+			},
+		}
+	}
+	if err := s.checkFeatureArgs(t, params.Arguments); err != nil {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &JSONRPCError{Code: CodeInvalidParams, Message: err.Error()},
 		}
 	}
 
-	if params.Name != "generate_config" {
+	problems, err := validateArguments(t, s.argumentSchema(t), params.Arguments)
+	if err != nil {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &JSONRPCError{Code: CodeInternalError, Message: err.Error()},
+		}
+	}
+	if len(problems) > 0 {
 		return &JSONRPCResponse{
 			JSONRPC: "2.0",
 			ID:      req.ID,
-			Error:   &JSONRPCError{Code: -32601, Message: "Tool not found"},
+			Error: &JSONRPCError{
+				Code:    CodeInvalidParams,
+				Message: fmt.Sprintf("Invalid params: %s: %s", problems[0].Field, problems[0].Message),
+				Data:    map[string]interface{}{"errors": problems},
+			},
 		}
 	}
 
-	yamlOutput, err := tool.GenerateConfig(params.Arguments)
+	s.progressToken, s.callID = params.Meta.ProgressToken, req.correlationID
+	defer func() { s.progressToken, s.callID = nil, "" }()
+	started := time.Now()
+	content, err := t.handler(s, params.Arguments)
+	s.recordCall(req.correlationID, t.name, time.Since(started), err != nil)
 	if err != nil {
+		logf(req.correlationID, "Tool %s failed: %v", t.name, err)
 		return &JSONRPCResponse{
 			JSONRPC: "2.0",
 			ID:      req.ID,
-			Error:   &JSONRPCError{Code: -32000, Message: err.Error()},
+			Result:  s.toolErrorResult(err),
 		}
 	}
 
@@ -291,12 +650,24 @@ func (s *Server) handleToolsCall(req *JSONRPCRequest) *JSONRPCResponse {
 		JSONRPC: "2.0",
 		ID:      req.ID,
 		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": yamlOutput,
-				},
-			},
+			"content": content,
 		},
 	}
 }
+
+// recordCall records the latency of a tool call in the metrics and logs a
+// warning with the steps of the call if it was slow.
+//
+// Parameters:
+//   - correlationID: The ID of the request.
+//   - name: The tool name.
+//   - d: How long the call took.
+//   - failed: Whether the call returned an error.
+func (s *Server) recordCall(correlationID, name string, d time.Duration, failed bool) {
+	if s.metrics != nil {
+		s.metrics.observe(name, d, failed)
+	}
+	if s.slowCall > 0 && d > s.slowCall {
+		logf(correlationID, "Warning: slow call: %s took %s (threshold %s); steps: %s", name, d.Round(time.Millisecond), s.slowCall, s.toolOptions.Trace)
+	}
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// handleAdviseSizing implements the advise_sizing tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The optional "config" and the planned "servers", "agents" and
+//     "workloads".
+//
+// Returns:
+//   - []map[string]interface{}: The sizing report as a JSON document,
+//     followed by its findings if any.
+//   - error: An error if the configuration is malformed or the cluster has
+//     no server.
+func handleAdviseSizing(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	var config map[string]interface{}
+	if v, ok := args["config"]; ok {
+		var err error
+		if config, err = tool.ParseConfig(v); err != nil {
+			return nil, err
+		}
+	} else if _, ok := args["servers"]; !ok {
+		return nil, fmt.Errorf("either config or servers is required")
+	}
+	var req tool.SizingRequest
+	for _, name := range []string{"servers", "agents", "workloads"} {
+		if v, ok := args[name]; ok {
+			if err := decodeArgument(map[string]interface{}{name: v}, name, &req); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	report, err := tool.AdviseSizing(config, req)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sizing report: %w", err)
+	}
+	content := []map[string]interface{}{textContent(string(out))}
+	if len(report.Findings) > 0 {
+		warnings := make([]string, len(report.Findings))
+		for i, f := range report.Findings {
+			warnings[i] = f.String()
+		}
+		content = append(content, textContent(s.formatWarnings(warnings)))
+	}
+	return content, nil
+}
@@ -0,0 +1,1727 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/e-minguez/eib-mcp/preset"
+	"github.com/e-minguez/eib-mcp/schema"
+	"github.com/e-minguez/eib-mcp/tool"
+	"gopkg.in/yaml.v3"
+)
+
+// toolHandler executes a tool with the given arguments.
+//
+// It returns the content blocks of the tool result, or an error.
+type toolHandler func(s *Server, args map[string]interface{}) ([]map[string]interface{}, error)
+
+// toolDefinition describes a tool exposed through tools/list and tools/call.
+type toolDefinition struct {
+	// name is the unique tool name.
+	name string
+	// description explains the tool to the client LLM.
+	description string
+	// inputSchema returns the JSON schema of the tool arguments.
+	inputSchema func() map[string]interface{}
+	// argsSchema, if set, returns the schema arguments are checked against
+	// before the handler runs, when the handler validates the rest itself.
+	argsSchema func() map[string]interface{}
+	// handler executes the tool.
+	handler toolHandler
+	// feature is the feature group the tool belongs to.
+	feature Feature
+	// featureArgs lists the arguments only available while a feature is enabled.
+	featureArgs map[Feature][]string
+	// requires lists other features the tool cannot run without, such as
+	// write-files for tools that always write.
+	requires []Feature
+}
+
+// tools returns the tools exposed by the server, in listing order.
+//
+// Returns:
+//   - []toolDefinition: The available tools.
+func (s *Server) tools() []toolDefinition {
+	return []toolDefinition{
+		{
+			name:        "generate_config",
+			description: generateConfigDescription,
+			inputSchema: generateConfigSchema,
+			argsSchema:  generateConfigControlsSchema,
+			handler:     handleGenerateConfig,
+			feature:     FeatureGenerate,
+			featureArgs: map[Feature][]string{FeatureWriteFiles: {"outputDir", "overwrite"}},
+		},
+		{
+			name: "patch_config",
+			description: `Applies a partial update to an existing edge-image-builder configuration and returns the validated, updated YAML.
+Use it for small changes (add a user, bump the kubernetes version, add a chart) instead of regenerating the whole configuration.
+"patchType" selects how "patch" is applied:
+- "merge" (default): objects are merged recursively; null removes a key; users, nodes, charts, repositories, groups and images are matched by their name (username, hostname, name, uri, url) and merged, otherwise appended; lists of strings gain missing values.
+- "merge-patch": RFC 7386 JSON Merge Patch; lists are replaced as a whole.
+- "json-patch": RFC 6902 JSON Patch; "patch" is a list of operations such as {"op": "replace", "path": "/kubernetes/version", "value": "1.30.0"}.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"config": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "The existing configuration, as an object or as a YAML string.",
+						},
+						"patch": map[string]interface{}{
+							"type":        []string{"object", "array"},
+							"description": "The patch: a partial configuration for the merge types, a list of operations for json-patch.",
+						},
+						"patchType": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{string(tool.PatchDeepMerge), string(tool.PatchMergePatch), string(tool.PatchJSONPatch)},
+							"description": "How to apply the patch. Defaults to \"merge\".",
+						},
+					},
+					"required":             []string{"config", "patch"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handlePatchConfig,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "plan_config",
+			description: `Reviews a change to an edge-image-builder configuration before applying it, like "terraform plan": reports the changed fields (secrets redacted), the unified diff of the generated definition, whether the image must be rebuilt and why, and a plan "id".
+Show the plan to the user, then call apply_config with the same arguments and the plan id to produce the final files.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type":                 "object",
+					"properties":           planArgumentsSchema(),
+					"required":             []string{"config", "patch"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handlePlanConfig,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "apply_config",
+			description: `Applies a change reviewed with plan_config and returns the final, validated YAML, or writes it to "outputDir".
+The "config", "patch" and "patchType" must be exactly those of the plan; otherwise the call fails and a new plan must be made.`,
+			inputSchema: func() map[string]interface{} {
+				properties := planArgumentsSchema()
+				properties["planId"] = map[string]interface{}{
+					"type":        "string",
+					"description": "The id of the reviewed plan, returned by plan_config.",
+				}
+				properties["outputDir"] = map[string]interface{}{
+					"type":        "string",
+					"description": "Image configuration directory on the server to write the definition and artifacts to. Existing files are never replaced unless \"overwrite\" is true.",
+				}
+				properties["overwrite"] = map[string]interface{}{
+					"type":        "boolean",
+					"description": "Replace existing files in \"outputDir\".",
+				}
+				return map[string]interface{}{
+					"type":                 "object",
+					"properties":           properties,
+					"required":             []string{"config", "patch", "planId"},
+					"additionalProperties": false,
+				}
+			},
+			handler:     handleApplyConfig,
+			feature:     FeatureGenerate,
+			featureArgs: map[Feature][]string{FeatureWriteFiles: {"outputDir", "overwrite"}},
+		},
+		{
+			name: "rancher_registration",
+			description: `Downloads the manifest that imports a cluster into Rancher, from the Rancher server URL and the cluster registration token, and returns it as the artifact kubernetes/manifests/rancher-registration.yaml of the image configuration directory (or writes it to "outputDir").
+EIB applies it on first boot, so the edge cluster registers itself with Rancher. The configuration must deploy Kubernetes. Requires network access to Rancher.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"rancherUrl": map[string]interface{}{
+							"type":        "string",
+							"description": "The Rancher server URL, e.g. https://rancher.example.com.",
+						},
+						"token": map[string]interface{}{
+							"type":        "string",
+							"description": "The registration token of the imported cluster, as shown in the Rancher registration command.",
+						},
+						"clusterId": map[string]interface{}{
+							"type":        "string",
+							"description": "The ID of the imported cluster, e.g. c-m-abc12def, part of the registration URL since Rancher 2.6.",
+						},
+						"outputDir": map[string]interface{}{
+							"type":        "string",
+							"description": "Image configuration directory on the server to write the manifest to, instead of returning it. An existing manifest is never replaced unless \"overwrite\" is true.",
+						},
+						"overwrite": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Replace an existing manifest in \"outputDir\".",
+						},
+					},
+					"required":             []string{"rancherUrl", "token"},
+					"additionalProperties": false,
+				}
+			},
+			handler:     handleRancherRegistration,
+			feature:     FeatureGenerate,
+			featureArgs: map[Feature][]string{FeatureWriteFiles: {"outputDir", "overwrite"}},
+		},
+		{
+			name: "bill_of_materials",
+			description: `Lists what an image built from an edge-image-builder configuration will contain, for security review before building: RPM packages and repositories, Helm charts with their versions and repositories, container images (from embeddedArtifactRegistry and from the downloaded Kubernetes manifests) and manifest URLs.
+Items that cannot be inventoried, such as the images of Helm charts, which are only known once rendered, are listed under "unresolved".`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"config": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "The configuration, as an object or as a YAML string.",
+						},
+					},
+					"required":             []string{"config"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleBillOfMaterials,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "render_helm_chart",
+			description: `Renders a Helm chart of an edge-image-builder configuration with "helm template" on the server and returns the resulting Kubernetes manifests, so you can confirm what will actually be deployed on the edge cluster before baking the chart into an image.
+The chart is downloaded from its repository in kubernetes.helm.repositories and rendered with the release name, namespace and apiVersions of its kubernetes.helm.charts entry, for the Kubernetes version of kubernetes.version. Pass the content of the chart's valuesFile as "values".
+The result lists the rendered objects and the container images they reference, followed by the manifests. Not available in offline mode.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"config": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "The configuration, as an object or as a YAML string.",
+						},
+						"chart": map[string]interface{}{
+							"type":        "string",
+							"description": "Name of the chart in kubernetes.helm.charts. Optional if the configuration has a single chart.",
+						},
+						"values": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "The chart values, as an object or as a YAML string: the content of the file named by the chart's valuesFile. Defaults to the chart's default values.",
+						},
+					},
+					"required":             []string{"config"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleRenderHelmChart,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "check_install_order",
+			description: `Finds custom resources of an edge-image-builder configuration whose CRD will not be there when they are created on first boot, which makes the cluster fail to reconcile after the image is built.
+Every Helm chart is rendered with "helm template", including its CRDs, and every kubernetes.manifests URL is downloaded; pass the files of the kubernetes/manifests directory as "manifests". Charts and manifests are installed concurrently, so custom resources whose CRD comes from another chart or manifest are reported under "install-order", and those whose CRD nothing installs under "missing-crd". Resources installed into a namespace that is not built in and that no chart (with createNamespace) or manifest creates are reported under "missing-namespace".
+The result lists the CRDs and namespaces each source installs, the custom resources it creates and the namespaces it installs into. Sources that cannot be rendered or downloaded, e.g. in offline mode, are reported and skipped.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"config": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "The configuration, as an object or as a YAML string.",
+						},
+						"values": map[string]interface{}{
+							"type": "object",
+							"additionalProperties": map[string]interface{}{
+								"type": []string{"object", "string"},
+							},
+							"description": "Chart values by chart name, as objects or YAML strings: the content of the file named by each chart's valuesFile.",
+						},
+						"manifests": map[string]interface{}{
+							"type": "object",
+							"additionalProperties": map[string]interface{}{
+								"type": "string",
+							},
+							"description": "The files of the kubernetes/manifests directory, by file name, e.g. {\"operator.yaml\": \"apiVersion: ...\"}.",
+						},
+					},
+					"required":             []string{"config"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleCheckInstallOrder,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "explain_nodes",
+			description: `Explains the settings each node of an edge-image-builder cluster starts with: its role (the initializer creating the cluster, a joining server or an agent), its configuration file (kubernetes/config/server.yaml or agent.yaml) and the options EIB derives from kubernetes.nodes and kubernetes.network, such as the address nodes join through and the names of the API server certificate.
+Pass the configuration files as "kubernetesConfig". Server-only options in agent.yaml, such as etcd or control plane settings, and cluster-init in the server.yaml of several servers are reported under "node-config-split".`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"config": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "The configuration, as an object or as a YAML string.",
+						},
+						"kubernetesConfig": kubernetesConfigSchema(),
+					},
+					"required":             []string{"config"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleExplainNodes,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "advise_sizing",
+			description: `Checks the size of an edge-image-builder cluster against its workloads and recommends changes: an even number of servers, which adds no fault tolerance to etcd, more than 7 servers, a single server managing agents, which is a single point of failure, too few servers for the agents, and too few nodes for the replicas of well-known charts such as Rancher, Longhorn or NeuVector.
+Pass a "config" to use its kubernetes.nodes and kubernetes.helm.charts, or plan a cluster with "servers", "agents" and "workloads", which override the configuration. The report includes how many servers can fail while etcd keeps its quorum.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"config": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "The configuration, as an object or as a YAML string.",
+						},
+						"servers": map[string]interface{}{
+							"type":        "integer",
+							"minimum":     1,
+							"description": "The number of server nodes, overriding those of kubernetes.nodes.",
+						},
+						"agents": map[string]interface{}{
+							"type":        "integer",
+							"minimum":     0,
+							"description": "The number of agent nodes, overriding those of kubernetes.nodes.",
+						},
+						"workloads": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]interface{}{"type": "string"},
+							"description": "The names of the charts the cluster runs, e.g. [\"rancher\", \"longhorn\"], overriding kubernetes.helm.charts.",
+						},
+					},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleAdviseSizing,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "validate_fragment",
+			description: `Validates one section of an edge-image-builder configuration, such as kubernetes.helm or operatingSystem.users, against the part of the schema describing it, without assembling the whole configuration first.
+Returns a verdict like generate_config with validateOnly: "valid", the "errors" and "warnings" inside the fragment, and their "findings". Sections missing around the fragment, such as image, are not reported; cross-field rules run once the fragment matches the schema, but rules relating it to other sections only run on the whole configuration.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"minLength":   1,
+							"description": "The dotted path of the section, e.g. \"kubernetes.helm\" or \"operatingSystem.time\". List items cannot be addressed; pass the whole list, e.g. \"kubernetes.nodes\".",
+						},
+						"fragment": map[string]interface{}{
+							"description": "The content of the section, as JSON or as a YAML string.",
+						},
+						"apiVersion": map[string]interface{}{
+							"type":        "string",
+							"description": "The apiVersion whose schema to use. Defaults to the latest.",
+						},
+					},
+					"required":             []string{"path", "fragment"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleValidateFragment,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "compose_config",
+			description: `Composes one edge-image-builder configuration from fragments maintained separately, e.g. os-base.yaml, networking.yaml and k8s.yaml owned by different teams, then validates and renders it like generate_config.
+Fragments are merged in order, later ones taking precedence, as patch_config merges: objects recursively, users, nodes, charts and other named list items by their name, null removes a field. Each value a fragment overrides is reported as a compose-conflict warning naming both fragments. A fragment listing sections in "owns" is the only one allowed to set fields inside them; any other fragment doing so fails with compose-ownership errors.
+The result ends with "sources", the fragments setting each top-level section.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"fragments": map[string]interface{}{
+							"type":        "array",
+							"minItems":    1,
+							"description": "The fragments, lowest precedence first.",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"name": map[string]interface{}{
+										"type":        "string",
+										"description": "The name of the fragment in findings, e.g. \"os-base.yaml\". Defaults to fragments.<index>.",
+									},
+									"content": map[string]interface{}{
+										"type":        []string{"object", "string"},
+										"description": "The partial configuration, as an object or as a YAML string.",
+									},
+									"owns": map[string]interface{}{
+										"type":        "array",
+										"items":       map[string]interface{}{"type": "string"},
+										"uniqueItems": true,
+										"description": "The dotted paths of the sections only this fragment may set, e.g. [\"operatingSystem.users\", \"kubernetes\"].",
+									},
+								},
+								"required":             []string{"content"},
+								"additionalProperties": false,
+							},
+						},
+					},
+					"required":             []string{"fragments"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleComposeConfig,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "generate_layered",
+			description: `Generates an edge-image-builder configuration from the three layers fleet operators organize their definitions in: a "base" template shared by every image, an "environment" overlay (e.g. production) and a "site" overlay for one location, then validates and renders it like generate_config.
+Each overlay is merged onto the layers below as patch_config merges: objects recursively, users, nodes, charts and other named list items by their name, lists of strings gain its values, null removes a field. Values the overlays replace are reported: layer-override notes for base values, layer-conflict warnings when the site replaces a value of its environment, and layer-redundant warnings for values a lower layer already sets.
+The result ends with "origins", the layer each field's value comes from.`,
+			inputSchema: func() map[string]interface{} {
+				layer := func(description string) map[string]interface{} {
+					return map[string]interface{}{
+						"type":        []string{"object", "string"},
+						"description": description + " As an object or as a YAML string.",
+					}
+				}
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"base":        layer("The base template shared by every image."),
+						"environment": layer("The environment overlay, e.g. for production."),
+						"site":        layer("The site overlay for one location."),
+					},
+					"required":             []string{"base"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleGenerateLayered,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "draft_from_system",
+			description: `Drafts an edge-image-builder configuration reproducing an existing edge node from a description of the running system, to move devices installed by hand to image-based management.
+Pass what can be gathered on the node, e.g. "rpm -qa" for "packages", /etc/passwd and authorized_keys for "users", "systemctl list-unit-files --state=enabled" for "services", /proc/cmdline for "kernelArgs" and "rke2 --version" for "kubernetes".
+Returns the draft YAML and its validation verdict as generate_config with validateOnly returns it. draft-review warnings list what the running system does not reveal, such as the base image, the install disk and user passwords, which the draft fills with defaults; draft-omitted notes list what the base image or EIB already provides, such as system users and Kubernetes packages.`,
+			inputSchema: func() map[string]interface{} {
+				strings := func(description string) map[string]interface{} {
+					return map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": description,
+					}
+				}
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"hostname": map[string]interface{}{
+							"type":        "string",
+							"description": "The node hostname; names the output image.",
+						},
+						"arch": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"x86_64", "aarch64", "amd64", "arm64"},
+							"description": "The architecture, as printed by uname -m. Defaults to x86_64.",
+						},
+						"packages": strings("The installed packages, as names or as printed by rpm -qa, e.g. \"vim-9.1.0836-1.1.x86_64\"."),
+						"users": map[string]interface{}{
+							"type":        "array",
+							"description": "The local users; those with a UID below 1000, except root, are system users and left out.",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"username":        map[string]interface{}{"type": "string"},
+									"uid":             map[string]interface{}{"type": "integer", "minimum": 0},
+									"primaryGroup":    map[string]interface{}{"type": "string"},
+									"secondaryGroups": strings("The names of the other groups."),
+									"sshKeys":         strings("The authorized SSH public keys."),
+								},
+								"required":             []string{"username"},
+								"additionalProperties": false,
+							},
+						},
+						"services":   strings("The enabled systemd units."),
+						"kernelArgs": strings("The kernel arguments, as in /proc/cmdline; those set by the installation, such as root=, are left out."),
+						"timezone": map[string]interface{}{
+							"type":        "string",
+							"description": "The time zone, e.g. \"Europe/Berlin\".",
+						},
+						"ntpServers": strings("The NTP servers in use."),
+						"keymap": map[string]interface{}{
+							"type":        "string",
+							"description": "The console keymap, e.g. \"de\".",
+						},
+						"kubernetes": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"version": map[string]interface{}{
+									"type":        "string",
+									"description": "The RKE2 or K3s version, e.g. \"v1.30.3+rke2r1\", or the output of rke2 --version.",
+								},
+								"role": map[string]interface{}{
+									"type":        "string",
+									"enum":        []string{"server", "agent"},
+									"description": "The node type. Defaults to server.",
+								},
+							},
+							"required":             []string{"version"},
+							"additionalProperties": false,
+						},
+					},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleDraftFromSystem,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "convert_config",
+			description: `Converts a cloud-init #cloud-config, Ignition (spec 3) or AutoYaST document into an edge-image-builder configuration, so that machines provisioned with those tools can be built as images instead.
+Users with passwords, SSH keys, groups and sudo rules, the time zone, NTP servers, keymap, packages, systemd units, kernel arguments and files are converted; files are returned as the "files" argument of generate_config.
+Returns the YAML, the detected "format" and "files", and the validation verdict as generate_config with validateOnly returns it. convert-unsupported warnings name each setting of the document with no EIB equivalent, such as runcmd or disk layouts; draft-review warnings list the defaults to review, such as the base image and install disk.`,
+			inputSchema: func() map[string]interface{} {
+				formats := make([]string, len(tool.ProvisioningFormats))
+				for i, f := range tool.ProvisioningFormats {
+					formats[i] = string(f)
+				}
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"document": map[string]interface{}{
+							"type":        "string",
+							"minLength":   1,
+							"description": "The document: YAML for cloud-init, JSON for Ignition, XML for AutoYaST.",
+						},
+						"format": map[string]interface{}{
+							"type":        "string",
+							"enum":        formats,
+							"description": "The format of the document. Detected when omitted: cloud-init documents start with #cloud-config, Ignition ones hold an \"ignition\" object and AutoYaST ones are XML.",
+						},
+					},
+					"required":             []string{"document"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleConvertConfig,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "generate_many",
+			description: `Generates several configurations in one call, several at a time, which is much faster than calling generate_config for each.
+Each item takes the same arguments as generate_config and gets the result generate_config would return, with its index; a failing item has isError and the error code in its _meta, and does not stop the others.
+Pass a progressToken in the call's _meta to receive notifications/progress as items complete.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"items": map[string]interface{}{
+							"type":        "array",
+							"minItems":    1,
+							"maxItems":    maxManyItems,
+							"items":       map[string]interface{}{"type": "object"},
+							"description": "The generate_config argument objects, one per configuration.",
+						},
+						"parallelism": map[string]interface{}{
+							"type":        "integer",
+							"minimum":     1,
+							"maximum":     maxParallelism,
+							"description": "How many configurations are generated at once. Defaults to the number of CPUs of the server.",
+						},
+					},
+					"required":             []string{"items"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleGenerateMany,
+			feature: FeatureGenerate,
+		},
+		{
+			name: "generate_fleet",
+			description: `Generates one validated edge-image-builder configuration per site of a fleet, plus nmstate network files for nodes with static IPs.
+Each site starts from the base "template" (or "preset"); the site's nodes replace "kubernetes.nodes", its apiVIP sets "kubernetes.network.apiVIP", and ${SITE_NAME} plus the site's variables are substituted.
+The result is a JSON map of "<site>/definition.yaml" and "<site>/network/<hostname>.yaml" files, or the list of written files when "outputDir" is set.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"inventory": map[string]interface{}{
+							"type":        []string{"array", "object", "string"},
+							"description": "The sites: a JSON list of {name, apiVIP, variables, nodes: [{hostname, type, initializer, ip (CIDR), gateway, interface, macAddress, dns}]}, or a CSV document with a header row and one row per node (columns: site, hostname, type, initializer, ip, gateway, interface, macAddress, dns separated by ';', apiVIP).",
+						},
+						"template": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "The base configuration shared by all sites, as an object or as a YAML string. Merged on top of \"preset\" if both are given.",
+						},
+						"preset": map[string]interface{}{
+							"type":        "string",
+							"enum":        preset.Names(),
+							"description": "Name of a preset to use as the base configuration.",
+						},
+						"variables": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": map[string]interface{}{"type": "string"},
+							"description":          "Values for ${NAME} references shared by all sites. Site variables take precedence.",
+						},
+						"layout": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{string(tool.LayoutFlat), string(tool.LayoutGitOps)},
+							"description": "How files are arranged: \"flat\" (default) puts each site in \"<site>/\"; \"gitops\" builds a Git repository for Flux or Argo CD, with each site in \"sites/<site>/\", kustomization.yaml files wrapping each site into an \"eib-<site>\" ConfigMap, and a .gitignore excluding built images.",
+						},
+						"outputDir": map[string]interface{}{
+							"type":        "string",
+							"description": "Directory on the server to write the files to, instead of returning them. Existing files, including the images named by image.outputImageName, are never replaced unless \"overwrite\" is true; the conflicts are reported instead.",
+						},
+						"overwrite": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Replace existing files in \"outputDir\".",
+						},
+					},
+					"required":             []string{"inventory"},
+					"additionalProperties": false,
+				}
+			},
+			handler:     handleGenerateFleet,
+			feature:     FeatureFleet,
+			featureArgs: map[Feature][]string{FeatureWriteFiles: {"outputDir", "overwrite"}},
+		},
+		{
+			name: "inspect_base_image",
+			description: `Inspects a base SL Micro ISO or raw image on the server without mounting it and reports its format, architecture and OS version (from the ISO9660 label, EFI boot loaders, an embedded os-release or the file name; the "*Source" fields say which).
+Pass "config" to check its image.imageType, image.arch and image.baseImage against the image before building.
+Pass "expectedChecksum" or "checksumUrl" to verify the image against its published SHA-256 or SHA-512 checksum, so a corrupted or tampered image is caught before a fleet is built on it; a mismatch is listed in "mismatches".`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path of the base image on the server, e.g. base-images/SL-Micro.x86_64-6.0-Base-SelfInstall-GM.install.iso.",
+						},
+						"config": map[string]interface{}{
+							"type":        []string{"object", "string"},
+							"description": "A configuration, as an object or as a YAML string, whose image section is compared with the inspected image.",
+						},
+						"expectedChecksum": map[string]interface{}{
+							"type":        "string",
+							"pattern":     "^([0-9a-fA-F]{64}|[0-9a-fA-F]{128})$",
+							"description": "The expected SHA-256 or SHA-512 digest of the image, in hexadecimal.",
+						},
+						"checksumUrl": map[string]interface{}{
+							"type":        "string",
+							"format":      "uri",
+							"description": "URL of the published checksum file of the image, e.g. the .sha256 file next to the download, in sha256sum or BSD format. Not available in offline mode.",
+						},
+					},
+					"required":             []string{"path"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleInspectBaseImage,
+			feature: FeatureBaseImage,
+		},
+		{
+			name: "list_base_images",
+			description: `Lists the base images in a directory on the server with their name, size, SHA-256 checksum, format ("iso" or "raw"), architecture and OS version, so a valid image.baseImage and image.arch can be chosen instead of guessing file names.
+Checksum files, signatures and partial downloads are skipped; files that are not images are listed with an "error".`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"dir": map[string]interface{}{
+							"type":        "string",
+							"description": "Directory holding the base images, e.g. the base-images/ directory of an image configuration directory. Defaults to the directory configured on the server.",
+						},
+					},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleListBaseImages,
+			feature: FeatureBaseImage,
+		},
+		{
+			name: "download_base_image",
+			description: `Downloads a SLE Micro or SL Micro base image into the base-images/ directory of an image configuration directory on the server and verifies its SHA-256 or SHA-512 checksum.
+An interrupted download is resumed when the tool is called again. The image only gets its final name once its checksum matches; an image already present with that checksum is not downloaded again. The report's "baseImage" is the value to use for image.baseImage.
+Pass a progressToken in the call's _meta to receive notifications/progress while it downloads. Not available in offline mode.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"url": map[string]interface{}{
+							"type":        "string",
+							"format":      "uri",
+							"description": "The base image URL.",
+						},
+						"outputDir": map[string]interface{}{
+							"type":        "string",
+							"description": "Image configuration directory on the server; the image is stored in its base-images/ directory.",
+						},
+						"fileName": map[string]interface{}{
+							"type":        "string",
+							"description": "Name to store the image under. Defaults to the last element of the URL path.",
+						},
+						"expectedChecksum": map[string]interface{}{
+							"type":        "string",
+							"pattern":     "^([0-9a-fA-F]{64}|[0-9a-fA-F]{128})$",
+							"description": "The expected SHA-256 or SHA-512 digest of the image, in hexadecimal.",
+						},
+						"checksumUrl": map[string]interface{}{
+							"type":        "string",
+							"format":      "uri",
+							"description": "URL of the published checksum file of the image, e.g. the .sha256 file next to the download, in sha256sum or BSD format.",
+						},
+						"overwrite": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Replace an existing image whose checksum does not match.",
+						},
+					},
+					"required":             []string{"url", "outputDir"},
+					"additionalProperties": false,
+				}
+			},
+			handler:  handleDownloadBaseImage,
+			feature:  FeatureBaseImage,
+			requires: []Feature{FeatureWriteFiles},
+		},
+		{
+			name: "list_capabilities",
+			description: `Reports what this server supports: tools, EIB apiVersions and the configuration sections of each, presets, the validations applied (mode, formats, cross-field rules, target EIB release, eib validate) and enabled network checks.
+Call it before planning a configuration to avoid asking for unsupported features.`,
+			inputSchema: func() map[string]interface{} {
+				return map[string]interface{}{
+					"type":                 "object",
+					"properties":           map[string]interface{}{},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleListCapabilities,
+			feature: FeatureDiscovery,
+		},
+		{
+			name: "schema_diff",
+			description: `Compares the EIB configuration schemas of two apiVersions and reports added, removed and changed fields.
+Use it to explain what a user gains (or must change) by bumping "apiVersion".`,
+			inputSchema: func() map[string]interface{} {
+				versions := schema.SupportedVersions()
+				return map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"from": map[string]interface{}{
+							"type":        "string",
+							"enum":        versions,
+							"description": "The apiVersion to compare from.",
+						},
+						"to": map[string]interface{}{
+							"type":        "string",
+							"enum":        versions,
+							"description": "The apiVersion to compare to. Defaults to the latest supported version.",
+						},
+					},
+					"required":             []string{"from"},
+					"additionalProperties": false,
+				}
+			},
+			handler: handleSchemaDiff,
+			feature: FeatureDiscovery,
+		},
+	}
+}
+
+// lookupTool finds a tool by name.
+//
+// Parameters:
+//   - name: The tool name.
+//
+// Returns:
+//   - toolDefinition: The tool, if found.
+//   - bool: True if the tool exists.
+func (s *Server) lookupTool(name string) (toolDefinition, bool) {
+	for _, t := range s.tools() {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return toolDefinition{}, false
+}
+
+// generateConfigDescription is the description of the generate_config tool.
+const generateConfigDescription = `Generates a valid edge-image-builder YAML configuration file.
+IMPORTANT GUIDELINES:
+1. "kubernetes.helm.charts.repositoryName" MUST match a "name" in "kubernetes.helm.repositories".
+2. "kubernetes.nodes" MUST NOT contain IP addresses (only hostname, type, initializer).
+3. "operatingSystem.time" MUST use "timezone" (lowercase), NOT "timeZone".
+4. Passwords: You can put plaintext in "encryptedPassword" or "password". The tool will automatically encrypt it.
+5. Variables: Any string may reference ${NAME}; values come from the "variables" argument (e.g. {"CLUSTER_NAME": "edge01"}) or from server-side environment variables. Write "$${" for a literal "${".
+6. Target release: Set "targetRelease" to the EIB release that will build the image (e.g. "1.1") to reject fields that release does not support.
+7. Presets: Set "preset" (e.g. "minimal-iso") to start from a named profile; the other arguments are merged on top of it, so only the differences need to be given.
+8. Documentation: The "eib://docs/<section>" resources describe each configuration section; read them when unsure about a field.
+
+Example Structure:
+apiVersion: "1.0"
+image:
+  imageType: "iso"
+  arch: "x86_64"
+  baseImage: "sles15.iso"
+  outputImageName: "output"
+operatingSystem:
+  users:
+    - username: "root"
+      encryptedPassword: "..."
+  isoConfiguration:
+    installDevice: "/dev/sda"
+  time:
+    timezone: "UTC"
+    ntp:
+      servers:
+        - "pool.ntp.org"
+kubernetes:
+  version: "1.29.0"
+  network:
+    apiVIP: "1.2.3.4"
+  nodes:
+    - hostname: "node1"
+      type: "server"
+  helm:
+    charts:
+      - name: "chart"
+        repositoryName: "repo"
+        version: "1.0.0"
+    repositories:
+      - name: "repo"
+        url: "https://charts.example.com"`
+
+// configSchema returns the advertised EIB configuration schema as a map.
+//
+// Returns:
+//   - map[string]interface{}: The parsed schema.
+func configSchema() map[string]interface{} {
+	// Load schema to embed in tool definition
+	schemaBytes := schema.GetRawSchema()
+	var schemaMap map[string]interface{}
+	if err := json.Unmarshal(schemaBytes, &schemaMap); err != nil {
+		// Should not happen with embedded valid JSON
+		schemaMap = map[string]interface{}{"type": "object", "error": "failed to parse schema"}
+	}
+	return schemaMap
+}
+
+// generateConfigControls describes the generate_config arguments that
+// control generation rather than being part of the configuration itself.
+//
+// Returns:
+//   - map[string]interface{}: The control argument schemas keyed by name.
+func generateConfigControls() map[string]interface{} {
+	description := "Name of a preset to start from; the other arguments are merged on top of it. Not part of the generated YAML. Available presets:"
+	for _, p := range preset.List() {
+		description += fmt.Sprintf("\n- %s: %s", p.Name, p.Description)
+	}
+	return map[string]interface{}{
+		"variables": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+			"description":          "Values for ${NAME} references in the configuration. Not part of the generated YAML.",
+		},
+		"preset": map[string]interface{}{
+			"type":        "string",
+			"enum":        preset.Names(),
+			"description": description,
+		},
+		"files": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path":     map[string]interface{}{"type": "string", "description": "Absolute path in the built system, e.g. /etc/motd."},
+					"content":  map[string]interface{}{"type": "string", "description": "File content."},
+					"encoding": map[string]interface{}{"type": "string", "enum": []string{"base64"}, "description": "Set to base64 for binary content."},
+					"mode":     map[string]interface{}{"type": "string", "description": "Octal file mode, e.g. \"0600\". Defaults to \"0644\"."},
+				},
+				"required":             []string{"path", "content"},
+				"additionalProperties": false,
+			},
+			"description": "Custom files to place into the built system. They are returned as os-files/ artifacts of the image configuration directory, not in the generated YAML.",
+		},
+		"certificates": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":    map[string]interface{}{"type": "string", "description": "File name under certificates/, ending in .pem or .crt. Derived from the subject if omitted."},
+					"content": map[string]interface{}{"type": "string", "description": "PEM encoded CA certificate or bundle."},
+				},
+				"required":             []string{"content"},
+				"additionalProperties": false,
+			},
+			"description": "CA certificates to install into the trust store of the built system. They are returned as certificates/ artifacts, not in the generated YAML.",
+		},
+		"gpgKeys": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":    map[string]interface{}{"type": "string", "description": "File name under rpms/gpg-keys/. Derived from the key ID if omitted."},
+					"content": map[string]interface{}{"type": "string", "description": "ASCII armored OpenPGP public key."},
+				},
+				"required":             []string{"content"},
+				"additionalProperties": false,
+			},
+			"description": "GPG public keys verifying the packages of operatingSystem.packages.additionalRepos and side-loaded RPMs. They are returned as rpms/gpg-keys/ artifacts, not in the generated YAML.",
+		},
+		"scripts": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":    map[string]interface{}{"type": "string", "description": "File name under custom/scripts/, with a numeric prefix ordering it, e.g. 10-network.sh."},
+					"content": map[string]interface{}{"type": "string", "description": "Script content, starting with a shebang line."},
+				},
+				"required":             []string{"name", "content"},
+				"additionalProperties": false,
+			},
+			"description": "Custom scripts run at first boot by combustion, listed in their intended execution order. Scripts run in lexical order of their names, so names whose order differs from the list are rejected. They are returned as custom/scripts/ artifacts, not in the generated YAML.",
+		},
+		"manifests": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":    map[string]interface{}{"type": "string", "description": "File name under kubernetes/manifests/, ending in .yaml or .yml, e.g. web.yaml."},
+					"content": map[string]interface{}{"type": "string", "description": "YAML stream of Kubernetes objects."},
+				},
+				"required":             []string{"name", "content"},
+				"additionalProperties": false,
+			},
+			"description": "Local Kubernetes manifests applied once the cluster is up. Objects of built-in kinds are checked against the Kubernetes API, so a mistyped kind or a Deployment without a selector fails the call instead of the booted cluster. They are returned as kubernetes/manifests/ artifacts, not in the generated YAML.",
+		},
+		"kubernetesConfig": kubernetesConfigSchema(),
+		"generateSecrets": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string", "enum": tool.ClusterSecretNames},
+			"uniqueItems": true,
+			"description": "Shared cluster secrets to generate into kubernetesConfig: \"token\", the join token of the cluster, and \"agent-token\", a token that only lets agents join. server.yaml gets every secret and agent.yaml the token the agents join with. The secrets are returned under \"Generated secrets\" and the files holding them are marked \"secret\"; keep them like passwords.",
+		},
+		"networkConfigs": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"hostname": map[string]interface{}{"type": "string", "description": "The node the file applies to; it must be one of kubernetes.nodes when nodes are listed."},
+					"content":  map[string]interface{}{"type": "string", "description": "The nmstate YAML document."},
+				},
+				"required":             []string{"hostname", "content"},
+				"additionalProperties": false,
+			},
+			"description": "nmstate network configurations, one per node. Interface names, MAC addresses and hostnames are checked, and the files are returned as network/<hostname>.yaml artifacts, not in the generated YAML.",
+		},
+		"renumberScripts": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Rename \"scripts\" to zero-padded numeric prefixes (10-, 20-, ...) following the list order instead of rejecting misordered names.",
+		},
+		"yaml": map[string]interface{}{
+			"type":        "string",
+			"description": "The whole configuration as a YAML document, instead of passing its fields as arguments. It is parsed, normalized (e.g. a numeric apiVersion is quoted), validated and re-emitted canonically. With \"preset\", it holds the overrides.",
+		},
+		"validateOnly": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Run every check but return only a JSON verdict {valid, kind, errors, warnings, findings} instead of the YAML; findings carry the rule ID, severity, path and suggestion of each error and warning. Plaintext passwords are not hashed and eib validate is not run, making it a cheap pre-flight check.",
+		},
+		"suppress": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "IDs of rules whose warnings and notes to drop, e.g. [\"script\", \"user-uid-system\"]. Every warning names its rule in brackets; list_capabilities validation.findingRules lists them all. Errors cannot be suppressed.",
+		},
+		"sign": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Also return an ASCII-armored OpenPGP detached signature of the definition, made with the server's signing key (see list_capabilities signingKey). With \"outputDir\", it is written as definition.yaml.asc.",
+		},
+		"reproducible": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Make identical input yield a byte-identical definition, for drift detection: plaintext passwords are hashed with a salt derived from the username and password instead of a random one, and the header omits the generation time. Users sharing a username and password get equal hashes across configurations, and anyone can confirm a guessed password by hashing it with the username, or match the hashes against ones precomputed for common usernames such as root; prefer encryptedPassword for passwords that could be guessed.",
+		},
+		"checkManifests": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Download the manifests of kubernetes.manifests.urls to confirm they are available and parse as Kubernetes YAML whose objects match the API of their built-in kind, reporting those that do not as warnings. Not available in offline mode.",
+		},
+		"snapshotManifests": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Download the manifests of kubernetes.manifests.urls and return them as kubernetes/manifests/ artifacts, removing the URLs from the definition, so the image can be rebuilt on an air-gapped host and always deploys the same manifests. A manifest that cannot be downloaded fails the call. With \"validateOnly\", the manifests are only checked.",
+		},
+		"header": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Start the definition with a comment header naming the generator and its version, the schema apiVersion, the generation time and the SHA-256 of the input, so the file can be traced back to how it was produced.",
+		},
+		"canonicalize": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Treat the input as an existing configuration to clean up: remove empty values, fill in EIB defaults, emit keys in canonical order and also return the list of changes and a unified diff from the input (from \"yaml\" if given).",
+		},
+		"outputDir": map[string]interface{}{
+			"type":        "string",
+			"description": "Image configuration directory on the server to write the definition (as definition.yaml) and artifacts to. Existing files, including the image named by image.outputImageName, are never replaced unless \"overwrite\" is true; the conflicts are reported instead.",
+		},
+		"overwrite": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Replace existing files in \"outputDir\".",
+		},
+		"targetRelease": map[string]interface{}{
+			"type":        "string",
+			"enum":        schema.SupportedVersions(),
+			"description": "The EIB release the image will be built with. Fields too new for it are rejected and deprecated fields are reported. Not part of the generated YAML.",
+		},
+	}
+}
+
+// generateConfigSchema returns the input schema of the generate_config tool.
+//
+// It is the configuration schema with the control arguments added to the
+// root definition, so that clients validating arguments accept them.
+//
+// Returns:
+//   - map[string]interface{}: The input schema.
+func generateConfigSchema() map[string]interface{} {
+	return withControls(configSchema(), generateConfigControls())
+}
+
+// generateConfigControlsSchema returns the schema generate_config arguments
+// are checked against before the tool runs.
+//
+// Only the control arguments are checked: the configuration itself may be a
+// partial preset override or contain fields accepted in permissive mode, and
+// is validated by the tool with precise error classes.
+//
+// Returns:
+//   - map[string]interface{}: The schema.
+func generateConfigControlsSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": generateConfigControls(),
+	}
+}
+
+// withControls adds control arguments to the root definition of a configuration schema.
+//
+// Parameters:
+//   - schemaMap: The parsed configuration schema.
+//   - controls: The control argument schemas keyed by name.
+//
+// Returns:
+//   - map[string]interface{}: The schema with the control arguments.
+func withControls(schemaMap map[string]interface{}, controls map[string]interface{}) map[string]interface{} {
+	props := argumentProperties(schemaMap)
+	if props == nil {
+		return schemaMap
+	}
+	for name, control := range controls {
+		props[name] = control
+	}
+	return schemaMap
+}
+
+// argumentProperties returns the properties describing the arguments of a
+// tool input schema: those of the root definition for configuration schemas,
+// or the top-level ones otherwise.
+//
+// Parameters:
+//   - schemaMap: The input schema.
+//
+// Returns:
+//   - map[string]interface{}: The argument schemas keyed by name, or nil if there are none.
+func argumentProperties(schemaMap map[string]interface{}) map[string]interface{} {
+	defs, _ := schemaMap["$defs"].(map[string]interface{})
+	def, _ := defs["Definition"].(map[string]interface{})
+	if props, ok := def["properties"].(map[string]interface{}); ok {
+		return props
+	}
+	props, _ := schemaMap["properties"].(map[string]interface{})
+	return props
+}
+
+// splitControls removes the control arguments from args.
+//
+// Parameters:
+//   - args: The tool arguments; control arguments are deleted from it.
+//   - names: The names of the control arguments.
+//
+// Returns:
+//   - map[string]interface{}: The removed control arguments, keyed by name.
+func splitControls(args map[string]interface{}, names ...string) map[string]interface{} {
+	controls := map[string]interface{}{}
+	for _, name := range names {
+		if v, ok := args[name]; ok {
+			controls[name] = v
+			delete(args, name)
+		}
+	}
+	return controls
+}
+
+// stringMap converts a decoded JSON object into a map of strings.
+//
+// Parameters:
+//   - v: The decoded value, expected to be an object.
+//   - name: The argument name, used in error messages.
+//
+// Returns:
+//   - map[string]string: The converted map, or nil if v is nil.
+//   - error: An error if v is not an object of scalars.
+func stringMap(v interface{}, name string) (map[string]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("argument %q must be an object", name)
+	}
+	result := make(map[string]string, len(obj))
+	for k, item := range obj {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}, nil:
+			return nil, fmt.Errorf("argument %q: value of %q must be a string", name, k)
+		}
+		result[k] = fmt.Sprint(item)
+	}
+	return result, nil
+}
+
+// decodeArgument decodes a structured tool argument into a Go value,
+// rejecting unknown fields.
+//
+// Parameters:
+//   - v: The decoded JSON argument.
+//   - name: The argument name, used in error messages.
+//   - target: A pointer to the value to fill.
+//
+// Returns:
+//   - error: An error if the argument does not match the target's shape.
+func decodeArgument(v interface{}, name string, target interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("argument %q: %w", name, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(target); err != nil {
+		return fmt.Errorf("argument %q: %w", name, err)
+	}
+	return nil
+}
+
+// handleGenerateConfig implements the generate_config tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The configuration to validate and render, or the overrides of a preset, plus control arguments.
+//
+// Returns:
+//   - []map[string]interface{}: The generated YAML, followed by warnings if any.
+//   - error: An error if the preset is unknown or the configuration is invalid.
+func handleGenerateConfig(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	controls := splitControls(args, "variables", "preset", "targetRelease", "files", "certificates", "gpgKeys", "scripts", "renumberScripts", "manifests", "kubernetesConfig", "generateSecrets", "networkConfigs", "outputDir", "overwrite", "yaml", "canonicalize", "validateOnly", "sign", "header", "reproducible", "suppress", "checkManifests", "snapshotManifests")
+	opts := s.toolOptions
+	vars, err := stringMap(controls["variables"], "variables")
+	if err != nil {
+		return nil, err
+	}
+	opts.Variables = vars
+	if v, ok := controls["targetRelease"]; ok {
+		release, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("argument \"targetRelease\" must be a string")
+		}
+		opts.TargetRelease = release
+	}
+	if v, ok := controls["files"]; ok {
+		if err := decodeArgument(v, "files", &opts.Files); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["certificates"]; ok {
+		if err := decodeArgument(v, "certificates", &opts.Certificates); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["gpgKeys"]; ok {
+		if err := decodeArgument(v, "gpgKeys", &opts.GPGKeys); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["scripts"]; ok {
+		if err := decodeArgument(v, "scripts", &opts.Scripts); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["manifests"]; ok {
+		if err := decodeArgument(v, "manifests", &opts.Manifests); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["kubernetesConfig"]; ok {
+		if err := decodeArgument(v, "kubernetesConfig", &opts.KubernetesConfig); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["generateSecrets"]; ok {
+		if err := decodeArgument(v, "generateSecrets", &opts.GenerateSecrets); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["networkConfigs"]; ok {
+		if err := decodeArgument(v, "networkConfigs", &opts.NetworkConfigs); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["renumberScripts"]; ok {
+		if err := decodeArgument(v, "renumberScripts", &opts.RenumberScripts); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := controls["canonicalize"]; ok {
+		if err := decodeArgument(v, "canonicalize", &opts.Canonicalize); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := controls["sign"]; ok {
+		if err := decodeArgument(v, "sign", &opts.Sign); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["reproducible"]; ok {
+		if err := decodeArgument(v, "reproducible", &opts.Reproducible); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["checkManifests"]; ok {
+		if err := decodeArgument(v, "checkManifests", &opts.CheckManifests); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["snapshotManifests"]; ok {
+		if err := decodeArgument(v, "snapshotManifests", &opts.SnapshotManifests); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := controls["header"]; ok {
+		if err := decodeArgument(v, "header", &opts.Header); err != nil {
+			return nil, err
+		}
+		opts.Generator = strings.TrimSpace(s.info.Name + " " + s.info.Version)
+	}
+
+	if v, ok := controls["suppress"]; ok {
+		if err := decodeArgument(v, "suppress", &opts.Suppress); err != nil {
+			return nil, err
+		}
+		if err := tool.CheckSuppress(opts.Suppress, tool.RuleCatalog(opts.Rules, opts.RulePolicy)); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := controls["validateOnly"]; ok {
+		if err := decodeArgument(v, "validateOnly", &opts.ValidateOnly); err != nil {
+			return nil, err
+		}
+		if _, ok := controls["outputDir"]; ok && opts.ValidateOnly {
+			return nil, fmt.Errorf("argument \"validateOnly\" cannot be combined with \"outputDir\"")
+		}
+	}
+
+	var original string
+	if opts.Canonicalize {
+		if _, ok := controls["preset"]; ok {
+			return nil, fmt.Errorf("argument \"canonicalize\" applies to existing configurations and cannot be combined with \"preset\"")
+		}
+		if text, ok := controls["yaml"].(string); ok {
+			original = text
+		} else {
+			raw, err := yaml.Marshal(args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode configuration: %w", err)
+			}
+			original = string(raw)
+		}
+	}
+
+	var notes []tool.Finding
+	var suppressed int
+	if v, ok := controls["yaml"]; ok {
+		text, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("argument \"yaml\" must be a string")
+		}
+		if len(args) > 0 {
+			return nil, fmt.Errorf("argument \"yaml\" cannot be combined with configuration fields, put them in the YAML document")
+		}
+		var repairs []string
+		if args, repairs, err = tool.ParseYAMLConfig(text); err != nil {
+			return nil, err
+		}
+		if notes, err = opts.RulePolicy.Check(tool.NewFindings("yaml-input", tool.SeverityInfo, repairs)); err != nil {
+			return nil, err
+		}
+		notes, suppressed = tool.SuppressFindings(notes, opts.Suppress)
+	}
+
+	var result *tool.Result
+	if name, ok := controls["preset"]; ok {
+		presetName, isString := name.(string)
+		if !isString {
+			return nil, fmt.Errorf("argument \"preset\" must be a string")
+		}
+		result, err = tool.GenerateFromPreset(presetName, args, opts)
+	} else {
+		result, err = tool.Generate(args, opts)
+	}
+	if opts.ValidateOnly {
+		return s.validationVerdict(result, notes, suppressed, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.addNotes(result, notes, suppressed)
+	content := s.resultContent(result)
+	if opts.Canonicalize {
+		content = append(content, textContent(canonicalReport(result, original)))
+	}
+	if v, ok := controls["outputDir"]; ok {
+		dir, isString := v.(string)
+		if !isString || dir == "" {
+			return nil, fmt.Errorf("argument \"outputDir\" must be a non-empty string")
+		}
+		var overwrite bool
+		if v, ok := controls["overwrite"]; ok {
+			if err := decodeArgument(v, "overwrite", &overwrite); err != nil {
+				return nil, err
+			}
+		}
+		written, err := s.writeResult(dir, result, overwrite)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, textContent(fmt.Sprintf("Wrote %d files:\n%s\n", len(written), strings.Join(written, "\n"))))
+	}
+	return content, nil
+}
+
+// writeResult writes a generated definition and its artifacts to an image
+// configuration directory.
+//
+// The paths are checked up front, including the image EIB will build there,
+// so that a conflict leaves the directory untouched.
+//
+// Parameters:
+//   - dir: The image configuration directory.
+//   - result: The generation result.
+//   - overwrite: Whether existing files may be replaced.
+//
+// Returns:
+//   - []string: The written paths.
+//   - error: A *tool.SandboxError if a path is not allowed, a *tool.ConflictError if files exist, or an error if a file cannot be written.
+func (s *Server) writeResult(dir string, result *tool.Result, overwrite bool) ([]string, error) {
+	files := map[string]string{tool.DefinitionFile: result.YAML}
+	if result.Signature != "" {
+		files[tool.SignatureFile] = result.Signature
+	}
+	paths := []string{tool.DefinitionFile}
+	if result.Signature != "" {
+		paths = append(paths, tool.SignatureFile)
+	}
+	for _, a := range result.Artifacts {
+		paths = append(paths, a.Path)
+	}
+	if result.OutputImage != "" {
+		paths = append(paths, result.OutputImage)
+	}
+	if err := s.checkOutputPaths(dir, paths); err != nil {
+		return nil, err
+	}
+	if err := tool.CheckOutput(dir, paths, overwrite); err != nil {
+		return nil, err
+	}
+
+	var secrets []string
+	if len(result.ResolvedSecrets) > 0 {
+		secrets = append(secrets, tool.DefinitionFile)
+	}
+	written, err := tool.WriteFiles(dir, files, secrets, true)
+	if err != nil {
+		return nil, err
+	}
+	artifacts, err := tool.WriteArtifacts(dir, result.Artifacts, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(written, artifacts...), nil
+}
+
+// resultContent renders a generation result as content blocks.
+//
+// Parameters:
+//   - result: The generation result.
+//
+// Returns:
+//   - []map[string]interface{}: The YAML, followed by warnings, the
+//     vulnerability summary, artifacts, generated secrets, checksums, the content hash and the
+//     signature if any. Resolved secrets are redacted from
+//     the YAML.
+func (s *Server) resultContent(result *tool.Result) []map[string]interface{} {
+	content := []map[string]interface{}{textContent(result.YAML)}
+	if len(result.ResolvedSecrets) > 0 {
+		content = []map[string]interface{}{
+			textContent(tool.RedactSecrets(result.YAML)),
+			textContent(fmt.Sprintf("Secret references were resolved in %s. Secrets are redacted above; only a definition written with outputDir holds their values.", strings.Join(result.ResolvedSecrets, ", "))),
+		}
+	}
+	if len(result.Warnings) > 0 {
+		content = append(content, textContent(s.formatWarnings(result.Warnings)))
+	}
+	if result.Suppressed > 0 {
+		content = append(content, textContent(s.catalog.Text(fmt.Sprintf("Findings of suppressed rules omitted: %d", result.Suppressed))))
+	}
+	if len(result.Advisories) > 0 {
+		lines := make([]string, len(result.Advisories))
+		for i, a := range result.Advisories {
+			lines[i] = fmt.Sprintf("- %s: %s", a.Image, a.Summary())
+			if a.Error != "" {
+				lines[i] = fmt.Sprintf("- %s: not checked (%s)", a.Image, a.Error)
+			}
+		}
+		content = append(content, textContent("Known vulnerabilities of embedded images:\n"+strings.Join(lines, "\n")))
+	}
+	if len(result.Artifacts) > 0 {
+		artifacts, err := json.MarshalIndent(result.Artifacts, "", "  ")
+		if err == nil {
+			content = append(content, textContent("Artifacts (paths relative to the image configuration directory):\n"+string(artifacts)))
+		}
+	}
+	if len(result.GeneratedSecrets) > 0 {
+		secrets, err := json.MarshalIndent(result.GeneratedSecrets, "", "  ")
+		if err == nil {
+			content = append(content, textContent("Generated secrets (stored nowhere else; keep them like passwords):\n"+string(secrets)))
+		}
+	}
+	if len(result.Checksums) > 0 {
+		content = append(content, textContent("SHA-256 checksums:\n"+tool.FormatChecksums(result.Checksums)))
+	}
+	if result.ContentHash != "" {
+		content = append(content, textContent("Content hash: "+result.ContentHash))
+	}
+	if result.Signature != "" {
+		content = append(content, textContent("Detached signature ("+tool.SignatureFile+"):\n"+result.Signature))
+	}
+	return content
+}
+
+// verdict is the result of generate_config with validateOnly set.
+type verdict struct {
+	// Valid is true if the configuration passed every check.
+	Valid bool `json:"valid"`
+	// Kind classifies the failure of an invalid configuration.
+	Kind tool.ErrorKind `json:"kind,omitempty"`
+	// Errors lists the findings that make the configuration invalid.
+	Errors []string `json:"errors,omitempty"`
+	// Warnings lists the non-fatal findings.
+	Warnings []string `json:"warnings,omitempty"`
+	// Findings lists the errors and warnings with their rule ID, path and
+	// suggestion. They are not translated.
+	Findings []tool.Finding `json:"findings,omitempty"`
+	// Suppressed is the number of findings dropped by "suppress".
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// addNotes puts the findings made before generation ahead of those of the
+// result, as the verdict of validationVerdict does.
+//
+// Parameters:
+//   - result: The generation result; its warnings, findings and suppressed
+//     count are updated.
+//   - notes: Findings made before generation, e.g. repairs of the YAML input.
+//   - suppressed: The number of notes dropped by "suppress".
+func (s *Server) addNotes(result *tool.Result, notes []tool.Finding, suppressed int) {
+	for i := len(notes) - 1; i >= 0; i-- {
+		result.Warnings = append([]string{notes[i].String()}, result.Warnings...)
+	}
+	result.Findings = append(notes, result.Findings...)
+	result.Suppressed += suppressed
+}
+
+// validationVerdict renders the outcome of a validateOnly run.
+//
+// Validation failures are part of the verdict rather than tool errors, so
+// that a pre-flight check always returns the same shape. Errors and warnings
+// are in the language of the session; the kind is not translated.
+//
+// Parameters:
+//   - result: The generation result, or nil on failure.
+//   - notes: Findings made before generation, e.g. repairs of the YAML input.
+//   - suppressed: The number of notes dropped by "suppress".
+//   - err: The generation error, or nil.
+//
+// Returns:
+//   - []map[string]interface{}: The verdict as a JSON document.
+//   - error: The generation error if it is not a validation failure.
+func (s *Server) validationVerdict(result *tool.Result, notes []tool.Finding, suppressed int, err error) ([]map[string]interface{}, error) {
+	v := verdict{Valid: err == nil, Suppressed: suppressed}
+	v.Findings = append(v.Findings, notes...)
+	if result != nil {
+		v.Findings = append(v.Findings, result.Findings...)
+		v.Suppressed += result.Suppressed
+	}
+	for _, f := range v.Findings {
+		v.Warnings = append(v.Warnings, s.catalog.Text(f.String()))
+	}
+	if err != nil {
+		v.Findings = append(tool.ErrorFindings(err), v.Findings...)
+		var classified *tool.Error
+		if !errors.As(err, &classified) {
+			return nil, err
+		}
+		v.Kind = classified.Kind
+		for _, line := range strings.Split(err.Error(), "\n") {
+			if finding, ok := strings.CutPrefix(line, "- "); ok {
+				v.Errors = append(v.Errors, s.catalog.Text(finding))
+			}
+		}
+		if len(v.Errors) == 0 {
+			v.Errors = []string{s.catalog.Text(err.Error())}
+		}
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode verdict: %w", err)
+	}
+	return []map[string]interface{}{textContent(string(out))}, nil
+}
+
+// canonicalReport describes how a configuration differs from its canonical form.
+//
+// Parameters:
+//   - result: The generation result, with the changes made by canonicalization.
+//   - original: The configuration as written.
+//
+// Returns:
+//   - string: The changes and the unified diff, or a note that the configuration is already canonical.
+func canonicalReport(result *tool.Result, original string) string {
+	diff := tool.CanonicalDiff(original, result.YAML)
+	if diff == "" {
+		return "The configuration is already canonical."
+	}
+	text := ""
+	if len(result.Changes) > 0 {
+		text = "Canonicalization changes:\n"
+		for _, c := range result.Changes {
+			text += fmt.Sprintf("- %s\n", c)
+		}
+		text += "\n"
+	}
+	return text + "Diff:\n" + diff
+}
+
+// handlePatchConfig implements the patch_config tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The existing "config", the "patch" to apply and the optional "patchType".
+//
+// Returns:
+//   - []map[string]interface{}: The updated YAML, followed by warnings if any.
+//   - error: An error if the arguments are malformed or the result is invalid.
+func handlePatchConfig(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	config, err := tool.ParseConfig(args["config"])
+	if err != nil {
+		return nil, err
+	}
+	patchType, _ := args["patchType"].(string)
+
+	result, err := tool.PatchConfig(config, args["patch"], tool.PatchType(patchType), s.toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	return s.resultContent(result), nil
+}
+
+// handleGenerateFleet implements the generate_fleet tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "inventory", the base "template" and/or "preset", shared "variables", the "layout" and an optional "outputDir".
+//
+// Returns:
+//   - []map[string]interface{}: The generated files as a JSON map, or the written paths, followed by warnings if any.
+//   - error: An error if the arguments are malformed or a site cannot be generated.
+func handleGenerateFleet(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	sites, err := tool.ParseInventory(args["inventory"])
+	if err != nil {
+		return nil, err
+	}
+
+	template := map[string]interface{}{}
+	if v, ok := args["template"]; ok {
+		if template, err = tool.ParseConfig(v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := args["preset"]; ok {
+		name, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("argument \"preset\" must be a string")
+		}
+		if template, err = tool.FromPreset(name, template); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := s.toolOptions
+	if opts.Variables, err = stringMap(args["variables"], "variables"); err != nil {
+		return nil, err
+	}
+
+	layoutName, _ := args["layout"].(string)
+	layout, err := tool.ParseLayout(layoutName)
+	if err != nil {
+		return nil, err
+	}
+
+	fleet, err := tool.GenerateFleet(template, sites, opts)
+	if err != nil {
+		return nil, err
+	}
+	if layout == tool.LayoutGitOps {
+		if err := tool.ApplyGitOpsLayout(fleet); err != nil {
+			return nil, err
+		}
+	}
+
+	var content []map[string]interface{}
+	if dir, _ := args["outputDir"].(string); dir != "" {
+		var overwrite bool
+		if v, ok := args["overwrite"]; ok {
+			if err := decodeArgument(v, "overwrite", &overwrite); err != nil {
+				return nil, err
+			}
+		}
+		paths := append([]string{}, fleet.OutputImages...)
+		for p := range fleet.Files {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		if err := s.checkOutputPaths(dir, paths); err != nil {
+			return nil, err
+		}
+		if err := tool.CheckOutput(dir, paths, overwrite); err != nil {
+			return nil, err
+		}
+		written, err := tool.WriteFiles(dir, fleet.Files, fleet.SecretFiles, true)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, textContent(fmt.Sprintf("Wrote %d files:\n%s\n", len(written), strings.Join(written, "\n"))))
+	} else {
+		for _, file := range fleet.SecretFiles {
+			fleet.Files[file] = tool.RedactSecrets(fleet.Files[file])
+		}
+		files, err := json.MarshalIndent(fleet.Files, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode files: %w", err)
+		}
+		content = append(content, textContent(string(files)))
+		if len(fleet.SecretFiles) > 0 {
+			content = append(content, textContent(fmt.Sprintf("Secret references were resolved in %s. Secrets are redacted above; only files written with outputDir hold their values.", strings.Join(fleet.SecretFiles, ", "))))
+		}
+	}
+	content = append(content, textContent("SHA-256 checksums:\n"+tool.FormatChecksums(fleet.Checksums)))
+	if len(fleet.Warnings) > 0 {
+		content = append(content, textContent(s.formatWarnings(fleet.Warnings)))
+	}
+	return content, nil
+}
+
+// checkOutputPaths verifies that an output directory and every path about to
+// be written below it are allowed by the sandbox.
+//
+// Each path is checked on its own because an existing symbolic link below the
+// output directory could otherwise redirect a write outside of it.
+//
+// Parameters:
+//   - dir: The output directory.
+//   - paths: The paths about to be written, relative to dir.
+//
+// Returns:
+//   - error: A *tool.SandboxError for the first path that is not allowed.
+func (s *Server) checkOutputPaths(dir string, paths []string) error {
+	if _, err := s.toolOptions.Sandbox.Check(dir); err != nil {
+		return err
+	}
+	for _, rel := range paths {
+		if _, err := s.toolOptions.Sandbox.Check(filepath.Join(dir, rel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleSchemaDiff implements the schema_diff tool.
+//
+// Parameters:
+//   - s: The server running the tool.
+//   - args: The "from" and optional "to" apiVersions.
+//
+// Returns:
+//   - []map[string]interface{}: The diff report.
+//   - error: An error if a version is missing or unsupported.
+func handleSchemaDiff(s *Server, args map[string]interface{}) ([]map[string]interface{}, error) {
+	from, _ := args["from"].(string)
+	if from == "" {
+		return nil, fmt.Errorf("argument \"from\" is required")
+	}
+	to, _ := args["to"].(string)
+	if to == "" {
+		to = schema.LatestVersion()
+	}
+
+	diff, err := schema.Diff(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]interface{}{textContent(diff.String())}, nil
+}
+
+// textContent builds a text content block for a tool result.
+//
+// Parameters:
+//   - text: The text to return.
+//
+// Returns:
+//   - map[string]interface{}: The content block.
+func textContent(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "text",
+		"text": text,
+	}
+}
+
+// formatWarnings renders warnings as a bulleted text block, in the language
+// of the session.
+//
+// Parameters:
+//   - warnings: The warnings to render.
+//
+// Returns:
+//   - string: The rendered warnings.
+func (s *Server) formatWarnings(warnings []string) string {
+	text := s.catalog.Label("Warnings") + ":\n"
+	for _, w := range warnings {
+		text += fmt.Sprintf("- %s\n", s.catalog.Text(w))
+	}
+	return text
+}
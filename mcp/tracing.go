@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/e-minguez/eib-mcp/otlp"
+	"github.com/e-minguez/eib-mcp/tool"
+)
+
+// tracedHandleRequest processes a request like safeHandleRequest, recording
+// the steps of tool calls for the slow-call log and exporting the trace of
+// the request, if either is enabled.
+//
+// Parameters:
+//   - req: The incoming JSON-RPC request.
+//
+// Returns:
+//   - *JSONRPCResponse: The response, or nil if no response is needed.
+func (s *Server) tracedHandleRequest(req *JSONRPCRequest) *JSONRPCResponse {
+	if s.exporter == nil && s.slowCall <= 0 {
+		return s.safeHandleRequest(req)
+	}
+	trace := &tool.Trace{}
+	s.toolOptions.Trace = trace
+	defer func() { s.toolOptions.Trace = nil }()
+
+	start := time.Now()
+	resp := s.safeHandleRequest(req)
+	if s.exporter != nil {
+		s.exportTrace(req, start, time.Now(), trace, resp)
+	}
+	return resp
+}
+
+// exportTrace exports a request as a server span, with its steps as child
+// spans.
+//
+// Parameters:
+//   - req: The request.
+//   - start: When handling started.
+//   - end: When handling ended.
+//   - trace: The steps of the request.
+//   - resp: The response, used for the status of the request span.
+func (s *Server) exportTrace(req *JSONRPCRequest, start, end time.Time, trace *tool.Trace, resp *JSONRPCResponse) {
+	root := otlp.Span{
+		TraceID: otlp.NewTraceID(),
+		SpanID:  otlp.NewSpanID(),
+		Name:    req.Method,
+		Kind:    otlp.KindServer,
+		Start:   start,
+		End:     end,
+		Attributes: map[string]string{
+			"rpc.system":             "jsonrpc",
+			"rpc.method":             req.Method,
+			"eib_mcp.correlation_id": req.correlationID,
+		},
+	}
+	if req.ID != nil {
+		root.Attributes["rpc.jsonrpc.request_id"] = fmt.Sprint(req.ID)
+	}
+	s.mu.Lock()
+	if s.inflight != nil && s.inflight.Tool != "" {
+		root.Name += " " + s.inflight.Tool
+		root.Attributes["mcp.tool"] = s.inflight.Tool
+	}
+	s.mu.Unlock()
+	if resp != nil {
+		root.Error = responseError(resp)
+	}
+
+	spans := []otlp.Span{root}
+	for _, step := range trace.Spans() {
+		spans = append(spans, otlp.Span{
+			TraceID:  root.TraceID,
+			SpanID:   otlp.NewSpanID(),
+			ParentID: root.SpanID,
+			Name:     step.Name,
+			Kind:     otlp.KindInternal,
+			Start:    step.Start,
+			End:      step.Start.Add(step.Duration),
+		})
+	}
+	s.exporter.Export(spans...)
+}
+
+// responseError describes the failure reported by a response: a JSON-RPC
+// error, or a tool result with isError set.
+//
+// Parameters:
+//   - resp: The response.
+//
+// Returns:
+//   - string: The error code and message, or empty on success.
+func responseError(resp *JSONRPCResponse) string {
+	if resp.Error != nil {
+		return fmt.Sprintf("%d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok || result["isError"] != true {
+		return ""
+	}
+	meta, _ := result["_meta"].(map[string]interface{})
+	message := "tool error"
+	if content, ok := result["content"].([]map[string]interface{}); ok && len(content) > 0 {
+		if text, ok := content[0]["text"].(string); ok {
+			message = text
+		}
+	}
+	return fmt.Sprintf("%v: %s", meta["errorCode"], message)
+}
@@ -0,0 +1,340 @@
+// Package otlp exports trace spans to an OpenTelemetry collector with the
+// OTLP/HTTP protocol, in its JSON encoding.
+//
+// Spans are queued and sent in batches in the background, so exporting never
+// delays a request. Only the small part of OTLP the server needs is
+// implemented, which keeps the server free of the OpenTelemetry SDK.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds and status codes of OTLP.
+const (
+	// KindInternal is an operation inside the server.
+	KindInternal = 1
+	// KindServer is the handling of a request from a client.
+	KindServer = 2
+
+	statusOK    = 1
+	statusError = 2
+)
+
+// Batching limits: a batch is sent once it holds batchSize spans or
+// batchDelay after its first span.
+const (
+	batchSize  = 256
+	batchDelay = 2 * time.Second
+	queueSize  = 4096
+)
+
+// Span is a finished operation.
+type Span struct {
+	// TraceID identifies the trace, as 32 hexadecimal digits.
+	TraceID string
+	// SpanID identifies the span, as 16 hexadecimal digits.
+	SpanID string
+	// ParentID is the SpanID of the parent span, or empty for a root span.
+	ParentID string
+	// Name describes the operation, e.g. "tools/call generate_config".
+	Name string
+	// Kind is KindServer or KindInternal.
+	Kind int
+	// Start and End delimit the operation.
+	Start, End time.Time
+	// Attributes describe the operation, e.g. "mcp.tool".
+	Attributes map[string]string
+	// Error is the error message if the operation failed.
+	Error string
+}
+
+// NewTraceID returns a random trace ID.
+//
+// Returns:
+//   - string: 32 hexadecimal digits.
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID returns a random span ID.
+//
+// Returns:
+//   - string: 16 hexadecimal digits.
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+// randomHex returns n random bytes in hexadecimal.
+//
+// Parameters:
+//   - n: The number of bytes.
+//
+// Returns:
+//   - string: The hexadecimal digits.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Exporter sends spans to an OTLP/HTTP endpoint. An Exporter is safe for
+// concurrent use.
+type Exporter struct {
+	endpoint string
+	service  string
+	headers  map[string]string
+	client   *http.Client
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+	queue   chan Span
+	done    chan struct{}
+}
+
+// NewExporter creates an exporter and starts sending in the background.
+//
+// Parameters:
+//   - endpoint: The collector URL, e.g. http://collector:4318. The path
+//     /v1/traces is added when the URL has no path.
+//   - service: The service.name resource attribute.
+//   - headers: Additional HTTP headers, e.g. for authentication.
+//
+// Returns:
+//   - *Exporter: The exporter.
+//   - error: An error if the endpoint is not an http or https URL.
+func NewExporter(endpoint, service string, headers map[string]string) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: an http or https URL is required", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	e := &Exporter{
+		endpoint: u.String(),
+		service:  service,
+		headers:  headers,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan Span, queueSize),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// ParseHeaders parses headers given as comma-separated key=value pairs, the
+// format of OTEL_EXPORTER_OTLP_HEADERS.
+//
+// Parameters:
+//   - s: The headers, e.g. "authorization=Bearer abc,x-tenant=edge".
+//
+// Returns:
+//   - map[string]string: The headers.
+//   - error: An error if a pair has no "=".
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	if s == "" {
+		return headers, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid OTLP header %q: expected key=value", pair)
+		}
+		k, err := url.QueryUnescape(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header %q: %w", pair, err)
+		}
+		v, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header %q: %w", pair, err)
+		}
+		headers[k] = v
+	}
+	return headers, nil
+}
+
+// Export queues spans for sending. Spans are dropped, and the drop reported
+// once per batch, if the collector cannot keep up or the exporter is shut
+// down.
+//
+// Parameters:
+//   - spans: The finished spans.
+func (e *Exporter) Export(spans ...Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range spans {
+		if e.closed {
+			e.dropped++
+			continue
+		}
+		select {
+		case e.queue <- s:
+		default:
+			e.dropped++
+		}
+	}
+}
+
+// Shutdown sends the queued spans and stops the exporter.
+//
+// Parameters:
+//   - ctx: Bounds the wait for the last batch.
+//
+// Returns:
+//   - error: ctx.Err() if the last batch could not be sent in time.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run batches the queued spans and sends them until the queue is closed.
+func (e *Exporter) run() {
+	defer close(e.done)
+	var batch []Span
+	var flush <-chan time.Time
+	for {
+		select {
+		case s, ok := <-e.queue:
+			if !ok {
+				e.send(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) == 1 {
+				flush = time.After(batchDelay)
+			}
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-flush:
+		}
+		e.send(batch)
+		batch, flush = nil, nil
+	}
+}
+
+// send posts a batch to the collector. Failures are logged on os.Stderr;
+// the batch is not retried.
+//
+// Parameters:
+//   - batch: The spans.
+func (e *Exporter) send(batch []Span) {
+	e.mu.Lock()
+	dropped := e.dropped
+	e.dropped = 0
+	e.mu.Unlock()
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "OTLP export: %d spans dropped\n", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "OTLP export failed: %v\n", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "OTLP export failed: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "OTLP export failed: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 {
+		fmt.Fprintf(os.Stderr, "OTLP export failed: %s returned %s\n", e.endpoint, resp.Status)
+	}
+}
+
+// request builds the ExportTraceServiceRequest of a batch, in the OTLP JSON
+// encoding.
+//
+// Parameters:
+//   - batch: The spans.
+//
+// Returns:
+//   - map[string]interface{}: The request body.
+func (e *Exporter) request(batch []Span) map[string]interface{} {
+	spans := make([]map[string]interface{}, len(batch))
+	for i, s := range batch {
+		span := map[string]interface{}{
+			"traceId":           s.TraceID,
+			"spanId":            s.SpanID,
+			"name":              s.Name,
+			"kind":              s.Kind,
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+			"attributes":        attributes(s.Attributes),
+			"status":            map[string]interface{}{"code": statusOK},
+		}
+		if s.ParentID != "" {
+			span["parentSpanId"] = s.ParentID
+		}
+		if s.Error != "" {
+			span["status"] = map[string]interface{}{"code": statusError, "message": s.Error}
+		}
+		spans[i] = span
+	}
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": attributes(map[string]string{"service.name": e.service}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "github.com/e-minguez/eib-mcp"},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// attributes converts attributes to OTLP key-value pairs.
+//
+// Parameters:
+//   - attrs: The attributes.
+//
+// Returns:
+//   - []map[string]interface{}: The key-value pairs.
+func attributes(attrs map[string]string) []map[string]interface{} {
+	kvs := make([]map[string]interface{}, 0, len(attrs))
+	for k, v := range attrs {
+		kvs = append(kvs, map[string]interface{}{"key": k, "value": map[string]interface{}{"stringValue": v}})
+	}
+	return kvs
+}
@@ -0,0 +1,911 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// env holds the variables an expression can refer to.
+type env map[string]interface{}
+
+// program is a compiled expression.
+type program func(env) (interface{}, error)
+
+// token is a lexical token of an expression.
+type token struct {
+	// kind is "ident", "number", "string" or "op".
+	kind string
+	// text is the identifier, the operator, the unquoted string or the
+	// number as written.
+	text string
+	// pos is the byte offset of the token in the expression.
+	pos int
+}
+
+// operators lists the operators, longest first so that "<=" wins over "<".
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "?", ":", ".", ",", "(", ")", "[", "]"}
+
+// lex splits an expression into tokens.
+//
+// Parameters:
+//   - src: The expression.
+//
+// Returns:
+//   - []token: The tokens.
+//   - error: An error if the expression holds an unknown character or an
+//     unterminated string.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: "ident", text: src[i:j], pos: i})
+			i = j
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: "number", text: src[i:j], pos: i})
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != src[i] {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text, err := unquote(src[i+1 : j])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: "string", text: text, pos: i})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			tokens = append(tokens, token{kind: "op", text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// unquote decodes the body of a single- or double-quoted string literal.
+// Both quotes may be escaped in either kind of literal, as in CEL, e.g.
+// 'it\'s'; the other escapes are those of Go.
+//
+// Parameters:
+//   - body: The literal without its quotes.
+//
+// Returns:
+//   - string: The decoded string.
+//   - error: An error if an escape sequence is invalid.
+func unquote(body string) (string, error) {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == '\\' && i+1 < len(body) && body[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case body[i] == '\\' && i+1 < len(body):
+			b.WriteString(body[i : i+2])
+			i++
+		case body[i] == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(body[i])
+		}
+	}
+	b.WriteByte('"')
+	return strconv.Unquote(b.String())
+}
+
+// parser compiles tokens into a program by recursive descent.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// compile compiles an expression.
+//
+// The language is a subset of CEL, the Common Expression Language: literals
+// (numbers, strings, true, false, null, lists), field selection and
+// indexing, the operators ! - * / % + == != < <= > >= in && || ?:, the
+// functions has() and size(), and the methods size(), contains(),
+// startsWith(), endsWith(), matches(), all(), exists(), exists_one(),
+// filter() and map().
+//
+// The rest of CEL is left out, as policies only read the decoded YAML of a
+// definition: map literals, bytes, raw and triple-quoted strings, the int,
+// uint and double types (every number is a double), type conversions such
+// as int() and string(), timestamps and durations, optional values and
+// protocol buffer messages. Expressions are not type-checked before they
+// run.
+//
+// Parameters:
+//   - src: The expression.
+//
+// Returns:
+//   - program: The compiled expression.
+//   - error: An error if the expression is malformed.
+func compile(src string) (program, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	prog, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	return prog, nil
+}
+
+// peek reports whether the next token is the operator or keyword op.
+func (p *parser) peek(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].text == op && p.tokens[p.pos].kind != "string"
+}
+
+// accept consumes the next token if it is op.
+func (p *parser) accept(op string) bool {
+	if p.peek(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes the next token, which must be op.
+func (p *parser) expect(op string) error {
+	if p.accept(op) {
+		return nil
+	}
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("expected %q at offset %d, found %q", op, p.tokens[p.pos].pos, p.tokens[p.pos].text)
+	}
+	return fmt.Errorf("expected %q at the end of the expression", op)
+}
+
+// expr parses a conditional expression.
+func (p *parser) expr() (program, error) {
+	cond, err := p.or()
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return func(e env) (interface{}, error) {
+		c, err := evalBool(cond, e)
+		if err != nil {
+			return nil, err
+		}
+		if c {
+			return then(e)
+		}
+		return otherwise(e)
+	}, nil
+}
+
+// or parses a disjunction.
+func (p *parser) or() (program, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right program
+		if right, err = p.and(); err == nil {
+			l, r := left, right
+			left = func(e env) (interface{}, error) {
+				a, err := evalBool(l, e)
+				if err != nil || a {
+					return a, err
+				}
+				return evalBool(r, e)
+			}
+		}
+	}
+	return left, err
+}
+
+// and parses a conjunction.
+func (p *parser) and() (program, error) {
+	left, err := p.relation()
+	for err == nil && p.accept("&&") {
+		var right program
+		if right, err = p.relation(); err == nil {
+			l, r := left, right
+			left = func(e env) (interface{}, error) {
+				a, err := evalBool(l, e)
+				if err != nil || !a {
+					return a, err
+				}
+				return evalBool(r, e)
+			}
+		}
+	}
+	return left, err
+}
+
+// relation parses a comparison or membership test.
+func (p *parser) relation() (program, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if !p.accept(op) {
+			continue
+		}
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return func(e env) (interface{}, error) {
+			a, err := left(e)
+			if err != nil {
+				return nil, err
+			}
+			b, err := right(e)
+			if err != nil {
+				return nil, err
+			}
+			return compare(op, a, b)
+		}, nil
+	}
+	return left, nil
+}
+
+// additive parses sums and differences.
+func (p *parser) additive() (program, error) {
+	left, err := p.multiplicative()
+	for err == nil && (p.peek("+") || p.peek("-")) {
+		op := p.tokens[p.pos].text
+		p.pos++
+		var right program
+		if right, err = p.multiplicative(); err == nil {
+			left = arithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+// multiplicative parses products, quotients and remainders.
+func (p *parser) multiplicative() (program, error) {
+	left, err := p.unary()
+	for err == nil && (p.peek("*") || p.peek("/") || p.peek("%")) {
+		op := p.tokens[p.pos].text
+		p.pos++
+		var right program
+		if right, err = p.unary(); err == nil {
+			left = arithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+// unary parses negations.
+func (p *parser) unary() (program, error) {
+	switch {
+	case p.accept("!"):
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e env) (interface{}, error) {
+			v, err := evalBool(operand, e)
+			return !v, err
+		}, nil
+	case p.accept("-"):
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic("-", func(env) (interface{}, error) { return 0.0, nil }, operand), nil
+	}
+	return p.member()
+}
+
+// member parses field selections, indexing and method calls.
+func (p *parser) member() (program, error) {
+	target, _, err := p.postfix()
+	return target, err
+}
+
+// postfix parses a primary expression followed by field selections,
+// indexing and method calls.
+//
+// Returns:
+//   - program: The compiled expression.
+//   - bool: Whether the expression is a field selection, e.g. config.image,
+//     rather than an index, a method call or a primary expression.
+//   - error: An error if the expression is malformed.
+func (p *parser) postfix() (program, bool, error) {
+	target, err := p.primary()
+	selection := false
+	for err == nil {
+		switch {
+		case p.accept("."):
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "ident" {
+				return nil, false, fmt.Errorf("expected a field or method name after \".\"")
+			}
+			name := p.tokens[p.pos].text
+			p.pos++
+			if p.peek("(") {
+				target, err = p.method(target, name)
+				selection = false
+				continue
+			}
+			target = selectField(target, name)
+			selection = true
+		case p.accept("["):
+			var index program
+			if index, err = p.expr(); err != nil {
+				return nil, false, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, false, err
+			}
+			target = indexValue(target, index)
+			selection = false
+		default:
+			return target, selection, nil
+		}
+	}
+	return nil, false, err
+}
+
+// primary parses literals, variables, global functions and parentheses.
+func (p *parser) primary() (program, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of the expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case "number":
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", t.text, t.pos)
+		}
+		return constant(n), nil
+	case "string":
+		return constant(t.text), nil
+	case "ident":
+		switch t.text {
+		case "true":
+			return constant(true), nil
+		case "false":
+			return constant(false), nil
+		case "null":
+			return constant(nil), nil
+		case "has":
+			return p.has()
+		case "size":
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != 1 {
+				return nil, fmt.Errorf("size() takes one argument")
+			}
+			return sizeOf(args[0]), nil
+		}
+		name := t.text
+		return func(e env) (interface{}, error) {
+			v, ok := e[name]
+			if !ok {
+				return nil, fmt.Errorf("undeclared reference to %q", name)
+			}
+			return v, nil
+		}, nil
+	}
+	switch t.text {
+	case "(":
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	case "[":
+		var items []program
+		for !p.accept("]") {
+			if len(items) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			item, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return func(e env) (interface{}, error) {
+			list := make([]interface{}, len(items))
+			for i, item := range items {
+				v, err := item(e)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = v
+			}
+			return list, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+// args parses the parenthesized arguments of a call.
+func (p *parser) args() ([]program, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []program
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// has parses has(x.field), which tests whether a field is set. Unlike CEL,
+// it is also false when a field along the path is missing, so that
+// has(config.a.b.c) needs no guard for config.a.b.
+func (p *parser) has() (program, error) {
+	usage := fmt.Errorf("has() takes a field selection, e.g. has(config.operatingSystem.time)")
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	arg, selection, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	if !selection || !p.accept(")") {
+		return nil, usage
+	}
+	return func(e env) (interface{}, error) {
+		_, err := arg(e)
+		if _, missing := err.(missingFieldError); missing {
+			return false, nil
+		}
+		return err == nil, err
+	}, nil
+}
+
+// method parses a method call on target.
+func (p *parser) method(target program, name string) (program, error) {
+	switch name {
+	case "all", "exists", "exists_one", "filter", "map":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "ident" {
+			return nil, fmt.Errorf("%s() takes a variable name and an expression", name)
+		}
+		variable := p.tokens[p.pos].text
+		p.pos++
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		body, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return comprehension(name, target, variable, body), nil
+	}
+
+	args, err := p.args()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "size":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("size() takes no arguments as a method")
+		}
+		return sizeOf(target), nil
+	case "contains", "startsWith", "endsWith", "matches":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s() takes one argument", name)
+		}
+		return stringMethod(name, target, args[0]), nil
+	}
+	return nil, fmt.Errorf("unknown method %q", name)
+}
+
+// missingFieldError reports a field selection on a map without the field.
+type missingFieldError struct {
+	field string
+}
+
+// Error implements the error interface.
+func (e missingFieldError) Error() string {
+	return fmt.Sprintf("no such key: %s", e.field)
+}
+
+// constant returns a program yielding v.
+func constant(v interface{}) program {
+	return func(env) (interface{}, error) { return v, nil }
+}
+
+// selectField returns a program selecting a field of a map.
+func selectField(target program, name string) program {
+	return func(e env) (interface{}, error) {
+		v, err := target(e)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot select field %q of %s", name, typeName(v))
+		}
+		field, ok := m[name]
+		if !ok {
+			return nil, missingFieldError{field: name}
+		}
+		return field, nil
+	}
+}
+
+// indexValue returns a program indexing a list by number or a map by key.
+func indexValue(target, index program) program {
+	return func(e env) (interface{}, error) {
+		v, err := target(e)
+		if err != nil {
+			return nil, err
+		}
+		i, err := index(e)
+		if err != nil {
+			return nil, err
+		}
+		switch c := v.(type) {
+		case []interface{}:
+			n, ok := number(i)
+			if !ok || n != float64(int(n)) || n < 0 || int(n) >= len(c) {
+				return nil, fmt.Errorf("index %v out of range for a list of %d items", i, len(c))
+			}
+			return c[int(n)], nil
+		case map[string]interface{}:
+			key, ok := i.(string)
+			if !ok {
+				return nil, fmt.Errorf("map keys are strings, not %s", typeName(i))
+			}
+			field, ok := c[key]
+			if !ok {
+				return nil, missingFieldError{field: key}
+			}
+			return field, nil
+		}
+		return nil, fmt.Errorf("cannot index %s", typeName(v))
+	}
+}
+
+// sizeOf returns a program measuring a string, list or map.
+func sizeOf(target program) program {
+	return func(e env) (interface{}, error) {
+		v, err := target(e)
+		if err != nil {
+			return nil, err
+		}
+		switch c := v.(type) {
+		case string:
+			return float64(len([]rune(c))), nil
+		case []interface{}:
+			return float64(len(c)), nil
+		case map[string]interface{}:
+			return float64(len(c)), nil
+		}
+		return nil, fmt.Errorf("size() is not defined for %s", typeName(v))
+	}
+}
+
+// stringMethod returns a program calling a string method.
+func stringMethod(name string, target, arg program) program {
+	return func(e env) (interface{}, error) {
+		v, err := target(e)
+		if err != nil {
+			return nil, err
+		}
+		a, err := arg(e)
+		if err != nil {
+			return nil, err
+		}
+		s, ok1 := v.(string)
+		sub, ok2 := a.(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s() is defined for strings, not %s and %s", name, typeName(v), typeName(a))
+		}
+		switch name {
+		case "contains":
+			return strings.Contains(s, sub), nil
+		case "startsWith":
+			return strings.HasPrefix(s, sub), nil
+		case "endsWith":
+			return strings.HasSuffix(s, sub), nil
+		}
+		re, err := regexp.Compile(sub)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", sub, err)
+		}
+		return re.MatchString(s), nil
+	}
+}
+
+// comprehension returns a program evaluating a macro such as all() over the
+// items of a list or the keys of a map.
+func comprehension(name string, target program, variable string, body program) program {
+	return func(e env) (interface{}, error) {
+		v, err := target(e)
+		if err != nil {
+			return nil, err
+		}
+		var items []interface{}
+		switch c := v.(type) {
+		case []interface{}:
+			items = c
+		case map[string]interface{}:
+			// Keys are visited in order, so that filter() and map() return
+			// the same list, and exists() the same error, on every run.
+			keys := make([]string, 0, len(c))
+			for k := range c {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				items = append(items, k)
+			}
+		default:
+			return nil, fmt.Errorf("%s() is defined for lists and maps, not %s", name, typeName(v))
+		}
+
+		inner := env{}
+		for k, val := range e {
+			inner[k] = val
+		}
+		var matches int
+		var results []interface{}
+		for _, item := range items {
+			inner[variable] = item
+			if name == "map" {
+				r, err := body(inner)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, r)
+				continue
+			}
+			ok, err := evalBool(body, inner)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case name == "all" && !ok:
+				return false, nil
+			case name == "exists" && ok:
+				return true, nil
+			case ok:
+				matches++
+				results = append(results, item)
+			}
+		}
+		switch name {
+		case "all":
+			return true, nil
+		case "exists":
+			return false, nil
+		case "exists_one":
+			return matches == 1, nil
+		}
+		if results == nil {
+			results = []interface{}{}
+		}
+		return results, nil
+	}
+}
+
+// arithmetic returns a program applying an arithmetic operator. "+" also
+// concatenates strings and lists.
+func arithmetic(op string, left, right program) program {
+	return func(e env) (interface{}, error) {
+		a, err := left(e)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right(e)
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			if s, ok := a.(string); ok {
+				if t, ok := b.(string); ok {
+					return s + t, nil
+				}
+			}
+			if l, ok := a.([]interface{}); ok {
+				if m, ok := b.([]interface{}); ok {
+					return append(append([]interface{}{}, l...), m...), nil
+				}
+			}
+		}
+		x, ok1 := number(a)
+		y, ok2 := number(b)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("operator %s is not defined for %s and %s", op, typeName(a), typeName(b))
+		}
+		switch op {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		}
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return x / y, nil
+		}
+		return float64(int64(x) % int64(y)), nil
+	}
+}
+
+// compare applies a comparison or membership operator.
+func compare(op string, a, b interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return equal(a, b), nil
+	case "!=":
+		return !equal(a, b), nil
+	case "in":
+		switch c := b.(type) {
+		case []interface{}:
+			for _, item := range c {
+				if equal(a, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := a.(string)
+			_, found := c[key]
+			return ok && found, nil
+		}
+		return nil, fmt.Errorf("operator in is not defined for %s", typeName(b))
+	}
+
+	var order int
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		if !ok {
+			return nil, fmt.Errorf("operator %s is not defined for %s and %s", op, typeName(a), typeName(b))
+		}
+		switch {
+		case x < y:
+			order = -1
+		case x > y:
+			order = 1
+		}
+	} else if s, ok := a.(string); ok {
+		t, ok := b.(string)
+		if !ok {
+			return nil, fmt.Errorf("operator %s is not defined for %s and %s", op, typeName(a), typeName(b))
+		}
+		order = strings.Compare(s, t)
+	} else {
+		return nil, fmt.Errorf("operator %s is not defined for %s", op, typeName(a))
+	}
+	switch op {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	}
+	return order >= 0, nil
+}
+
+// equal compares two values, numbers by value whatever their Go type.
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// evalBool evaluates a program that must yield a bool.
+func evalBool(prog program, e env) (bool, error) {
+	v, err := prog(e)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a bool, found %s", typeName(v))
+	}
+	return b, nil
+}
+
+// number converts the numeric types of decoded JSON and YAML to float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// typeName names the type of a value in CEL terms.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	if _, ok := number(v); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,168 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testConfig returns the configuration the expression tests evaluate
+// against, as decoded from YAML.
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"image": map[string]interface{}{"arch": "x86_64", "imageType": "iso"},
+		"operatingSystem": map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{"username": "root", "sshKeys": []interface{}{"ssh-ed25519 AAAA"}},
+				map[string]interface{}{"username": "bob"},
+			},
+			"time": map[string]interface{}{"timezone": "Europe/Madrid"},
+		},
+		"kubernetes": map[string]interface{}{
+			"version": "v1.30.3+rke2r1",
+			"nodes": map[string]interface{}{
+				"node3": "server",
+				"node1": "server",
+				"node2": "agent",
+			},
+		},
+	}
+}
+
+// TestCompile checks the value of expressions covering each construct.
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want interface{}
+	}{
+		// Precedence.
+		{"multiplication before addition", "1 + 2 * 3", 7.0},
+		{"parentheses", "(1 + 2) * 3", 9.0},
+		{"left associative", "10 - 4 - 3", 3.0},
+		{"unary minus", "-2 * 3", -6.0},
+		{"remainder", "7 % 4 + 1", 4.0},
+		{"comparison before and", "1 < 2 && 3 > 4", false},
+		{"and before or", "true || false && false", true},
+		{"not binds to operand", "!false && false", false},
+		{"conditional", "1 > 2 ? 'a' : 'b'", "b"},
+		{"nested conditional", "false ? 1 : true ? 2 : 3", 2.0},
+		{"short-circuit or", "true || config.missing", true},
+		{"short-circuit and", "false && config.missing", false},
+		{"string concatenation", "'a' + 'b' == 'ab'", true},
+
+		// String literals.
+		{"escaped single quote", `'it\'s'`, "it's"},
+		{"double quote in single quotes", `'say "hi"'`, `say "hi"`},
+		{"escaped double quote", `"say \"hi\""`, `say "hi"`},
+		{"escaped single quote in double quotes", `"it\'s"`, "it's"},
+		{"escaped backslash", `'a\\b'`, `a\b`},
+		{"escaped newline", `'a\nb'`, "a\nb"},
+
+		// in.
+		{"in list", "'bob' in ['root', 'bob']", true},
+		{"not in list", "'alice' in ['root', 'bob']", false},
+		{"number in list", "2 in [1, 2, 3]", true},
+		{"in map keys", "'node1' in config.kubernetes.nodes", true},
+		{"value not in map keys", "'server' in config.kubernetes.nodes", false},
+		{"in binds after addition", "1 + 1 in [2]", true},
+
+		// has.
+		{"has set field", "has(config.image.arch)", true},
+		{"has unset field", "has(config.image.baseImage)", false},
+		{"has missing parent", "has(config.network.hosts.name)", false},
+		{"has after index", "has(config.operatingSystem.users[0].sshKeys)", true},
+		{"has unset after index", "has(config.operatingSystem.users[1].sshKeys)", false},
+		{"has in condition", "!has(config.operatingSystem.time) || config.operatingSystem.time.timezone != ''", true},
+
+		// size.
+		{"size of list", "size(config.operatingSystem.users)", 2.0},
+		{"size of map", "size(config.kubernetes.nodes)", 3.0},
+		{"size of string counts runes", "size('añb')", 3.0},
+		{"size method", "config.image.arch.size()", 6.0},
+		{"size in comparison", "size(config.operatingSystem.users) > 1", true},
+
+		// Comprehensions.
+		{"all", "config.operatingSystem.users.all(u, has(u.username))", true},
+		{"all false", "config.operatingSystem.users.all(u, has(u.sshKeys))", false},
+		{"exists", "config.operatingSystem.users.exists(u, u.username == 'bob')", true},
+		{"exists false", "config.operatingSystem.users.exists(u, u.username == 'alice')", false},
+		{"exists_one", "[1, 2, 3].exists_one(n, n > 2)", true},
+		{"exists_one twice", "[1, 2, 3].exists_one(n, n > 1)", false},
+		{"filter list", "[1, 2, 3, 4].filter(n, n % 2 == 0)", []interface{}{2.0, 4.0}},
+		{"filter empty", "[1, 2].filter(n, n > 5)", []interface{}{}},
+		{"map list", "config.operatingSystem.users.map(u, u.username)", []interface{}{"root", "bob"}},
+		{"filter map keys in order", "config.kubernetes.nodes.filter(n, config.kubernetes.nodes[n] == 'server')", []interface{}{"node1", "node3"}},
+		{"map map keys in order", "config.kubernetes.nodes.map(n, n + '=' + config.kubernetes.nodes[n])", []interface{}{"node1=server", "node2=agent", "node3=server"}},
+		{"exists over map keys", "config.kubernetes.nodes.exists(n, n == 'node2')", true},
+		{"comprehension sees outer variables", "config.operatingSystem.users.all(u, config.image.arch == 'x86_64')", true},
+
+		// String methods.
+		{"startsWith", "config.kubernetes.version.startsWith('v1.30')", true},
+		{"matches", "config.kubernetes.version.matches('\\\\+rke2r[0-9]+$')", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := compile(tt.expr)
+			if err != nil {
+				t.Fatalf("compile(%q): %v", tt.expr, err)
+			}
+			got, err := prog(env{"config": testConfig()})
+			if err != nil {
+				t.Fatalf("evaluating %q: %v", tt.expr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q = %#v, want %#v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCompileErrors checks that malformed expressions are rejected when
+// compiled, and that ill-typed ones fail when evaluated.
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		// compileErr is a substring of the compile error; empty if the
+		// expression compiles.
+		compileErr string
+		// evalErr is a substring of the evaluation error.
+		evalErr string
+	}{
+		{name: "has of a variable", expr: "has(config)", compileErr: "has() takes a field selection"},
+		{name: "has of an index", expr: "has(config.operatingSystem.users[0])", compileErr: "has() takes a field selection"},
+		{name: "has of a method call", expr: "has(config.image.arch.size())", compileErr: "has() takes a field selection"},
+		{name: "has of a sum ending in a selection", expr: "has(1 + config.image.arch)", compileErr: "has() takes a field selection"},
+		{name: "has of a comparison", expr: "has(config.image.arch == 'x')", compileErr: "has() takes a field selection"},
+		{name: "has with two arguments", expr: "has(config.image, config.image.arch)", compileErr: "has() takes a field selection"},
+		{name: "size with two arguments", expr: "size([], [])", compileErr: "size() takes one argument"},
+		{name: "unterminated string", expr: "'abc", compileErr: "unterminated string"},
+		{name: "invalid escape", expr: `'a\qb'`, compileErr: "invalid string"},
+		{name: "trailing token", expr: "1 2", compileErr: "unexpected \"2\""},
+		{name: "comprehension without variable", expr: "[1].all(1, true)", compileErr: "all() takes a variable name"},
+		{name: "missing field", expr: "config.network.hosts", evalErr: "no such key: network"},
+		{name: "in on a string", expr: "'a' in 'abc'", evalErr: "operator in is not defined for string"},
+		{name: "size of a number", expr: "size(1)", evalErr: "size() is not defined for number"},
+		{name: "comprehension over a string", expr: "'abc'.all(c, true)", evalErr: "all() is defined for lists and maps, not string"},
+		{name: "non-bool condition", expr: "1 && true", evalErr: "expected a bool, found number"},
+		{name: "division by zero", expr: "1 / 0", evalErr: "division by zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := compile(tt.expr)
+			if tt.compileErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.compileErr) {
+					t.Fatalf("compile(%q): error %v, want one containing %q", tt.expr, err, tt.compileErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compile(%q): %v", tt.expr, err)
+			}
+			if _, err := prog(env{"config": testConfig()}); err == nil || !strings.Contains(err.Error(), tt.evalErr) {
+				t.Fatalf("evaluating %q: error %v, want one containing %q", tt.expr, err, tt.evalErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package policy evaluates organization policies: rules written by platform
+// teams as expressions over the configuration, such as "all images must set
+// NTP servers" or "only approved Helm repositories".
+//
+// Expressions use a subset of CEL, the Common Expression Language, in which
+// the configuration is the variable "config". The subset is implemented in
+// this package, which keeps the server free of the CEL runtime. Policies are
+// turned into cross-field rules (see tool.Rule), so their violations are
+// findings like those of the built-in rules.
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/e-minguez/eib-mcp/tool"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is an organization rule.
+type Policy struct {
+	// ID identifies the policy in findings, e.g. "ntp-required".
+	ID string `yaml:"id"`
+	// Description explains what the policy enforces.
+	Description string `yaml:"description"`
+	// Severity is "error" (the default) or "warning".
+	Severity tool.Severity `yaml:"severity"`
+	// Expression must evaluate to true for a compliant configuration, e.g.
+	// has(config.operatingSystem.time.ntp.servers).
+	Expression string `yaml:"expression"`
+	// Message is reported when the expression is false. It defaults to the
+	// description.
+	Message string `yaml:"message"`
+
+	program program
+}
+
+// File is the content of a policy file.
+type File struct {
+	// Policies are the policies, evaluated in order after the built-in rules.
+	Policies []Policy `yaml:"policies"`
+}
+
+// idPattern matches valid policy IDs, which read like built-in rule IDs.
+var idPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Load reads and compiles a policy file of the form:
+//
+//	policies:
+//	  - id: ntp-required
+//	    description: Every image must set NTP servers.
+//	    expression: size(config.operatingSystem.time.ntp.servers) > 0
+//
+// Parameters:
+//   - path: The path of the file.
+//   - builtin: The rules the policies are added to; a policy cannot reuse
+//     their IDs.
+//
+// Returns:
+//   - []tool.Rule: The policies as rules.
+//   - error: An error if the file cannot be read or a policy is invalid.
+func Load(path string, builtin []tool.Rule) ([]tool.Rule, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+
+	ids := map[string]bool{}
+	for _, r := range tool.RuleCatalog(builtin, nil) {
+		ids[r.ID] = true
+	}
+	var rules []tool.Rule
+	for i := range f.Policies {
+		p := &f.Policies[i]
+		if !idPattern.MatchString(p.ID) {
+			return nil, fmt.Errorf("invalid policy file %s: policy %d: invalid id %q (expected lower case words separated by dashes)", path, i, p.ID)
+		}
+		if ids[p.ID] {
+			return nil, fmt.Errorf("invalid policy file %s: policy %q: the id is already used", path, p.ID)
+		}
+		ids[p.ID] = true
+		switch p.Severity {
+		case "":
+			p.Severity = tool.SeverityError
+		case tool.SeverityError, tool.SeverityWarning:
+		default:
+			return nil, fmt.Errorf("invalid policy file %s: policy %q: unknown severity %q (expected %s or %s)", path, p.ID, p.Severity, tool.SeverityError, tool.SeverityWarning)
+		}
+		if p.program, err = compile(p.Expression); err != nil {
+			return nil, fmt.Errorf("invalid policy file %s: policy %q: %w", path, p.ID, err)
+		}
+		if p.Message == "" {
+			p.Message = p.Description
+		}
+		if p.Message == "" {
+			p.Message = "the configuration does not satisfy " + p.Expression
+		}
+		rules = append(rules, p.rule())
+	}
+	return rules, nil
+}
+
+// evaluate evaluates the policy against a configuration.
+//
+// Parameters:
+//   - config: The configuration.
+//
+// Returns:
+//   - bool: Whether the configuration complies.
+//   - error: An error if the expression fails, e.g. selects a field that is
+//     not set without a has() guard, or does not yield a bool.
+func (p *Policy) evaluate(config map[string]interface{}) (bool, error) {
+	return evalBool(p.program, env{"config": config})
+}
+
+// rule returns the policy as a cross-field rule. An expression that fails
+// is reported as a violation naming the failure.
+//
+// Returns:
+//   - tool.Rule: The rule.
+func (p *Policy) rule() tool.Rule {
+	description := p.Description
+	if description == "" {
+		description = "Organization policy: " + p.Expression
+	}
+	return tool.Rule{
+		ID:          p.ID,
+		Description: description,
+		Severity:    p.Severity,
+		Check: func(cfg map[string]interface{}) []string {
+			ok, err := p.evaluate(cfg)
+			if err != nil {
+				return []string{fmt.Sprintf("policy expression could not be evaluated: %v", err)}
+			}
+			if !ok {
+				return []string{p.Message}
+			}
+			return nil
+		},
+	}
+}
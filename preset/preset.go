@@ -0,0 +1,200 @@
+// Package preset manages named configuration presets.
+//
+// A preset is a partial EIB configuration (e.g. "minimal-iso" or
+// "suse-edge-3.2-ha") that callers start from, applying only their own
+// overrides on top. Presets are embedded in the binary and can be extended or
+// overridden with YAML files from a directory.
+package preset
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed presets/*.yaml
+var embedded embed.FS
+
+// Preset is a named partial configuration.
+type Preset struct {
+	// Name identifies the preset, e.g. "minimal-iso".
+	Name string `yaml:"-"`
+	// Description explains what the preset provides.
+	Description string `yaml:"description"`
+	// Config is the partial configuration.
+	Config map[string]interface{} `yaml:"config"`
+}
+
+var (
+	mu      sync.RWMutex
+	presets = mustLoadEmbedded()
+)
+
+// mustLoadEmbedded parses the embedded presets.
+//
+// It panics if an embedded preset is invalid, which can only happen if the
+// binary was built incorrectly.
+//
+// Returns:
+//   - map[string]*Preset: The embedded presets keyed by name.
+func mustLoadEmbedded() map[string]*Preset {
+	entries, err := embedded.ReadDir("presets")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read embedded presets: %v", err))
+	}
+	result := map[string]*Preset{}
+	for _, entry := range entries {
+		raw, err := embedded.ReadFile("presets/" + entry.Name())
+		if err != nil {
+			panic(fmt.Sprintf("failed to read embedded preset %s: %v", entry.Name(), err))
+		}
+		p, err := parse(entry.Name(), raw)
+		if err != nil {
+			panic(err.Error())
+		}
+		result[p.Name] = p
+	}
+	return result
+}
+
+// LoadDir adds the presets found in a directory, overriding embedded
+// presets with the same name.
+//
+// Every *.yaml or *.yml file is a preset named after the file.
+//
+// Parameters:
+//   - dir: The directory to read.
+//
+// Returns:
+//   - []string: The names of the loaded presets.
+//   - error: An error if the directory or a preset cannot be read.
+func LoadDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := map[string]*Preset{}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		p, err := parse(entry.Name(), raw)
+		if err != nil {
+			return nil, err
+		}
+		loaded[p.Name] = p
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for name, p := range loaded {
+		presets[name] = p
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// parse decodes a preset file.
+//
+// Parameters:
+//   - fileName: The file name, whose base name without extension is the preset name.
+//   - raw: The YAML content.
+//
+// Returns:
+//   - *Preset: The parsed preset.
+//   - error: An error if the content is not a valid preset.
+func parse(fileName string, raw []byte) (*Preset, error) {
+	var p Preset
+	if err := yaml.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("invalid preset %s: %w", fileName, err)
+	}
+	if p.Config == nil {
+		return nil, fmt.Errorf("invalid preset %s: missing \"config\"", fileName)
+	}
+	p.Name = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	return &p, nil
+}
+
+// Names returns the names of all available presets.
+//
+// Returns:
+//   - []string: The preset names in lexical order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns all available presets.
+//
+// Returns:
+//   - []*Preset: The presets ordered by name.
+func List() []*Preset {
+	var result []*Preset
+	for _, name := range Names() {
+		p, _ := Get(name)
+		result = append(result, p)
+	}
+	return result
+}
+
+// Get returns a copy of the named preset, safe to modify.
+//
+// Parameters:
+//   - name: The preset name.
+//
+// Returns:
+//   - *Preset: The preset.
+//   - error: An error if no preset has this name.
+func Get(name string) (*Preset, error) {
+	mu.RLock()
+	p, ok := presets[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return &Preset{
+		Name:        p.Name,
+		Description: p.Description,
+		Config:      deepCopy(p.Config).(map[string]interface{}),
+	}, nil
+}
+
+// deepCopy returns a deep copy of a decoded YAML value.
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = deepCopy(item)
+		}
+		return out
+	default:
+		return val
+	}
+}
@@ -0,0 +1,155 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompatIssue describes a configuration field that does not fit the EIB
+// release it will be built with.
+type CompatIssue struct {
+	// Path is the dotted field path, with "[]" marking list items.
+	Path string `json:"path"`
+	// Message explains the issue.
+	Message string `json:"message"`
+	// Deprecated is true if the field still exists but is deprecated, and
+	// false if the release does not support it at all.
+	Deprecated bool `json:"deprecated"`
+}
+
+// CheckCompatibility verifies that a configuration only uses fields and
+// features available in a given EIB release.
+//
+// EIB release X.Y understands definitions up to apiVersion X.Y, so each
+// field of the configuration is looked up in the schema of that apiVersion.
+// Fields only introduced later are reported with the first release that
+// supports them; fields the release marks as "deprecated" are reported as
+// deprecated. Fields unknown to every version are left to schema validation.
+//
+// Parameters:
+//   - config: The configuration to check.
+//   - release: The EIB release, e.g. "1.1".
+//
+// Returns:
+//   - []CompatIssue: The issues, ordered by path.
+//   - error: An error if the release is unknown.
+func CheckCompatibility(config map[string]interface{}, release string) ([]CompatIssue, error) {
+	if !IsSupportedVersion(release) {
+		return nil, fmt.Errorf("unknown EIB release %q (supported: %s)", release, strings.Join(SupportedVersions(), ", "))
+	}
+	target, err := versionFields(release)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []CompatIssue
+	if v, ok := config["apiVersion"].(string); ok && IsSupportedVersion(v) && CompareVersions(v, release) > 0 {
+		issues = append(issues, CompatIssue{
+			Path:    "apiVersion",
+			Message: fmt.Sprintf("apiVersion %s requires EIB %s or newer", v, v),
+		})
+	}
+
+	paths := map[string]bool{}
+	collectPaths(config, "", paths)
+	delete(paths, "apiVersion")
+
+	newer := map[string]string{}
+	for _, path := range sortedKeys(boolKeys(paths)) {
+		if summary, ok := target[path]; ok {
+			if strings.HasSuffix(summary, "deprecated") {
+				issues = append(issues, CompatIssue{
+					Path:       path,
+					Message:    fmt.Sprintf("%s is deprecated in EIB %s", path, release),
+					Deprecated: true,
+				})
+			}
+			continue
+		}
+		if parentReported(path, newer) {
+			continue
+		}
+		since, err := introducedIn(path, release)
+		if err != nil {
+			return nil, err
+		}
+		if since == "" {
+			continue
+		}
+		newer[path] = since
+		issues = append(issues, CompatIssue{
+			Path:    path,
+			Message: fmt.Sprintf("%s requires EIB %s or newer", path, since),
+		})
+	}
+	return issues, nil
+}
+
+// introducedIn finds the first version after release whose schema has a field.
+//
+// Parameters:
+//   - path: The dotted field path.
+//   - release: The release the field is missing from.
+//
+// Returns:
+//   - string: The first newer version defining the field, or empty if none does.
+//   - error: An error if a schema cannot be parsed.
+func introducedIn(path, release string) (string, error) {
+	for _, v := range SupportedVersions() {
+		if CompareVersions(v, release) <= 0 {
+			continue
+		}
+		fields, err := versionFields(v)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := fields[path]; ok {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+// parentReported reports whether an ancestor of path was already reported
+// as too new, so that only the outermost new field is flagged.
+func parentReported(path string, reported map[string]string) bool {
+	for parent := range reported {
+		if strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[]") {
+			return true
+		}
+	}
+	return false
+}
+
+// collectPaths records the dotted path of every field set in a configuration.
+//
+// Parameters:
+//   - v: The configuration value to walk.
+//   - prefix: The dotted path of v.
+//   - paths: The set receiving the paths.
+func collectPaths(v interface{}, prefix string, paths map[string]bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			paths[path] = true
+			collectPaths(item, path, paths)
+		}
+	case []interface{}:
+		for _, item := range val {
+			collectPaths(item, prefix+"[]", paths)
+		}
+	}
+}
+
+// boolKeys converts a set into a map usable with sortedKeys.
+func boolKeys(set map[string]bool) map[string]string {
+	m := make(map[string]string, len(set))
+	for k := range set {
+		m[k] = ""
+	}
+	return m
+}
@@ -0,0 +1,304 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldChange describes a field whose constraints differ between two schema versions.
+type FieldChange struct {
+	// Path is the dotted field path, with "[]" marking list items.
+	Path string `json:"path"`
+	// From summarizes the field's constraints in the older version.
+	From string `json:"from"`
+	// To summarizes the field's constraints in the newer version.
+	To string `json:"to"`
+}
+
+// DiffResult lists the field-level differences between two schema versions.
+type DiffResult struct {
+	// From is the apiVersion compared from.
+	From string `json:"from"`
+	// To is the apiVersion compared to.
+	To string `json:"to"`
+	// Added lists fields present only in To, with their constraint summary.
+	Added map[string]string `json:"added"`
+	// Removed lists fields present only in From, with their constraint summary.
+	Removed map[string]string `json:"removed"`
+	// Changed lists fields present in both versions with different constraints.
+	Changed []FieldChange `json:"changed"`
+}
+
+// Diff compares the schemas of two apiVersions field by field.
+//
+// Parameters:
+//   - from: The apiVersion to compare from.
+//   - to: The apiVersion to compare to.
+//
+// Returns:
+//   - *DiffResult: The added, removed and changed fields.
+//   - error: An error if a version is unsupported or its schema cannot be parsed.
+func Diff(from, to string) (*DiffResult, error) {
+	fromFields, err := versionFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := versionFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DiffResult{From: from, To: to, Added: map[string]string{}, Removed: map[string]string{}}
+	for path, summary := range toFields {
+		old, ok := fromFields[path]
+		if !ok {
+			result.Added[path] = summary
+			continue
+		}
+		if old != summary {
+			result.Changed = append(result.Changed, FieldChange{Path: path, From: old, To: summary})
+		}
+	}
+	for path, summary := range fromFields {
+		if _, ok := toFields[path]; !ok {
+			result.Removed[path] = summary
+		}
+	}
+	sort.Slice(result.Changed, func(i, j int) bool {
+		return result.Changed[i].Path < result.Changed[j].Path
+	})
+	return result, nil
+}
+
+// String renders the diff as a human readable report.
+func (d *DiffResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Schema changes from apiVersion %s to %s:\n", d.From, d.To)
+	if len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 {
+		b.WriteString("No field-level changes.\n")
+		return b.String()
+	}
+
+	writeFields := func(title, marker string, fields map[string]string) {
+		if len(fields) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%s:\n", title)
+		for _, path := range sortedKeys(fields) {
+			fmt.Fprintf(&b, "  %s %s (%s)\n", marker, path, fields[path])
+		}
+	}
+	writeFields("Added", "+", d.Added)
+	writeFields("Removed", "-", d.Removed)
+	if len(d.Changed) > 0 {
+		b.WriteString("Changed:\n")
+		for _, c := range d.Changed {
+			fmt.Fprintf(&b, "  ~ %s: %s -> %s\n", c.Path, c.From, c.To)
+		}
+	}
+	return b.String()
+}
+
+// versionFields flattens the schema of an apiVersion into field summaries.
+//
+// The apiVersion field itself is skipped, since its enum always differs.
+//
+// Parameters:
+//   - version: The apiVersion.
+//
+// Returns:
+//   - map[string]string: Constraint summaries keyed by dotted field path.
+//   - error: An error if the version is unsupported or its schema cannot be parsed.
+func versionFields(version string) (map[string]string, error) {
+	raw, err := GetRawSchemaForVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse schema for apiVersion %s: %w", version, err)
+	}
+
+	defs, _ := doc["$defs"].(map[string]interface{})
+	fields := map[string]string{}
+	flattenFields(defs, resolveRef(defs, map[string]interface{}{"$ref": "#/$defs/Definition"}), "", fields, map[string]bool{})
+	delete(fields, "apiVersion")
+	return fields, nil
+}
+
+// Sections lists the configuration sections of an apiVersion: the object
+// fields of the first two levels, such as "operatingSystem" and
+// "operatingSystem.time".
+//
+// Parameters:
+//   - version: The apiVersion.
+//
+// Returns:
+//   - []string: The dotted section paths, sorted.
+//   - error: An error if the version is unsupported or its schema cannot be parsed.
+func Sections(version string) ([]string, error) {
+	fields, err := versionFields(version)
+	if err != nil {
+		return nil, err
+	}
+	var sections []string
+	for path, summary := range fields {
+		if strings.Count(path, ".") < 2 && !strings.Contains(path, "[]") && strings.HasPrefix(summary, "object") {
+			sections = append(sections, path)
+		}
+	}
+	sort.Strings(sections)
+	return sections, nil
+}
+
+// Fields lists the fields of an apiVersion with a summary of their
+// constraints, such as "object" or "string, required".
+//
+// Parameters:
+//   - version: The apiVersion.
+//
+// Returns:
+//   - map[string]string: The summaries, keyed by dotted field path, with
+//     "[]" marking list items.
+//   - error: An error if the version is unsupported or its schema cannot be parsed.
+func Fields(version string) (map[string]string, error) {
+	return versionFields(version)
+}
+
+// flattenFields records a summary for every property reachable from node.
+//
+// Parameters:
+//   - defs: The schema's $defs, used to resolve references.
+//   - node: The schema object to walk.
+//   - prefix: The dotted path of node.
+//   - fields: The map receiving the summaries.
+//   - visiting: The $defs currently being walked, to stop on cycles.
+func flattenFields(defs, node map[string]interface{}, prefix string, fields map[string]string, visiting map[string]bool) {
+	props, _ := node["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if list, ok := node["required"].([]interface{}); ok {
+		for _, r := range list {
+			if name, ok := r.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	for name, p := range props {
+		prop, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		ref, _ := prop["$ref"].(string)
+		target := resolveRef(defs, prop)
+		fields[path] = summarize(target, required[name])
+
+		if ref != "" {
+			if visiting[ref] {
+				continue
+			}
+			visiting[ref] = true
+			flattenFields(defs, target, path, fields, visiting)
+			delete(visiting, ref)
+			continue
+		}
+
+		if items, ok := target["items"].(map[string]interface{}); ok {
+			itemRef, _ := items["$ref"].(string)
+			if itemRef == "" {
+				flattenFields(defs, items, path+"[]", fields, visiting)
+				continue
+			}
+			if visiting[itemRef] {
+				continue
+			}
+			visiting[itemRef] = true
+			flattenFields(defs, resolveRef(defs, items), path+"[]", fields, visiting)
+			delete(visiting, itemRef)
+		}
+	}
+}
+
+// resolveRef follows a local "#/$defs/<Name>" reference.
+//
+// Parameters:
+//   - defs: The schema's $defs.
+//   - node: The schema object that may contain a $ref.
+//
+// Returns:
+//   - map[string]interface{}: The referenced definition, or node itself if it has no resolvable reference.
+func resolveRef(defs, node map[string]interface{}) map[string]interface{} {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	target, ok := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+	if !ok {
+		return node
+	}
+	return target
+}
+
+// summarize renders the constraints of a property that matter to users.
+//
+// Parameters:
+//   - prop: The resolved property schema.
+//   - required: Whether the property is required by its parent.
+//
+// Returns:
+//   - string: A compact summary such as "string, format=semver, required".
+func summarize(prop map[string]interface{}, required bool) string {
+	var parts []string
+	if t, ok := prop["type"].(string); ok {
+		if t == "array" {
+			if items, ok := prop["items"].(map[string]interface{}); ok {
+				if it, ok := items["type"].(string); ok {
+					t = "array of " + it
+				}
+			}
+		}
+		parts = append(parts, t)
+	}
+	for _, key := range []string{"format", "pattern", "minimum", "maximum", "minLength"} {
+		if v, ok := prop[key]; ok {
+			parts = append(parts, fmt.Sprintf("%s=%v", key, v))
+		}
+	}
+	if enum, ok := prop["enum"].([]interface{}); ok {
+		values := make([]string, len(enum))
+		for i, v := range enum {
+			values[i] = fmt.Sprint(v)
+		}
+		parts = append(parts, "enum="+strings.Join(values, "|"))
+	}
+	if required {
+		parts = append(parts, "required")
+	}
+	if deprecated, _ := prop["deprecated"].(bool); deprecated {
+		parts = append(parts, "deprecated")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortedKeys returns the keys of a map in lexical order.
+//
+// Parameters:
+//   - m: The map.
+//
+// Returns:
+//   - []string: The sorted keys.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,130 @@
+package schema
+
+import (
+	"net/netip"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// semverPattern matches semantic versions with an optional "v" prefix, such
+// as "1.29.0", "v1.30.3+rke2r1" or "1.33.4-k3s1".
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// hostnameLabelPattern matches a single RFC 1123 hostname label.
+var hostnameLabelPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// formatDescriptions maps each custom format name to a human readable
+// description used in validation error messages.
+var formatDescriptions = map[string]string{
+	"hostname":  "hostname (RFC 1123, e.g. node1.example.com)",
+	"ipv4":      "IPv4 address (e.g. 192.168.1.10)",
+	"ipv6":      "IPv6 address (e.g. fd00::10)",
+	"semver":    "semantic version (e.g. 1.29.0 or v1.30.3+rke2r1)",
+	"duration":  "duration (e.g. 30s, 5m or 1h30m)",
+	"url":       "absolute URL with a scheme and host (e.g. https://charts.example.com)",
+	"unix-path": "absolute Unix path (e.g. /dev/sda)",
+}
+
+// init registers the custom format checkers with gojsonschema.
+//
+// Checkers are registered globally, so they apply to every schema compiled
+// by this package, including remote and overlay-merged ones.
+func init() {
+	gojsonschema.FormatCheckers.
+		Add("hostname", stringFormat(isHostname)).
+		Add("ipv4", stringFormat(isIPv4)).
+		Add("ipv6", stringFormat(isIPv6)).
+		Add("semver", stringFormat(semverPattern.MatchString)).
+		Add("duration", stringFormat(isDuration)).
+		Add("url", stringFormat(isURL)).
+		Add("unix-path", stringFormat(isUnixPath))
+}
+
+// FormatDescription returns a human readable description of a schema format.
+//
+// Parameters:
+//   - format: The format name, e.g. "semver".
+//
+// Returns:
+//   - string: The description, or the format name itself if it is unknown.
+func FormatDescription(format string) string {
+	if desc, ok := formatDescriptions[format]; ok {
+		return desc
+	}
+	return format
+}
+
+// Formats lists the custom string formats checked by the schemas.
+//
+// Returns:
+//   - []string: The format names, sorted.
+func Formats() []string {
+	names := make([]string, 0, len(formatDescriptions))
+	for name := range formatDescriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stringFormat adapts a string predicate to the gojsonschema.FormatChecker interface.
+//
+// Non-string values are accepted, as type checking is the schema's job.
+type stringFormat func(string) bool
+
+// IsFormat implements gojsonschema.FormatChecker.
+func (f stringFormat) IsFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return true
+	}
+	return f(s)
+}
+
+// isHostname reports whether s is a valid RFC 1123 hostname.
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if !hostnameLabelPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIPv4 reports whether s is a valid dotted-quad IPv4 address.
+func isIPv4(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is4()
+}
+
+// isIPv6 reports whether s is a valid IPv6 address without a zone.
+func isIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is6() && addr.Zone() == ""
+}
+
+// isDuration reports whether s is a valid Go duration such as "1h30m".
+func isDuration(s string) bool {
+	_, err := time.ParseDuration(s)
+	return err == nil
+}
+
+// isURL reports whether s is an absolute URL with a scheme and a host.
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// isUnixPath reports whether s is an absolute Unix path.
+func isUnixPath(s string) bool {
+	return strings.HasPrefix(s, "/") && !strings.ContainsRune(s, 0)
+}
@@ -0,0 +1,542 @@
+// Command gen regenerates an EIB configuration schema from the Go types of
+// the upstream edge-image-builder definition package.
+//
+// It parses the Go sources, vendored in a local directory or fetched from
+// URLs of raw .go files, and emits one "$defs" entry per struct reachable
+// from the root type, with the properties named by the yaml tags. The
+// descriptions, formats, required lists and other hand-written annotations of
+// an existing schema are carried over for the definitions and properties
+// that still exist, so only the structure follows upstream. For example:
+//
+//	go run ./schema/gen -src ../edge-image-builder/pkg/image -base schema/versions/1.3.json -out schema/versions/1.3.json
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// structuralKeys are the schema keys derived from the Go types; every other
+// key of a base schema is an annotation and is carried over.
+var structuralKeys = map[string]bool{
+	"type": true, "$ref": true, "items": true, "properties": true, "additionalProperties": true,
+}
+
+// basicTypes maps Go predeclared types to JSON schema types.
+var basicTypes = map[string]string{
+	"string": "string", "bool": "boolean",
+	"int": "integer", "int8": "integer", "int16": "integer", "int32": "integer", "int64": "integer",
+	"uint": "integer", "uint8": "integer", "uint16": "integer", "uint32": "integer", "uint64": "integer",
+	"float32": "number", "float64": "number",
+}
+
+// main parses the flags and runs the generator.
+//
+// It exits with status code 1 if generation fails.
+func main() {
+	src := flag.String("src", "", "comma separated directories, .go files or URLs of raw .go files of the upstream definition package")
+	root := flag.String("root", "Definition", "name of the root type")
+	base := flag.String("base", "", "existing schema whose annotations (descriptions, formats, required fields, ...) are kept")
+	out := flag.String("out", "-", "output file, or - for standard output")
+	flag.Parse()
+
+	if *src == "" {
+		fmt.Fprintln(os.Stderr, "gen: -src is required")
+		os.Exit(2)
+	}
+	if err := run(strings.Split(*src, ","), *root, *base, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the schema and writes it.
+//
+// Parameters:
+//   - sources: The directories, files or URLs of the Go sources.
+//   - root: The name of the root type.
+//   - basePath: The schema to keep annotations from, or empty.
+//   - out: The output file, or "-" for standard output.
+//
+// Returns:
+//   - error: An error if the sources cannot be read or parsed, or the root type is missing.
+func run(sources []string, root, basePath, out string) error {
+	types, err := parseSources(sources)
+	if err != nil {
+		return err
+	}
+	if _, ok := types[root]; !ok {
+		return fmt.Errorf("root type %s not found in the sources", root)
+	}
+
+	g := &generator{types: types, defs: map[string]*object{}}
+	g.define(root)
+
+	var base map[string]interface{}
+	if basePath != "" {
+		raw, err := os.ReadFile(basePath)
+		if err != nil {
+			return fmt.Errorf("failed to read base schema: %w", err)
+		}
+		if err := json.Unmarshal(raw, &base); err != nil {
+			return fmt.Errorf("failed to parse base schema: %w", err)
+		}
+	}
+	doc := g.document(root, base)
+
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	raw = append(raw, '\n')
+	for _, note := range g.notes {
+		fmt.Fprintln(os.Stderr, "gen: "+note)
+	}
+	if out == "-" {
+		_, err = os.Stdout.Write(raw)
+		return err
+	}
+	return os.WriteFile(out, raw, 0o644)
+}
+
+// parseSources parses the type declarations of Go sources.
+//
+// Parameters:
+//   - sources: Directories (every non-test .go file is read), .go files or
+//     http(s) URLs of raw .go files.
+//
+// Returns:
+//   - map[string]ast.Expr: The declared types, by name.
+//   - error: An error if a source cannot be read or parsed.
+func parseSources(sources []string) (map[string]ast.Expr, error) {
+	var files []string
+	for _, src := range sources {
+		src = strings.TrimSpace(src)
+		if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			files = append(files, src)
+			continue
+		}
+		info, err := os.Stat(src)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, src)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(src, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !strings.HasSuffix(m, "_test.go") {
+				files = append(files, m)
+			}
+		}
+	}
+
+	types := map[string]ast.Expr{}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		content, err := readSource(name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, name, content, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				types[ts.Name.Name] = ts.Type
+			}
+		}
+	}
+	return types, nil
+}
+
+// readSource reads a local file or downloads a URL.
+//
+// Parameters:
+//   - name: The file path or URL.
+//
+// Returns:
+//   - []byte: The content.
+//   - error: An error if it cannot be read.
+func readSource(name string) ([]byte, error) {
+	if !strings.HasPrefix(name, "http://") && !strings.HasPrefix(name, "https://") {
+		return os.ReadFile(name)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", name, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// object is a JSON object that keeps the order its keys were set in, so that
+// the generated schema follows the field order of the Go types.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// newObject returns an empty object.
+func newObject() *object {
+	return &object{values: map[string]interface{}{}}
+}
+
+// set sets a key, keeping its position if it already exists.
+func (o *object) set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler, emitting the keys in order.
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// generator converts Go type declarations into schema definitions.
+type generator struct {
+	// types are the declared types, by name.
+	types map[string]ast.Expr
+	// defs are the generated definitions, by type name.
+	defs map[string]*object
+	// notes are reported on standard error, e.g. for types that cannot be mapped.
+	notes []string
+}
+
+// define generates the definition of a struct type and of every struct it
+// references.
+//
+// Parameters:
+//   - name: The type name.
+func (g *generator) define(name string) {
+	if _, done := g.defs[name]; done {
+		return
+	}
+	st, ok := g.types[name].(*ast.StructType)
+	if !ok {
+		return
+	}
+	def := newObject()
+	g.defs[name] = def
+	props := newObject()
+	g.addFields(props, st)
+	def.set("properties", props)
+	def.set("additionalProperties", false)
+	def.set("type", "object")
+}
+
+// addFields adds the properties of the fields of a struct, inlining
+// embedded structs and fields tagged yaml:",inline".
+//
+// Parameters:
+//   - props: The properties object receiving the fields.
+//   - st: The struct type.
+func (g *generator) addFields(props *object, st *ast.StructType) {
+	for _, field := range st.Fields.List {
+		name, inline, skip := yamlName(field)
+		if skip {
+			continue
+		}
+		if inline {
+			if embedded, ok := g.types[typeName(field.Type)].(*ast.StructType); ok {
+				g.addFields(props, embedded)
+			}
+			continue
+		}
+		props.set(name, g.schemaOf(field.Type))
+	}
+}
+
+// schemaOf returns the schema of a Go type expression.
+//
+// Parameters:
+//   - expr: The type expression.
+//
+// Returns:
+//   - *object: The schema.
+func (g *generator) schemaOf(expr ast.Expr) *object {
+	s := newObject()
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return g.schemaOf(t.X)
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && id.Name == "byte" {
+			s.set("type", "string")
+			return s
+		}
+		s.set("items", g.schemaOf(t.Elt))
+		s.set("type", "array")
+	case *ast.MapType:
+		s.set("additionalProperties", g.schemaOf(t.Value))
+		s.set("type", "object")
+	case *ast.InterfaceType:
+		// Any value.
+	case *ast.Ident:
+		if jsonType, ok := basicTypes[t.Name]; ok {
+			s.set("type", jsonType)
+			return s
+		}
+		switch decl := g.types[t.Name].(type) {
+		case *ast.StructType:
+			g.define(t.Name)
+			s.set("$ref", "#/$defs/"+t.Name)
+		case nil:
+			g.notes = append(g.notes, fmt.Sprintf("type %s is not declared in the sources; it accepts any value", t.Name))
+		default:
+			return g.schemaOf(decl)
+		}
+	default:
+		g.notes = append(g.notes, fmt.Sprintf("type %s is not declared in the sources; it accepts any value", typeName(expr)))
+	}
+	return s
+}
+
+// document assembles the schema document, carrying over the annotations of
+// a base schema.
+//
+// Parameters:
+//   - root: The name of the root type.
+//   - base: The decoded base schema, or nil.
+//
+// Returns:
+//   - *object: The schema document.
+func (g *generator) document(root string, base map[string]interface{}) *object {
+	doc := newObject()
+	doc.set("$schema", stringOr(base["$schema"], "http://json-schema.org/draft-07/schema#"))
+	doc.set("$id", stringOr(base["$id"], "https://github.com/suse-edge/edge-image-builder/pkg/image/definition"))
+
+	baseDefs, _ := base["$defs"].(map[string]interface{})
+	names := make([]string, 0, len(g.defs))
+	for name := range g.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	defs := newObject()
+	for _, name := range names {
+		def := g.defs[name]
+		if baseDef, ok := baseDefs[name].(map[string]interface{}); ok {
+			g.annotate(name, def, baseDef)
+		} else if base != nil {
+			g.notes = append(g.notes, fmt.Sprintf("new definition %s", name))
+		}
+		defs.set(name, def)
+	}
+	for name := range baseDefs {
+		if _, ok := g.defs[name]; !ok {
+			g.notes = append(g.notes, fmt.Sprintf("definition %s no longer exists upstream and was dropped", name))
+		}
+	}
+	doc.set("$defs", defs)
+
+	ref := newObject()
+	ref.set("$ref", "#/$defs/"+root)
+	doc.set("allOf", []interface{}{ref})
+	doc.set("type", "object")
+	doc.set("title", stringOr(base["title"], "Edge Image Builder Configuration"))
+	doc.set("description", stringOr(base["description"], "Schema for the configuration file used by the SUSE Edge Image Builder."))
+	return doc
+}
+
+// annotate copies the annotations of a base definition onto a generated
+// one, dropping those about properties that no longer exist.
+//
+// Parameters:
+//   - name: The definition name, for notes.
+//   - def: The generated definition.
+//   - baseDef: The base definition.
+func (g *generator) annotate(name string, def *object, baseDef map[string]interface{}) {
+	props := def.values["properties"].(*object)
+	baseProps, _ := baseDef["properties"].(map[string]interface{})
+	for _, prop := range props.keys {
+		baseProp, ok := baseProps[prop].(map[string]interface{})
+		if !ok {
+			g.notes = append(g.notes, fmt.Sprintf("new property %s.%s", name, prop))
+			continue
+		}
+		generated := props.values[prop].(*object)
+		if !reflect.DeepEqual(baseProp["type"], generated.values["type"]) && baseProp["type"] != nil {
+			g.notes = append(g.notes, fmt.Sprintf("property %s.%s changed type from %v to %v", name, prop, baseProp["type"], generated.values["type"]))
+		}
+		carryAnnotations(generated, baseProp)
+	}
+	for prop := range baseProps {
+		if _, ok := props.values[prop]; !ok {
+			g.notes = append(g.notes, fmt.Sprintf("property %s.%s no longer exists upstream and was dropped", name, prop))
+		}
+	}
+
+	for _, key := range annotationKeys(baseDef) {
+		value := baseDef[key]
+		if key == "required" {
+			value = existing(value, props)
+			if value == nil {
+				continue
+			}
+		}
+		def.set(key, value)
+	}
+}
+
+// carryAnnotations copies the annotations of a base property schema onto a
+// generated one, including those of its list items and map values.
+//
+// Parameters:
+//   - generated: The generated schema.
+//   - base: The base schema.
+func carryAnnotations(generated *object, base map[string]interface{}) {
+	for _, key := range annotationKeys(base) {
+		generated.set(key, base[key])
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		sub, ok := generated.values[key].(*object)
+		baseSub, baseOK := base[key].(map[string]interface{})
+		if ok && baseOK {
+			carryAnnotations(sub, baseSub)
+		}
+	}
+}
+
+// annotationKeys returns the non-structural keys of a schema, sorted.
+func annotationKeys(s map[string]interface{}) []string {
+	var keys []string
+	for key := range s {
+		if !structuralKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// existing filters a required list down to the properties that exist.
+//
+// Parameters:
+//   - required: The base required list.
+//   - props: The generated properties.
+//
+// Returns:
+//   - interface{}: The filtered list, or nil if it is empty.
+func existing(required interface{}, props *object) interface{} {
+	list, _ := required.([]interface{})
+	var kept []interface{}
+	for _, r := range list {
+		if name, ok := r.(string); ok && props.values[name] != nil {
+			kept = append(kept, name)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// yamlName returns the property name of a struct field from its yaml tag,
+// following the gopkg.in/yaml rules.
+//
+// Parameters:
+//   - field: The struct field.
+//
+// Returns:
+//   - string: The property name.
+//   - bool: Whether the field is inlined.
+//   - bool: Whether the field is skipped (unexported or tagged "-").
+func yamlName(field *ast.Field) (string, bool, bool) {
+	var tag string
+	if field.Tag != nil {
+		if unquoted, err := strconv.Unquote(field.Tag.Value); err == nil {
+			tag = reflect.StructTag(unquoted).Get("yaml")
+		}
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	inline := strings.Contains(","+options+",", ",inline,")
+	if len(field.Names) == 0 {
+		// Embedded fields are inlined unless they have a name.
+		return name, inline || name == "", false
+	}
+	if !ast.IsExported(field.Names[0].Name) {
+		return "", false, true
+	}
+	if name == "" {
+		name = strings.ToLower(field.Names[0].Name)
+	}
+	return name, inline, false
+}
+
+// typeName renders a type expression for messages and lookups.
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return typeName(t.X)
+	case *ast.SelectorExpr:
+		return typeName(t.X) + "." + t.Sel.Name
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+// stringOr returns v if it is a non-empty string, and fallback otherwise.
+func stringOr(v interface{}, fallback string) string {
+	if s, ok := v.(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
@@ -1,29 +1,298 @@
 // Package schema handles loading and access to the EIB configuration schema.
 //
-// It provides functionality to load the embedded JSON schema and retrieve it
-// for validation purposes.
+// It embeds one JSON schema per supported EIB definition apiVersion and
+// provides functionality to retrieve them, raw or compiled, for validation
+// purposes.
 package schema
 
 import (
-	_ "embed"
+	"embed"
+	"encoding/json"
 	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/xeipuuv/gojsonschema"
 )
 
-//go:embed schema.json
-var schemaJSON []byte
+//go:embed versions/*.json
+var versionFiles embed.FS
 
-// LoadSchema loads the EIB configuration schema from the embedded file.
+var (
+	// mu guards the variables below, which can change at runtime when remote
+	// schemas are registered or an overlay is applied.
+	mu sync.RWMutex
+	// generation is incremented every time versions or overlay change, so
+	// that schemas compiled from stale inputs are not cached.
+	generation int
+	// overlay is the operator-supplied schema merged into every version.
+	overlay map[string]interface{}
+	// versions holds the raw schema bytes keyed by apiVersion.
+	versions = mustReadVersions()
+	// supportedVersions lists the keys of versions in ascending order.
+	supportedVersions = sortedVersions(versions)
+	// compiled caches compiled schemas keyed by apiVersion.
+	compiled = map[string]*gojsonschema.Schema{}
+	// advertisedSchema caches the result of GetRawSchema.
+	advertisedSchema []byte
+)
+
+// mustReadVersions reads every embedded versions/<apiVersion>.json file.
 //
-// It parses the embedded JSON schema and returns a compiled schema object
-// ready for validation.
+// It panics if the embedded directory cannot be read, which can only happen
+// if the binary was built incorrectly.
+//
+// Returns:
+//   - map[string][]byte: The raw schema bytes keyed by apiVersion.
+func mustReadVersions() map[string][]byte {
+	entries, err := versionFiles.ReadDir("versions")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read embedded schemas: %v", err))
+	}
+
+	result := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		raw, err := versionFiles.ReadFile(path.Join("versions", name))
+		if err != nil {
+			panic(fmt.Sprintf("failed to read embedded schema %s: %v", name, err))
+		}
+		result[strings.TrimSuffix(name, ".json")] = raw
+	}
+	return result
+}
+
+// sortedVersions returns the keys of the given map in ascending version order.
+//
+// Parameters:
+//   - m: The map of raw schemas keyed by apiVersion.
+//
+// Returns:
+//   - []string: The sorted apiVersions.
+func sortedVersions(m map[string][]byte) []string {
+	result := make([]string, 0, len(m))
+	for v := range m {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return CompareVersions(result[i], result[j]) < 0
+	})
+	return result
+}
+
+// CompareVersions compares two dotted numeric versions such as "1.2" and "1.10".
+//
+// Non-numeric components compare as zero.
+//
+// Parameters:
+//   - a: The first version.
+//   - b: The second version.
+//
+// Returns:
+//   - int: -1 if a < b, 0 if a == b, and 1 if a > b.
+func CompareVersions(a, b string) int {
+	pa := strings.Split(a, ".")
+	pb := strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// SupportedVersions returns the apiVersions for which a schema is available.
+//
+// Returns:
+//   - []string: The supported apiVersions in ascending order.
+func SupportedVersions() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]string(nil), supportedVersions...)
+}
+
+// LatestVersion returns the newest apiVersion for which a schema is available.
+//
+// Returns:
+//   - string: The latest supported apiVersion.
+func LatestVersion() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return supportedVersions[len(supportedVersions)-1]
+}
+
+// IsSupportedVersion reports whether a schema is available for the given apiVersion.
+//
+// Parameters:
+//   - version: The apiVersion to check.
+//
+// Returns:
+//   - bool: True if the version is supported.
+func IsSupportedVersion(version string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := versions[version]
+	return ok
+}
+
+// LoadSchema loads the schema for the latest supported apiVersion.
+//
+// It is equivalent to calling LoadSchemaForVersion with LatestVersion.
 //
 // Returns:
 //   - *gojsonschema.Schema: The compiled JSON schema.
 //   - error: An error if the schema cannot be parsed.
 func LoadSchema() (*gojsonschema.Schema, error) {
-	loader := gojsonschema.NewBytesLoader(schemaJSON)
+	return LoadSchemaForVersion(LatestVersion())
+}
+
+// LoadSchemaForVersion loads the schema for the given apiVersion.
+//
+// Each schema is compiled on first use only; subsequent calls return the
+// cached compiled schema, so callers can invoke it on every request without
+// paying the compilation cost.
+//
+// Parameters:
+//   - version: The EIB definition apiVersion, e.g. "1.2".
+//
+// Returns:
+//   - *gojsonschema.Schema: The compiled JSON schema.
+//   - error: An error if the version is unsupported or the schema cannot be parsed.
+func LoadSchemaForVersion(version string) (*gojsonschema.Schema, error) {
+	return loadSchema(version, false)
+}
+
+// LoadPermissiveSchemaForVersion loads a permissive variant of the schema for
+// the given apiVersion.
+//
+// The permissive variant is identical to the regular schema except that it
+// does not forbid additional properties, so fields unknown to this schema
+// (e.g. from unreleased EIB versions) are accepted. It is cached like the
+// regular schema.
+//
+// Parameters:
+//   - version: The EIB definition apiVersion, e.g. "1.2".
+//
+// Returns:
+//   - *gojsonschema.Schema: The compiled permissive JSON schema.
+//   - error: An error if the version is unsupported or the schema cannot be parsed.
+func LoadPermissiveSchemaForVersion(version string) (*gojsonschema.Schema, error) {
+	return loadSchema(version, true)
+}
+
+// loadSchema compiles, caches and returns the schema for an apiVersion.
+//
+// Parameters:
+//   - version: The EIB definition apiVersion.
+//   - permissive: Whether to drop "additionalProperties": false constraints.
+//
+// Returns:
+//   - *gojsonschema.Schema: The compiled JSON schema.
+//   - error: An error if the version is unsupported or the schema cannot be parsed.
+func loadSchema(version string, permissive bool) (*gojsonschema.Schema, error) {
+	key := version
+	if permissive {
+		key += "/permissive"
+	}
+
+	mu.RLock()
+	s, ok := compiled[key]
+	raw, known := versions[version]
+	gen := generation
+	ov := overlay
+	mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+	if !known {
+		return nil, unsupportedVersionError(version)
+	}
+
+	raw = applyOverlay(raw, ov)
+	if permissive {
+		raw = allowAdditionalProperties(raw)
+	}
+	s, err := compileSchema(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Only cache the result if nothing was replaced while compiling.
+	if gen == generation {
+		compiled[key] = s
+	}
+	return s, nil
+}
+
+// unsupportedVersionError builds the error returned for unknown apiVersions.
+//
+// Parameters:
+//   - version: The requested apiVersion.
+//
+// Returns:
+//   - error: An error listing the supported versions.
+func unsupportedVersionError(version string) error {
+	return fmt.Errorf("unsupported apiVersion %q (supported: %s)", version, strings.Join(SupportedVersions(), ", "))
+}
+
+// register adds or replaces the raw schema for an apiVersion.
+//
+// The schema is compiled before being registered, so an invalid schema never
+// replaces a working one.
+//
+// Parameters:
+//   - version: The apiVersion the schema describes.
+//   - raw: The raw JSON schema bytes.
+//
+// Returns:
+//   - error: An error if the schema cannot be compiled.
+func register(version string, raw []byte) error {
+	if _, err := compileSchema(raw); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	versions[version] = raw
+	supportedVersions = sortedVersions(versions)
+	invalidateLocked()
+	return nil
+}
+
+// invalidateLocked drops every cached derived schema.
+//
+// It must be called with mu held for writing.
+func invalidateLocked() {
+	generation++
+	compiled = map[string]*gojsonschema.Schema{}
+	advertisedSchema = nil
+}
+
+// compileSchema parses and compiles a raw JSON schema.
+//
+// Parameters:
+//   - raw: The raw JSON schema bytes.
+//
+// Returns:
+//   - *gojsonschema.Schema: The compiled JSON schema.
+//   - error: An error if the schema cannot be parsed.
+func compileSchema(raw []byte) (*gojsonschema.Schema, error) {
+	loader := gojsonschema.NewBytesLoader(raw)
 	schema, err := gojsonschema.NewSchema(loader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load schema: %w", err)
@@ -32,7 +301,11 @@ func LoadSchema() (*gojsonschema.Schema, error) {
 	return schema, nil
 }
 
-// GetRawSchema returns the raw JSON bytes of the schema.
+// GetRawSchema returns the raw JSON bytes of the advertised schema.
+//
+// The advertised schema is the latest version's schema with its apiVersion
+// enum widened to every supported version, so that clients see a single
+// schema accepting any definition this server can validate.
 //
 // This is useful for clients that need to inspect the schema directly
 // or embed it in other tools.
@@ -40,5 +313,122 @@ func LoadSchema() (*gojsonschema.Schema, error) {
 // Returns:
 //   - []byte: The raw JSON schema bytes.
 func GetRawSchema() []byte {
-	return schemaJSON
+	mu.RLock()
+	cached := advertisedSchema
+	mu.RUnlock()
+	if cached != nil {
+		return cached
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if advertisedSchema == nil {
+		latest := supportedVersions[len(supportedVersions)-1]
+		advertisedSchema = widenAPIVersionEnum(applyOverlay(versions[latest], overlay), supportedVersions)
+	}
+	return advertisedSchema
+}
+
+// GetRawSchemaForVersion returns the raw JSON bytes of the schema for the given apiVersion.
+//
+// Parameters:
+//   - version: The EIB definition apiVersion.
+//
+// Returns:
+//   - []byte: The raw JSON schema bytes.
+//   - error: An error if the version is unsupported.
+func GetRawSchemaForVersion(version string) ([]byte, error) {
+	mu.RLock()
+	raw, ok := versions[version]
+	ov := overlay
+	mu.RUnlock()
+	if !ok {
+		return nil, unsupportedVersionError(version)
+	}
+	return applyOverlay(raw, ov), nil
+}
+
+// widenAPIVersionEnum rewrites the apiVersion enum of a raw schema.
+//
+// If the schema does not have the expected shape it is returned unchanged.
+//
+// Parameters:
+//   - raw: The raw JSON schema bytes.
+//   - enum: The apiVersion values to allow.
+//
+// Returns:
+//   - []byte: The rewritten raw JSON schema bytes.
+func widenAPIVersionEnum(raw []byte, enum []string) []byte {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return raw
+	}
+
+	apiVersion := apiVersionProperty(doc)
+	if apiVersion == nil {
+		return raw
+	}
+	apiVersion["enum"] = enum
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// apiVersionProperty returns the apiVersion property definition of a parsed schema.
+//
+// Parameters:
+//   - doc: The parsed JSON schema.
+//
+// Returns:
+//   - map[string]interface{}: The apiVersion property, or nil if the schema does not have the expected shape.
+func apiVersionProperty(doc map[string]interface{}) map[string]interface{} {
+	defs, _ := doc["$defs"].(map[string]interface{})
+	def, _ := defs["Definition"].(map[string]interface{})
+	props, _ := def["properties"].(map[string]interface{})
+	apiVersion, _ := props["apiVersion"].(map[string]interface{})
+	return apiVersion
+}
+
+// allowAdditionalProperties removes every "additionalProperties": false
+// constraint from a raw schema.
+//
+// If the schema cannot be parsed it is returned unchanged.
+//
+// Parameters:
+//   - raw: The raw JSON schema bytes.
+//
+// Returns:
+//   - []byte: The relaxed raw JSON schema bytes.
+func allowAdditionalProperties(raw []byte) []byte {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return raw
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if ap, ok := val["additionalProperties"].(bool); ok && !ap {
+				delete(val, "additionalProperties")
+			}
+			for _, item := range val {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		}
+	}
+	walk(doc)
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return raw
+	}
+	return out
 }
@@ -0,0 +1,22 @@
+package schema
+
+import "testing"
+
+// BenchmarkCompileSchema measures the cost of compiling the embedded schema
+// from scratch, which is what every generate_config call used to pay.
+func BenchmarkCompileSchema(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := compileSchema(versions[LatestVersion()]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLoadSchema measures the cost of retrieving the cached schema.
+func BenchmarkLoadSchema(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadSchema(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
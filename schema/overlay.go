@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SetOverlay installs an operator-supplied overlay schema.
+//
+// The overlay is a JSON schema fragment that is deep-merged into the schema of
+// every supported apiVersion at load time, so company policies (extra required
+// fields, restricted enums, additional constraints) are enforced through the
+// same validation path as the EIB schema itself. Merge rules:
+//   - objects are merged key by key, recursively;
+//   - "required" arrays are unioned;
+//   - "allOf" arrays are appended to;
+//   - any other value in the overlay replaces the base value.
+//
+// Passing nil or empty raw bytes removes the overlay.
+//
+// Parameters:
+//   - raw: The raw JSON overlay bytes.
+//
+// Returns:
+//   - error: An error if the overlay is not a JSON object or produces an invalid schema.
+func SetOverlay(raw []byte) error {
+	var doc map[string]interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return fmt.Errorf("overlay is not a JSON object: %w", err)
+		}
+	}
+
+	// Make sure the overlay produces valid schemas before installing it.
+	for _, version := range SupportedVersions() {
+		mu.RLock()
+		base := versions[version]
+		mu.RUnlock()
+		if _, err := compileSchema(applyOverlay(base, doc)); err != nil {
+			return fmt.Errorf("overlay produces an invalid schema for apiVersion %s: %w", version, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	overlay = doc
+	invalidateLocked()
+	return nil
+}
+
+// applyOverlay merges an overlay into a raw schema.
+//
+// If there is no overlay, or the base cannot be parsed, the base is returned unchanged.
+//
+// Parameters:
+//   - raw: The raw JSON schema bytes.
+//   - ov: The parsed overlay, or nil.
+//
+// Returns:
+//   - []byte: The merged raw JSON schema bytes.
+func applyOverlay(raw []byte, ov map[string]interface{}) []byte {
+	if len(ov) == 0 {
+		return raw
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return raw
+	}
+
+	merged, err := json.MarshalIndent(mergeSchema(doc, ov), "", "  ")
+	if err != nil {
+		return raw
+	}
+	return merged
+}
+
+// schemaMaps lists the keywords whose value maps names to subschemas, so
+// that the keys directly inside them are names rather than keywords.
+var schemaMaps = map[string]bool{
+	"properties":        true,
+	"patternProperties": true,
+	"definitions":       true,
+	"$defs":             true,
+	"dependentSchemas":  true,
+}
+
+// mergeSchema deep-merges an overlay object into a base object.
+//
+// The overlay is never modified; the base is modified in place and returned.
+//
+// Parameters:
+//   - base: The base schema object.
+//   - ov: The overlay schema object.
+//
+// Returns:
+//   - map[string]interface{}: The merged schema object.
+func mergeSchema(base, ov map[string]interface{}) map[string]interface{} {
+	return mergeObject(base, ov, true)
+}
+
+// mergeObject deep-merges an overlay object into a base object. The
+// "required" and "allOf" lists of a schema are merged as unions rather than
+// replaced, but not properties of those names, e.g. a "required" field
+// under "properties".
+//
+// Parameters:
+//   - base: The base object.
+//   - ov: The overlay object.
+//   - schema: Whether the objects are schemas, whose keys are keywords,
+//     rather than the value of a keyword such as "properties".
+//
+// Returns:
+//   - map[string]interface{}: The merged object.
+func mergeObject(base, ov map[string]interface{}, schema bool) map[string]interface{} {
+	for key, ovVal := range ov {
+		baseVal, exists := base[key]
+		if !exists {
+			base[key] = deepCopy(ovVal)
+			continue
+		}
+
+		switch {
+		case schema && key == "required":
+			base[key] = unionStrings(baseVal, ovVal)
+			continue
+		case schema && key == "allOf":
+			if baseList, ok := baseVal.([]interface{}); ok {
+				if ovList, ok := ovVal.([]interface{}); ok {
+					base[key] = append(baseList, deepCopy(ovList).([]interface{})...)
+					continue
+				}
+			}
+		}
+
+		baseMap, baseIsMap := baseVal.(map[string]interface{})
+		ovMap, ovIsMap := ovVal.(map[string]interface{})
+		if baseIsMap && ovIsMap {
+			base[key] = mergeObject(baseMap, ovMap, !schema || !schemaMaps[key])
+			continue
+		}
+		base[key] = deepCopy(ovVal)
+	}
+	return base
+}
+
+// unionStrings returns the union of two JSON string arrays, preserving order.
+//
+// Parameters:
+//   - a: The first array.
+//   - b: The second array.
+//
+// Returns:
+//   - []interface{}: The union of both arrays.
+func unionStrings(a, b interface{}) []interface{} {
+	var result []interface{}
+	seen := map[interface{}]bool{}
+	for _, list := range []interface{}{a, b} {
+		items, _ := list.([]interface{})
+		for _, item := range items {
+			if !seen[item] {
+				seen[item] = true
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
+
+// deepCopy returns a deep copy of a decoded JSON value.
+//
+// Parameters:
+//   - v: The value to copy.
+//
+// Returns:
+//   - interface{}: The copy.
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = deepCopy(item)
+		}
+		return out
+	default:
+		return val
+	}
+}
@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// decodeSchema decodes a schema object of a test.
+func decodeSchema(t *testing.T, doc string) map[string]interface{} {
+	t.Helper()
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// TestMergeSchemaRequired checks that required lists are unioned, and that
+// properties named required are merged like any other property.
+func TestMergeSchemaRequired(t *testing.T) {
+	base := decodeSchema(t, `{
+		"required": ["image"],
+		"properties": {
+			"required": {"type": "array", "items": {"type": "string"}},
+			"image": {"type": "object", "required": ["arch"]}
+		},
+		"definitions": {"required": {"enum": ["a"]}}
+	}`)
+	ov := decodeSchema(t, `{
+		"required": ["apiVersion"],
+		"properties": {
+			"required": {"items": {"type": "integer"}},
+			"image": {"required": ["imageType"]}
+		},
+		"definitions": {"required": {"enum": ["b"]}}
+	}`)
+	want := decodeSchema(t, `{
+		"required": ["image", "apiVersion"],
+		"properties": {
+			"required": {"type": "array", "items": {"type": "integer"}},
+			"image": {"type": "object", "required": ["arch", "imageType"]}
+		},
+		"definitions": {"required": {"enum": ["b"]}}
+	}`)
+	if got := mergeSchema(base, ov); !reflect.DeepEqual(got, want) {
+		out, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("merged schema:\n%s", out)
+	}
+}
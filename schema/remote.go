@@ -0,0 +1,279 @@
+package schema
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxRemoteSchemaSize caps the size of a downloaded schema or checksum file.
+const maxRemoteSchemaSize = 4 << 20
+
+// versionPattern matches the dotted numeric apiVersions accepted from remote
+// schemas, which are also used as cache file names.
+var versionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+// RemoteOptions configures fetching a schema from a remote location.
+type RemoteOptions struct {
+	// URL is the location of the JSON schema to download.
+	URL string
+	// SHA256 is the expected hex-encoded SHA-256 of the schema. If empty,
+	// the checksum is fetched from ChecksumURL.
+	SHA256 string
+	// ChecksumURL is the location of the checksum of the schema, used when
+	// SHA256 is empty. It should be served from another origin than URL:
+	// a checksum from the same server only detects corruption, since whoever
+	// can replace the schema there can replace its checksum too.
+	ChecksumURL string
+	// CacheDir is the directory where verified schemas are stored.
+	CacheDir string
+	// Client is the HTTP client to use. If nil, a client with a 30s timeout is used.
+	Client *http.Client
+}
+
+// DefaultCacheDir returns the default on-disk cache directory for remote schemas.
+//
+// Returns:
+//   - string: The cache directory path.
+//   - error: An error if the user cache directory cannot be determined.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "eib-mcp", "schemas"), nil
+}
+
+// Refresh downloads a schema, verifies its checksum, stores it in the cache
+// directory and registers it for the apiVersion it describes.
+//
+// A schema that fails verification or compilation is never cached nor
+// registered, so the embedded (or previously cached) schema stays in use.
+// The checksum is never derived from URL: it must be pinned with SHA256 or
+// fetched from ChecksumURL.
+//
+// Parameters:
+//   - ctx: The context controlling the download.
+//   - opts: The remote source and cache configuration.
+//
+// Returns:
+//   - string: The apiVersion the downloaded schema was registered for.
+//   - error: An error if no checksum is configured, or downloading,
+//     verification or registration fails.
+func Refresh(ctx context.Context, opts RemoteOptions) (string, error) {
+	if opts.URL == "" {
+		return "", fmt.Errorf("no schema URL configured")
+	}
+	if opts.SHA256 == "" && opts.ChecksumURL == "" {
+		return "", fmt.Errorf("no schema checksum configured; pin the SHA-256 of the schema or give the URL of its checksum")
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	raw, err := fetch(ctx, client, opts.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download schema: %w", err)
+	}
+
+	expected := opts.SHA256
+	if expected == "" {
+		sum, err := fetch(ctx, client, opts.ChecksumURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to download schema checksum: %w", err)
+		}
+		expected = parseChecksum(sum)
+	}
+	if err := verifyChecksum(raw, expected); err != nil {
+		return "", err
+	}
+
+	version, err := schemaVersion(raw)
+	if err != nil {
+		return "", err
+	}
+	if err := register(version, raw); err != nil {
+		return "", err
+	}
+
+	if opts.CacheDir != "" {
+		if err := writeCache(opts.CacheDir, version, raw); err != nil {
+			return version, fmt.Errorf("schema registered but not cached: %w", err)
+		}
+	}
+	return version, nil
+}
+
+// LoadCache registers every verified schema found in the cache directory.
+//
+// Each cached schema is stored as <apiVersion>.json next to a
+// <apiVersion>.json.sha256 file; entries whose checksum does not match are
+// skipped. A missing cache directory is not an error.
+//
+// Parameters:
+//   - cacheDir: The cache directory to read.
+//
+// Returns:
+//   - []string: The apiVersions that were registered from the cache.
+//   - error: An error if the directory cannot be read or a cached schema is invalid.
+func LoadCache(cacheDir string) ([]string, error) {
+	entries, err := os.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []string
+	for _, entry := range entries {
+		name := entry.Name()
+		version := strings.TrimSuffix(name, ".json")
+		if entry.IsDir() || filepath.Ext(name) != ".json" || !versionPattern.MatchString(version) {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(cacheDir, name))
+		if err != nil {
+			return loaded, err
+		}
+		sum, err := os.ReadFile(filepath.Join(cacheDir, name+".sha256"))
+		if err != nil {
+			continue
+		}
+		if err := verifyChecksum(raw, parseChecksum(sum)); err != nil {
+			continue
+		}
+		if err := register(version, raw); err != nil {
+			return loaded, fmt.Errorf("cached schema %s: %w", name, err)
+		}
+		loaded = append(loaded, version)
+	}
+	return loaded, nil
+}
+
+// fetch performs a size-limited HTTP GET.
+//
+// Parameters:
+//   - ctx: The context controlling the request.
+//   - client: The HTTP client to use.
+//   - url: The URL to fetch.
+//
+// Returns:
+//   - []byte: The response body.
+//   - error: An error if the request fails or does not return 200 OK.
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxRemoteSchemaSize))
+}
+
+// parseChecksum extracts the hex digest from sha256sum-style content.
+//
+// Parameters:
+//   - content: Either a bare digest or a "<digest>  <filename>" line.
+//
+// Returns:
+//   - string: The digest.
+func parseChecksum(content []byte) string {
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// verifyChecksum checks that raw hashes to the expected SHA-256 digest.
+//
+// Parameters:
+//   - raw: The content to verify.
+//   - expected: The expected hex-encoded digest.
+//
+// Returns:
+//   - error: An error if the digest is missing or does not match.
+func verifyChecksum(raw []byte, expected string) error {
+	if expected == "" {
+		return fmt.Errorf("no checksum available to verify schema")
+	}
+	sum := sha256.Sum256(raw)
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("schema checksum mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+// schemaVersion determines the apiVersion a raw schema describes.
+//
+// It reads the apiVersion enum of the Definition and picks the highest value,
+// so that a schema accepting several versions is registered as the newest one.
+//
+// Parameters:
+//   - raw: The raw JSON schema bytes.
+//
+// Returns:
+//   - string: The apiVersion.
+//   - error: An error if the schema does not declare an apiVersion enum.
+func schemaVersion(raw []byte) (string, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return "", fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+
+	apiVersion := apiVersionProperty(doc)
+	enum, _ := apiVersion["enum"].([]interface{})
+	var latest string
+	for _, v := range enum {
+		if s, ok := v.(string); ok && (latest == "" || CompareVersions(s, latest) > 0) {
+			latest = s
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("schema does not declare any apiVersion")
+	}
+	if !versionPattern.MatchString(latest) {
+		return "", fmt.Errorf("schema declares invalid apiVersion %q", latest)
+	}
+	return latest, nil
+}
+
+// writeCache stores a verified schema and its checksum in the cache directory.
+//
+// Parameters:
+//   - cacheDir: The cache directory.
+//   - version: The apiVersion the schema describes.
+//   - raw: The raw JSON schema bytes.
+//
+// Returns:
+//   - error: An error if the files cannot be written.
+func writeCache(cacheDir, version string, raw []byte) error {
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return err
+	}
+	name := filepath.Join(cacheDir, version+".json")
+	if err := os.WriteFile(name, raw, 0o644); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(raw)
+	return os.WriteFile(name+".sha256", []byte(hex.EncodeToString(sum[:])+"  "+version+".json\n"), 0o644)
+}
@@ -0,0 +1,274 @@
+// Command structgen generates typed Go structs from the EIB configuration schema.
+//
+// It reads the newest schema found in a directory of <apiVersion>.json files,
+// emits one struct per object definition in "$defs", and writes a gofmt'ed Go
+// source file. It is meant to be run through go:generate, e.g.:
+//
+//	//go:generate go run ../schema/structgen -schema-dir ../schema/versions -package definition -out types_gen.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/e-minguez/eib-mcp/schema"
+)
+
+// initialisms lists the lowercase words rendered fully uppercase in Go names.
+var initialisms = map[string]bool{
+	"api": true, "ca": true, "fips": true, "gid": true, "gpg": true, "http": true,
+	"https": true, "id": true, "iso": true, "luks": true, "ntp": true, "scc": true,
+	"ssh": true, "tls": true, "uid": true, "uri": true, "url": true, "vip": true,
+}
+
+// main parses the flags and runs the generator.
+//
+// It exits with status code 1 if generation fails.
+func main() {
+	schemaDir := flag.String("schema-dir", "schema/versions", "directory containing <apiVersion>.json schemas")
+	pkg := flag.String("package", "definition", "package name of the generated file")
+	out := flag.String("out", "types_gen.go", "output file")
+	flag.Parse()
+
+	if err := run(*schemaDir, *pkg, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "structgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the structs for the newest schema in schemaDir.
+//
+// Parameters:
+//   - schemaDir: The directory containing the versioned schemas.
+//   - pkg: The package name of the generated file.
+//   - out: The output file path.
+//
+// Returns:
+//   - error: An error if the schema cannot be read or the code cannot be generated.
+func run(schemaDir, pkg, out string) error {
+	version, raw, err := newestSchema(schemaDir)
+	if err != nil {
+		return err
+	}
+
+	var doc struct {
+		Defs map[string]map[string]interface{} `json:"$defs"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	src, err := generate(pkg, version, doc.Defs)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
+
+// newestSchema returns the schema with the highest apiVersion in a directory.
+//
+// Parameters:
+//   - dir: The directory containing <apiVersion>.json files.
+//
+// Returns:
+//   - string: The apiVersion.
+//   - []byte: The raw schema.
+//   - error: An error if no schema is found.
+func newestSchema(dir string) (string, []byte, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return "", nil, err
+	}
+	var latest string
+	for _, m := range matches {
+		v := strings.TrimSuffix(filepath.Base(m), ".json")
+		if latest == "" || schema.CompareVersions(v, latest) > 0 {
+			latest = v
+		}
+	}
+	if latest == "" {
+		return "", nil, fmt.Errorf("no schema found in %s", dir)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, latest+".json"))
+	return latest, raw, err
+}
+
+// generate renders the Go source for every object definition.
+//
+// Parameters:
+//   - pkg: The package name.
+//   - version: The apiVersion of the schema, recorded in the header.
+//   - defs: The schema's $defs.
+//
+// Returns:
+//   - []byte: The formatted Go source.
+//   - error: An error if the generated code cannot be formatted.
+func generate(pkg, version string, defs map[string]map[string]interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by structgen from schema apiVersion %s; DO NOT EDIT.\n\n", version)
+	fmt.Fprintf(&b, "package %s\n", pkg)
+
+	names := make([]string, 0, len(defs))
+	for name, def := range defs {
+		if _, ok := def["properties"]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		writeStruct(&b, name, defs[name])
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w\n%s", err, b.String())
+	}
+	return src, nil
+}
+
+// writeStruct renders a single struct.
+//
+// Parameters:
+//   - b: The buffer receiving the code.
+//   - name: The definition name, used as the struct name.
+//   - def: The definition schema.
+func writeStruct(b *bytes.Buffer, name string, def map[string]interface{}) {
+	props, _ := def["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if list, ok := def["required"].([]interface{}); ok {
+		for _, r := range list {
+			if s, ok := r.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	fmt.Fprintf(b, "\n// %s mirrors the %q schema definition.\n", name, name)
+	if desc, ok := def["description"].(string); ok {
+		fmt.Fprintf(b, "//\n// %s\n", firstLine(desc))
+	}
+	fmt.Fprintf(b, "type %s struct {\n", name)
+
+	fieldNames := make([]string, 0, len(props))
+	for field := range props {
+		fieldNames = append(fieldNames, field)
+	}
+	sort.Strings(fieldNames)
+
+	for _, field := range fieldNames {
+		prop, _ := props[field].(map[string]interface{})
+		goType, pointer := goType(prop)
+		if pointer && !required[field] {
+			goType = "*" + goType
+		}
+		tag := field
+		if !required[field] {
+			tag += ",omitempty"
+		}
+		if desc, ok := prop["description"].(string); ok {
+			fmt.Fprintf(b, "\t// %s\n", firstLine(desc))
+		}
+		fmt.Fprintf(b, "\t%s %s `json:%q yaml:%q`\n", goName(field), goType, tag, tag)
+	}
+	b.WriteString("}\n")
+}
+
+// goType maps a property schema to a Go type.
+//
+// Parameters:
+//   - prop: The property schema.
+//
+// Returns:
+//   - string: The Go type.
+//   - bool: Whether optional values of this type should be pointers, so that
+//     an explicit zero value (e.g. uid 0) survives a round trip.
+func goType(prop map[string]interface{}) (string, bool) {
+	if ref, ok := prop["$ref"].(string); ok {
+		return strings.TrimPrefix(ref, "#/$defs/"), true
+	}
+	switch prop["type"] {
+	case "string":
+		return "string", false
+	case "boolean":
+		return "bool", false
+	case "integer":
+		return "int", true
+	case "number":
+		return "float64", true
+	case "array":
+		items, _ := prop["items"].(map[string]interface{})
+		item, _ := goType(items)
+		return "[]" + item, false
+	default:
+		return "interface{}", false
+	}
+}
+
+// goName converts a camelCase JSON field name into an exported Go identifier.
+//
+// Parameters:
+//   - field: The JSON field name, e.g. "apiVIP6".
+//
+// Returns:
+//   - string: The Go name, e.g. "APIVIP6".
+func goName(field string) string {
+	var b strings.Builder
+	for _, word := range splitWords(field) {
+		lower := strings.ToLower(word)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		if stem := strings.TrimSuffix(lower, "s"); stem != lower && initialisms[stem] {
+			b.WriteString(strings.ToUpper(stem) + "s")
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// splitWords splits a camelCase identifier into words.
+//
+// A new word starts at an uppercase letter following a lowercase letter or
+// digit, or at the last uppercase letter of an uppercase run followed by a
+// lowercase letter ("skipTLSVerify" splits into "skip", "TLS", "Verify").
+// Trailing digits stay attached to their word.
+//
+// Parameters:
+//   - s: The identifier.
+//
+// Returns:
+//   - []string: The words.
+func splitWords(s string) []string {
+	runes := []rune(s)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			(unicode.IsUpper(prev) && unicode.IsLower(next))) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
+
+// firstLine returns the first line of a description.
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(s, "\n", 2)[0])
+}
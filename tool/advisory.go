@@ -0,0 +1,193 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultAdvisoryTimeout bounds the scan of a single image.
+const defaultAdvisoryTimeout = 5 * time.Minute
+
+// advisorySeverities are the vulnerability severities, most severe first.
+var advisorySeverities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// AdvisoryScanner looks up the known vulnerabilities of container images
+// with Trivy, optionally against a Trivy server holding the vulnerability
+// database, so that images with known CVEs are noticed before they are baked
+// into an image.
+type AdvisoryScanner struct {
+	// Binary is the path of the trivy binary. Defaults to "trivy".
+	Binary string
+	// Server is the URL of a Trivy server, e.g. http://trivy:4954. If empty,
+	// Trivy uses its local database.
+	Server string
+	// Timeout bounds the scan of each image. Defaults to five minutes.
+	Timeout time.Duration
+}
+
+// ImageAdvisory summarizes the known vulnerabilities of an image.
+type ImageAdvisory struct {
+	// Image is the image reference, as written in the configuration.
+	Image string `json:"image"`
+	// Counts is the number of vulnerabilities per severity, e.g. "CRITICAL".
+	Counts map[string]int `json:"counts,omitempty"`
+	// Critical lists the IDs of the critical and high vulnerabilities, e.g.
+	// CVE-2024-12345.
+	Critical []string `json:"critical,omitempty"`
+	// Error explains why the image could not be scanned.
+	Error string `json:"error,omitempty"`
+}
+
+// Summary renders the counts, most severe first, e.g. "2 critical, 5 high".
+//
+// Returns:
+//   - string: The counts, or "no known vulnerabilities".
+func (a ImageAdvisory) Summary() string {
+	var parts []string
+	for _, severity := range advisorySeverities {
+		if n := a.Counts[severity]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, strings.ToLower(severity)))
+		}
+	}
+	if len(parts) == 0 {
+		return "no known vulnerabilities"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// trivyReport is the part of a `trivy image --format json` report the
+// scanner reads.
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// Scan looks up the known vulnerabilities of an image.
+//
+// Parameters:
+//   - ctx: The context bounding the scan.
+//   - image: The image reference.
+//
+// Returns:
+//   - ImageAdvisory: The summary; Error is set if the image could not be scanned.
+func (s *AdvisoryScanner) Scan(ctx context.Context, image string) ImageAdvisory {
+	advisory := ImageAdvisory{Image: image}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = defaultAdvisoryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	binary := s.Binary
+	if binary == "" {
+		binary = "trivy"
+	}
+	args := []string{"image", "--quiet", "--format", "json", "--scanners", "vuln"}
+	if s.Server != "" {
+		args = append(args, "--server", s.Server)
+	}
+	cmd := exec.CommandContext(ctx, binary, append(args, image)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndex(msg, "\n"); i >= 0 {
+			msg = msg[i+1:]
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		advisory.Error = "trivy failed: " + msg
+		return advisory
+	}
+
+	var report trivyReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		advisory.Error = fmt.Sprintf("invalid trivy report: %v", err)
+		return advisory
+	}
+	advisory.Counts = map[string]int{}
+	seen := map[string]bool{}
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			if seen[v.VulnerabilityID] {
+				continue
+			}
+			seen[v.VulnerabilityID] = true
+			severity := strings.ToUpper(v.Severity)
+			if severity == "" {
+				severity = "UNKNOWN"
+			}
+			advisory.Counts[severity]++
+			if severity == "CRITICAL" || severity == "HIGH" {
+				advisory.Critical = append(advisory.Critical, v.VulnerabilityID)
+			}
+		}
+	}
+	sort.Strings(advisory.Critical)
+	return advisory
+}
+
+// checkAdvisories scans the embedded images of a configuration.
+//
+// Helm charts are not scanned: their images are only known once the charts
+// are rendered, which a note says.
+//
+// Parameters:
+//   - config: The configuration.
+//   - opts: The options; Advisories, Offline and Trace are used.
+//
+// Returns:
+//   - []ImageAdvisory: One summary per image.
+//   - []string: Warnings about images with critical or high vulnerabilities
+//     and about images or charts that were not scanned.
+func checkAdvisories(config map[string]interface{}, opts Options) ([]ImageAdvisory, []string) {
+	images := lookupMaps(config, "embeddedArtifactRegistry", "images")
+	charts := lookupMaps(config, "kubernetes", "helm", "charts")
+	if len(images) == 0 && len(charts) == 0 {
+		return nil, nil
+	}
+	if opts.Offline {
+		return nil, []string{"the vulnerability check was skipped in offline mode"}
+	}
+
+	var advisories []ImageAdvisory
+	var warnings []string
+	for i, img := range images {
+		name, _ := img["name"].(string)
+		if name == "" {
+			continue
+		}
+		endStep := opts.Trace.Step("vulnerability scan " + name)
+		advisory := opts.Advisories.Scan(context.Background(), name)
+		endStep()
+		advisories = append(advisories, advisory)
+		switch {
+		case advisory.Error != "":
+			warnings = append(warnings, fmt.Sprintf("embeddedArtifactRegistry.images.%d: image %q could not be checked for vulnerabilities: %s", i, name, advisory.Error))
+		case len(advisory.Critical) > 0:
+			ids := advisory.Critical
+			if len(ids) > 5 {
+				ids = append(ids[:5:5], fmt.Sprintf("%d more", len(advisory.Critical)-5))
+			}
+			warnings = append(warnings, fmt.Sprintf("embeddedArtifactRegistry.images.%d: image %q has known vulnerabilities (%s): %s; use a patched tag", i, name, advisory.Summary(), strings.Join(ids, ", ")))
+		}
+	}
+	for i, chart := range charts {
+		name, _ := chart["name"].(string)
+		warnings = append(warnings, fmt.Sprintf("kubernetes.helm.charts.%d: the images of chart %q were not checked for vulnerabilities, they are only known once the chart is rendered; add them to embeddedArtifactRegistry.images to check them", i, name))
+	}
+	return advisories, warnings
+}
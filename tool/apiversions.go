@@ -0,0 +1,106 @@
+package tool
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// apiVersionHintPattern matches the entries of a chart's apiVersions, which
+// Helm accepts as "group/version" or "group/version/Kind", e.g. "apps/v1",
+// "monitoring.coreos.com/v1/ServiceMonitor" or, for the core group, "v1".
+var apiVersionHintPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9.-]*[a-z0-9])?/)?v[0-9]+((alpha|beta)[0-9]+)?(/[A-Z][A-Za-z0-9]*)?$`)
+
+// kubernetesMinorPattern extracts the minor version of kubernetes.version,
+// e.g. 30 from "v1.30.3+rke2r1".
+var kubernetesMinorPattern = regexp.MustCompile(`^v?1\.([0-9]+)\.`)
+
+// apiVersionLifecycle is the range of Kubernetes minor versions serving a
+// built-in API version: from Added, if set, up to but excluding Removed, if
+// set.
+type apiVersionLifecycle struct {
+	Added   int
+	Removed int
+}
+
+// builtinAPIVersions lists the built-in API versions that were added or
+// removed in Kubernetes 1.16 or later. Versions that are not listed, such as
+// CRDs, are not checked.
+var builtinAPIVersions = map[string]apiVersionLifecycle{
+	"extensions/v1beta1":                   {Removed: 22},
+	"apps/v1beta1":                         {Removed: 16},
+	"apps/v1beta2":                         {Removed: 16},
+	"admissionregistration.k8s.io/v1beta1": {Removed: 22},
+	"apiextensions.k8s.io/v1beta1":         {Removed: 22},
+	"apiregistration.k8s.io/v1beta1":       {Removed: 22},
+	"authentication.k8s.io/v1beta1":        {Removed: 22},
+	"authorization.k8s.io/v1beta1":         {Removed: 22},
+	"certificates.k8s.io/v1beta1":          {Removed: 22},
+	"certificates.k8s.io/v1":               {Added: 19},
+	"coordination.k8s.io/v1beta1":          {Removed: 22},
+	"networking.k8s.io/v1beta1":            {Removed: 22},
+	"rbac.authorization.k8s.io/v1beta1":    {Removed: 22},
+	"rbac.authorization.k8s.io/v1alpha1":   {Removed: 22},
+	"scheduling.k8s.io/v1beta1":            {Removed: 22},
+	"batch/v1beta1":                        {Removed: 25},
+	"discovery.k8s.io/v1beta1":             {Removed: 25},
+	"discovery.k8s.io/v1":                  {Added: 21},
+	"events.k8s.io/v1beta1":                {Removed: 25},
+	"node.k8s.io/v1beta1":                  {Removed: 25},
+	"node.k8s.io/v1":                       {Added: 20},
+	"policy/v1beta1":                       {Removed: 25},
+	"policy/v1":                            {Added: 21},
+	"autoscaling/v2beta1":                  {Removed: 25},
+	"autoscaling/v2beta2":                  {Removed: 26},
+	"autoscaling/v2":                       {Added: 23},
+	"flowcontrol.apiserver.k8s.io/v1beta1": {Removed: 26},
+	"flowcontrol.apiserver.k8s.io/v1beta2": {Removed: 29},
+	"flowcontrol.apiserver.k8s.io/v1beta3": {Added: 26, Removed: 32},
+	"flowcontrol.apiserver.k8s.io/v1":      {Added: 29},
+	"storage.k8s.io/v1beta1":               {Removed: 27},
+}
+
+// checkChartAPIVersions implements the helm-chart-api-versions rule.
+func checkChartAPIVersions(cfg map[string]interface{}) []string {
+	var msgs []string
+	for i, chart := range lookupMaps(cfg, "kubernetes", "helm", "charts") {
+		for j, hint := range stringList(chart["apiVersions"]) {
+			if !apiVersionHintPattern.MatchString(hint) {
+				msgs = append(msgs, fmt.Sprintf("kubernetes.helm.charts.%d.apiVersions.%d: %q is not an API version; use \"group/version\" or \"group/version/Kind\", e.g. \"monitoring.coreos.com/v1\"", i, j, hint))
+			}
+		}
+	}
+	return msgs
+}
+
+// checkChartAPIVersionsKubernetes implements the
+// helm-chart-api-versions-kubernetes rule.
+func checkChartAPIVersionsKubernetes(cfg map[string]interface{}) []string {
+	version, _ := lookupMap(cfg, "kubernetes")["version"].(string)
+	m := kubernetesMinorPattern.FindStringSubmatch(version)
+	if m == nil {
+		return nil
+	}
+	minor, _ := strconv.Atoi(m[1])
+
+	var msgs []string
+	for i, chart := range lookupMaps(cfg, "kubernetes", "helm", "charts") {
+		for j, hint := range stringList(chart["apiVersions"]) {
+			// A trailing Kind, e.g. in "apps/v1/Deployment", starts upper case.
+			groupVersion := hint
+			if k := strings.LastIndex(hint, "/"); k >= 0 && hint[k+1:] != strings.ToLower(hint[k+1:]) {
+				groupVersion = hint[:k]
+			}
+			lifecycle, ok := builtinAPIVersions[groupVersion]
+			switch {
+			case !ok:
+			case lifecycle.Removed > 0 && minor >= lifecycle.Removed:
+				msgs = append(msgs, fmt.Sprintf("kubernetes.helm.charts.%d.apiVersions.%d: %s was removed in Kubernetes 1.%d, so the cluster of kubernetes.version %s does not serve it and the chart renders for APIs it cannot install; remove it", i, j, groupVersion, lifecycle.Removed, version))
+			case lifecycle.Added > 0 && minor < lifecycle.Added:
+				msgs = append(msgs, fmt.Sprintf("kubernetes.helm.charts.%d.apiVersions.%d: %s was added in Kubernetes 1.%d, so the cluster of kubernetes.version %s does not serve it yet and the chart renders for APIs it cannot install; remove it", i, j, groupVersion, lifecycle.Added, version))
+			}
+		}
+	}
+	return msgs
+}
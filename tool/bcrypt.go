@@ -0,0 +1,63 @@
+package tool
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+// bcryptCost is the bcrypt cost of generated password hashes.
+const bcryptCost = 10
+
+// bcryptMagic is the text bcrypt encrypts with the expanded key.
+var bcryptMagic = []byte("OrpheanBeholderScryDoubt")
+
+// bcryptEncoding is the base64 alphabet of bcrypt hashes, without padding.
+var bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+// reproduciblePassword hashes a password with bcrypt like encryptPassword,
+// but with a salt derived from the username and password instead of a random
+// one, so identical input yields an identical hash.
+//
+// golang.org/x/crypto/bcrypt always draws a random salt, so the hash is
+// computed here with the same Blowfish primitives; the result is a standard
+// $2a$ hash that any bcrypt implementation verifies.
+//
+// Parameters:
+//   - username: The user the password belongs to, mixed into the salt so that
+//     users sharing a password get different hashes.
+//   - password: The plaintext password.
+//
+// Returns:
+//   - string: The bcrypt hash of the password.
+//   - error: An error if the password is longer than bcrypt's 72 bytes.
+func reproduciblePassword(username, password string) (string, error) {
+	if len(password) > 72 {
+		return "", fmt.Errorf("password length exceeds 72 bytes")
+	}
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte("eib-mcp reproducible salt\x00" + username))
+	salt := mac.Sum(nil)[:16]
+
+	// The key includes the trailing NUL, as in the C implementation.
+	key := append([]byte(password), 0)
+	c, err := blowfish.NewSaltedCipher(key, salt)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < 1<<bcryptCost; i++ {
+		blowfish.ExpandKey(key, c)
+		blowfish.ExpandKey(salt, c)
+	}
+	data := append([]byte(nil), bcryptMagic...)
+	for i := 0; i < len(data); i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(data[i:i+8], data[i:i+8])
+		}
+	}
+	// Only 23 of the 24 encrypted bytes are encoded, as in the C implementation.
+	return fmt.Sprintf("$2a$%02d$%s%s", bcryptCost, bcryptEncoding.EncodeToString(salt), bcryptEncoding.EncodeToString(data[:23])), nil
+}
@@ -0,0 +1,52 @@
+package tool
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestReproduciblePassword checks that reproducible hashes are bcrypt hashes
+// of the password, equal for identical input and different across users.
+func TestReproduciblePassword(t *testing.T) {
+	hash, err := reproduciblePassword("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("correct horse")); err != nil {
+		t.Errorf("the hash does not verify: %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("wrong horse")); err == nil {
+		t.Error("the hash verifies a wrong password")
+	}
+	if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != bcryptCost {
+		t.Errorf("cost %d (%v), want %d", cost, err, bcryptCost)
+	}
+
+	again, err := reproduciblePassword("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != hash {
+		t.Errorf("hashes of the same input differ: %s and %s", hash, again)
+	}
+	other, err := reproduciblePassword("bob", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Error("users sharing a password get the same hash")
+	}
+}
+
+// TestReproduciblePasswordTooLong checks that passwords bcrypt would truncate
+// are rejected.
+func TestReproduciblePasswordTooLong(t *testing.T) {
+	long := make([]byte, 73)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if _, err := reproduciblePassword("alice", string(long)); err == nil {
+		t.Error("a 73-byte password was accepted")
+	}
+}
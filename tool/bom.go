@@ -0,0 +1,245 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/e-minguez/eib-mcp/httpcache"
+	"gopkg.in/yaml.v3"
+)
+
+// BOM is the bill of materials of a configuration: what the built image
+// will contain, for review before it is built.
+type BOM struct {
+	// APIVersion is the apiVersion of the configuration.
+	APIVersion string `json:"apiVersion,omitempty"`
+	// BaseImage is the SL Micro image the build starts from.
+	BaseImage string `json:"baseImage,omitempty"`
+	// Arch is the image architecture.
+	Arch string `json:"arch,omitempty"`
+	// KubernetesVersion is the Kubernetes distribution and version, if any.
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// Packages lists the RPM packages installed in addition to the base image.
+	Packages []string `json:"packages"`
+	// PackageRepositories lists the URLs of the additional RPM repositories.
+	PackageRepositories []string `json:"packageRepositories"`
+	// Charts lists the Helm charts deployed on the cluster.
+	Charts []BOMChart `json:"charts"`
+	// ContainerImages lists the container images referenced by the configuration.
+	ContainerImages []BOMImage `json:"containerImages"`
+	// Manifests lists the URLs of the Kubernetes manifests applied on the cluster.
+	Manifests []string `json:"manifests"`
+	// Unresolved lists what could not be inventoried, such as images of
+	// manifests that could not be downloaded.
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// BOMChart is a Helm chart of a bill of materials.
+type BOMChart struct {
+	// Name is the chart name.
+	Name string `json:"name"`
+	// Version is the chart version.
+	Version string `json:"version"`
+	// Repository is the URL of the chart repository.
+	Repository string `json:"repository,omitempty"`
+	// Namespace is the namespace the chart is installed into.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// BOMImage is a container image of a bill of materials.
+type BOMImage struct {
+	// Name is the image reference.
+	Name string `json:"name"`
+	// Source says where the image is referenced: "embeddedArtifactRegistry"
+	// or the URL of a manifest.
+	Source string `json:"source"`
+}
+
+// BillOfMaterials inventories the packages, charts, container images and
+// manifests of a configuration.
+//
+// The images of manifests are found by downloading them (through opts.HTTP)
+// and reading every "image" field; in offline mode, or if a download fails,
+// the manifest is reported as unresolved instead. The images of Helm charts
+// are only known once the charts are rendered, so charts are listed by name
+// and version.
+//
+// Parameters:
+//   - ctx: The context controlling manifest downloads.
+//   - config: The configuration; it is not modified.
+//   - opts: The options; HTTP and Offline are used.
+//
+// Returns:
+//   - *BOM: The bill of materials.
+func BillOfMaterials(ctx context.Context, config map[string]interface{}, opts Options) *BOM {
+	bom := &BOM{
+		Packages:            []string{},
+		PackageRepositories: []string{},
+		Charts:              []BOMChart{},
+		ContainerImages:     []BOMImage{},
+		Manifests:           []string{},
+	}
+	bom.APIVersion, _ = config["apiVersion"].(string)
+	image := lookupMap(config, "image")
+	bom.BaseImage, _ = image["baseImage"].(string)
+	bom.Arch, _ = image["arch"].(string)
+	bom.KubernetesVersion, _ = lookupMap(config, "kubernetes")["version"].(string)
+
+	packages := lookupMap(config, "operatingSystem", "packages")
+	bom.Packages = appendStrings(bom.Packages, packages["packageList"])
+	sort.Strings(bom.Packages)
+	for _, repo := range lookupMaps(config, "operatingSystem", "packages", "additionalRepos") {
+		if url, ok := repo["url"].(string); ok {
+			bom.PackageRepositories = append(bom.PackageRepositories, url)
+		}
+	}
+
+	repositories := map[string]string{}
+	for _, repo := range lookupMaps(config, "kubernetes", "helm", "repositories") {
+		name, _ := repo["name"].(string)
+		repositories[name], _ = repo["url"].(string)
+	}
+	for _, chart := range lookupMaps(config, "kubernetes", "helm", "charts") {
+		c := BOMChart{}
+		c.Name, _ = chart["name"].(string)
+		c.Version, _ = chart["version"].(string)
+		c.Namespace, _ = chart["targetNamespace"].(string)
+		repoName, _ := chart["repositoryName"].(string)
+		c.Repository = repositories[repoName]
+		bom.Charts = append(bom.Charts, c)
+	}
+	if len(bom.Charts) > 0 {
+		bom.Unresolved = append(bom.Unresolved, "container images of Helm charts are only known once the charts are rendered; review the charts listed")
+	}
+
+	for _, img := range lookupMaps(config, "embeddedArtifactRegistry", "images") {
+		if name, ok := img["name"].(string); ok {
+			bom.ContainerImages = append(bom.ContainerImages, BOMImage{Name: name, Source: "embeddedArtifactRegistry"})
+		}
+	}
+
+	bom.Manifests = appendStrings(bom.Manifests, lookupMap(config, "kubernetes", "manifests")["urls"])
+	for _, url := range bom.Manifests {
+		if opts.Offline {
+			bom.Unresolved = append(bom.Unresolved, fmt.Sprintf("images of manifest %s: not downloaded in offline mode", url))
+			continue
+		}
+		endStep := opts.Trace.Step("download " + url)
+		images, err := manifestImages(ctx, opts.HTTP, url)
+		endStep()
+		if err != nil {
+			bom.Unresolved = append(bom.Unresolved, fmt.Sprintf("images of manifest %s: %v", url, err))
+			continue
+		}
+		for _, name := range images {
+			bom.ContainerImages = append(bom.ContainerImages, BOMImage{Name: name, Source: url})
+		}
+	}
+	return bom
+}
+
+// appendStrings appends the strings of a list, skipping other items.
+//
+// Parameters:
+//   - dst: The list to extend.
+//   - v: A value expected to be a list of strings.
+//
+// Returns:
+//   - []string: The extended list.
+func appendStrings(dst []string, v interface{}) []string {
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			dst = append(dst, s)
+		}
+	}
+	return dst
+}
+
+// manifestImages downloads a Kubernetes manifest and lists the container
+// images it references.
+//
+// Parameters:
+//   - ctx: The context controlling the download.
+//   - cache: The download cache, or nil to download without caching.
+//   - url: The manifest URL.
+//
+// Returns:
+//   - []string: The distinct images, sorted.
+//   - error: An error if the manifest cannot be downloaded or parsed.
+func manifestImages(ctx context.Context, cache *httpcache.Cache, url string) ([]string, error) {
+	body, err := downloadManifest(ctx, cache, url)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		collectImages(doc, seen)
+	}
+
+	images := make([]string, 0, len(seen))
+	for name := range seen {
+		images = append(images, name)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// downloadManifest downloads a Kubernetes manifest through the network
+// cache.
+//
+// Parameters:
+//   - ctx: The context bounding the download.
+//   - cache: The network cache; nil downloads without caching.
+//   - url: The manifest URL.
+//
+// Returns:
+//   - []byte: The manifest.
+//   - error: An error if the download fails.
+func downloadManifest(ctx context.Context, cache *httpcache.Cache, url string) ([]byte, error) {
+	if cache == nil {
+		cache = httpcache.New("", 0)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	return cache.Get(ctx, url)
+}
+
+// collectImages records the values of every "image" field of a decoded
+// manifest, such as those of container specs.
+//
+// Parameters:
+//   - v: The decoded YAML value.
+//   - seen: The images found so far.
+func collectImages(v interface{}, seen map[string]bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			if name, ok := child.(string); ok && key == "image" && strings.TrimSpace(name) != "" {
+				seen[name] = true
+				continue
+			}
+			collectImages(child, seen)
+		}
+	case []interface{}:
+		for _, child := range val {
+			collectImages(child, seen)
+		}
+	}
+}
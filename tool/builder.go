@@ -0,0 +1,370 @@
+package tool
+
+import (
+	"github.com/e-minguez/eib-mcp/definition"
+	"github.com/e-minguez/eib-mcp/schema"
+)
+
+// ConfigBuilder builds EIB configurations through a fluent API.
+//
+// It lets Go automation use this package as a library without crafting
+// nested maps by hand:
+//
+//	result, err := tool.NewConfig().
+//		WithISOImage("x86_64", "slmicro.iso", "edge.iso").
+//		WithInstallDevice("/dev/sda").
+//		AddUser("root", "secret").
+//		Build()
+//
+// Every method returns the builder so calls can be chained. Build runs the
+// same validation pipeline as Generate.
+type ConfigBuilder struct {
+	def  definition.Definition
+	opts Options
+}
+
+// NewConfig creates a builder for a configuration using the latest apiVersion.
+//
+// Returns:
+//   - *ConfigBuilder: The new builder.
+func NewConfig() *ConfigBuilder {
+	return &ConfigBuilder{def: definition.Definition{APIVersion: schema.LatestVersion()}}
+}
+
+// WithAPIVersion sets the EIB definition apiVersion.
+//
+// Parameters:
+//   - version: The apiVersion, e.g. "1.2".
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithAPIVersion(version string) *ConfigBuilder {
+	b.def.APIVersion = version
+	return b
+}
+
+// WithOptions sets the options used by Build.
+//
+// Parameters:
+//   - opts: The generation options.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithOptions(opts Options) *ConfigBuilder {
+	b.opts = opts
+	return b
+}
+
+// WithISOImage configures an ISO image build.
+//
+// Parameters:
+//   - arch: The target architecture, "x86_64" or "aarch64".
+//   - baseImage: The base image file name.
+//   - outputImageName: The output image file name.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithISOImage(arch, baseImage, outputImageName string) *ConfigBuilder {
+	return b.withImage("iso", arch, baseImage, outputImageName)
+}
+
+// WithRawImage configures a RAW image build.
+//
+// Parameters:
+//   - arch: The target architecture, "x86_64" or "aarch64".
+//   - baseImage: The base image file name.
+//   - outputImageName: The output image file name.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithRawImage(arch, baseImage, outputImageName string) *ConfigBuilder {
+	return b.withImage("raw", arch, baseImage, outputImageName)
+}
+
+// withImage sets the image section.
+func (b *ConfigBuilder) withImage(imageType, arch, baseImage, outputImageName string) *ConfigBuilder {
+	b.def.Image = definition.Image{
+		ImageType:       imageType,
+		Arch:            arch,
+		BaseImage:       baseImage,
+		OutputImageName: outputImageName,
+	}
+	return b
+}
+
+// WithInstallDevice sets the device an ISO image installs to.
+//
+// Parameters:
+//   - device: The device path, e.g. "/dev/sda".
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithInstallDevice(device string) *ConfigBuilder {
+	b.def.OperatingSystem.ISOConfiguration = &definition.IsoConfiguration{InstallDevice: device}
+	return b
+}
+
+// WithDiskSize sets the disk size of a RAW image.
+//
+// Parameters:
+//   - size: The disk size, e.g. "32G".
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithDiskSize(size string) *ConfigBuilder {
+	if b.def.OperatingSystem.RawConfiguration == nil {
+		b.def.OperatingSystem.RawConfiguration = &definition.RawConfiguration{}
+	}
+	b.def.OperatingSystem.RawConfiguration.DiskSize = size
+	return b
+}
+
+// AddUser adds an operating system user.
+//
+// A plaintext password is encrypted by Build; an existing hash (starting
+// with "$") is kept as is. Either a password or SSH keys must be given.
+//
+// Parameters:
+//   - username: The user name.
+//   - password: The plaintext password or hash, or empty for key-only users.
+//   - sshKeys: The authorized SSH public keys.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) AddUser(username, password string, sshKeys ...string) *ConfigBuilder {
+	b.def.OperatingSystem.Users = append(b.def.OperatingSystem.Users, definition.OperatingSystemUser{
+		Username:          username,
+		EncryptedPassword: password,
+		SSHKeys:           sshKeys,
+	})
+	return b
+}
+
+// AddKernelArgs appends kernel command line arguments.
+//
+// Parameters:
+//   - args: The kernel arguments.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) AddKernelArgs(args ...string) *ConfigBuilder {
+	b.def.OperatingSystem.KernelArgs = append(b.def.OperatingSystem.KernelArgs, args...)
+	return b
+}
+
+// EnableSystemdUnits appends systemd units to enable.
+//
+// Parameters:
+//   - units: The unit names.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) EnableSystemdUnits(units ...string) *ConfigBuilder {
+	if b.def.OperatingSystem.Systemd == nil {
+		b.def.OperatingSystem.Systemd = &definition.Systemd{}
+	}
+	b.def.OperatingSystem.Systemd.Enable = append(b.def.OperatingSystem.Systemd.Enable, units...)
+	return b
+}
+
+// AddPackages appends packages to install.
+//
+// Installing packages requires either WithSCCRegistrationCode or additional
+// repositories.
+//
+// Parameters:
+//   - packages: The package names.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) AddPackages(packages ...string) *ConfigBuilder {
+	pkgs := b.packages()
+	pkgs.PackageList = append(pkgs.PackageList, packages...)
+	return b
+}
+
+// WithSCCRegistrationCode sets the SUSE Customer Center registration code.
+//
+// Parameters:
+//   - code: The registration code.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithSCCRegistrationCode(code string) *ConfigBuilder {
+	b.packages().SCCRegistrationCode = code
+	return b
+}
+
+// packages returns the packages section, creating it if needed.
+func (b *ConfigBuilder) packages() *definition.Packages {
+	if b.def.OperatingSystem.Packages == nil {
+		b.def.OperatingSystem.Packages = &definition.Packages{}
+	}
+	return b.def.OperatingSystem.Packages
+}
+
+// WithTimezone sets the system timezone.
+//
+// Parameters:
+//   - timezone: The timezone, e.g. "Europe/Madrid".
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithTimezone(timezone string) *ConfigBuilder {
+	b.time().Timezone = timezone
+	return b
+}
+
+// AddNTPServers appends NTP servers.
+//
+// Parameters:
+//   - servers: The NTP server addresses.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) AddNTPServers(servers ...string) *ConfigBuilder {
+	t := b.time()
+	if t.NTP == nil {
+		t.NTP = &definition.NtpConfiguration{}
+	}
+	t.NTP.Servers = append(t.NTP.Servers, servers...)
+	return b
+}
+
+// time returns the time section, creating it if needed.
+func (b *ConfigBuilder) time() *definition.Time {
+	if b.def.OperatingSystem.Time == nil {
+		b.def.OperatingSystem.Time = &definition.Time{}
+	}
+	return b.def.OperatingSystem.Time
+}
+
+// WithKubernetes sets the Kubernetes version to deploy.
+//
+// Parameters:
+//   - version: The Kubernetes version, e.g. "v1.30.3+rke2r1".
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithKubernetes(version string) *ConfigBuilder {
+	b.kubernetes().Version = version
+	return b
+}
+
+// WithAPIVIP sets the IPv4 virtual IP of the Kubernetes API.
+//
+// Parameters:
+//   - vip: The virtual IP address.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) WithAPIVIP(vip string) *ConfigBuilder {
+	k := b.kubernetes()
+	if k.Network == nil {
+		k.Network = &definition.Network{}
+	}
+	k.Network.APIVIP = vip
+	return b
+}
+
+// AddNode adds a Kubernetes node.
+//
+// Parameters:
+//   - hostname: The node hostname.
+//   - nodeType: The node type, "server" or "agent".
+//   - initializer: Whether the node initializes the cluster.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) AddNode(hostname, nodeType string, initializer bool) *ConfigBuilder {
+	k := b.kubernetes()
+	k.Nodes = append(k.Nodes, definition.Node{Hostname: hostname, Type: nodeType, Initializer: initializer})
+	return b
+}
+
+// AddHelmRepository adds a Helm repository.
+//
+// Parameters:
+//   - name: The repository name referenced by charts.
+//   - url: The repository URL.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) AddHelmRepository(name, url string) *ConfigBuilder {
+	h := b.helm()
+	h.Repositories = append(h.Repositories, definition.HelmRepository{Name: name, URL: url})
+	return b
+}
+
+// AddHelmChart adds a Helm chart.
+//
+// Parameters:
+//   - name: The chart name.
+//   - repositoryName: The name of a repository added with AddHelmRepository.
+//   - version: The chart version.
+//   - targetNamespace: The namespace to install into, or empty for the default.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) AddHelmChart(name, repositoryName, version, targetNamespace string) *ConfigBuilder {
+	h := b.helm()
+	h.Charts = append(h.Charts, definition.HelmChart{
+		Name:            name,
+		RepositoryName:  repositoryName,
+		Version:         version,
+		TargetNamespace: targetNamespace,
+		CreateNamespace: targetNamespace != "",
+	})
+	return b
+}
+
+// AddManifestURL adds a Kubernetes manifest URL to deploy.
+//
+// Parameters:
+//   - url: The manifest URL.
+//
+// Returns:
+//   - *ConfigBuilder: The builder, for chaining.
+func (b *ConfigBuilder) AddManifestURL(url string) *ConfigBuilder {
+	k := b.kubernetes()
+	if k.Manifests == nil {
+		k.Manifests = &definition.Manifests{}
+	}
+	k.Manifests.URLs = append(k.Manifests.URLs, url)
+	return b
+}
+
+// kubernetes returns the kubernetes section, creating it if needed.
+func (b *ConfigBuilder) kubernetes() *definition.Kubernetes {
+	if b.def.Kubernetes == nil {
+		b.def.Kubernetes = &definition.Kubernetes{}
+	}
+	return b.def.Kubernetes
+}
+
+// helm returns the helm section, creating it if needed.
+func (b *ConfigBuilder) helm() *definition.Helm {
+	k := b.kubernetes()
+	if k.Helm == nil {
+		k.Helm = &definition.Helm{}
+	}
+	return k.Helm
+}
+
+// Definition returns a shallow copy of the configuration built so far, without validation.
+//
+// Returns:
+//   - *definition.Definition: The typed configuration.
+func (b *ConfigBuilder) Definition() *definition.Definition {
+	def := b.def
+	return &def
+}
+
+// Build validates the configuration and renders it as YAML.
+//
+// Returns:
+//   - *Result: The generated YAML configuration and any warnings.
+//   - error: An error if the configuration is invalid.
+func (b *ConfigBuilder) Build() (*Result, error) {
+	return GenerateDefinition(&b.def, b.opts)
+}
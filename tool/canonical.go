@@ -0,0 +1,107 @@
+package tool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// canonicalDefaults are the defaults Edge Image Builder applies to omitted
+// fields, keyed by the path of the list they apply to and the field name.
+var canonicalDefaults = []struct {
+	list  []string
+	field string
+	value string
+}{
+	{[]string{"kubernetes", "nodes"}, "type", "server"},
+	{[]string{"kubernetes", "helm", "charts"}, "installationNamespace", "kube-system"},
+}
+
+// Canonicalize normalizes a configuration so that equivalent configurations
+// render identically.
+//
+// Null values and empty strings, lists and objects are removed, and fields
+// Edge Image Builder defaults when omitted are filled in with their default.
+// Keys are always emitted in sorted order, so together with this the
+// rendered YAML of a configuration is canonical.
+//
+// Parameters:
+//   - config: The configuration; it is modified in place.
+//
+// Returns:
+//   - []string: The changes made, sorted.
+func Canonicalize(config map[string]interface{}) []string {
+	var changes []string
+	pruneEmpty(config, "", &changes)
+	for _, d := range canonicalDefaults {
+		for i, m := range lookupMaps(config, d.list...) {
+			if _, set := m[d.field]; !set {
+				m[d.field] = d.value
+				changes = append(changes, fmt.Sprintf("%s[%d].%s: filled in the default %q", strings.Join(d.list, "."), i, d.field, d.value))
+			}
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// pruneEmpty removes null and empty values from an object, recursively.
+//
+// Parameters:
+//   - m: The object; it is modified in place.
+//   - prefix: The path of m, used in change descriptions.
+//   - changes: Collects a description of every removed value.
+func pruneEmpty(m map[string]interface{}, prefix string, changes *[]string) {
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			pruneEmpty(val, path, changes)
+		case []interface{}:
+			for i, item := range val {
+				if im, ok := item.(map[string]interface{}); ok {
+					pruneEmpty(im, fmt.Sprintf("%s[%d]", path, i), changes)
+				}
+			}
+		}
+		if isEmptyValue(m[k]) {
+			delete(m, k)
+			*changes = append(*changes, fmt.Sprintf("%s: removed empty value", path))
+		}
+	}
+}
+
+// isEmptyValue reports whether a value is null or an empty string, list or object.
+func isEmptyValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []interface{}:
+		return len(val) == 0
+	case map[string]interface{}:
+		return len(val) == 0
+	default:
+		return false
+	}
+}
+
+// CanonicalDiff renders the differences between a configuration as it was
+// written and its canonical YAML.
+//
+// Parameters:
+//   - original: The configuration as written; a Markdown code fence around it is ignored.
+//   - canonical: The canonical YAML, as generated.
+//
+// Returns:
+//   - string: The unified diff, or empty if the configuration is already canonical.
+func CanonicalDiff(original, canonical string) string {
+	if stripped, ok := stripCodeFence(original); ok {
+		original = stripped
+	}
+	return UnifiedDiff(original, canonical, "original", "canonical")
+}
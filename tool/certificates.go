@@ -0,0 +1,185 @@
+package tool
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// certificatesDir is the image configuration directory whose certificates
+// EIB installs into the system trust store.
+const certificatesDir = "certificates"
+
+// certificateExpiryWarning is how long before expiry a certificate is
+// reported as expiring soon.
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// certificateNamePattern matches the certificate file names EIB installs.
+var certificateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*\.(pem|crt)$`)
+
+// nonNameChars matches runs of characters not allowed in derived file names.
+var nonNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Certificate is a CA certificate to install into the trust store of the
+// built system.
+type Certificate struct {
+	// Name is the file name under certificates/, ending in ".pem" or ".crt".
+	// If empty, it is derived from the certificate's subject.
+	Name string `json:"name,omitempty"`
+	// Content is the PEM content, possibly a bundle of several certificates.
+	Content string `json:"content"`
+}
+
+// PrepareCertificates validates CA certificates and turns them into
+// certificates/ artifacts.
+//
+// The content must consist of PEM encoded X.509 certificates only. Names
+// without a ".pem" or ".crt" extension get ".pem" appended, since EIB
+// ignores other files. Expired certificates, certificates expiring within
+// 30 days and certificates that are not CAs are reported as warnings.
+//
+// Parameters:
+//   - certs: The certificates.
+//   - now: The time against which expiry is checked.
+//
+// Returns:
+//   - []Artifact: The artifacts, ordered by path.
+//   - []string: The warnings.
+//   - error: An error listing every invalid certificate.
+func PrepareCertificates(certs []Certificate, now time.Time) ([]Artifact, []string, error) {
+	var artifacts []Artifact
+	var warnings, errs []string
+	seen := map[string]bool{}
+
+	for i, c := range certs {
+		label := c.Name
+		if label == "" {
+			label = fmt.Sprintf("certificate %d", i)
+		}
+
+		parsed, err := parsePEMCertificates(c.Content)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+
+		name, err := certificateFileName(c.Name, parsed[0], i)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Sprintf("%s: duplicate file name %q", label, name))
+			continue
+		}
+		seen[name] = true
+
+		for _, cert := range parsed {
+			warnings = append(warnings, certificateWarnings(name, cert, now)...)
+			if !cert.IsCA {
+				warnings = append(warnings, fmt.Sprintf("certificate %s (%s) is not a CA certificate", name, cert.Subject))
+			}
+		}
+
+		artifacts = append(artifacts, Artifact{
+			Path:    certificatesDir + "/" + name,
+			Content: c.Content,
+			Mode:    defaultFileMode,
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid certificates:\n- %s", strings.Join(errs, "\n- "))
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Path < artifacts[j].Path })
+	return artifacts, warnings, nil
+}
+
+// parsePEMCertificates decodes every certificate of a PEM bundle.
+//
+// Parameters:
+//   - content: The PEM content.
+//
+// Returns:
+//   - []*x509.Certificate: The certificates, in order.
+//   - error: An error if the content has no certificate, a non-certificate
+//     block, trailing garbage or an unparseable certificate.
+func parsePEMCertificates(content string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(content)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block %q, only CERTIFICATE blocks are allowed", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate %d: %w", len(certs), err)
+		}
+		certs = append(certs, cert)
+	}
+	if strings.TrimSpace(string(rest)) != "" {
+		return nil, fmt.Errorf("content is not valid PEM")
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+// certificateWarnings reports expired or soon-expiring certificates.
+//
+// Parameters:
+//   - name: The file name, used in messages.
+//   - cert: The certificate.
+//   - now: The reference time.
+//
+// Returns:
+//   - []string: The warnings.
+func certificateWarnings(name string, cert *x509.Certificate, now time.Time) []string {
+	switch {
+	case now.After(cert.NotAfter):
+		return []string{fmt.Sprintf("certificate %s (%s) expired on %s", name, cert.Subject, cert.NotAfter.Format(time.DateOnly))}
+	case now.Before(cert.NotBefore):
+		return []string{fmt.Sprintf("certificate %s (%s) is not valid before %s", name, cert.Subject, cert.NotBefore.Format(time.DateOnly))}
+	case cert.NotAfter.Sub(now) < certificateExpiryWarning:
+		days := int(cert.NotAfter.Sub(now).Hours() / 24)
+		return []string{fmt.Sprintf("certificate %s (%s) expires on %s, in %d days", name, cert.Subject, cert.NotAfter.Format(time.DateOnly), days)}
+	}
+	return nil
+}
+
+// certificateFileName returns the file name of a certificate under certificates/.
+//
+// Parameters:
+//   - name: The requested name, or empty to derive it.
+//   - cert: The first certificate of the bundle, used to derive a name.
+//   - index: The position of the certificate, used as a last resort.
+//
+// Returns:
+//   - string: The file name.
+//   - error: An error if the requested name is not a valid file name.
+func certificateFileName(name string, cert *x509.Certificate, index int) (string, error) {
+	if name == "" {
+		base := strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(cert.Subject.CommonName), "-"), "-")
+		if base == "" {
+			base = fmt.Sprintf("certificate-%d", index)
+		}
+		return base + ".pem", nil
+	}
+	if !strings.HasSuffix(name, ".pem") && !strings.HasSuffix(name, ".crt") {
+		name += ".pem"
+	}
+	if !certificateNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid file name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return name, nil
+}
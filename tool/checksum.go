@@ -0,0 +1,138 @@
+package tool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/e-minguez/eib-mcp/baseimage"
+	"github.com/e-minguez/eib-mcp/httpcache"
+)
+
+// sha256Hex returns the hexadecimal SHA-256 digest of data.
+//
+// Parameters:
+//   - data: The content to hash.
+//
+// Returns:
+//   - string: The digest.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ResultChecksums computes the SHA-256 of a generated definition and of the
+// decoded content of its artifacts.
+//
+// Parameters:
+//   - definition: The definition YAML, stored as DefinitionFile.
+//   - artifacts: The artifacts written next to it.
+//
+// Returns:
+//   - map[string]string: The digests keyed by path relative to the image
+//     configuration directory.
+//   - error: An error if an artifact's base64 content is invalid.
+func ResultChecksums(definition string, artifacts []Artifact) (map[string]string, error) {
+	sums := map[string]string{DefinitionFile: sha256Hex([]byte(definition))}
+	for _, a := range artifacts {
+		content := []byte(a.Content)
+		if a.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(a.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 content for %q: %w", a.Path, err)
+			}
+			content = decoded
+		}
+		sums[a.Path] = sha256Hex(content)
+	}
+	return sums, nil
+}
+
+// FileChecksums computes the SHA-256 of text files.
+//
+// Parameters:
+//   - files: The file contents keyed by path.
+//
+// Returns:
+//   - map[string]string: The digests keyed by the same paths.
+func FileChecksums(files map[string]string) map[string]string {
+	sums := make(map[string]string, len(files))
+	for path, content := range files {
+		sums[path] = sha256Hex([]byte(content))
+	}
+	return sums
+}
+
+// FormatChecksums renders digests in the format of sha256sum, sorted by
+// path, so the output can be verified with `sha256sum -c`.
+//
+// Parameters:
+//   - sums: The digests keyed by path.
+//
+// Returns:
+//   - string: One "<digest>  <path>" line per file.
+func FormatChecksums(sums map[string]string) string {
+	paths := make([]string, 0, len(sums))
+	for path := range sums {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", sums[path], path)
+	}
+	return b.String()
+}
+
+// ContentHash combines the digests of a definition and its artifacts into a
+// single hash, so that a configuration repository can be compared with a
+// regenerated configuration in one step.
+//
+// Parameters:
+//   - sums: The digests keyed by path, see ResultChecksums.
+//
+// Returns:
+//   - string: "sha256:" followed by the SHA-256 of the FormatChecksums output.
+func ContentHash(sums map[string]string) string {
+	return "sha256:" + sha256Hex([]byte(FormatChecksums(sums)))
+}
+
+// PublishedChecksum downloads a published checksum file, such as the .sha256
+// file next to a SUSE base image download, and returns the digest listed for
+// an image. The file is always downloaded afresh, never from the cache.
+//
+// Parameters:
+//   - ctx: The context controlling the download.
+//   - url: The checksum file URL.
+//   - fileName: The base name of the image.
+//   - opts: The options; HTTP and Offline are used.
+//
+// Returns:
+//   - string: The digest.
+//   - error: A *Error of KindNetwork if the file cannot be downloaded, or an
+//     error if it does not list the image.
+func PublishedChecksum(ctx context.Context, url, fileName string, opts Options) (string, error) {
+	if opts.Offline {
+		return "", classify(KindNetwork, errors.New("published checksums cannot be downloaded in offline mode; pass the expected digest instead"))
+	}
+	client := httpcache.New("", 0)
+	if opts.HTTP != nil {
+		client.Client = opts.HTTP.Client
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	endStep := opts.Trace.Step("download " + url)
+	body, err := client.Get(ctx, url)
+	endStep()
+	if err != nil {
+		return "", classify(KindNetwork, fmt.Errorf("failed to download checksums from %s: %w", url, err))
+	}
+	return baseimage.PublishedDigest(body, fileName)
+}
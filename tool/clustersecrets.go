@@ -0,0 +1,122 @@
+package tool
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ClusterSecretNames are the shared secrets GenerateClusterSecrets can
+// generate: the cluster join token and the token that only lets agents join.
+var ClusterSecretNames = []string{"token", "agent-token"}
+
+// clusterSecretBytes is the number of random bytes of a generated secret.
+const clusterSecretBytes = 32
+
+// GeneratedSecret is a secret generated into the configuration files of the
+// nodes, which is not stored anywhere else and must be kept like a password.
+type GeneratedSecret struct {
+	// Name is the option holding the secret, e.g. "token".
+	Name string `json:"name"`
+	// Value is the secret.
+	Value string `json:"value"`
+	// Files lists the files the secret was written to, e.g.
+	// "kubernetes/config/server.yaml".
+	Files []string `json:"files"`
+}
+
+// GenerateClusterSecrets generates the shared secrets of a cluster into the
+// server.yaml and agent.yaml of its nodes.
+//
+// Each secret is 32 random bytes, hex encoded, which RKE2 and K3s accept as
+// the short token format. Servers get every secret. Agents, if the cluster has
+// any, get the token they join with: the agent-token if it is generated, and
+// the token otherwise. A file that already sets a secret is not changed; the
+// conflict is reported instead.
+//
+// Parameters:
+//   - kc: The configuration files.
+//   - config: The configuration, for its nodes.
+//   - names: The secrets to generate, from ClusterSecretNames.
+//
+// Returns:
+//   - KubernetesConfig: The configuration files with the secrets.
+//   - []GeneratedSecret: The secrets, in the order of names.
+//   - error: A *FindingsError if the configuration installs no Kubernetes or
+//     a file already sets a secret, or an error if no random data is
+//     available.
+func GenerateClusterSecrets(kc KubernetesConfig, config map[string]interface{}, names []string) (KubernetesConfig, []GeneratedSecret, error) {
+	if lookupMap(config, "kubernetes") == nil {
+		return kc, nil, &FindingsError{Summary: "cluster secrets cannot be generated", Findings: NewFindings("cluster-secret", SeverityError, []string{"generateSecrets: the configuration installs no Kubernetes; remove generateSecrets"})}
+	}
+	_, agents := nodeCounts(config)
+	serverOptions, _ := parseKubernetesConfig(kc.Server)
+	agentOptions, _ := parseKubernetesConfig(kc.Agent)
+	agentName := ""
+	for _, name := range names {
+		if name == "agent-token" || agentName == "" {
+			agentName = name
+		}
+	}
+	var errs []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if _, ok := serverOptions[name]; ok && !seen[name] {
+			errs = append(errs, fmt.Sprintf("%s/server.yaml: %s is already set; remove it, or remove %s from generateSecrets", KubernetesConfigDir, name, name))
+		}
+		seen[name] = true
+	}
+	if _, ok := agentOptions["token"]; ok && agents > 0 && agentName != "" {
+		errs = append(errs, fmt.Sprintf("%s/agent.yaml: token is already set; remove it, or remove %s from generateSecrets", KubernetesConfigDir, agentName))
+	}
+	if len(errs) > 0 {
+		return kc, nil, &FindingsError{Summary: "cluster secrets cannot be generated", Findings: NewFindings("cluster-secret", SeverityError, errs)}
+	}
+
+	var secrets []GeneratedSecret
+	for _, name := range names {
+		if !seen[name] {
+			continue
+		}
+		delete(seen, name)
+		value, err := randomSecret()
+		if err != nil {
+			return kc, nil, err
+		}
+		secret := GeneratedSecret{Name: name, Value: value, Files: []string{KubernetesConfigDir + "/server.yaml"}}
+		kc.Server = appendOption(kc.Server, name, value)
+		if name == agentName && agents > 0 {
+			kc.Agent = appendOption(kc.Agent, "token", value)
+			secret.Files = append(secret.Files, KubernetesConfigDir+"/agent.yaml")
+		}
+		secrets = append(secrets, secret)
+	}
+	return kc, secrets, nil
+}
+
+// randomSecret returns clusterSecretBytes random bytes, hex encoded.
+func randomSecret() (string, error) {
+	b := make([]byte, clusterSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// appendOption appends an option to a configuration file, keeping its
+// content, comments included, unchanged.
+//
+// Parameters:
+//   - content: The file content, or empty.
+//   - name: The option name.
+//   - value: The option value, written quoted.
+//
+// Returns:
+//   - string: The file with the option.
+func appendOption(content, name, value string) string {
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + fmt.Sprintf("%s: %q\n", name, value)
+}
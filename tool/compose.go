@@ -0,0 +1,264 @@
+package tool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/e-minguez/eib-mcp/schema"
+)
+
+// Fragment is a partial configuration maintained on its own, such as the
+// os-base.yaml of the team owning the operating system.
+type Fragment struct {
+	// Name identifies the fragment in findings, e.g. "os-base.yaml".
+	Name string `json:"name"`
+	// Content is the partial configuration, as an object or a YAML string.
+	Content interface{} `json:"content"`
+	// Owns lists the sections only this fragment may set, e.g.
+	// "operatingSystem.users"; other fragments setting fields inside them
+	// are errors.
+	Owns []string `json:"owns,omitempty"`
+}
+
+// Composition is the result of ComposeConfig.
+type Composition struct {
+	// Config is the merged configuration.
+	Config map[string]interface{} `json:"-"`
+	// Sources maps each top-level section of the configuration to the
+	// fragments setting fields in it, in precedence order.
+	Sources map[string][]string `json:"sources"`
+	// Findings lists the fields a fragment overrides, as compose-conflict
+	// warnings.
+	Findings []Finding `json:"findings"`
+}
+
+// override records a field whose value, set by one fragment, another one
+// sets again.
+type override struct {
+	// Path is the dotted path of the field.
+	Path string
+	// Name is the fragment setting the field again.
+	Name string
+	// Previous is the fragment whose value is replaced.
+	Previous string
+	// Removed is true if the field is removed with null.
+	Removed bool
+	// Same is true if the field is set to the value it already has.
+	Same bool
+}
+
+// composer tracks which fragment set each field while fragments are merged.
+type composer struct {
+	// setters maps the dotted path of each field to the fragment that set it.
+	setters map[string]string
+	// owners maps each owned section to the fragment owning it.
+	owners map[string]string
+	// sources maps each top-level section to the fragments setting it.
+	sources map[string][]string
+	// overrides lists the values a fragment replaced or removed.
+	overrides []override
+	// violations collects the ownership finding messages.
+	violations []string
+	// reported records the fragment and section pairs already reported as
+	// ownership violations, so that each is reported once.
+	reported map[string]bool
+}
+
+// ComposeConfig merges configuration fragments into one configuration, so
+// that teams owning different sections, such as the operating system, the
+// network and Kubernetes, can each maintain their own file.
+//
+// Fragments are merged in order as MergeConfig merges a patch, so a later
+// fragment takes precedence: its values replace those of earlier fragments,
+// users, nodes, charts and the other keyed list items are merged by their
+// identifying field, and null removes a field. Every value replaced with a
+// different one is reported as a compose-conflict warning naming both
+// fragments. A fragment may own sections; another fragment setting a field
+// inside an owned section is a compose-ownership error, whatever its
+// precedence.
+//
+// Parameters:
+//   - fragments: The fragments, lowest precedence first; an empty name is
+//     replaced with "fragments.<index>".
+//
+// Returns:
+//   - *Composition: The merged configuration, the fragments of each section
+//     and the override warnings.
+//   - error: An error if a fragment cannot be parsed or owns a section that
+//     is not a field of the schema, or a *FindingsError listing the sections
+//     owned twice and the fields set outside the fragment owning them.
+func ComposeConfig(fragments []Fragment) (*Composition, error) {
+	if len(fragments) == 0 {
+		return nil, fmt.Errorf("no fragments to compose; pass at least one")
+	}
+	fields, err := schema.Fields(schema.LatestVersion())
+	if err != nil {
+		return nil, err
+	}
+	c := &composer{
+		setters:  map[string]string{},
+		owners:   map[string]string{},
+		sources:  map[string][]string{},
+		reported: map[string]bool{},
+	}
+	contents := make([]map[string]interface{}, len(fragments))
+	names := make([]string, len(fragments))
+	for i, f := range fragments {
+		names[i] = f.Name
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("fragments.%d", i)
+		}
+		content, err := ParseConfig(f.Content)
+		if err != nil {
+			return nil, fmt.Errorf("fragment %s: %w", names[i], err)
+		}
+		contents[i] = deepCopyValue(content).(map[string]interface{})
+		for _, section := range f.Owns {
+			if _, ok := fields[section]; !ok || strings.Contains(section, "[]") {
+				return nil, fmt.Errorf("fragment %s owns %q, which is not a section of the configuration; %s", names[i], section, suggestPath(section, fields))
+			}
+			for owned, owner := range c.owners {
+				if section == owned || strings.HasPrefix(section, owned+".") || strings.HasPrefix(owned, section+".") {
+					c.violations = append(c.violations, fmt.Sprintf("fragments.%d.owns: %s of %s overlaps %s, which %s owns; remove it from one of them", i, section, names[i], owned, owner))
+				}
+			}
+			c.owners[section] = names[i]
+		}
+	}
+
+	config := map[string]interface{}{}
+	for i, content := range contents {
+		c.walk(config, content, "", "", names[i])
+		for key := range content {
+			if len(c.sources[key]) == 0 || c.sources[key][len(c.sources[key])-1] != names[i] {
+				c.sources[key] = append(c.sources[key], names[i])
+			}
+		}
+		config = MergeConfig(config, content)
+	}
+	if len(c.violations) > 0 {
+		sort.Strings(c.violations)
+		return nil, classify(KindRule, &FindingsError{Summary: "fragments cannot be composed", Findings: NewFindings("compose-ownership", SeverityError, c.violations)})
+	}
+	return &Composition{Config: config, Sources: c.sources, Findings: NewFindings("compose-conflict", SeverityWarning, c.conflicts())}, nil
+}
+
+// walk records the fields a fragment sets, before it is merged into the
+// configuration, following the merge rules of MergeConfig.
+//
+// Parameters:
+//   - base: The configuration composed so far at this path, or nil.
+//   - patch: The fragment at this path.
+//   - keyPath: The path used by listKeys, with "[]" for list items.
+//   - path: The dotted path of the fields, with list indexes.
+//   - name: The name of the fragment.
+func (c *composer) walk(base, patch map[string]interface{}, keyPath, path, name string) {
+	for key, value := range patch {
+		childKey, childPath := key, key
+		if path != "" {
+			childKey, childPath = keyPath+"."+key, path+"."+key
+		}
+		existing, exists := base[key]
+		switch v := value.(type) {
+		case map[string]interface{}:
+			nested, _ := existing.(map[string]interface{})
+			c.walk(nested, v, childKey, childPath, name)
+			continue
+		case []interface{}:
+			list, isList := existing.([]interface{})
+			idKey, keyed := listKeys[childKey]
+			if !isList {
+				break
+			}
+			if !keyed {
+				// Lists without identifying fields gain the items, so
+				// nothing is overridden.
+				c.set(childPath, name, nil, false, value)
+				continue
+			}
+			appended := len(list)
+			for _, item := range v {
+				obj, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if idx := indexByKey(list, idKey, obj[idKey]); idx >= 0 {
+					nested, _ := list[idx].(map[string]interface{})
+					c.walk(nested, obj, childKey+"[]", fmt.Sprintf("%s.%d", childPath, idx), name)
+					continue
+				}
+				c.walk(nil, obj, childKey+"[]", fmt.Sprintf("%s.%d", childPath, appended), name)
+				appended++
+			}
+			continue
+		}
+		c.set(childPath, name, existing, exists, value)
+	}
+}
+
+// set records that a fragment sets a field, and the value of another
+// fragment it replaces, and reports a violation if another fragment owns the
+// field.
+//
+// Parameters:
+//   - path: The dotted path of the field.
+//   - name: The name of the fragment.
+//   - existing: The value composed so far.
+//   - exists: Whether the field was set so far.
+//   - value: The value of the fragment; nil removes the field.
+func (c *composer) set(path, name string, existing interface{}, exists bool, value interface{}) {
+	if previous, ok := c.setters[path]; ok && exists && previous != name {
+		c.overrides = append(c.overrides, override{Path: path, Name: name, Previous: previous, Removed: value == nil, Same: value != nil && jsonEqual(existing, value)})
+	}
+	if value == nil {
+		for field := range c.setters {
+			if field == path || strings.HasPrefix(field, path+".") {
+				delete(c.setters, field)
+			}
+		}
+	} else {
+		c.setters[path] = name
+	}
+	for section, owner := range c.owners {
+		if owner == name || c.reported[name+" "+section] || !inSection(path, section) {
+			continue
+		}
+		c.reported[name+" "+section] = true
+		c.violations = append(c.violations, fmt.Sprintf("%s: %s sets a field of %s, which %s owns; remove it from %s", path, name, section, owner, name))
+	}
+}
+
+// conflicts returns the overrides as messages for NewFindings, sorted.
+// The values are not quoted, since they may be secrets.
+//
+// Returns:
+//   - []string: One message per value replaced with a different one or
+//     removed.
+func (c *composer) conflicts() []string {
+	var msgs []string
+	for _, o := range c.overrides {
+		switch {
+		case o.Removed:
+			msgs = append(msgs, fmt.Sprintf("%s: %s removes the value set by %s; remove it from one of them", o.Path, o.Name, o.Previous))
+		case !o.Same:
+			msgs = append(msgs, fmt.Sprintf("%s: %s overrides the value set by %s; remove it from one of them", o.Path, o.Name, o.Previous))
+		}
+	}
+	sort.Strings(msgs)
+	return msgs
+}
+
+// inSection reports whether a field path, with list indexes, lies inside a
+// section of the schema, e.g. "kubernetes.nodes.0.hostname" inside
+// "kubernetes.nodes".
+func inSection(path, section string) bool {
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" || strings.Trim(segment, "0123456789") != "" {
+			segments = append(segments, segment)
+		}
+	}
+	field := strings.Join(segments, ".")
+	return field == section || strings.HasPrefix(field, section+".")
+}
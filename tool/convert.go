@@ -0,0 +1,859 @@
+package tool
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProvisioningFormat names a provisioning format ConvertProvisioning reads.
+type ProvisioningFormat string
+
+const (
+	// FormatCloudInit is a cloud-init #cloud-config document.
+	FormatCloudInit ProvisioningFormat = "cloud-init"
+	// FormatIgnition is an Ignition configuration, spec 3.
+	FormatIgnition ProvisioningFormat = "ignition"
+	// FormatAutoYaST is an AutoYaST profile.
+	FormatAutoYaST ProvisioningFormat = "autoyast"
+)
+
+// ProvisioningFormats lists the formats ConvertProvisioning reads.
+var ProvisioningFormats = []ProvisioningFormat{FormatCloudInit, FormatIgnition, FormatAutoYaST}
+
+// autoyastKeymaps maps the AutoYaST keyboard layouts to console keymaps.
+var autoyastKeymaps = map[string]string{
+	"english-us": "us",
+	"english-uk": "gb",
+	"german":     "de",
+	"german-ch":  "de-ch",
+	"french":     "fr",
+	"french-ch":  "fr-ch",
+	"spanish":    "es",
+	"italian":    "it",
+	"portugese":  "pt",
+	"dutch":      "nl",
+	"polish":     "pl",
+	"czech":      "cz",
+	"swedish":    "se",
+	"norwegian":  "no",
+	"danish":     "dk",
+	"finnish":    "fi",
+	"japanese":   "jp",
+}
+
+// Conversion is the result of ConvertProvisioning.
+type Conversion struct {
+	// Format is the format of the converted document.
+	Format ProvisioningFormat `json:"format"`
+	// Config is the converted configuration.
+	Config map[string]interface{} `json:"-"`
+	// Files are the files of the document, to place into the built system.
+	Files []CustomFile `json:"files,omitempty"`
+	// Findings lists what has no EIB equivalent, as convert-unsupported
+	// warnings, and what must be reviewed, as draft-review warnings.
+	Findings []Finding `json:"findings"`
+}
+
+// converter accumulates the sections of a converted configuration.
+type converter struct {
+	// config is the configuration, and osConfig its operatingSystem section.
+	config, osConfig map[string]interface{}
+	// users and groups are the operatingSystem users and groups.
+	users, groups []interface{}
+	// packages, enable, disable, kernelArgs and ntp list the values of
+	// the corresponding fields.
+	packages, enable, disable, kernelArgs, ntp []string
+	// files are the custom files.
+	files []CustomFile
+	// review and unsupported collect the finding messages.
+	review, unsupported []string
+}
+
+// ConvertProvisioning converts a cloud-init, Ignition or AutoYaST document
+// into a configuration, so that machines provisioned with those tools can be
+// built as images instead.
+//
+// Users with their passwords, SSH keys and groups, the time zone, NTP
+// servers, keymap, packages, systemd units, kernel arguments and files are
+// converted into their operatingSystem equivalents and custom files, on the
+// defaults of a draft_from_system draft, reported as draft-review warnings.
+// Everything else, such as commands to run, disk layouts or remote file
+// sources, has no EIB equivalent and is reported as a convert-unsupported
+// warning naming its path in the document.
+//
+// Parameters:
+//   - document: The document: YAML for cloud-init, JSON for Ignition, XML
+//     for AutoYaST.
+//   - format: The format, or empty to detect it from the document.
+//
+// Returns:
+//   - *Conversion: The configuration, its files and findings.
+//   - error: An error if the format is unknown or cannot be detected, or the
+//     document cannot be parsed.
+func ConvertProvisioning(document string, format ProvisioningFormat) (*Conversion, error) {
+	if format == "" {
+		format = detectProvisioningFormat(document)
+		if format == "" {
+			return nil, fmt.Errorf("the format of the document cannot be detected; set format to cloud-init, ignition or autoyast")
+		}
+	}
+	config, osConfig := draftSkeleton("x86_64", "")
+	c := &converter{config: config, osConfig: osConfig, review: []string{
+		"image.baseImage: the document does not name the image it provisions; set it to the SUSE Linux Micro image in base-images/",
+		"operatingSystem.isoConfiguration.installDevice: the disk to install to is not converted from the document; set it to the disk of the target machines",
+		"image.arch: the document does not name an architecture; set it to the architecture of the target machines",
+	}}
+	var err error
+	switch format {
+	case FormatCloudInit:
+		err = c.cloudInit(document)
+	case FormatIgnition:
+		err = c.ignition(document)
+	case FormatAutoYaST:
+		err = c.autoyast(document)
+	default:
+		return nil, fmt.Errorf("format %q is not supported; use cloud-init, ignition or autoyast", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s document: %w", format, err)
+	}
+	c.finish()
+
+	conv := &Conversion{Format: format, Config: c.config, Files: c.files, Findings: []Finding{}}
+	conv.Findings = append(conv.Findings, NewFindings("convert-unsupported", SeverityWarning, c.unsupported)...)
+	conv.Findings = append(conv.Findings, NewFindings("draft-review", SeverityWarning, c.review)...)
+	return conv, nil
+}
+
+// detectProvisioningFormat guesses the format of a document.
+//
+// Parameters:
+//   - document: The document.
+//
+// Returns:
+//   - ProvisioningFormat: The format, or empty if it cannot be told.
+func detectProvisioningFormat(document string) ProvisioningFormat {
+	trimmed := strings.TrimSpace(document)
+	switch {
+	case strings.HasPrefix(trimmed, "#cloud-config"):
+		return FormatCloudInit
+	case strings.HasPrefix(trimmed, "<"):
+		return FormatAutoYaST
+	case strings.HasPrefix(trimmed, "{"):
+		var doc map[string]interface{}
+		if json.Unmarshal([]byte(trimmed), &doc) == nil && doc["ignition"] != nil {
+			return FormatIgnition
+		}
+	}
+	return ""
+}
+
+// finish puts the collected lists into the configuration.
+func (c *converter) finish() {
+	if len(c.users) > 0 {
+		c.osConfig["users"] = c.users
+	}
+	if len(c.groups) > 0 {
+		c.osConfig["groups"] = c.groups
+	}
+	if len(c.packages) > 0 {
+		c.osConfig["packages"] = map[string]interface{}{"packageList": interfaceList(c.packages)}
+		c.review = append(c.review, "operatingSystem.packages: EIB resolves the packages from SUSE Customer Center or other repositories; set sccRegistrationCode, or add additionalRepos")
+	}
+	if len(c.enable) > 0 || len(c.disable) > 0 {
+		systemd := map[string]interface{}{}
+		if len(c.enable) > 0 {
+			systemd["enable"] = interfaceList(c.enable)
+		}
+		if len(c.disable) > 0 {
+			systemd["disable"] = interfaceList(c.disable)
+		}
+		c.osConfig["systemd"] = systemd
+	}
+	if len(c.kernelArgs) > 0 {
+		c.osConfig["kernelArgs"] = interfaceList(c.kernelArgs)
+	}
+	if len(c.ntp) > 0 {
+		t, _ := c.osConfig["time"].(map[string]interface{})
+		if t == nil {
+			t = map[string]interface{}{}
+			c.osConfig["time"] = t
+		}
+		t["ntp"] = map[string]interface{}{"servers": interfaceList(c.ntp)}
+	}
+	sort.Slice(c.files, func(i, j int) bool { return c.files[i].Path < c.files[j].Path })
+}
+
+// unsupportedKey reports a key of the document that has no EIB equivalent.
+//
+// Parameters:
+//   - path: The dotted path of the key in the document.
+//   - suggestion: What to do instead, starting with a suggestion verb, or
+//     empty.
+func (c *converter) unsupportedKey(path, suggestion string) {
+	msg := path + ": the setting has no EIB equivalent and was left out"
+	if suggestion != "" {
+		msg += "; " + suggestion
+	}
+	c.unsupported = append(c.unsupported, msg)
+}
+
+// hostname names the output image after the hostname of the document and
+// reports that EIB sets the hostname from the network configuration.
+func (c *converter) hostname(name string) {
+	image := c.config["image"].(map[string]interface{})
+	image["outputImageName"] = name + ".iso"
+	c.review = append(c.review, fmt.Sprintf("operatingSystem: EIB sets the hostname from the network configuration; add network/%s.yaml to keep the hostname %s", name, name))
+}
+
+// sudoers adds the sudo rules of a user as a file of /etc/sudoers.d.
+//
+// Parameters:
+//   - username: The user.
+//   - rules: The rules, e.g. "ALL=(ALL) NOPASSWD:ALL".
+func (c *converter) sudoers(username string, rules []string) {
+	var b strings.Builder
+	for _, rule := range rules {
+		fmt.Fprintf(&b, "%s %s\n", username, rule)
+	}
+	c.files = append(c.files, CustomFile{Path: "/etc/sudoers.d/" + username, Content: b.String(), Mode: "0440"})
+}
+
+// cloudInit converts a cloud-init #cloud-config document.
+//
+// Parameters:
+//   - document: The YAML document.
+//
+// Returns:
+//   - error: An error if the document is not a YAML mapping.
+func (c *converter) cloudInit(document string) error {
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(document), &raw); err != nil {
+		return err
+	}
+	doc, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("the document must be a mapping at the top level")
+	}
+	for _, key := range sortedKeys(stringKeys(doc)) {
+		value := doc[key]
+		switch key {
+		case "users":
+			switch v := value.(type) {
+			case []interface{}:
+				for i, item := range v {
+					c.cloudInitUser(fmt.Sprintf("users.%d", i), item)
+				}
+			case string:
+				// The string form lists the usernames, e.g. "default,bob".
+				for i, name := range strings.Split(v, ",") {
+					c.cloudInitUser(fmt.Sprintf("users.%d", i), strings.TrimSpace(name))
+				}
+			default:
+				c.unsupportedKey(key, "")
+			}
+		case "ssh_authorized_keys":
+			keys := stringValues(value)
+			if len(keys) == 0 {
+				c.unsupportedKey(key, "")
+				continue
+			}
+			c.users = append(c.users, map[string]interface{}{"username": "root", "sshKeys": interfaceList(keys)})
+			c.review = append(c.review, fmt.Sprintf("operatingSystem.users.%d: cloud-init gives the top-level ssh_authorized_keys to the default user, which EIB does not have; set username to the user that should get them", len(c.users)-1))
+		case "timezone":
+			c.osConfig["time"] = map[string]interface{}{"timezone": fmt.Sprint(value)}
+		case "ntp":
+			ntp, ok := value.(map[string]interface{})
+			if !ok {
+				c.unsupportedKey(key, "")
+				continue
+			}
+			for _, field := range sortedKeys(stringKeys(ntp)) {
+				switch field {
+				case "servers", "pools":
+					c.ntp = append(c.ntp, stringValues(ntp[field])...)
+				case "enabled":
+				default:
+					c.unsupportedKey("ntp."+field, "")
+				}
+			}
+		case "keyboard":
+			keyboard, ok := value.(map[string]interface{})
+			if !ok {
+				c.unsupportedKey(key, "")
+				continue
+			}
+			if layout, ok := keyboard["layout"].(string); ok {
+				c.osConfig["keymap"] = layout
+			}
+			for _, field := range sortedKeys(stringKeys(keyboard)) {
+				if field != "layout" {
+					c.unsupportedKey("keyboard."+field, "")
+				}
+			}
+		case "packages":
+			list, ok := value.([]interface{})
+			if !ok {
+				c.unsupportedKey(key, "")
+				continue
+			}
+			for i, item := range list {
+				switch p := item.(type) {
+				case string:
+					c.packages = append(c.packages, p)
+				case []interface{}:
+					if len(p) > 0 {
+						c.packages = append(c.packages, fmt.Sprint(p[0]))
+					}
+					c.unsupportedKey(fmt.Sprintf("packages.%d.1", i), "")
+				default:
+					c.unsupportedKey(fmt.Sprintf("packages.%d", i), "")
+				}
+			}
+		case "write_files":
+			list, ok := value.([]interface{})
+			if !ok {
+				c.unsupportedKey(key, "")
+				continue
+			}
+			for i, item := range list {
+				c.cloudInitFile(fmt.Sprintf("write_files.%d", i), item)
+			}
+		case "hostname", "fqdn":
+			if key == "fqdn" && doc["hostname"] != nil {
+				continue
+			}
+			name, _, _ := strings.Cut(fmt.Sprint(value), ".")
+			c.hostname(name)
+		case "runcmd", "bootcmd":
+			c.unsupportedKey(key, "add the commands as a script in custom/scripts")
+		default:
+			c.unsupportedKey(key, "")
+		}
+	}
+	return nil
+}
+
+// cloudInitUser converts an entry of the cloud-init users list.
+//
+// Parameters:
+//   - path: The path of the entry in the document.
+//   - item: The entry, a mapping or a username.
+func (c *converter) cloudInitUser(path string, item interface{}) {
+	if name, ok := item.(string); ok && name != "default" && name != "" {
+		item = map[string]interface{}{"name": name}
+	}
+	u, ok := item.(map[string]interface{})
+	switch {
+	case item == "default":
+		c.unsupportedKey(path, "add the default user of the distribution explicitly")
+		return
+	case !ok:
+		c.unsupportedKey(path, "")
+		return
+	}
+	name, _ := u["name"].(string)
+	user := map[string]interface{}{"username": name}
+	if name != "root" && u["no_create_home"] != true {
+		user["createHomeDir"] = true
+	}
+	for _, field := range sortedKeys(stringKeys(u)) {
+		value := u[field]
+		switch field {
+		case "name", "no_create_home":
+		case "passwd", "hashed_passwd":
+			user["encryptedPassword"] = fmt.Sprint(value)
+		case "plain_text_passwd":
+			user["password"] = fmt.Sprint(value)
+		case "ssh_authorized_keys":
+			user["sshKeys"] = interfaceList(stringValues(value))
+		case "uid":
+			if uid, err := strconv.Atoi(fmt.Sprint(value)); err == nil {
+				user["uid"] = uid
+			}
+		case "primary_group":
+			user["primaryGroup"] = fmt.Sprint(value)
+		case "groups":
+			var groups []string
+			if s, ok := value.(string); ok {
+				for _, g := range strings.Split(s, ",") {
+					groups = append(groups, strings.TrimSpace(g))
+				}
+			} else {
+				groups = stringValues(value)
+			}
+			user["secondaryGroups"] = interfaceList(groups)
+		case "sudo":
+			if rules := stringValues(value); len(rules) > 0 {
+				c.sudoers(name, rules)
+			}
+		default:
+			c.unsupportedKey(path+"."+field, "")
+		}
+	}
+	c.users = append(c.users, user)
+}
+
+// cloudInitFile converts an entry of the cloud-init write_files list.
+//
+// Parameters:
+//   - path: The path of the entry in the document.
+//   - item: The entry.
+func (c *converter) cloudInitFile(path string, item interface{}) {
+	f, ok := item.(map[string]interface{})
+	if !ok {
+		c.unsupportedKey(path, "")
+		return
+	}
+	file := CustomFile{Path: fmt.Sprint(f["path"]), Content: fmt.Sprint(f["content"])}
+	if f["content"] == nil {
+		file.Content = ""
+	}
+	for _, field := range sortedKeys(stringKeys(f)) {
+		value := f[field]
+		switch field {
+		case "path", "content":
+		case "encoding":
+			switch value {
+			case "b64", "base64":
+				file.Encoding = "base64"
+			case "text/plain":
+			default:
+				c.unsupportedKey(path+".encoding", "use plain or base64 content")
+				return
+			}
+		case "permissions":
+			file.Mode = fmt.Sprint(value)
+		case "owner":
+			if value != "root" && value != "root:root" {
+				c.unsupportedKey(path+".owner", "add a script in custom/scripts changing the owner")
+			}
+		case "defer":
+		default:
+			c.unsupportedKey(path+"."+field, "")
+		}
+	}
+	c.files = append(c.files, file)
+}
+
+// ignition converts an Ignition configuration.
+//
+// Parameters:
+//   - document: The JSON document.
+//
+// Returns:
+//   - error: An error if the document is not a JSON object.
+func (c *converter) ignition(document string) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(document), &doc); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(stringKeys(doc)) {
+		section, _ := doc[key].(map[string]interface{})
+		switch key {
+		case "ignition":
+		case "passwd":
+			for _, field := range sortedKeys(stringKeys(section)) {
+				list, _ := section[field].([]interface{})
+				switch field {
+				case "users":
+					for i, item := range list {
+						c.ignitionUser(fmt.Sprintf("passwd.users.%d", i), item)
+					}
+				case "groups":
+					for i, item := range list {
+						g, _ := item.(map[string]interface{})
+						group := map[string]interface{}{"name": g["name"]}
+						if gid, ok := g["gid"].(float64); ok {
+							group["gid"] = int(gid)
+						}
+						for _, f := range sortedKeys(stringKeys(g)) {
+							if f != "name" && f != "gid" {
+								c.unsupportedKey(fmt.Sprintf("passwd.groups.%d.%s", i, f), "")
+							}
+						}
+						c.groups = append(c.groups, group)
+					}
+				default:
+					c.unsupportedKey("passwd."+field, "")
+				}
+			}
+		case "storage":
+			for _, field := range sortedKeys(stringKeys(section)) {
+				if field != "files" {
+					c.unsupportedKey("storage."+field, "")
+					continue
+				}
+				list, _ := section[field].([]interface{})
+				for i, item := range list {
+					c.ignitionFile(fmt.Sprintf("storage.files.%d", i), item)
+				}
+			}
+		case "systemd":
+			units, _ := section["units"].([]interface{})
+			for i, item := range units {
+				c.ignitionUnit(fmt.Sprintf("systemd.units.%d", i), item)
+			}
+		case "kernelArguments":
+			c.kernelArgs = append(c.kernelArgs, stringValues(section["shouldExist"])...)
+			if section["shouldNotExist"] != nil {
+				c.unsupportedKey("kernelArguments.shouldNotExist", "")
+			}
+		default:
+			c.unsupportedKey(key, "")
+		}
+	}
+	return nil
+}
+
+// ignitionUser converts an entry of the Ignition passwd.users list.
+//
+// Parameters:
+//   - path: The path of the entry in the document.
+//   - item: The entry.
+func (c *converter) ignitionUser(path string, item interface{}) {
+	u, _ := item.(map[string]interface{})
+	name, _ := u["name"].(string)
+	user := map[string]interface{}{"username": name}
+	if name != "root" && u["noCreateHome"] != true {
+		user["createHomeDir"] = true
+	}
+	for _, field := range sortedKeys(stringKeys(u)) {
+		value := u[field]
+		switch field {
+		case "name", "noCreateHome":
+		case "passwordHash":
+			user["encryptedPassword"] = fmt.Sprint(value)
+		case "sshAuthorizedKeys":
+			user["sshKeys"] = interfaceList(stringValues(value))
+		case "uid":
+			if uid, ok := value.(float64); ok {
+				user["uid"] = int(uid)
+			}
+		case "primaryGroup":
+			user["primaryGroup"] = fmt.Sprint(value)
+		case "groups":
+			user["secondaryGroups"] = interfaceList(stringValues(value))
+		default:
+			c.unsupportedKey(path+"."+field, "")
+		}
+	}
+	c.users = append(c.users, user)
+}
+
+// ignitionFile converts an entry of the Ignition storage.files list.
+//
+// Parameters:
+//   - path: The path of the entry in the document.
+//   - item: The entry.
+func (c *converter) ignitionFile(path string, item interface{}) {
+	f, _ := item.(map[string]interface{})
+	file := CustomFile{Path: fmt.Sprint(f["path"])}
+	for _, field := range sortedKeys(stringKeys(f)) {
+		value := f[field]
+		switch field {
+		case "path", "overwrite":
+		case "contents":
+			contents, _ := value.(map[string]interface{})
+			source, _ := contents["source"].(string)
+			content, encoding, ok := decodeDataURL(source)
+			if !ok || contents["compression"] != nil {
+				c.unsupportedKey(path+".contents", "add the content of the file inline")
+				return
+			}
+			file.Content, file.Encoding = content, encoding
+		case "mode":
+			if mode, ok := value.(float64); ok {
+				file.Mode = fmt.Sprintf("%04o", int(mode))
+			}
+		case "user", "group":
+			owner, _ := value.(map[string]interface{})
+			if owner["name"] != "root" && owner["id"] != float64(0) {
+				c.unsupportedKey(path+"."+field, "add a script in custom/scripts changing the owner")
+			}
+		default:
+			c.unsupportedKey(path+"."+field, "")
+		}
+	}
+	c.files = append(c.files, file)
+}
+
+// ignitionUnit converts an entry of the Ignition systemd.units list.
+//
+// Parameters:
+//   - path: The path of the entry in the document.
+//   - item: The entry.
+func (c *converter) ignitionUnit(path string, item interface{}) {
+	u, _ := item.(map[string]interface{})
+	name, _ := u["name"].(string)
+	for _, field := range sortedKeys(stringKeys(u)) {
+		value := u[field]
+		switch field {
+		case "name":
+		case "enabled":
+			if value == true {
+				c.enable = append(c.enable, name)
+			} else {
+				c.disable = append(c.disable, name)
+			}
+		case "contents":
+			c.files = append(c.files, CustomFile{Path: "/etc/systemd/system/" + name, Content: fmt.Sprint(value)})
+		default:
+			c.unsupportedKey(path+"."+field, "")
+		}
+	}
+}
+
+// decodeDataURL decodes the content of a data URL, as Ignition embeds files.
+//
+// Parameters:
+//   - source: The URL, e.g. "data:,hello%20world" or "data:;base64,aGk=".
+//
+// Returns:
+//   - string: The content.
+//   - string: "base64" if the content is base64 encoded, or empty.
+//   - bool: False if source is not a data URL or is malformed.
+func decodeDataURL(source string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(source, "data:")
+	if !ok {
+		return "", "", false
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", "", false
+	}
+	if strings.HasSuffix(meta, ";base64") {
+		if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+			return "", "", false
+		}
+		return data, "base64", true
+	}
+	content, err := url.PathUnescape(data)
+	if err != nil {
+		return "", "", false
+	}
+	return content, "", true
+}
+
+// xmlNode is an element of an AutoYaST profile.
+type xmlNode struct {
+	// XMLName is the element name.
+	XMLName xml.Name
+	// Text is the character data of the element.
+	Text string `xml:",chardata"`
+	// Children are the child elements.
+	Children []xmlNode `xml:",any"`
+}
+
+// child returns the first child element with a name, or nil.
+func (n *xmlNode) child(name string) *xmlNode {
+	for i := range n.Children {
+		if n.Children[i].XMLName.Local == name {
+			return &n.Children[i]
+		}
+	}
+	return nil
+}
+
+// text returns the trimmed character data of the child element with a
+// name, or empty.
+func (n *xmlNode) text(name string) string {
+	if c := n.child(name); c != nil {
+		return strings.TrimSpace(c.Text)
+	}
+	return ""
+}
+
+// autoyast converts an AutoYaST profile.
+//
+// Parameters:
+//   - document: The XML document.
+//
+// Returns:
+//   - error: An error if the document is not an AutoYaST profile.
+func (c *converter) autoyast(document string) error {
+	var profile xmlNode
+	if err := xml.Unmarshal([]byte(document), &profile); err != nil {
+		return err
+	}
+	if profile.XMLName.Local != "profile" {
+		return fmt.Errorf("the root element is %s, not profile", profile.XMLName.Local)
+	}
+	for _, section := range profile.Children {
+		name := section.XMLName.Local
+		switch name {
+		case "users":
+			for i, u := range section.Children {
+				c.autoyastUser(fmt.Sprintf("users.%d", i), u)
+			}
+		case "groups":
+			for i, g := range section.Children {
+				group := map[string]interface{}{"name": g.text("groupname")}
+				if gid, err := strconv.Atoi(g.text("gid")); err == nil {
+					group["gid"] = gid
+				}
+				for _, f := range g.Children {
+					if f.XMLName.Local != "groupname" && f.XMLName.Local != "gid" {
+						c.unsupportedKey(fmt.Sprintf("groups.%d.%s", i, f.XMLName.Local), "")
+					}
+				}
+				c.groups = append(c.groups, group)
+			}
+		case "timezone":
+			if tz := section.text("timezone"); tz != "" {
+				c.osConfig["time"] = map[string]interface{}{"timezone": tz}
+			}
+			for _, f := range section.Children {
+				if f.XMLName.Local != "timezone" {
+					c.unsupportedKey("timezone."+f.XMLName.Local, "")
+				}
+			}
+		case "keyboard":
+			layout := section.text("keymap")
+			if keymap, ok := autoyastKeymaps[layout]; ok {
+				c.osConfig["keymap"] = keymap
+			} else if layout != "" {
+				c.unsupportedKey("keyboard.keymap", "set operatingSystem.keymap to the console keymap of "+layout)
+			}
+		case "software":
+			for _, s := range section.Children {
+				if s.XMLName.Local != "packages" {
+					c.unsupportedKey("software."+s.XMLName.Local, "")
+					continue
+				}
+				for _, p := range s.Children {
+					c.packages = append(c.packages, strings.TrimSpace(p.Text))
+				}
+			}
+		case "files":
+			for i, f := range section.Children {
+				c.autoyastFile(fmt.Sprintf("files.%d", i), f)
+			}
+		case "services-manager":
+			services := section.child("services")
+			if services == nil {
+				continue
+			}
+			for _, list := range services.Children {
+				for _, s := range list.Children {
+					switch list.XMLName.Local {
+					case "enable":
+						c.enable = append(c.enable, strings.TrimSpace(s.Text))
+					case "disable":
+						c.disable = append(c.disable, strings.TrimSpace(s.Text))
+					}
+				}
+			}
+		case "ntp-client":
+			if servers := section.child("ntp_servers"); servers != nil {
+				for _, s := range servers.Children {
+					c.ntp = append(c.ntp, s.text("address"))
+				}
+			}
+		case "networking":
+			if dns := section.child("dns"); dns != nil && dns.text("hostname") != "" {
+				c.hostname(dns.text("hostname"))
+			}
+			c.unsupportedKey("networking", "add the network configuration as nmstate files in network/")
+		case "bootloader":
+			if global := section.child("global"); global != nil {
+				c.kernelArgs = append(c.kernelArgs, strings.Fields(global.text("append"))...)
+			}
+		case "scripts":
+			c.unsupportedKey("scripts", "add the scripts in custom/scripts")
+		default:
+			c.unsupportedKey(name, "")
+		}
+	}
+	return nil
+}
+
+// autoyastUser converts an entry of the AutoYaST users list.
+//
+// Parameters:
+//   - path: The path of the entry in the document.
+//   - u: The entry.
+func (c *converter) autoyastUser(path string, u xmlNode) {
+	name := u.text("username")
+	user := map[string]interface{}{"username": name}
+	if name != "root" {
+		user["createHomeDir"] = true
+	}
+	encrypted := u.text("encrypted") == "true"
+	for _, f := range u.Children {
+		field := f.XMLName.Local
+		switch field {
+		case "username", "encrypted":
+		case "user_password":
+			if encrypted {
+				user["encryptedPassword"] = strings.TrimSpace(f.Text)
+			} else {
+				user["password"] = strings.TrimSpace(f.Text)
+			}
+		case "uid":
+			if uid, err := strconv.Atoi(strings.TrimSpace(f.Text)); err == nil {
+				user["uid"] = uid
+			}
+		case "authorized_keys":
+			var keys []string
+			for _, k := range f.Children {
+				keys = append(keys, strings.TrimSpace(k.Text))
+			}
+			user["sshKeys"] = interfaceList(keys)
+		default:
+			c.unsupportedKey(path+"."+field, "")
+		}
+	}
+	c.users = append(c.users, user)
+}
+
+// autoyastFile converts an entry of the AutoYaST files list.
+//
+// Parameters:
+//   - path: The path of the entry in the document.
+//   - f: The entry.
+func (c *converter) autoyastFile(path string, f xmlNode) {
+	file := CustomFile{Path: f.text("file_path")}
+	for _, field := range f.Children {
+		switch field.XMLName.Local {
+		case "file_path":
+		case "file_contents":
+			file.Content = strings.TrimPrefix(field.Text, "\n")
+		case "file_permissions":
+			file.Mode = strings.TrimSpace(field.Text)
+			if len(file.Mode) == 3 {
+				file.Mode = "0" + file.Mode
+			}
+		case "file_owner":
+			if owner := strings.TrimSpace(field.Text); owner != "root" && owner != "root.root" && owner != "root:root" {
+				c.unsupportedKey(path+".file_owner", "add a script in custom/scripts changing the owner")
+			}
+		default:
+			c.unsupportedKey(path+"."+field.XMLName.Local, "")
+		}
+	}
+	c.files = append(c.files, file)
+}
+
+// stringValues returns a string, or the strings of a list, as a list.
+func stringValues(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		var out []string
+		for _, item := range val {
+			out = append(out, fmt.Sprint(item))
+		}
+		return out
+	}
+	return nil
+}
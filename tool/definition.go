@@ -0,0 +1,75 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/definition"
+)
+
+// GenerateDefinition validates a typed configuration and returns its YAML representation.
+//
+// It lets Go programs build configurations with the generated structs of the
+// definition package, catching shape errors at compile time, while still going
+// through the same validation pipeline as Generate.
+//
+// Parameters:
+//   - def: The typed configuration.
+//   - opts: The generation options.
+//
+// Returns:
+//   - *Result: The generated YAML configuration and any warnings.
+//   - error: An error if conversion, validation or generation fails.
+func GenerateDefinition(def *definition.Definition, opts Options) (*Result, error) {
+	input, err := DefinitionToMap(def)
+	if err != nil {
+		return nil, err
+	}
+	return Generate(input, opts)
+}
+
+// DefinitionToMap converts a typed configuration into the generic map form
+// accepted by Generate.
+//
+// Parameters:
+//   - def: The typed configuration.
+//
+// Returns:
+//   - map[string]interface{}: The configuration as nested maps.
+//   - error: An error if the configuration cannot be converted.
+func DefinitionToMap(def *definition.Definition) (map[string]interface{}, error) {
+	raw, err := json.Marshal(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode definition: %w", err)
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return nil, fmt.Errorf("failed to decode definition: %w", err)
+	}
+	return input, nil
+}
+
+// MapToDefinition converts a generic configuration map into a typed configuration.
+//
+// Unknown fields are rejected, so the conversion doubles as a shape check.
+//
+// Parameters:
+//   - input: The configuration as nested maps.
+//
+// Returns:
+//   - *definition.Definition: The typed configuration.
+//   - error: An error if the map does not match the definition structs.
+func MapToDefinition(input map[string]interface{}) (*definition.Definition, error) {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var def definition.Definition
+	if err := dec.Decode(&def); err != nil {
+		return nil, fmt.Errorf("configuration does not match the definition types: %w", err)
+	}
+	return &def, nil
+}
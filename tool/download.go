@@ -0,0 +1,280 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/e-minguez/eib-mcp/baseimage"
+)
+
+// BaseImagesDir is the directory of an image configuration directory that
+// holds base images, which image.baseImage names are relative to.
+const BaseImagesDir = "base-images"
+
+// partialSuffix is appended to the name of a base image while it downloads,
+// so an interrupted download can be resumed and is never mistaken for the
+// image.
+const partialSuffix = ".part"
+
+// Download describes a base image download.
+type Download struct {
+	// URL is the downloaded URL.
+	URL string `json:"url"`
+	// Path is the downloaded file.
+	Path string `json:"path"`
+	// BaseImage is the value of image.baseImage that refers to the file.
+	BaseImage string `json:"baseImage"`
+	// Size is the file size in bytes.
+	Size int64 `json:"size"`
+	// ResumedFrom is the number of bytes kept from an interrupted download.
+	ResumedFrom int64 `json:"resumedFrom,omitempty"`
+	// AlreadyPresent is true if the file existed with the expected checksum
+	// and was not downloaded again.
+	AlreadyPresent bool `json:"alreadyPresent,omitempty"`
+	// Checksum is the verification of the file.
+	Checksum *baseimage.Verification `json:"checksum"`
+}
+
+// DownloadRequest holds the parameters of DownloadBaseImage.
+type DownloadRequest struct {
+	// URL is the base image URL.
+	URL string
+	// Dir is the image configuration directory; the image is stored in its
+	// BaseImagesDir.
+	Dir string
+	// FileName is the name to store the image under. If empty, the last
+	// element of the URL path is used.
+	FileName string
+	// Expected is the expected SHA-256 or SHA-512 digest, in hexadecimal.
+	Expected string
+	// ChecksumURL is the URL of the published checksum file, used when
+	// Expected is empty.
+	ChecksumURL string
+	// Overwrite replaces an existing image whose checksum does not match.
+	Overwrite bool
+	// Progress, if not nil, is called as the download advances with the
+	// bytes downloaded so far and the total size, or -1 if unknown.
+	Progress func(done, total int64)
+}
+
+// DownloadFileName returns the name a base image is stored under.
+//
+// Parameters:
+//   - req: The download request.
+//
+// Returns:
+//   - string: The file name.
+//   - error: An error if the name is empty or not a plain file name.
+func DownloadFileName(req DownloadRequest) (string, error) {
+	name := req.FileName
+	if name == "" {
+		trimmed := strings.TrimRight(strings.SplitN(strings.SplitN(req.URL, "?", 2)[0], "#", 2)[0], "/")
+		name = trimmed[strings.LastIndex(trimmed, "/")+1:]
+	}
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("cannot store a base image as %q; pass a plain fileName", name)
+	}
+	return name, nil
+}
+
+// DownloadBaseImage downloads a base image into the base-images directory
+// of an image configuration directory and verifies its checksum.
+//
+// The image is downloaded to a ".part" file first, and a download
+// interrupted earlier is resumed with an HTTP range request when the server
+// supports it. The file only gets its final name once its checksum matches;
+// otherwise it is deleted. An existing image with the expected checksum is
+// not downloaded again.
+//
+// Parameters:
+//   - ctx: The context controlling the download.
+//   - req: What to download and where.
+//   - opts: The options; HTTP and Offline are used.
+//
+// Returns:
+//   - *Download: The download report.
+//   - error: A *Error of KindNetwork if the download fails, of KindChecksum if
+//     the image does not match its checksum, a *ConflictError if a different
+//     image exists and req.Overwrite is false, or an error if the checksum
+//     cannot be obtained or the file cannot be written.
+func DownloadBaseImage(ctx context.Context, req DownloadRequest, opts Options) (*Download, error) {
+	name, err := DownloadFileName(req)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Offline {
+		return nil, classify(KindNetwork, errors.New("base images cannot be downloaded in offline mode"))
+	}
+	expected, source := req.Expected, "argument"
+	if expected == "" {
+		if req.ChecksumURL == "" {
+			return nil, errors.New("an expected checksum or checksum URL is required to verify the download")
+		}
+		if expected, err = PublishedChecksum(ctx, req.ChecksumURL, name, opts); err != nil {
+			return nil, err
+		}
+		source = req.ChecksumURL
+	}
+
+	dir := filepath.Join(req.Dir, BaseImagesDir)
+	dest := filepath.Join(dir, name)
+	result := &Download{URL: req.URL, Path: dest, BaseImage: name}
+	if _, err := os.Stat(dest); err == nil {
+		verification, err := baseimage.Verify(dest, expected, source)
+		if err != nil {
+			return nil, err
+		}
+		if verification.Match {
+			result.AlreadyPresent, result.Checksum = true, verification
+			result.Size, _ = fileSize(dest)
+			return result, nil
+		}
+		if !req.Overwrite {
+			return nil, &ConflictError{Root: req.Dir, Conflicts: []Conflict{{
+				Path:   filepath.Join(BaseImagesDir, name),
+				Reason: fmt.Sprintf("a different image exists (%s %s)", verification.Algorithm, verification.Actual),
+			}}}
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	part := dest + partialSuffix
+	endStep := opts.Trace.Step("download " + req.URL)
+	result.ResumedFrom, err = fetchResumable(ctx, req.URL, part, opts, req.Progress)
+	endStep()
+	if err != nil {
+		return nil, err
+	}
+	endStep = opts.Trace.Step("checksum")
+	verification, err := baseimage.Verify(part, expected, source)
+	endStep()
+	if err != nil {
+		return nil, err
+	}
+	if !verification.Match {
+		os.Remove(part)
+		return nil, classify(KindChecksum, fmt.Errorf("the %s checksum of the downloaded image is %s but %s was expected (from %s); the download was deleted", verification.Algorithm, verification.Actual, verification.Expected, source))
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return nil, fmt.Errorf("failed to move the download to %s: %w", dest, err)
+	}
+	result.Checksum = verification
+	result.Size, _ = fileSize(dest)
+	return result, nil
+}
+
+// fetchResumable downloads a URL to a file, continuing from the bytes the
+// file already holds if the server honours range requests.
+//
+// Parameters:
+//   - ctx: The context controlling the download.
+//   - url: The URL.
+//   - path: The file, created or appended to.
+//   - opts: The options; the HTTP client of HTTP is used, without its timeout.
+//   - progress: Called as the download advances, or nil.
+//
+// Returns:
+//   - int64: The number of bytes kept from a previous attempt.
+//   - error: A *Error of KindNetwork if the download fails, or an error if
+//     the file cannot be written.
+func fetchResumable(ctx context.Context, url, path string, opts Options, progress func(done, total int64)) (int64, error) {
+	// Images take longer than the timeout of network checks to download, so
+	// only the transport of the configured client is reused.
+	client := &http.Client{}
+	if opts.HTTP != nil && opts.HTTP.Client != nil {
+		client.Transport = opts.HTTP.Client.Transport
+	}
+
+	offset, _ := fileSize(path)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, classify(KindNetwork, err)
+	}
+	if offset > 0 {
+		httpReq.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, classify(KindNetwork, fmt.Errorf("failed to download %s: %w", url, err))
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The previous attempt already got every byte.
+		return offset, nil
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the range, so the download starts over.
+		flags |= os.O_TRUNC
+		offset = 0
+	default:
+		return 0, classify(KindNetwork, fmt.Errorf("failed to download %s: %s", url, resp.Status))
+	}
+
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	var w io.Writer = f
+	if progress != nil {
+		w = &progressWriter{w: f, done: offset, total: total, report: progress}
+		progress(offset, total)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return 0, classify(KindNetwork, fmt.Errorf("download of %s interrupted, call the tool again to resume: %w", url, err))
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// progressStep is the number of bytes between progress reports.
+const progressStep = 8 << 20
+
+// progressWriter reports the progress of a download every progressStep bytes.
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	reported int64
+	report   func(done, total int64)
+}
+
+// Write implements io.Writer.
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.done-p.reported >= progressStep || p.done == p.total {
+		p.reported = p.done
+		p.report(p.done, p.total)
+	}
+	return n, err
+}
+
+// fileSize returns the size of a file, or 0 if it does not exist.
+func fileSize(path string) (int64, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return st.Size(), nil
+}
@@ -0,0 +1,306 @@
+package tool
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/e-minguez/eib-mcp/schema"
+)
+
+// kubernetesVersionPattern finds an RKE2 or K3s version, e.g. in the output
+// of "rke2 --version": "rke2 version v1.30.3+rke2r1 (1a2b3c4)".
+var kubernetesVersionPattern = regexp.MustCompile(`v?([0-9]+\.[0-9]+\.[0-9]+\+(?:rke2r|k3s)[0-9]+)`)
+
+// rpmArches are the architecture suffixes of "rpm -qa" package names.
+var rpmArches = []string{".x86_64", ".aarch64", ".noarch", ".i586", ".i686"}
+
+// installKernelArgs are the kernel arguments the installation of the base
+// image sets, which a definition must not repeat.
+var installKernelArgs = []string{"BOOT_IMAGE", "root", "initrd", "ro", "rw", "resume", "rootflags"}
+
+// SystemDescription describes an existing edge node, as gathered from the
+// running system, for DraftFromSystem.
+type SystemDescription struct {
+	// Hostname is the node hostname.
+	Hostname string `json:"hostname,omitempty"`
+	// Arch is the machine architecture, as printed by "uname -m".
+	Arch string `json:"arch,omitempty"`
+	// Packages lists the installed packages, as names or as printed by
+	// "rpm -qa", e.g. "vim-9.1.0836-1.1.x86_64".
+	Packages []string `json:"packages,omitempty"`
+	// Users lists the local users.
+	Users []SystemUser `json:"users,omitempty"`
+	// Services lists the enabled systemd units.
+	Services []string `json:"services,omitempty"`
+	// KernelArgs lists the kernel arguments, as in /proc/cmdline.
+	KernelArgs []string `json:"kernelArgs,omitempty"`
+	// Timezone is the time zone, e.g. "Europe/Berlin".
+	Timezone string `json:"timezone,omitempty"`
+	// NTPServers lists the NTP servers and pools in use.
+	NTPServers []string `json:"ntpServers,omitempty"`
+	// Keymap is the console keymap, e.g. "de".
+	Keymap string `json:"keymap,omitempty"`
+	// Kubernetes describes the Kubernetes installation, if any.
+	Kubernetes *SystemKubernetes `json:"kubernetes,omitempty"`
+}
+
+// SystemUser describes a local user of an existing edge node.
+type SystemUser struct {
+	// Username is the login name.
+	Username string `json:"username"`
+	// UID is the user ID, or nil if unknown.
+	UID *int `json:"uid,omitempty"`
+	// PrimaryGroup is the name of the primary group.
+	PrimaryGroup string `json:"primaryGroup,omitempty"`
+	// SecondaryGroups lists the names of the other groups.
+	SecondaryGroups []string `json:"secondaryGroups,omitempty"`
+	// SSHKeys lists the authorized SSH public keys.
+	SSHKeys []string `json:"sshKeys,omitempty"`
+}
+
+// SystemKubernetes describes the Kubernetes installation of an existing edge
+// node.
+type SystemKubernetes struct {
+	// Version is the RKE2 or K3s version, e.g. "v1.30.3+rke2r1", or the
+	// output of "rke2 --version".
+	Version string `json:"version"`
+	// Role is the node type, "server" or "agent". Defaults to "server".
+	Role string `json:"role,omitempty"`
+}
+
+// Draft is the result of DraftFromSystem.
+type Draft struct {
+	// Config is the drafted configuration.
+	Config map[string]interface{} `json:"-"`
+	// Findings lists what must be reviewed, as draft-review warnings, and
+	// what was left out, as draft-omitted notes.
+	Findings []Finding `json:"findings"`
+}
+
+// DraftFromSystem drafts a configuration reproducing an existing edge node,
+// so that devices installed by hand can move to image-based management.
+//
+// The draft installs the node's packages, users, enabled services, kernel
+// arguments, time settings, keymap and Kubernetes version on a self-installing
+// ISO of SUSE Linux Micro 6.0. What a running system does not reveal, such as
+// the base image it was installed from, its install disk and the passwords
+// of its users, is filled with defaults or left out and reported as
+// draft-review warnings. What the base image or EIB already provides, such as
+// system users, the Kubernetes packages and installation kernel arguments,
+// is left out and reported as draft-omitted notes.
+//
+// Parameters:
+//   - desc: The description of the node.
+//
+// Returns:
+//   - *Draft: The drafted configuration and its findings.
+//   - error: An error if the architecture or the Kubernetes version or role
+//     is not recognized.
+func DraftFromSystem(desc SystemDescription) (*Draft, error) {
+	arch := desc.Arch
+	switch arch {
+	case "":
+		arch = "x86_64"
+	case "x86_64", "aarch64":
+	case "amd64":
+		arch = "x86_64"
+	case "arm64":
+		arch = "aarch64"
+	default:
+		return nil, fmt.Errorf("architecture %q is not supported; use x86_64 or aarch64", desc.Arch)
+	}
+	var omitted []string
+	config, osConfig := draftSkeleton(arch, desc.Hostname)
+	review := []string{
+		"image.baseImage: the base image the node was installed from is unknown; set it to the image in base-images/",
+		"operatingSystem.isoConfiguration.installDevice: the disk the node boots from is unknown; set it to the disk to install to",
+	}
+	if desc.Arch == "" {
+		review = append(review, "image.arch: the architecture is unknown; set it to the output of uname -m")
+	}
+
+	var packages, skipped []string
+	seen := map[string]bool{}
+	for _, p := range desc.Packages {
+		name := rpmName(strings.TrimSpace(p))
+		switch {
+		case name == "" || seen[name]:
+			continue
+		case strings.HasPrefix(name, "gpg-pubkey") || strings.HasPrefix(name, "rke2") || strings.HasPrefix(name, "k3s") || strings.HasPrefix(name, "kernel-"):
+			skipped = append(skipped, name)
+		default:
+			packages = append(packages, name)
+		}
+		seen[name] = true
+	}
+	if len(skipped) > 0 {
+		omitted = append(omitted, fmt.Sprintf("operatingSystem.packages.packageList: %s are installed by the base image or by EIB with Kubernetes", strings.Join(skipped, ", ")))
+	}
+	if len(packages) > 0 {
+		osConfig["packages"] = map[string]interface{}{"packageList": interfaceList(packages)}
+		review = append(review,
+			"operatingSystem.packages.packageList: the list holds the packages of the base image too; remove those, which EIB need not install",
+			"operatingSystem.packages: EIB resolves the packages from SUSE Customer Center or other repositories; set sccRegistrationCode, or add additionalRepos")
+	}
+
+	var users []interface{}
+	for _, u := range desc.Users {
+		if u.UID != nil && *u.UID != 0 && (*u.UID < 1000 || *u.UID == 65534) {
+			omitted = append(omitted, fmt.Sprintf("operatingSystem.users: %s is a system user, which the base image creates", u.Username))
+			continue
+		}
+		user := map[string]interface{}{"username": u.Username}
+		if u.UID != nil && *u.UID != 0 {
+			user["uid"] = *u.UID
+		}
+		if u.PrimaryGroup != "" && u.Username != "root" {
+			user["primaryGroup"] = u.PrimaryGroup
+		}
+		if len(u.SecondaryGroups) > 0 {
+			user["secondaryGroups"] = interfaceList(u.SecondaryGroups)
+		}
+		if len(u.SSHKeys) > 0 {
+			user["sshKeys"] = interfaceList(u.SSHKeys)
+		}
+		if len(u.SSHKeys) == 0 {
+			review = append(review, fmt.Sprintf("operatingSystem.users.%d: the password of %s is unknown; set encryptedPassword to its hash in /etc/shadow, or add sshKeys", len(users), u.Username))
+		}
+		users = append(users, user)
+	}
+	if len(users) > 0 {
+		osConfig["users"] = users
+	}
+
+	var services []string
+	for _, s := range desc.Services {
+		if strings.HasPrefix(s, "rke2-") || strings.HasPrefix(s, "k3s") {
+			omitted = append(omitted, fmt.Sprintf("operatingSystem.systemd.enable: %s is enabled by EIB with Kubernetes", s))
+			continue
+		}
+		services = append(services, s)
+	}
+	if len(services) > 0 {
+		osConfig["systemd"] = map[string]interface{}{"enable": interfaceList(services)}
+		review = append(review, "operatingSystem.systemd.enable: the list holds the units the base image enables too; remove those")
+	}
+
+	var kernelArgs []string
+	for _, arg := range desc.KernelArgs {
+		name, _, _ := strings.Cut(arg, "=")
+		install := false
+		for _, a := range installKernelArgs {
+			install = install || name == a
+		}
+		if install {
+			omitted = append(omitted, fmt.Sprintf("operatingSystem.kernelArgs: %s is set by the installation of the base image", name))
+			continue
+		}
+		kernelArgs = append(kernelArgs, arg)
+	}
+	if len(kernelArgs) > 0 {
+		osConfig["kernelArgs"] = interfaceList(kernelArgs)
+	}
+
+	if desc.Timezone != "" || len(desc.NTPServers) > 0 {
+		t := map[string]interface{}{}
+		if desc.Timezone != "" {
+			t["timezone"] = desc.Timezone
+		}
+		if len(desc.NTPServers) > 0 {
+			t["ntp"] = map[string]interface{}{"servers": interfaceList(desc.NTPServers)}
+		}
+		osConfig["time"] = t
+	}
+	if desc.Keymap != "" {
+		osConfig["keymap"] = desc.Keymap
+	}
+
+	if k := desc.Kubernetes; k != nil {
+		m := kubernetesVersionPattern.FindStringSubmatch(k.Version)
+		if m == nil {
+			return nil, fmt.Errorf("kubernetes version %q is not an RKE2 or K3s version; use a version such as v1.30.3+rke2r1", k.Version)
+		}
+		config["kubernetes"] = map[string]interface{}{"version": "v" + m[1]}
+		switch k.Role {
+		case "", "server":
+			review = append(review, "kubernetes.nodes: the draft builds a single-node cluster; add the other nodes, with an apiVIP, if the node was one of several")
+		case "agent":
+			review = append(review, "kubernetes: the node is an agent of a cluster the draft does not describe; add kubernetes/config/agent.yaml with the server and token of the cluster")
+		default:
+			return nil, fmt.Errorf("kubernetes role %q is not supported; use server or agent", k.Role)
+		}
+	}
+	if desc.Hostname != "" {
+		review = append(review, fmt.Sprintf("operatingSystem: EIB sets the hostname from the network configuration; add network/%s.yaml to keep the hostname %s", desc.Hostname, desc.Hostname))
+	}
+
+	d := &Draft{Config: config, Findings: []Finding{}}
+	d.Findings = append(d.Findings, NewFindings("draft-review", SeverityWarning, review)...)
+	d.Findings = append(d.Findings, NewFindings("draft-omitted", SeverityInfo, omitted)...)
+	return d, nil
+}
+
+// draftSkeleton returns the configuration a draft starts from: a
+// self-installing ISO of SUSE Linux Micro 6.0, installing to /dev/sda. The
+// callers report the defaults to review in terms of their input.
+//
+// Parameters:
+//   - arch: The architecture, x86_64 or aarch64.
+//   - hostname: The node hostname, naming the output image, or empty.
+//
+// Returns:
+//   - map[string]interface{}: The configuration.
+//   - map[string]interface{}: Its operatingSystem section.
+func draftSkeleton(arch, hostname string) (map[string]interface{}, map[string]interface{}) {
+	output := "eib-image.iso"
+	if hostname != "" {
+		output = hostname + ".iso"
+	}
+	osConfig := map[string]interface{}{
+		"isoConfiguration": map[string]interface{}{"installDevice": "/dev/sda"},
+	}
+	config := map[string]interface{}{
+		"apiVersion": schema.LatestVersion(),
+		"image": map[string]interface{}{
+			"imageType":       "iso",
+			"arch":            arch,
+			"baseImage":       fmt.Sprintf("SL-Micro.%s-6.0-Base-SelfInstall-GM.install.iso", arch),
+			"outputImageName": output,
+		},
+		"operatingSystem": osConfig,
+	}
+	return config, osConfig
+}
+
+// rpmName returns the name of a package given as printed by "rpm -qa",
+// without its version, release and architecture.
+//
+// Parameters:
+//   - p: The package, e.g. "vim-9.1.0836-1.1.x86_64", or a plain name.
+//
+// Returns:
+//   - string: The package name, e.g. "vim".
+func rpmName(p string) string {
+	trimmed := p
+	for _, arch := range rpmArches {
+		trimmed = strings.TrimSuffix(trimmed, arch)
+	}
+	if trimmed == p {
+		return p
+	}
+	parts := strings.Split(trimmed, "-")
+	if len(parts) < 3 || parts[len(parts)-2] == "" || parts[len(parts)-2][0] < '0' || parts[len(parts)-2][0] > '9' {
+		return p
+	}
+	return strings.Join(parts[:len(parts)-2], "-")
+}
+
+// interfaceList converts strings into a decoded JSON list.
+func interfaceList(values []string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return list
+}
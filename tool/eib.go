@@ -0,0 +1,135 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultEIBTimeout bounds a single `eib validate` run.
+const defaultEIBTimeout = 2 * time.Minute
+
+// EIBValidator validates generated configurations with the real Edge Image
+// Builder, guaranteeing parity with the actual builder.
+//
+// It runs `eib validate` either from a local binary or, if Image is set,
+// inside the EIB container with the configuration directory mounted at /eib.
+type EIBValidator struct {
+	// Binary is the path of a local eib binary. Used when Image is empty.
+	Binary string
+	// Image is the EIB container image, e.g. "registry.suse.com/edge/3.2/edge-image-builder:1.1.0".
+	Image string
+	// Runtime is the container runtime used with Image. Defaults to "podman".
+	Runtime string
+	// Timeout bounds each run. Defaults to two minutes.
+	Timeout time.Duration
+	// Offline prevents the container runtime from pulling Image, which must
+	// then already be present locally.
+	Offline bool
+}
+
+// Validate runs `eib validate` against a configuration.
+//
+// The configuration and its artifacts are written to a temporary
+// configuration directory, which is removed afterwards. Findings reported by EIB are returned one per line;
+// a failure to run EIB at all is returned as an error.
+//
+// Parameters:
+//   - yamlConfig: The YAML configuration to validate.
+//   - artifacts: The files accompanying the configuration, such as custom files.
+//
+// Returns:
+//   - []string: The findings reported by EIB, empty if the configuration is valid.
+//   - error: An error if EIB could not be run.
+func (v *EIBValidator) Validate(yamlConfig string, artifacts []Artifact) ([]string, error) {
+	dir, err := os.MkdirTemp("", "eib-validate-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create configuration directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, DefinitionFile), []byte(yamlConfig), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write definition: %w", err)
+	}
+	if _, err := WriteArtifacts(dir, artifacts, false); err != nil {
+		return nil, err
+	}
+
+	timeout := v.Timeout
+	if timeout == 0 {
+		timeout = defaultEIBTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := v.command(ctx, dir)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return nil, nil
+	case errors.As(err, &exitErr) && ctx.Err() == nil:
+		findings := parseEIBOutput(output.String())
+		if len(findings) == 0 {
+			findings = []string{fmt.Sprintf("eib validate exited with status %d", exitErr.ExitCode())}
+		}
+		return findings, nil
+	default:
+		return nil, fmt.Errorf("failed to run eib validate: %w", err)
+	}
+}
+
+// command builds the `eib validate` command for a configuration directory.
+//
+// Parameters:
+//   - ctx: The context bounding the run.
+//   - dir: The configuration directory containing the definition file.
+//
+// Returns:
+//   - *exec.Cmd: The command to run.
+func (v *EIBValidator) command(ctx context.Context, dir string) *exec.Cmd {
+	if v.Image == "" {
+		binary := v.Binary
+		if binary == "" {
+			binary = "eib"
+		}
+		return exec.CommandContext(ctx, binary, "validate", "--config-dir", dir, "--definition-file", DefinitionFile)
+	}
+
+	runtime := v.Runtime
+	if runtime == "" {
+		runtime = "podman"
+	}
+	args := []string{"run", "--rm", "-v", dir + ":/eib:Z"}
+	if v.Offline {
+		args = append(args, "--pull=never")
+	}
+	args = append(args, v.Image, "validate", "--definition-file", DefinitionFile)
+	return exec.CommandContext(ctx, runtime, args...)
+}
+
+// parseEIBOutput extracts the findings from `eib validate` output.
+//
+// Parameters:
+//   - output: The combined standard output and error of the command.
+//
+// Returns:
+//   - []string: The non-empty output lines, trimmed.
+func parseEIBOutput(output string) []string {
+	var findings []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			findings = append(findings, line)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,61 @@
+package tool
+
+// ErrorKind classifies why a configuration could not be generated, so that
+// callers can branch on the class of failure instead of parsing messages.
+type ErrorKind string
+
+const (
+	// KindUndefinedVariable means a ${NAME} reference has no value.
+	KindUndefinedVariable ErrorKind = "undefined-variable"
+	// KindEncryption means a plaintext password could not be encrypted.
+	KindEncryption ErrorKind = "encryption"
+	// KindSchema means the configuration does not match the EIB schema.
+	KindSchema ErrorKind = "schema-validation"
+	// KindCompatibility means the configuration uses fields the target EIB release does not support.
+	KindCompatibility ErrorKind = "release-compatibility"
+	// KindRule means the configuration violates a cross-field rule.
+	KindRule ErrorKind = "cross-field-rule"
+	// KindArtifact means a custom file, certificate, GPG key, script, manifest, network configuration or PEM block is invalid.
+	KindArtifact ErrorKind = "invalid-artifact"
+	// KindNetwork means a network check could not be completed.
+	KindNetwork ErrorKind = "network-check"
+	// KindSecret means a vault:// or env:// secret reference could not be resolved.
+	KindSecret ErrorKind = "secret-reference"
+	// KindPasswordPolicy means a plaintext password violates the password policy.
+	KindPasswordPolicy ErrorKind = "password-policy"
+	// KindChecksum means a downloaded file does not match its expected checksum.
+	KindChecksum ErrorKind = "checksum-mismatch"
+)
+
+// Error is a generation failure of a known kind.
+type Error struct {
+	// Kind classifies the failure.
+	Kind ErrorKind
+	// Err is the underlying error, whose message is reported unchanged.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// classify wraps a non-nil error with its kind.
+//
+// Parameters:
+//   - kind: The kind of failure.
+//   - err: The error, or nil.
+//
+// Returns:
+//   - error: The classified error, or nil if err is nil.
+func classify(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
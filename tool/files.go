@@ -0,0 +1,239 @@
+package tool
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// osFilesDir is the image configuration directory whose content EIB copies
+// to the root of the built system.
+const osFilesDir = "os-files"
+
+// defaultFileMode is the mode of custom files that do not specify one.
+const defaultFileMode = "0644"
+
+// forbiddenFilePrefixes lists the directories that are mounted over at
+// runtime, so files placed there would never be visible.
+var forbiddenFilePrefixes = []string{"/dev", "/proc", "/run", "/sys", "/tmp"}
+
+// writableFilePrefixes lists the directories that are writable on a running
+// SL Micro system. Files elsewhere live in the read-only root file system,
+// where package updates may overwrite them.
+var writableFilePrefixes = []string{"/etc", "/home", "/opt", "/root", "/srv", "/usr/local", "/var"}
+
+// CustomFile is a file to place into the built system.
+type CustomFile struct {
+	// Path is the absolute path of the file in the built system, e.g. "/etc/motd".
+	Path string `json:"path"`
+	// Content is the file content, encoded according to Encoding.
+	Content string `json:"content"`
+	// Encoding is "base64" for binary content, or empty for plain text.
+	Encoding string `json:"encoding,omitempty"`
+	// Mode is the octal file mode, e.g. "0600". Defaults to "0644".
+	Mode string `json:"mode,omitempty"`
+}
+
+// Artifact is a file of the image configuration directory that accompanies
+// the definition file.
+type Artifact struct {
+	// Path is the path relative to the image configuration directory, e.g. "os-files/etc/motd".
+	Path string `json:"path"`
+	// Content is the file content, encoded according to Encoding.
+	Content string `json:"content"`
+	// Encoding is "base64" for binary content, or empty for plain text.
+	Encoding string `json:"encoding,omitempty"`
+	// Mode is the octal file mode, e.g. "0644".
+	Mode string `json:"mode"`
+	// Secret is true if the content holds generated secrets, which must be
+	// stored like passwords.
+	Secret bool `json:"secret,omitempty"`
+}
+
+// PrepareFiles validates custom files and turns them into os-files artifacts.
+//
+// Paths must be absolute and normalized, must not be repeated and must not
+// point into runtime file systems such as /proc or /tmp. Files in the
+// read-only root file system, and world-writable or setuid/setgid modes, are
+// reported as warnings.
+//
+// Parameters:
+//   - files: The custom files.
+//
+// Returns:
+//   - []Artifact: The artifacts, ordered by path.
+//   - []string: The warnings.
+//   - error: An error listing every invalid file.
+func PrepareFiles(files []CustomFile) ([]Artifact, []string, error) {
+	var artifacts []Artifact
+	var warnings, errs []string
+	seen := map[string]bool{}
+
+	for _, f := range files {
+		if msg := checkFilePath(f.Path); msg != "" {
+			errs = append(errs, fmt.Sprintf("%q: %s", f.Path, msg))
+			continue
+		}
+		if seen[f.Path] {
+			errs = append(errs, fmt.Sprintf("%q: duplicate path", f.Path))
+			continue
+		}
+		seen[f.Path] = true
+
+		switch f.Encoding {
+		case "":
+		case "base64":
+			if _, err := base64.StdEncoding.DecodeString(f.Content); err != nil {
+				errs = append(errs, fmt.Sprintf("%q: invalid base64 content: %v", f.Path, err))
+				continue
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%q: unknown encoding %q (expected \"base64\" or none)", f.Path, f.Encoding))
+			continue
+		}
+
+		mode := f.Mode
+		if mode == "" {
+			mode = defaultFileMode
+		}
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || perm > 0o7777 {
+			errs = append(errs, fmt.Sprintf("%q: invalid mode %q (expected an octal mode such as \"0644\")", f.Path, f.Mode))
+			continue
+		}
+		if perm&0o002 != 0 {
+			warnings = append(warnings, fmt.Sprintf("file %s is world-writable (mode %s)", f.Path, mode))
+		}
+		if perm&0o6000 != 0 {
+			warnings = append(warnings, fmt.Sprintf("file %s has the setuid or setgid bit set (mode %s)", f.Path, mode))
+		}
+		if !hasPathPrefix(f.Path, writableFilePrefixes) {
+			warnings = append(warnings, fmt.Sprintf("file %s is outside %s, in the read-only root file system where package updates may overwrite it", f.Path, strings.Join(writableFilePrefixes, ", ")))
+		}
+
+		artifacts = append(artifacts, Artifact{
+			Path:     osFilesDir + f.Path,
+			Content:  f.Content,
+			Encoding: f.Encoding,
+			Mode:     fmt.Sprintf("%04o", perm),
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid custom files:\n- %s", strings.Join(errs, "\n- "))
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Path < artifacts[j].Path })
+	return artifacts, warnings, nil
+}
+
+// checkFilePath validates the target path of a custom file.
+//
+// Parameters:
+//   - p: The absolute path in the built system.
+//
+// Returns:
+//   - string: The problem with the path, or empty if it is valid.
+func checkFilePath(p string) string {
+	switch {
+	case p == "":
+		return "path is required"
+	case !strings.HasPrefix(p, "/"):
+		return "path must be absolute"
+	case path.Clean(p) != p || p == "/":
+		return "path must be a normalized file path without \".\", \"..\" or trailing slashes"
+	case hasPathPrefix(p, forbiddenFilePrefixes):
+		return fmt.Sprintf("path must not be below %s, which are mounted over at runtime", strings.Join(forbiddenFilePrefixes, ", "))
+	}
+	return ""
+}
+
+// hasPathPrefix reports whether p is one of the directories or below one of them.
+func hasPathPrefix(p string, dirs []string) bool {
+	for _, dir := range dirs {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteArtifacts writes artifacts below an image configuration directory,
+// creating directories as needed and applying each artifact's mode. Secret
+// artifacts keep only the owner bits of their mode, e.g. 0600 for 0644, so
+// that only their owner can read them.
+//
+// Nothing is written if an artifact already exists and overwrite is false.
+//
+// Parameters:
+//   - root: The image configuration directory.
+//   - artifacts: The artifacts to write.
+//   - overwrite: Whether existing files may be replaced.
+//
+// Returns:
+//   - []string: The written paths.
+//   - error: A *ConflictError if files exist, or an error if a path escapes the root or a file cannot be written.
+func WriteArtifacts(root string, artifacts []Artifact, overwrite bool) ([]string, error) {
+	paths := make([]string, 0, len(artifacts))
+	for _, a := range artifacts {
+		if !filepath.IsLocal(a.Path) {
+			return nil, fmt.Errorf("refusing to write %q outside of %q", a.Path, root)
+		}
+		paths = append(paths, a.Path)
+	}
+	if err := CheckOutput(root, paths, overwrite); err != nil {
+		return nil, err
+	}
+
+	written := make([]string, 0, len(artifacts))
+	for _, a := range artifacts {
+		content := []byte(a.Content)
+		if a.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(a.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 content for %q: %w", a.Path, err)
+			}
+			content = decoded
+		}
+		perm, err := strconv.ParseUint(a.Mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode %q for %q", a.Mode, a.Path)
+		}
+		if a.Secret {
+			perm &= 0o700
+		}
+
+		p := filepath.Join(root, a.Path)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %q: %w", a.Path, err)
+		}
+		if err := os.WriteFile(p, content, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write %q: %w", a.Path, err)
+		}
+		if err := os.Chmod(p, os.FileMode(perm)&os.ModePerm|fileModeBits(perm)); err != nil {
+			return nil, fmt.Errorf("failed to set mode of %q: %w", a.Path, err)
+		}
+		written = append(written, p)
+	}
+	return written, nil
+}
+
+// fileModeBits converts the setuid, setgid and sticky bits of a Unix mode
+// into their os.FileMode equivalents.
+func fileModeBits(perm uint64) os.FileMode {
+	var mode os.FileMode
+	if perm&0o4000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if perm&0o2000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if perm&0o1000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
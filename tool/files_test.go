@@ -0,0 +1,34 @@
+package tool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteArtifactsSecretMode checks that secret artifacts are only
+// readable by their owner, whatever their mode.
+func TestWriteArtifactsSecretMode(t *testing.T) {
+	root := t.TempDir()
+	artifacts := []Artifact{
+		{Path: "kubernetes/config/server.yaml", Content: "token: abc\n", Mode: "0644", Secret: true},
+		{Path: "custom/scripts/10-setup.sh", Content: "#!/bin/sh\n", Mode: "0755", Secret: true},
+		{Path: "os-files/etc/motd", Content: "hello\n", Mode: "0644"},
+	}
+	if _, err := WriteArtifacts(root, artifacts, false); err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range map[string]os.FileMode{
+		"kubernetes/config/server.yaml": 0o600,
+		"custom/scripts/10-setup.sh":    0o700,
+		"os-files/etc/motd":             0o644,
+	} {
+		info, err := os.Stat(filepath.Join(root, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: mode %o, want %o", rel, got, want)
+		}
+	}
+}
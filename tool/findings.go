@@ -0,0 +1,279 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Finding is a single result of a validation check: a schema error, a
+// cross-field rule violation, or a warning about an artifact or the
+// network configuration.
+//
+// Every finding names the rule that produced it, so that a warning known to
+// be harmless can be suppressed by rule ID (see Options.Suppress).
+type Finding struct {
+	// RuleID is the ID of the rule or check, e.g. "helm-chart-repository".
+	RuleID string `json:"rule"`
+	// Severity is the severity of the finding.
+	Severity Severity `json:"severity"`
+	// Path is the field or file the finding is about, if known, e.g.
+	// "operatingSystem.users.0".
+	Path string `json:"path,omitempty"`
+	// Message describes the finding.
+	Message string `json:"message"`
+	// Suggestion tells how to resolve the finding, if known.
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Violation is a single rule violation.
+type Violation = Finding
+
+// Text renders the finding as "path: message; suggestion". The path is
+// omitted when the message already names it.
+func (f Finding) Text() string {
+	text := f.Message
+	if f.Path != "" && !strings.HasPrefix(text, f.Path) {
+		text = f.Path + ": " + text
+	}
+	if f.Suggestion != "" {
+		text += "; " + f.Suggestion
+	}
+	return text
+}
+
+// String renders the finding as "[rule-id] path: message; suggestion".
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s", f.RuleID, f.Text())
+}
+
+// findingPathPattern matches the field or file a message starts with, e.g.
+// "kubernetes.nodes.1: ".
+var findingPathPattern = regexp.MustCompile(`^(\(root\)|[A-Za-z0-9_./\[\]-]+): `)
+
+// suggestionVerbs start the trailing "; <suggestion>" of a message.
+var suggestionVerbs = []string{"add ", "configure ", "mark ", "omit ", "remove ", "set ", "use "}
+
+// NewFindings turns the messages of a check into findings.
+//
+// A leading "path: " becomes the Path of the finding, and a trailing
+// "; <suggestion>" starting with a verb such as "remove" or "use" becomes
+// its Suggestion.
+//
+// Parameters:
+//   - rule: The ID of the rule or check.
+//   - severity: The severity of the findings.
+//   - messages: The messages, one per finding.
+//
+// Returns:
+//   - []Finding: The findings, in message order.
+func NewFindings(rule string, severity Severity, messages []string) []Finding {
+	findings := make([]Finding, 0, len(messages))
+	for _, msg := range messages {
+		f := Finding{RuleID: rule, Severity: severity, Message: msg}
+		if m := findingPathPattern.FindStringSubmatch(msg); m != nil {
+			f.Path, f.Message = m[1], msg[len(m[0]):]
+		}
+		if i := strings.LastIndex(f.Message, "; "); i >= 0 {
+			for _, verb := range suggestionVerbs {
+				if strings.HasPrefix(f.Message[i+2:], verb) {
+					f.Message, f.Suggestion = f.Message[:i], f.Message[i+2:]
+					break
+				}
+			}
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// FindingsError reports every error-severity finding of a check at once,
+// so that all of them can be fixed in one round.
+type FindingsError struct {
+	// Summary names the check that failed, e.g. "configuration is invalid".
+	Summary string `json:"-"`
+	// Findings lists the findings.
+	Findings []Finding `json:"findings"`
+}
+
+// Error implements the error interface, listing one finding per line.
+func (e *FindingsError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Summary + ":\n")
+	for _, f := range e.Findings {
+		fmt.Fprintf(&b, "- %s\n", f.Text())
+	}
+	return b.String()
+}
+
+// ErrorFindings returns the findings an error reports.
+//
+// Parameters:
+//   - err: An error returned by Generate.
+//
+// Returns:
+//   - []Finding: The findings, or nil if err does not report findings.
+func ErrorFindings(err error) []Finding {
+	var rules *RuleError
+	if errors.As(err, &rules) {
+		return rules.Violations
+	}
+	var findings *FindingsError
+	if errors.As(err, &findings) {
+		return findings.Findings
+	}
+	return nil
+}
+
+// RuleInfo describes a rule or check that produces findings.
+type RuleInfo struct {
+	// ID identifies the rule in findings and in Options.Suppress.
+	ID string `json:"id"`
+	// Severity is the severity of the findings of the rule.
+	Severity Severity `json:"severity"`
+	// Description explains what the rule checks.
+	Description string `json:"description"`
+}
+
+// checkRules describes the checks other than the cross-field rules.
+var checkRules = []RuleInfo{
+	{ID: "schema", Severity: SeverityError, Description: "The configuration must match the EIB JSON schema of its apiVersion."},
+	{ID: "schema-unknown-field", Severity: SeverityWarning, Description: "Unknown fields accepted in permissive mode are passed through to EIB."},
+	{ID: "compatibility", Severity: SeverityError, Description: "Fields must be supported by the target EIB release."},
+	{ID: "compatibility-deprecated", Severity: SeverityWarning, Description: "Fields deprecated in the target EIB release are reported."},
+	{ID: "password-policy", Severity: SeverityWarning, Description: "Plaintext passwords must meet the password policy; violations are errors when the policy is enforced."},
+	{ID: "custom-file", Severity: SeverityWarning, Description: "Custom files should not be world-writable, setuid or outside the writable directories."},
+	{ID: "certificate", Severity: SeverityWarning, Description: "Certificates should be CA certificates that are valid and not about to expire."},
+	{ID: "gpg-key", Severity: SeverityWarning, Description: "GPG keys should be valid and agree with the package verification settings."},
+	{ID: "script", Severity: SeverityWarning, Description: "Combustion scripts should have a numeric prefix and a shebang line."},
+	{ID: "network-config", Severity: SeverityWarning, Description: "nmstate network configurations should identify their interfaces and reach their gateways."},
+	{ID: "pem", Severity: SeverityWarning, Description: "Embedded private keys should not be readable by other users and certificates should not expire soon."},
+	{ID: "plaintext-secret", Severity: SeverityWarning, Description: "Secrets should be given as secret references rather than literal values."},
+	{ID: "eib-validate", Severity: SeverityWarning, Description: "Findings of `eib validate`, when the server runs it."},
+	{ID: "image-advisory", Severity: SeverityWarning, Description: "Embedded images with known critical or high vulnerabilities, when the server checks them."},
+	{ID: "manifest-url", Severity: SeverityWarning, Description: "Manifest URLs that cannot be downloaded or are not Kubernetes YAML, when checkManifests is set."},
+	{ID: "manifest-schema", Severity: SeverityError, Description: "Kubernetes objects of manifests must match the API of their built-in kind, e.g. a Deployment needs a selector matching its pod template."},
+	{ID: "manifest-snapshot", Severity: SeverityInfo, Description: "Manifest URLs replaced by local snapshots, when snapshotManifests is set."},
+	{ID: "install-order", Severity: SeverityWarning, Description: "Custom resources whose CRD is installed by another chart or manifest, reported by check_install_order."},
+	{ID: "missing-crd", Severity: SeverityWarning, Description: "Custom resources whose CRD no chart or manifest installs, reported by check_install_order."},
+	{ID: "node-pattern", Severity: SeverityError, Description: "Hostname patterns of kubernetes.nodes must hold valid numeric ranges and expand to valid hostnames."},
+	{ID: "node-pattern-expansion", Severity: SeverityInfo, Description: "Hostname patterns of kubernetes.nodes expanded to one node per hostname."},
+	{ID: "cluster-secret", Severity: SeverityError, Description: "Cluster secrets can only be generated for a configuration installing Kubernetes, into configuration files that do not set them already."},
+	{ID: "node-config-split", Severity: SeverityError, Description: "agent.yaml must not hold server-only options when the cluster has agents, and server.yaml must not set cluster-init when it has several servers."},
+	{ID: "node-config", Severity: SeverityWarning, Description: "Kubernetes configuration files should not be unused or set options EIB derives from the nodes."},
+	{ID: "sizing-quorum", Severity: SeverityWarning, Description: "Clusters should have an odd number of servers, at most 7, so that etcd gains fault tolerance from each one, reported by advise_sizing."},
+	{ID: "sizing-single-point-of-failure", Severity: SeverityWarning, Description: "A single server should not manage agent nodes, since its failure leaves their pods unmanaged, reported by advise_sizing."},
+	{ID: "sizing-single-node", Severity: SeverityInfo, Description: "Single-node clusters have no redundancy, reported by advise_sizing."},
+	{ID: "sizing-control-plane", Severity: SeverityWarning, Description: "Clusters should have enough servers for their agents, reported by advise_sizing."},
+	{ID: "sizing-workload", Severity: SeverityWarning, Description: "Well-known charts such as Rancher or Longhorn need enough nodes for their replicas, reported by advise_sizing."},
+	{ID: "missing-namespace", Severity: SeverityWarning, Description: "Charts and manifests should not install resources into a namespace that no chart or manifest creates."},
+	{ID: "compose-conflict", Severity: SeverityWarning, Description: "Fragments composed by compose_config should not set the same field to different values, since only the later one is kept."},
+	{ID: "compose-ownership", Severity: SeverityError, Description: "Fragments composed by compose_config must not set fields inside a section another fragment owns, nor own overlapping sections."},
+	{ID: "layer-conflict", Severity: SeverityWarning, Description: "Site overlays of generate_layered should not replace values their environment overlay sets, since the site then departs from its environment."},
+	{ID: "layer-redundant", Severity: SeverityWarning, Description: "Overlays of generate_layered should not set a field to the value a lower layer already sets."},
+	{ID: "layer-override", Severity: SeverityInfo, Description: "Values of the base template replaced or removed by an overlay of generate_layered."},
+	{ID: "draft-review", Severity: SeverityWarning, Description: "Settings draft_from_system and convert_config could not learn from their input and filled with defaults or left out, to review before building."},
+	{ID: "draft-omitted", Severity: SeverityInfo, Description: "Packages, users, services and kernel arguments of the running system draft_from_system left out, since the base image or EIB provides them."},
+	{ID: "convert-unsupported", Severity: SeverityWarning, Description: "Settings of cloud-init, Ignition or AutoYaST documents with no EIB equivalent, left out by convert_config."},
+	{ID: "yaml-input", Severity: SeverityInfo, Description: "Repairs made while reading a configuration given as YAML text."},
+}
+
+// RuleCatalog lists every rule and check that produces findings.
+//
+// Parameters:
+//   - rules: The cross-field rules in use; nil selects DefaultRules.
+//   - policy: The rule policy, or nil.
+//
+// Returns:
+//   - []RuleInfo: The checks, followed by the cross-field rules, with the
+//     severity set by the policy; disabled rules have SeverityOff.
+func RuleCatalog(rules []Rule, policy *RulePolicy) []RuleInfo {
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	var catalog []RuleInfo
+	for _, r := range checkRules {
+		if !fixedRules[r.ID] {
+			r.Severity = policy.severity(r.ID, r.Severity)
+		}
+		catalog = append(catalog, r)
+	}
+	for _, r := range rules {
+		catalog = append(catalog, RuleInfo{ID: r.ID, Severity: policy.severity(r.ID, r.Severity), Description: r.Description})
+	}
+	return catalog
+}
+
+// CheckSuppress verifies that rule IDs can be suppressed: they must exist,
+// and errors cannot be suppressed.
+//
+// Parameters:
+//   - ids: The rule IDs.
+//   - catalog: The rules in use, as returned by RuleCatalog.
+//
+// Returns:
+//   - error: An error naming the first ID that cannot be suppressed.
+func CheckSuppress(ids []string, catalog []RuleInfo) error {
+	severities := map[string]Severity{}
+	var known []string
+	for _, r := range catalog {
+		severities[r.ID] = r.Severity
+		known = append(known, r.ID)
+	}
+	for _, id := range ids {
+		severity, ok := severities[id]
+		if !ok {
+			sort.Strings(known)
+			return fmt.Errorf("unknown rule %q (known rules: %s)", id, strings.Join(known, ", "))
+		}
+		if severity == SeverityError {
+			return fmt.Errorf("rule %q reports errors, which cannot be suppressed", id)
+		}
+	}
+	return nil
+}
+
+// SuppressFindings removes the warnings and notes of suppressed rules.
+// Errors are always kept.
+//
+// Parameters:
+//   - findings: The findings.
+//   - suppress: The IDs of the suppressed rules.
+//
+// Returns:
+//   - []Finding: The remaining findings.
+//   - int: The number of findings removed.
+func SuppressFindings(findings []Finding, suppress []string) ([]Finding, int) {
+	if len(suppress) == 0 {
+		return findings, 0
+	}
+	suppressed := map[string]bool{}
+	for _, id := range suppress {
+		suppressed[id] = true
+	}
+	var kept []Finding
+	for _, f := range findings {
+		if f.Severity != SeverityError && suppressed[f.RuleID] {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept, len(findings) - len(kept)
+}
+
+// findingStrings renders findings with String.
+//
+// Parameters:
+//   - findings: The findings.
+//
+// Returns:
+//   - []string: One line per finding.
+func findingStrings(findings []Finding) []string {
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, f.String())
+	}
+	return lines
+}
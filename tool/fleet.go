@@ -0,0 +1,486 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// siteNamePattern restricts site names to DNS labels, which keeps them safe
+// to use as directory names.
+var siteNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// hostnamePattern matches RFC 1123 hostnames, which are safe to use as file names.
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9]*[A-Za-z0-9])?(\.[A-Za-z0-9]([-A-Za-z0-9]*[A-Za-z0-9])?)*$`)
+
+// Site describes one deployment site of a fleet.
+type Site struct {
+	// Name identifies the site and names its output directory.
+	Name string `json:"name"`
+	// APIVIP is the virtual IP of the site's Kubernetes API, if any.
+	APIVIP string `json:"apiVIP,omitempty"`
+	// Variables are site-specific values for ${NAME} references.
+	Variables map[string]string `json:"variables,omitempty"`
+	// Nodes are the machines of the site.
+	Nodes []SiteNode `json:"nodes,omitempty"`
+}
+
+// SiteNode describes one machine of a site.
+type SiteNode struct {
+	// Hostname is the node hostname.
+	Hostname string `json:"hostname"`
+	// Type is the Kubernetes node type, "server" or "agent".
+	Type string `json:"type,omitempty"`
+	// Initializer marks the node that initializes the cluster.
+	Initializer bool `json:"initializer,omitempty"`
+	// IP is the static address in CIDR notation, e.g. "192.168.1.10/24". Empty uses DHCP.
+	IP string `json:"ip,omitempty"`
+	// Gateway is the default gateway for a static address.
+	Gateway string `json:"gateway,omitempty"`
+	// Interface is the network interface name. Defaults to "eth0".
+	Interface string `json:"interface,omitempty"`
+	// MACAddress identifies the interface; EIB uses it to match the network file to the machine.
+	MACAddress string `json:"macAddress,omitempty"`
+	// DNS lists the DNS servers for a static address.
+	DNS []string `json:"dns,omitempty"`
+}
+
+// FleetResult holds the files generated for a fleet.
+type FleetResult struct {
+	// Files maps relative paths ("<site>/definition.yaml", "<site>/network/<hostname>.yaml") to their content.
+	Files map[string]string
+	// Warnings lists non-fatal findings, prefixed with the site name.
+	Warnings []string
+	// OutputImages lists the relative paths ("<site>/<outputImageName>") of
+	// the images EIB will build, which must not clobber existing files either.
+	OutputImages []string
+	// Checksums holds the SHA-256 of every file, keyed by the paths of Files.
+	Checksums map[string]string
+	// SecretFiles lists the definitions holding resolved secret references
+	// in plaintext.
+	SecretFiles []string
+}
+
+// ParseInventory decodes a fleet inventory.
+//
+// The inventory is either a JSON value (a list of sites, or an object with a
+// "sites" list) or a CSV document with a header row and one row per node.
+// CSV columns are site, hostname, type, initializer, ip, gateway, interface,
+// macAddress, dns (semicolon separated) and apiVIP; only site and hostname
+// are required.
+//
+// Parameters:
+//   - v: The decoded JSON inventory, or a string containing JSON or CSV.
+//
+// Returns:
+//   - []Site: The sites, in inventory order.
+//   - error: An error if the inventory is malformed.
+func ParseInventory(v interface{}) ([]Site, error) {
+	raw, isString := v.(string)
+	if isString && !strings.HasPrefix(strings.TrimSpace(raw), "[") && !strings.HasPrefix(strings.TrimSpace(raw), "{") {
+		return parseCSVInventory(raw)
+	}
+	if !isString {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode inventory: %w", err)
+		}
+		raw = string(encoded)
+	}
+
+	trimmed := strings.TrimSpace(raw)
+	if strings.HasPrefix(trimmed, "{") {
+		var wrapper struct {
+			Sites json.RawMessage `json:"sites"`
+		}
+		if err := json.Unmarshal([]byte(trimmed), &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid inventory: %w", err)
+		}
+		if wrapper.Sites == nil {
+			return nil, fmt.Errorf("invalid inventory: missing \"sites\"")
+		}
+		trimmed = string(wrapper.Sites)
+	}
+
+	var sites []Site
+	dec := json.NewDecoder(bytes.NewReader([]byte(trimmed)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sites); err != nil {
+		return nil, fmt.Errorf("invalid inventory: %w", err)
+	}
+	return sites, nil
+}
+
+// parseCSVInventory decodes a CSV inventory with one row per node.
+//
+// Parameters:
+//   - raw: The CSV document, including the header row.
+//
+// Returns:
+//   - []Site: The sites, in order of first appearance.
+//   - error: An error if the document is malformed.
+func parseCSVInventory(raw string) ([]Site, error) {
+	r := csv.NewReader(strings.NewReader(raw))
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV inventory: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("invalid CSV inventory: expected a header row and at least one node")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"site", "hostname"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("invalid CSV inventory: missing column %q", required)
+		}
+	}
+
+	var sites []Site
+	index := map[string]int{}
+	for line, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		node := SiteNode{
+			Hostname:   field("hostname"),
+			Type:       field("type"),
+			IP:         field("ip"),
+			Gateway:    field("gateway"),
+			Interface:  field("interface"),
+			MACAddress: field("macAddress"),
+		}
+		if s := field("initializer"); s != "" {
+			node.Initializer, err = strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CSV inventory: line %d: invalid initializer %q", line+2, s)
+			}
+		}
+		for _, server := range strings.Split(field("dns"), ";") {
+			if server = strings.TrimSpace(server); server != "" {
+				node.DNS = append(node.DNS, server)
+			}
+		}
+
+		name := field("site")
+		i, ok := index[name]
+		if !ok {
+			i = len(sites)
+			index[name] = i
+			sites = append(sites, Site{Name: name})
+		}
+		if vip := field("apiVIP"); vip != "" {
+			if sites[i].APIVIP != "" && sites[i].APIVIP != vip {
+				return nil, fmt.Errorf("invalid CSV inventory: line %d: conflicting apiVIP for site %q", line+2, name)
+			}
+			sites[i].APIVIP = vip
+		}
+		sites[i].Nodes = append(sites[i].Nodes, node)
+	}
+	return sites, nil
+}
+
+// GenerateFleet produces one validated configuration, plus network files for
+// statically addressed nodes, per site of an inventory.
+//
+// Each site starts from a copy of the template. ${SITE_NAME} and the site's
+// variables are substituted on top of opts.Variables; the site's nodes
+// replace kubernetes.nodes and its apiVIP sets kubernetes.network.apiVIP.
+// All sites are generated even if some fail, and the failures are reported
+// together.
+//
+// Parameters:
+//   - template: The base configuration shared by all sites.
+//   - sites: The sites to generate.
+//   - opts: The generation options.
+//
+// Returns:
+//   - *FleetResult: The generated files and warnings.
+//   - error: An error listing every site that could not be generated.
+func GenerateFleet(template map[string]interface{}, sites []Site, opts Options) (*FleetResult, error) {
+	if len(sites) == 0 {
+		return nil, fmt.Errorf("inventory contains no sites")
+	}
+
+	result := &FleetResult{Files: map[string]string{}}
+	var failures []string
+	seen := map[string]bool{}
+	for _, site := range sites {
+		if !siteNamePattern.MatchString(site.Name) {
+			failures = append(failures, fmt.Sprintf("site %q: name must be a lowercase DNS label", site.Name))
+			continue
+		}
+		if seen[site.Name] {
+			failures = append(failures, fmt.Sprintf("site %q: duplicate site name", site.Name))
+			continue
+		}
+		seen[site.Name] = true
+
+		files, outputImage, warnings, secrets, err := generateSite(template, site, opts)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("site %q: %v", site.Name, err))
+			continue
+		}
+		for path, content := range files {
+			result.Files[path] = content
+		}
+		if outputImage != "" {
+			result.OutputImages = append(result.OutputImages, outputImage)
+		}
+		for _, w := range warnings {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %s", site.Name, w))
+		}
+		if len(secrets) > 0 {
+			result.SecretFiles = append(result.SecretFiles, site.Name+"/"+DefinitionFile)
+		}
+	}
+
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("fleet generation failed:\n- %s", strings.Join(failures, "\n- "))
+	}
+	result.Checksums = FileChecksums(result.Files)
+	return result, nil
+}
+
+// generateSite produces the files of one site.
+//
+// Parameters:
+//   - template: The base configuration; it is not modified.
+//   - site: The site to generate.
+//   - opts: The generation options.
+//
+// Returns:
+//   - map[string]string: The generated files keyed by relative path.
+//   - string: The relative path of the image EIB will build, or empty if unset.
+//   - []string: The warnings.
+//   - []string: The fields whose secret references were resolved.
+//   - error: An error if the site's configuration or network files are invalid.
+func generateSite(template map[string]interface{}, site Site, opts Options) (map[string]string, string, []string, []string, error) {
+	config := deepCopyValue(template).(map[string]interface{})
+
+	vars := map[string]string{"SITE_NAME": site.Name}
+	for k, v := range opts.Variables {
+		vars[k] = v
+	}
+	for k, v := range site.Variables {
+		vars[k] = v
+	}
+	opts.Variables = vars
+
+	if site.APIVIP != "" || len(site.Nodes) > 0 {
+		k, _ := config["kubernetes"].(map[string]interface{})
+		if k == nil {
+			k = map[string]interface{}{}
+			config["kubernetes"] = k
+		}
+		if site.APIVIP != "" {
+			network, _ := k["network"].(map[string]interface{})
+			if network == nil {
+				network = map[string]interface{}{}
+				k["network"] = network
+			}
+			network["apiVIP"] = site.APIVIP
+		}
+		if len(site.Nodes) > 0 {
+			nodes := make([]interface{}, 0, len(site.Nodes))
+			for _, n := range site.Nodes {
+				node := map[string]interface{}{"hostname": n.Hostname}
+				if n.Type != "" {
+					node["type"] = n.Type
+				}
+				if n.Initializer {
+					node["initializer"] = true
+				}
+				nodes = append(nodes, node)
+			}
+			k["nodes"] = nodes
+		}
+	}
+
+	generated, err := Generate(config, opts)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	files := map[string]string{site.Name + "/" + DefinitionFile: generated.YAML}
+	var networks []NetworkConfig
+	for _, node := range site.Nodes {
+		if !hostnamePattern.MatchString(node.Hostname) {
+			return nil, "", nil, nil, fmt.Errorf("node %q: invalid hostname", node.Hostname)
+		}
+		if node.IP == "" {
+			continue
+		}
+		content, err := networkConfig(node)
+		if err != nil {
+			return nil, "", nil, nil, fmt.Errorf("node %q: %w", node.Hostname, err)
+		}
+		networks = append(networks, NetworkConfig{Hostname: node.Hostname, Content: content})
+	}
+	// The generated files get the checks of supplied ones, e.g. against a MAC
+	// address listed for two nodes.
+	networkArtifacts, networkWarnings, err := PrepareNetworkConfigs(networks, config)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+	for _, a := range networkArtifacts {
+		files[site.Name+"/"+a.Path] = a.Content
+	}
+	networkFindings, err := opts.RulePolicy.Check(NewFindings("network-config", SeverityWarning, networkWarnings))
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+	networkFindings, _ = SuppressFindings(networkFindings, opts.Suppress)
+	generated.Warnings = append(generated.Warnings, findingStrings(networkFindings)...)
+	var outputImage string
+	if generated.OutputImage != "" {
+		outputImage = site.Name + "/" + generated.OutputImage
+	}
+	return files, outputImage, generated.Warnings, generated.ResolvedSecrets, nil
+}
+
+// networkConfig renders the nmstate network file of a statically addressed node.
+//
+// Parameters:
+//   - node: The node, with IP set.
+//
+// Returns:
+//   - string: The nmstate YAML.
+//   - error: An error if an address is invalid.
+func networkConfig(node SiteNode) (string, error) {
+	ip, ipNet, err := net.ParseCIDR(node.IP)
+	if err != nil {
+		return "", fmt.Errorf("ip must be in CIDR notation, e.g. 192.168.1.10/24: %q", node.IP)
+	}
+	prefix, _ := ipNet.Mask.Size()
+	family, defaultRoute := "ipv4", "0.0.0.0/0"
+	if ip.To4() == nil {
+		family, defaultRoute = "ipv6", "::/0"
+	}
+
+	iface := node.Interface
+	if iface == "" {
+		iface = "eth0"
+	}
+	ifaceConfig := map[string]interface{}{
+		"name":  iface,
+		"type":  "ethernet",
+		"state": "up",
+		family: map[string]interface{}{
+			"enabled": true,
+			"dhcp":    false,
+			"address": []interface{}{
+				map[string]interface{}{"ip": ip.String(), "prefix-length": prefix},
+			},
+		},
+	}
+	if node.MACAddress != "" {
+		if _, err := net.ParseMAC(node.MACAddress); err != nil {
+			return "", fmt.Errorf("invalid macAddress %q", node.MACAddress)
+		}
+		ifaceConfig["mac-address"] = strings.ToUpper(node.MACAddress)
+	}
+	doc := map[string]interface{}{"interfaces": []interface{}{ifaceConfig}}
+
+	if node.Gateway != "" {
+		if net.ParseIP(node.Gateway) == nil {
+			return "", fmt.Errorf("invalid gateway %q", node.Gateway)
+		}
+		doc["routes"] = map[string]interface{}{
+			"config": []interface{}{
+				map[string]interface{}{
+					"destination":        defaultRoute,
+					"next-hop-address":   node.Gateway,
+					"next-hop-interface": iface,
+				},
+			},
+		}
+	}
+	if len(node.DNS) > 0 {
+		servers := make([]interface{}, 0, len(node.DNS))
+		for _, s := range node.DNS {
+			if net.ParseIP(s) == nil {
+				return "", fmt.Errorf("invalid DNS server %q", s)
+			}
+			servers = append(servers, s)
+		}
+		doc["dns-resolver"] = map[string]interface{}{"config": map[string]interface{}{"server": servers}}
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate network file: %w", err)
+	}
+	return string(out), nil
+}
+
+// WriteFiles writes generated files below a root directory, creating
+// directories as needed.
+//
+// Nothing is written if a file already exists and overwrite is false. Files
+// are readable by everyone, except those holding secrets, which only their
+// owner can read, as WriteArtifacts writes secret artifacts.
+//
+// Parameters:
+//   - root: The root directory.
+//   - files: The file contents keyed by relative path.
+//   - secrets: The relative paths of the files holding resolved or generated
+//     secrets.
+//   - overwrite: Whether existing files may be replaced.
+//
+// Returns:
+//   - []string: The written paths, sorted.
+//   - error: A *ConflictError if files exist, or an error if a path escapes the root or a file cannot be written.
+func WriteFiles(root string, files map[string]string, secrets []string, overwrite bool) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for rel := range files {
+		if !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("refusing to write %q outside of %q", rel, root)
+		}
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	if err := CheckOutput(root, paths, overwrite); err != nil {
+		return nil, err
+	}
+
+	written := make([]string, 0, len(paths))
+	for _, rel := range paths {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %q: %w", rel, err)
+		}
+		perm := os.FileMode(0o644)
+		for _, secret := range secrets {
+			if secret == rel {
+				perm = 0o600
+			}
+		}
+		if err := os.WriteFile(path, []byte(files[rel]), 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write %q: %w", rel, err)
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			return nil, fmt.Errorf("failed to set mode of %q: %w", rel, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
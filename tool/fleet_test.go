@@ -0,0 +1,52 @@
+package tool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteFilesModes checks that files holding secrets are only readable by
+// their owner, and that the other files stay readable by everyone.
+func TestWriteFilesModes(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"site-a/" + DefinitionFile: "apiVersion: \"1.2\"\n",
+		"site-b/" + DefinitionFile: "apiVersion: \"1.2\"\n",
+	}
+	if _, err := WriteFiles(root, files, []string{"site-b/" + DefinitionFile}, false); err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range map[string]os.FileMode{
+		"site-a/" + DefinitionFile: 0o644,
+		"site-b/" + DefinitionFile: 0o600,
+	} {
+		info, err := os.Stat(filepath.Join(root, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: mode %o, want %o", rel, got, want)
+		}
+	}
+}
+
+// TestWriteFilesOverwriteSecret checks that a secret file replacing a
+// readable one loses its permissions for others.
+func TestWriteFilesOverwriteSecret(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{DefinitionFile: "apiVersion: \"1.2\"\n"}
+	if _, err := WriteFiles(root, files, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteFiles(root, files, []string{DefinitionFile}, true); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(root, DefinitionFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0o600 {
+		t.Errorf("mode %o, want 600", got)
+	}
+}
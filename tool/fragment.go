@@ -0,0 +1,136 @@
+package tool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/e-minguez/eib-mcp/schema"
+)
+
+// FragmentReport is the result of ValidateFragment.
+type FragmentReport struct {
+	// Path is the dotted path of the fragment, e.g. "kubernetes.helm".
+	Path string `json:"path"`
+	// APIVersion is the apiVersion whose schema the fragment was checked
+	// against.
+	APIVersion string `json:"apiVersion"`
+	// Valid is true if the fragment has no error findings.
+	Valid bool `json:"valid"`
+	// Findings lists the schema errors and cross-field rule findings inside
+	// the fragment.
+	Findings []Finding `json:"findings"`
+}
+
+// ValidateFragment checks one section of a configuration, such as
+// kubernetes.helm, against the subtree of the schema describing it, so that
+// a section can be worked on before the rest of the configuration exists.
+//
+// The fragment is placed at its path in an otherwise empty configuration,
+// which is validated as generate_config would with ValidateOnly set: plaintext
+// passwords are accepted, and the cross-field rules run once the fragment
+// matches the schema. Only the findings inside the fragment are reported, so
+// the missing sections around it, such as image, are not; rules relating the
+// fragment to other sections, such as the API VIP of a multi-node cluster,
+// are only checked on the whole configuration.
+//
+// Parameters:
+//   - path: The dotted path of the fragment, e.g. "kubernetes.helm"; list
+//     items cannot be addressed, so pass the whole list instead.
+//   - fragment: The fragment, decoded from JSON or YAML.
+//   - apiVersion: The apiVersion whose schema to use, or empty for the latest.
+//   - opts: The options; Mode, Rules and RulePolicy are used.
+//
+// Returns:
+//   - *FragmentReport: The findings of the fragment.
+//   - error: An error if the apiVersion is unsupported or the path is not a
+//     field of its schema.
+func ValidateFragment(path string, fragment interface{}, apiVersion string, opts Options) (*FragmentReport, error) {
+	if apiVersion == "" {
+		apiVersion = schema.LatestVersion()
+	}
+	fields, err := schema.Fields(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := fields[path]; !ok || path == "apiVersion" {
+		return nil, fmt.Errorf("%q is not a section of apiVersion %s; %s", path, apiVersion, suggestPath(path, fields))
+	}
+
+	input := map[string]interface{}{"apiVersion": apiVersion}
+	parent := input
+	segments := strings.Split(path, ".")
+	for _, segment := range segments[:len(segments)-1] {
+		child := map[string]interface{}{}
+		parent[segment] = child
+		parent = child
+	}
+	parent[segments[len(segments)-1]] = fragment
+	if err := processPasswords(input, placeholderPassword); err != nil {
+		return nil, err
+	}
+
+	inside := func(f Finding) bool {
+		return f.Path == path || strings.HasPrefix(f.Path, path+".")
+	}
+	report := &FragmentReport{Path: path, APIVersion: apiVersion, Valid: true, Findings: []Finding{}}
+	warnings, err := validate(input, opts.Mode)
+	if err != nil && ErrorFindings(err) == nil {
+		return nil, err
+	}
+	for _, f := range append(ErrorFindings(err), warnings...) {
+		if inside(f) {
+			report.Findings = append(report.Findings, f)
+		}
+	}
+	for _, f := range report.Findings {
+		if f.Severity == SeverityError {
+			report.Valid = false
+		}
+	}
+	if !report.Valid {
+		return report, nil
+	}
+
+	rules := opts.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	for _, v := range RunRules(input, opts.RulePolicy.applyRules(rules)) {
+		if !inside(v) {
+			continue
+		}
+		report.Findings = append(report.Findings, v)
+		if v.Severity == SeverityError {
+			report.Valid = false
+		}
+	}
+	return report, nil
+}
+
+// suggestPath suggests the sections closest to an unknown fragment path.
+//
+// Parameters:
+//   - path: The unknown path.
+//   - fields: The fields of the schema, keyed by dotted path.
+//
+// Returns:
+//   - string: A suggestion starting with "use".
+func suggestPath(path string, fields map[string]string) string {
+	var sections []string
+	best := -1
+	for field, summary := range fields {
+		if strings.Contains(field, "[]") || !strings.HasPrefix(summary, "object") && !strings.HasPrefix(summary, "array") {
+			continue
+		}
+		d := editDistance(strings.ToLower(path), strings.ToLower(field))
+		switch {
+		case best < 0 || d < best:
+			best, sections = d, []string{field}
+		case d == best:
+			sections = append(sections, field)
+		}
+	}
+	sort.Strings(sections)
+	return "use a section such as " + strings.Join(sections, ", ")
+}
@@ -5,21 +5,214 @@
 package tool
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"path"
 	"strings"
+	"time"
 
+	"github.com/e-minguez/eib-mcp/httpcache"
 	"github.com/e-minguez/eib-mcp/schema"
 	"github.com/xeipuuv/gojsonschema"
 	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/yaml.v3"
 )
 
+// ValidationMode controls how fields unknown to the schema are handled.
+type ValidationMode string
+
+const (
+	// ValidationStrict rejects any field unknown to the schema. This catches
+	// typos such as "userss" and is the default.
+	ValidationStrict ValidationMode = "strict"
+	// ValidationPermissive passes unknown fields through to the output and
+	// reports them as warnings, for users tracking unreleased EIB features.
+	ValidationPermissive ValidationMode = "permissive"
+)
+
+// ParseValidationMode converts a string into a ValidationMode.
+//
+// Parameters:
+//   - s: The mode name, "strict" or "permissive".
+//
+// Returns:
+//   - ValidationMode: The parsed mode.
+//   - error: An error if the mode is unknown.
+func ParseValidationMode(s string) (ValidationMode, error) {
+	switch ValidationMode(s) {
+	case ValidationStrict, ValidationPermissive:
+		return ValidationMode(s), nil
+	default:
+		return "", fmt.Errorf("unknown validation mode %q (expected %q or %q)", s, ValidationStrict, ValidationPermissive)
+	}
+}
+
+// Options configures how configurations are validated and generated.
+//
+// The zero value is valid and selects strict validation.
+type Options struct {
+	// Mode selects strict or permissive handling of unknown fields.
+	Mode ValidationMode
+	// Rules are the cross-field rules evaluated after schema validation.
+	// If nil, DefaultRules is used.
+	Rules []Rule
+	// Variables are the values substituted for ${NAME} references.
+	Variables map[string]string
+	// EnvPrefix is the prefix of environment variables that can provide
+	// values for ${NAME} references. If empty, the environment is not used.
+	EnvPrefix string
+	// TargetRelease is the EIB release the configuration will be built with,
+	// e.g. "1.1". If set, fields too new for that release are rejected and
+	// deprecated fields are reported as warnings.
+	TargetRelease string
+	// Files are custom files to place into the built system. They are
+	// returned as os-files artifacts of the image configuration directory.
+	Files []CustomFile
+	// Certificates are CA certificates to install into the trust store of
+	// the built system. They are returned as certificates/ artifacts.
+	Certificates []Certificate
+	// GPGKeys are public keys verifying side-loaded RPMs and additional
+	// repositories. They are returned as rpms/gpg-keys/ artifacts.
+	GPGKeys []GPGKey
+	// Scripts are custom combustion scripts, in their intended execution
+	// order. They are returned as custom/scripts/ artifacts.
+	Scripts []Script
+	// Manifests are local Kubernetes manifests, checked against the API of
+	// their kinds and returned as kubernetes/manifests/ artifacts.
+	Manifests []Manifest
+	// KubernetesConfig holds the server.yaml and agent.yaml of the nodes,
+	// checked against the node types and returned as kubernetes/config/
+	// artifacts.
+	KubernetesConfig KubernetesConfig
+	// GenerateSecrets lists the shared secrets of the cluster to generate
+	// into KubernetesConfig, from ClusterSecretNames (see
+	// GenerateClusterSecrets).
+	GenerateSecrets []string
+	// NetworkConfigs are the nmstate network configurations of the nodes,
+	// returned as network/ artifacts.
+	NetworkConfigs []NetworkConfig
+	// RenumberScripts renames Scripts so that they run in list order,
+	// instead of rejecting names whose order differs from it.
+	RenumberScripts bool
+	// ValidateOnly runs the checks without the expensive steps: plaintext
+	// passwords are replaced by a placeholder instead of being hashed, and
+	// `eib validate` is not run. Result.YAML must then not be used.
+	ValidateOnly bool
+	// Canonicalize removes empty values and fills in defaults before
+	// validation (see Canonicalize); the changes are reported in Result.Changes.
+	Canonicalize bool
+	// EIB, if set, additionally validates the generated YAML with the real
+	// Edge Image Builder and reports its findings as warnings.
+	EIB *EIBValidator
+	// Advisories, if set, looks up the known vulnerabilities of the embedded
+	// images, unless ValidateOnly is set, and summarizes them in
+	// Result.Advisories.
+	Advisories *AdvisoryScanner
+	// CheckManifests downloads the manifests of kubernetes.manifests.urls
+	// and reports those that cannot be downloaded or are not Kubernetes
+	// YAML (see CheckManifestURLs).
+	CheckManifests bool
+	// SnapshotManifests replaces kubernetes.manifests.urls with snapshots of
+	// the manifests in the kubernetes/manifests artifacts (see
+	// SnapshotManifests), unless ValidateOnly is set, which checks them
+	// instead.
+	SnapshotManifests bool
+	// Helm renders charts for RenderChart. If nil, the helm binary on PATH
+	// is used.
+	Helm *HelmRenderer
+	// HTTP is the cache shared by network-dependent validations. If nil,
+	// those validations download without caching.
+	HTTP *httpcache.Cache
+	// Sandbox restricts the server paths tools may read or write. If nil,
+	// every path is allowed.
+	Sandbox *Sandbox
+	// Offline disables everything that contacts external services, making
+	// results deterministic on air-gapped hosts. Skipped network checks are
+	// reported as warnings.
+	Offline bool
+	// SecretEnvPrefix is the prefix of environment variables env:// secret
+	// references can read (see ResolveSecrets). If empty, env:// references
+	// are rejected.
+	SecretEnvPrefix string
+	// Vault resolves vault:// secret references. If nil, they are rejected.
+	Vault *VaultClient
+	// Signer is the key generated definitions are signed with when Sign is set.
+	Signer *Signer
+	// Sign returns a detached signature of the definition in
+	// Result.Signature. It requires Signer.
+	Sign bool
+	// Header prepends a comment header naming the Generator, the schema
+	// apiVersion, the generation time and the input hash to the definition.
+	Header bool
+	// Generator is the generator name and version written in the header.
+	Generator string
+	// PasswordPolicy, if set, checks plaintext passwords before they are
+	// hashed, reporting violations as errors or warnings per its Mode.
+	PasswordPolicy *PasswordPolicy
+	// Reproducible makes identical input yield a byte-identical definition:
+	// plaintext passwords are hashed with a salt derived from the username
+	// and password instead of a random one, and the header omits the
+	// generation time.
+	Reproducible bool
+	// Trace, if set, records how long the steps of the call take.
+	Trace *Trace
+	// RulePolicy, if set, changes the severity of rules or turns them off.
+	// It applies to the findings of every check other than the schema and
+	// compatibility checks.
+	RulePolicy *RulePolicy
+	// Suppress lists the IDs of rules whose warnings and notes are dropped
+	// from the result (see RuleCatalog). Errors cannot be suppressed; use
+	// CheckSuppress to reject such IDs.
+	Suppress []string
+}
+
+// Result is the outcome of a successful configuration generation.
+type Result struct {
+	// YAML is the generated configuration.
+	YAML string
+	// Warnings lists non-fatal findings, such as unknown fields accepted in
+	// permissive mode, rendered as "[rule-id] path: message".
+	Warnings []string
+	// Findings are the non-fatal findings with their rule, path and
+	// suggestion; Warnings renders them.
+	Findings []Finding
+	// Suppressed is the number of findings removed by Options.Suppress.
+	Suppressed int
+	// Artifacts are the files that accompany the definition in the image
+	// configuration directory, such as custom files under os-files/.
+	Artifacts []Artifact
+	// OutputImage is the name of the image EIB will build into the image
+	// configuration directory, from image.outputImageName.
+	OutputImage string
+	// Changes lists the normalizations applied when Options.Canonicalize is set.
+	Changes []string
+	// Checksums holds the SHA-256 of the definition and of every artifact,
+	// keyed by path relative to the image configuration directory.
+	Checksums map[string]string
+	// ResolvedSecrets lists the fields whose secret references were replaced
+	// by their values, which YAML then holds in plaintext.
+	ResolvedSecrets []string
+	// GeneratedSecrets lists the secrets generated into the Kubernetes
+	// configuration files when Options.GenerateSecrets is set; the artifacts
+	// holding them are marked Secret.
+	GeneratedSecrets []GeneratedSecret
+	// Signature is the ASCII-armored detached signature of YAML, written as
+	// SignatureFile, when Options.Sign is set.
+	Signature string
+	// ContentHash identifies the definition and artifacts, not the
+	// signature. It only changes when the generated files do, and with
+	// Options.Reproducible identical input always yields the same hash.
+	ContentHash string
+	// Advisories summarizes the known vulnerabilities of the embedded images
+	// when Options.Advisories is set.
+	Advisories []ImageAdvisory
+}
+
 // GenerateConfig validates the input map against the EIB schema and returns the YAML representation.
 //
-// It performs the following steps:
-// 1. Encrypts any plaintext passwords found in the input.
-// 2. Validates the input against the EIB JSON schema.
-// 3. Marshals the valid input into a YAML string.
+// It is equivalent to calling Generate with the default (strict) options and
+// discarding warnings.
 //
 // Parameters:
 //   - input: A map representing the configuration data.
@@ -28,41 +221,445 @@ import (
 //   - string: The generated YAML configuration.
 //   - error: An error if validation or generation fails.
 func GenerateConfig(input map[string]interface{}) (string, error) {
-	// 1. Process Passwords (encrypt plaintext 'password' fields)
+	result, err := Generate(input, Options{})
+	if err != nil {
+		return "", err
+	}
+	return result.YAML, nil
+}
+
+// Generate validates the input map against the EIB schema and returns the YAML representation.
+//
+// It performs the following steps:
+// 1. Substitutes ${NAME} variable references, resolves secret references, expands node hostname patterns (see ExpandNodePatterns) and canonicalizes the input if requested.
+// 2. Encrypts any plaintext passwords found in the input.
+// 3. Validates the input against the EIB JSON schema matching its apiVersion.
+// 4. Checks compatibility with the target EIB release, if any.
+// 5. Evaluates the cross-field rules.
+// 6. Prepares custom files, certificates, GPG keys, scripts, manifests, Kubernetes configuration files, with the generated cluster secrets if requested, and network configurations as artifacts and checks every embedded PEM block, then checks or snapshots the manifest URLs if requested.
+// 7. Marshals the valid input into a YAML string, after the metadata header if Options.Header is set.
+// 8. Optionally runs `eib validate` on the result (see Options.EIB) and looks up the known vulnerabilities of the embedded images (see Options.Advisories), unless Options.ValidateOnly is set, then applies the rule policy to the findings.
+// 9. Signs the result if Options.Sign is set.
+//
+// Parameters:
+//   - input: A map representing the configuration data.
+//   - opts: The generation options.
+//
+// Returns:
+//   - *Result: The generated YAML configuration and any warnings.
+//   - error: An error if validation or generation fails.
+func Generate(input map[string]interface{}, opts Options) (*Result, error) {
+	var inputHash string
+	if opts.Header {
+		var err error
+		if inputHash, err = InputHash(input); err != nil {
+			return nil, err
+		}
+	}
+
+	// 1. Substitute variables, resolve secrets and expand node hostname
+	// patterns, then canonicalize if requested
+	endStep := opts.Trace.Step("variable substitution")
+	if _, err := SubstituteVariables(input, opts.Variables, opts.EnvPrefix); err != nil {
+		return nil, classify(KindUndefinedVariable, err)
+	}
+	secrets, err := ResolveSecrets(input, opts)
+	if err != nil {
+		return nil, classify(KindSecret, err)
+	}
+	expansions, err := ExpandNodePatterns(input)
+	if err != nil {
+		return nil, classify(KindSchema, err)
+	}
+	var changes []string
+	if opts.Canonicalize {
+		changes = Canonicalize(input)
+	}
+	endStep()
+
+	// 2. Process Passwords (encrypt plaintext 'password' fields)
 	// We do this BEFORE validation so that 'password' is replaced by 'encryptedPassword',
 	// which complies with the strict schema.
-	if err := processPasswords(input); err != nil {
-		return "", fmt.Errorf("failed to encrypt passwords: %w", err)
+	encrypt := encryptPassword
+	switch {
+	case opts.ValidateOnly:
+		encrypt = placeholderPassword
+	case opts.Reproducible:
+		encrypt = reproduciblePassword
+	}
+	var policyFindings []string
+	if policy := opts.PasswordPolicy; policy != nil {
+		hash := encrypt
+		encrypt = func(username, password string) (string, error) {
+			policyFindings = append(policyFindings, policy.Check(username, password)...)
+			return hash(username, password)
+		}
+	}
+	endStep = opts.Trace.Step("password hashing")
+	err = processPasswords(input, encrypt)
+	endStep()
+	if err != nil {
+		return nil, classify(KindEncryption, fmt.Errorf("failed to encrypt passwords: %w", err))
+	}
+	if len(policyFindings) > 0 && opts.PasswordPolicy.Mode == PasswordPolicyEnforce {
+		return nil, classify(KindPasswordPolicy, &FindingsError{Summary: "passwords violate the password policy", Findings: NewFindings("password-policy", SeverityError, policyFindings)})
 	}
 
-	// 2. Load Schema
-	s, err := schema.LoadSchema()
+	// 3. Validate against the schema matching the input's apiVersion
+	endStep = opts.Trace.Step("schema validation")
+	schemaFindings, err := validate(input, opts.Mode)
+	endStep()
 	if err != nil {
-		return "", fmt.Errorf("failed to load schema: %w", err)
+		return nil, err
+	}
+	findings := append(NewFindings("node-pattern-expansion", SeverityInfo, expansions), NewFindings("password-policy", SeverityWarning, policyFindings)...)
+	findings = append(findings, schemaFindings...)
+
+	// 4. Check compatibility with the target EIB release
+	if opts.TargetRelease != "" {
+		issues, err := schema.CheckCompatibility(input, opts.TargetRelease)
+		if err != nil {
+			return nil, err
+		}
+		compatErr := &FindingsError{Summary: "configuration is not compatible with EIB " + opts.TargetRelease}
+		for _, issue := range issues {
+			f := Finding{RuleID: "compatibility", Severity: SeverityError, Path: issue.Path, Message: issue.Message}
+			if issue.Deprecated {
+				f.RuleID, f.Severity = "compatibility-deprecated", SeverityWarning
+				findings = append(findings, f)
+				continue
+			}
+			compatErr.Findings = append(compatErr.Findings, f)
+		}
+		if len(compatErr.Findings) > 0 {
+			return nil, classify(KindCompatibility, compatErr)
+		}
+	}
+
+	// 5. Evaluate cross-field rules
+	rules := opts.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	rules = opts.RulePolicy.applyRules(rules)
+	ruleErr := &RuleError{}
+	endStep = opts.Trace.Step("cross-field rules")
+	violations := RunRules(input, rules)
+	endStep()
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			ruleErr.Violations = append(ruleErr.Violations, v)
+			continue
+		}
+		findings = append(findings, v)
+	}
+	if len(ruleErr.Violations) > 0 {
+		return nil, classify(KindRule, ruleErr)
+	}
+
+	// 6. Prepare custom files, certificates, GPG keys, scripts, manifests, Kubernetes configuration files and network configurations
+	endStep = opts.Trace.Step("artifacts")
+	artifacts, fileWarnings, err := PrepareFiles(opts.Files)
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	findings = append(findings, NewFindings("custom-file", SeverityWarning, fileWarnings)...)
+	certArtifacts, certWarnings, err := PrepareCertificates(opts.Certificates, time.Now())
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	artifacts = append(artifacts, certArtifacts...)
+	findings = append(findings, NewFindings("certificate", SeverityWarning, certWarnings)...)
+	keyArtifacts, keyWarnings, err := PrepareGPGKeys(opts.GPGKeys, input, time.Now())
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	artifacts = append(artifacts, keyArtifacts...)
+	findings = append(findings, NewFindings("gpg-key", SeverityWarning, keyWarnings)...)
+	scriptArtifacts, scriptWarnings, err := PrepareScripts(opts.Scripts, opts.RenumberScripts)
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	artifacts = append(artifacts, scriptArtifacts...)
+	findings = append(findings, NewFindings("script", SeverityWarning, scriptWarnings)...)
+	manifestArtifacts, err := PrepareManifests(opts.Manifests)
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	artifacts = append(artifacts, manifestArtifacts...)
+	// Check, or snapshot, the manifests of kubernetes.manifests.urls; the
+	// snapshots are local manifests for the namespace check
+	manifests := append([]Manifest{}, opts.Manifests...)
+	if opts.SnapshotManifests && !opts.ValidateOnly {
+		snapshots, notes, err := SnapshotManifests(context.Background(), input, opts.Manifests, opts)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, snapshots...)
+		findings = append(findings, NewFindings("manifest-snapshot", SeverityInfo, notes)...)
+		for _, snapshot := range snapshots {
+			manifests = append(manifests, Manifest{Name: path.Base(snapshot.Path), Content: snapshot.Content})
+		}
+	} else if opts.CheckManifests || opts.SnapshotManifests {
+		findings = append(findings, NewFindings("manifest-url", SeverityWarning, CheckManifestURLs(context.Background(), input, opts))...)
+	}
+	findings = append(findings, CheckNamespaces(input, manifests)...)
+	kubernetesConfig := opts.KubernetesConfig
+	var generated []GeneratedSecret
+	if len(opts.GenerateSecrets) > 0 {
+		kubernetesConfig, generated, err = GenerateClusterSecrets(kubernetesConfig, input, opts.GenerateSecrets)
+		if err != nil {
+			return nil, classify(KindArtifact, err)
+		}
+	}
+	configArtifacts, configWarnings, err := PrepareKubernetesConfig(kubernetesConfig, input)
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	for _, secret := range generated {
+		for _, file := range secret.Files {
+			for i := range configArtifacts {
+				if configArtifacts[i].Path == file {
+					configArtifacts[i].Secret = true
+				}
+			}
+		}
+	}
+	artifacts = append(artifacts, configArtifacts...)
+	findings = append(findings, NewFindings("node-config", SeverityWarning, configWarnings)...)
+	networkArtifacts, networkWarnings, err := PrepareNetworkConfigs(opts.NetworkConfigs, input)
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	artifacts = append(artifacts, networkArtifacts...)
+	findings = append(findings, NewFindings("network-config", SeverityWarning, networkWarnings)...)
+	pemWarnings, err := CheckPEM(input, artifacts, time.Now())
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	findings = append(findings, NewFindings("pem", SeverityWarning, pemWarnings)...)
+	findings = append(findings, NewFindings("plaintext-secret", SeverityWarning, CheckPlaintextSecrets(input, secrets))...)
+	endStep()
+
+	// 7. Convert to YAML. The document is built in memory rather than
+	// streamed to the response, since the checksums, the signature and
+	// eib validate below need it whole
+	var out bytes.Buffer
+	if opts.Header {
+		apiVersion, _ := input["apiVersion"].(string)
+		generated := time.Now()
+		if opts.Reproducible {
+			generated = time.Time{}
+		}
+		out.WriteString(metadataHeader(opts.Generator, apiVersion, inputHash, generated))
+	}
+	enc := yaml.NewEncoder(&out)
+	if err := enc.Encode(input); err != nil {
+		return nil, fmt.Errorf("failed to marshal to YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal to YAML: %w", err)
+	}
+	yamlText := out.String()
+
+	// 8. Validate with the real EIB binary, if configured
+	if opts.EIB != nil && !opts.ValidateOnly {
+		eib := *opts.EIB
+		eib.Offline = eib.Offline || opts.Offline
+		endStep := opts.Trace.Step("eib validate")
+		eibFindings, err := eib.Validate(yamlText, artifacts)
+		endStep()
+		if err != nil {
+			findings = append(findings, NewFindings("eib-validate", SeverityWarning, []string{fmt.Sprintf("eib validate could not be run: %v", err)})...)
+		}
+		for _, f := range eibFindings {
+			findings = append(findings, NewFindings("eib-validate", SeverityWarning, []string{"eib validate: " + f})...)
+		}
+	}
+
+	// Look up the known vulnerabilities of the embedded images, if configured
+	var advisories []ImageAdvisory
+	if opts.Advisories != nil && !opts.ValidateOnly {
+		var advisoryWarnings []string
+		advisories, advisoryWarnings = checkAdvisories(input, opts)
+		findings = append(findings, NewFindings("image-advisory", SeverityWarning, advisoryWarnings)...)
+	}
+
+	// Apply the rule policy to the findings of the checks
+	if findings, err = opts.RulePolicy.Check(findings); err != nil {
+		return nil, err
+	}
+
+	checksums, err := ResultChecksums(yamlText, artifacts)
+	if err != nil {
+		return nil, classify(KindArtifact, err)
+	}
+	contentHash := ContentHash(checksums)
+
+	// 9. Sign the definition
+	var signature string
+	if opts.Sign && !opts.ValidateOnly {
+		if opts.Signer == nil {
+			return nil, fmt.Errorf("signing is not configured on this server (see -signing-key)")
+		}
+		if signature, err = opts.Signer.Sign(yamlText); err != nil {
+			return nil, err
+		}
+		checksums[SignatureFile] = sha256Hex([]byte(signature))
+	}
+
+	findings, suppressed := SuppressFindings(findings, opts.Suppress)
+	return &Result{YAML: yamlText, Warnings: findingStrings(findings), Findings: findings, Suppressed: suppressed, Artifacts: artifacts, OutputImage: OutputImageName(input), Changes: changes, Checksums: checksums, ResolvedSecrets: secrets, GeneratedSecrets: generated, Signature: signature, ContentHash: contentHash, Advisories: advisories}, nil
+}
+
+// validate checks the input against the schema in the given mode.
+//
+// In permissive mode, the input is validated against a schema that accepts
+// unknown fields, and a second pass against the strict schema reports those
+// fields as warnings.
+//
+// Parameters:
+//   - input: The configuration map to validate.
+//   - mode: The validation mode.
+//
+// Returns:
+//   - []Finding: Warnings about unknown fields accepted in permissive mode.
+//   - error: A *FindingsError if the input is invalid, or an error if the
+//     schema cannot be loaded.
+func validate(input map[string]interface{}, mode ValidationMode) ([]Finding, error) {
+	s, err := loadSchemaFor(input, mode == ValidationPermissive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
 	}
 
-	// 3. Validate Input
 	inputLoader := gojsonschema.NewGoLoader(input)
 	result, err := s.Validate(inputLoader)
 	if err != nil {
-		return "", fmt.Errorf("validation failed: %w", err)
+		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
 	if !result.Valid() {
-		var errMsgs string
+		// A user without credentials fails the oneOf of the user schema and
+		// the required check of one of its branches; the rewritten oneOf
+		// error replaces both.
+		credentials := map[string]bool{}
 		for _, desc := range result.Errors() {
-			errMsgs += fmt.Sprintf("- %s\n", desc)
+			if isUserCredentialError(desc) {
+				credentials[desc.Field()] = true
+			}
 		}
-		return "", fmt.Errorf("configuration is invalid:\n%s", errMsgs)
+		schemaErr := &FindingsError{Summary: "configuration is invalid"}
+		for _, desc := range result.Errors() {
+			if desc.Type() == "required" && credentials[desc.Field()] {
+				continue
+			}
+			schemaErr.Findings = append(schemaErr.Findings, NewFindings("schema", SeverityError, []string{describeError(desc)})...)
+		}
+		return nil, classify(KindSchema, schemaErr)
+	}
+
+	if mode != ValidationPermissive {
+		return nil, nil
 	}
 
-	// 4. Convert to YAML
-	yamlBytes, err := yaml.Marshal(input)
+	strict, err := loadSchemaFor(input, false)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal to YAML: %w", err)
+		return nil, fmt.Errorf("failed to load schema: %w", err)
 	}
+	strictResult, err := strict.Validate(inputLoader)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	var findings []Finding
+	for _, desc := range strictResult.Errors() {
+		if desc.Type() == "additional_property_not_allowed" {
+			findings = append(findings, Finding{RuleID: "schema-unknown-field", Severity: SeverityWarning, Path: desc.Field(), Message: fmt.Sprintf("unknown field %q passed through", desc.Details()["property"])})
+		}
+	}
+	return findings, nil
+}
+
+// describeError renders a schema validation error as a message.
+//
+// Format errors are rewritten to name the expected format precisely; all
+// other errors use the gojsonschema rendering.
+//
+// Parameters:
+//   - desc: The validation error.
+//
+// Returns:
+//   - string: The error message.
+func describeError(desc gojsonschema.ResultError) string {
+	if desc.Type() == "format" {
+		format, _ := desc.Details()["format"].(string)
+		return fmt.Sprintf("%s: %q is not a valid %s", desc.Field(), fmt.Sprint(desc.Value()), schema.FormatDescription(format))
+	}
+	if isUserCredentialError(desc) {
+		return describeUserCredentials(desc.Field(), desc.Value())
+	}
+	return desc.String()
+}
 
-	return string(yamlBytes), nil
+// isUserCredentialError reports whether a schema error is the oneOf failure
+// of an operatingSystem.users entry, which requires exactly one of
+// encryptedPassword and sshKeys.
+func isUserCredentialError(desc gojsonschema.ResultError) bool {
+	if desc.Type() != "number_one_of" {
+		return false
+	}
+	index, ok := strings.CutPrefix(desc.Field(), "operatingSystem.users.")
+	return ok && index != "" && strings.Trim(index, "0123456789") == ""
+}
+
+// describeUserCredentials explains why a user fails the credentials check.
+//
+// The generic oneOf message does not say which fields are involved; a root
+// user without credentials is called out, as the image then has no working
+// root login.
+//
+// Parameters:
+//   - field: The path of the user, e.g. "operatingSystem.users.0".
+//   - value: The user object.
+//
+// Returns:
+//   - string: The error message.
+func describeUserCredentials(field string, value interface{}) string {
+	user, _ := value.(map[string]interface{})
+	name, _ := user["username"].(string)
+	_, hasPassword := user["encryptedPassword"]
+	_, hasKeys := user["sshKeys"]
+	switch {
+	case hasPassword && hasKeys:
+		return fmt.Sprintf("%s: user %q sets both encryptedPassword and sshKeys, but each user must use exactly one of them", field, name)
+	case name == "root":
+		return fmt.Sprintf("%s: the root user needs a password (password or encryptedPassword) or sshKeys; without them, root cannot log in to the built image", field)
+	default:
+		return fmt.Sprintf("%s: user %q needs a password (password or encryptedPassword) or sshKeys", field, name)
+	}
+}
+
+// loadSchemaFor returns the compiled schema matching the input's apiVersion.
+//
+// If the apiVersion is missing or unsupported, the latest schema is returned
+// so that validation reports the problem against the apiVersion field itself.
+//
+// Parameters:
+//   - input: The configuration map to validate.
+//   - permissive: Whether to load the variant accepting unknown fields.
+//
+// Returns:
+//   - *gojsonschema.Schema: The compiled schema to validate against.
+//   - error: An error if the schema cannot be loaded.
+func loadSchemaFor(input map[string]interface{}, permissive bool) (*gojsonschema.Schema, error) {
+	version, ok := input["apiVersion"].(string)
+	if !ok || !schema.IsSupportedVersion(version) {
+		version = schema.LatestVersion()
+	}
+	if permissive {
+		return schema.LoadPermissiveSchemaForVersion(version)
+	}
+	return schema.LoadSchemaForVersion(version)
 }
 
 // processPasswords iterates through the configuration and encrypts plaintext passwords.
@@ -73,10 +670,11 @@ func GenerateConfig(input map[string]interface{}) (string, error) {
 //
 // Parameters:
 //   - input: The configuration map to process.
+//   - encrypt: Hashes the plaintext password of a user, normally encryptPassword.
 //
 // Returns:
 //   - error: An error if encryption fails.
-func processPasswords(input map[string]interface{}) error {
+func processPasswords(input map[string]interface{}, encrypt func(username, password string) (string, error)) error {
 	osVal, ok := input["operatingSystem"]
 	if !ok {
 		return nil
@@ -100,9 +698,10 @@ func processPasswords(input map[string]interface{}) error {
 		if !ok {
 			continue
 		}
+		username, _ := userMap["username"].(string)
 		// Check for 'password' field (virtual field for plaintext)
 		if pwd, ok := userMap["password"].(string); ok && pwd != "" {
-			hash, err := encryptPassword(pwd)
+			hash, err := encrypt(username, pwd)
 			if err != nil {
 				return fmt.Errorf("encryption failed: %w", err)
 			}
@@ -111,7 +710,7 @@ func processPasswords(input map[string]interface{}) error {
 		} else if encPwd, ok := userMap["encryptedPassword"].(string); ok && encPwd != "" {
 			// Check if 'encryptedPassword' is actually plaintext (doesn't start with $)
 			if !strings.HasPrefix(encPwd, "$") {
-				hash, err := encryptPassword(encPwd)
+				hash, err := encrypt(username, encPwd)
 				if err != nil {
 					return fmt.Errorf("encryption failed: %w", err)
 				}
@@ -127,17 +726,32 @@ func processPasswords(input map[string]interface{}) error {
 // It uses a default cost of 10.
 //
 // Parameters:
+//   - username: The user the password belongs to; unused.
 //   - password: The plaintext password to encrypt.
 //
 // Returns:
 //   - string: The bcrypt hash of the password.
 //   - error: An error if hashing fails.
-func encryptPassword(password string) (string, error) {
+func encryptPassword(username, password string) (string, error) {
 	// Use bcrypt (native Go) instead of shelling out to openssl.
 	// Cost 10 is a reasonable default.
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
 	if err != nil {
 		return "", err
 	}
 	return string(hash), nil
 }
+
+// placeholderPassword stands in for encryptPassword when only validating:
+// it skips the deliberately slow hashing and returns a fixed crypt-style value.
+//
+// Parameters:
+//   - username: The user the password belongs to; unused.
+//   - password: The plaintext password; unused.
+//
+// Returns:
+//   - string: The placeholder hash.
+//   - error: Always nil.
+func placeholderPassword(username, password string) (string, error) {
+	return "$2a$10$validate.only.placeholder.hash.not.a.real.password..", nil
+}
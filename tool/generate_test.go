@@ -0,0 +1,32 @@
+package tool
+
+import "testing"
+
+// benchmarkInput returns a minimal valid configuration without passwords, so
+// the benchmark measures validation and marshalling rather than bcrypt.
+func benchmarkInput() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "1.0",
+		"image": map[string]interface{}{
+			"imageType":       "iso",
+			"arch":            "x86_64",
+			"baseImage":       "slmicro.iso",
+			"outputImageName": "eib-image.iso",
+		},
+		"operatingSystem": map[string]interface{}{
+			"isoConfiguration": map[string]interface{}{
+				"installDevice": "/dev/sda",
+			},
+		},
+	}
+}
+
+// BenchmarkGenerateConfig measures the per-call latency of GenerateConfig
+// as seen by an interactive agent loop.
+func BenchmarkGenerateConfig(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := GenerateConfig(benchmarkInput()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package tool
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layout selects how the files of a fleet are arranged.
+type Layout string
+
+const (
+	// LayoutFlat places each site's files in a "<site>/" directory.
+	LayoutFlat Layout = "flat"
+	// LayoutGitOps arranges the files as a Git repository for Flux or Argo
+	// CD: each site in "sites/<site>/" with a kustomization.yaml, a root
+	// kustomization.yaml listing the sites, and a .gitignore excluding the
+	// images EIB builds.
+	LayoutGitOps Layout = "gitops"
+)
+
+// kustomization is the subset of a Kustomize kustomization.yaml written by
+// the GitOps layout.
+type kustomization struct {
+	APIVersion       string               `yaml:"apiVersion"`
+	Kind             string               `yaml:"kind"`
+	Resources        []string             `yaml:"resources,omitempty"`
+	GeneratorOptions *generatorOptions    `yaml:"generatorOptions,omitempty"`
+	ConfigMaps       []configMapGenerator `yaml:"configMapGenerator,omitempty"`
+}
+
+// generatorOptions are the options of the Kustomize generators.
+type generatorOptions struct {
+	DisableNameSuffixHash bool `yaml:"disableNameSuffixHash"`
+}
+
+// configMapGenerator generates a ConfigMap from files.
+type configMapGenerator struct {
+	Name  string   `yaml:"name"`
+	Files []string `yaml:"files"`
+}
+
+// ParseLayout converts a string to a Layout.
+//
+// Parameters:
+//   - s: The layout name; empty selects LayoutFlat.
+//
+// Returns:
+//   - Layout: The layout.
+//   - error: An error if the name is unknown.
+func ParseLayout(s string) (Layout, error) {
+	switch Layout(s) {
+	case "", LayoutFlat:
+		return LayoutFlat, nil
+	case LayoutGitOps:
+		return LayoutGitOps, nil
+	default:
+		return "", fmt.Errorf("unknown layout %q (expected %q or %q)", s, LayoutFlat, LayoutGitOps)
+	}
+}
+
+// ApplyGitOpsLayout rearranges the files of a fleet as a GitOps repository.
+//
+// Each site moves to "sites/<site>/" and gains a kustomization.yaml that
+// wraps its definition and network files into an "eib-<site>" ConfigMap, so
+// Flux or Argo CD can sync them to a management cluster. A root
+// kustomization.yaml lists every site, and a .gitignore excludes the images
+// EIB will build next to the definitions. OutputImages and Checksums are
+// updated to match.
+//
+// Parameters:
+//   - result: The fleet; it is modified in place.
+//
+// Returns:
+//   - error: An error if a kustomization cannot be encoded.
+func ApplyGitOpsLayout(result *FleetResult) error {
+	sites := map[string][]string{}
+	files := make(map[string]string, len(result.Files))
+	for rel, content := range result.Files {
+		site, rest, _ := strings.Cut(rel, "/")
+		sites[site] = append(sites[site], rest)
+		files[path.Join("sites", site, rest)] = content
+	}
+
+	names := make([]string, 0, len(sites))
+	for site := range sites {
+		names = append(names, site)
+	}
+	sort.Strings(names)
+
+	root := kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	for _, site := range names {
+		root.Resources = append(root.Resources, path.Join("sites", site))
+
+		paths := sites[site]
+		sort.Strings(paths)
+		generator := configMapGenerator{Name: "eib-" + site}
+		for _, p := range paths {
+			// ConfigMap keys cannot contain "/", so nested files are renamed.
+			if key := strings.ReplaceAll(p, "/", "-"); key != p {
+				p = key + "=" + p
+			}
+			generator.Files = append(generator.Files, p)
+		}
+		k := kustomization{
+			APIVersion:       root.APIVersion,
+			Kind:             root.Kind,
+			GeneratorOptions: &generatorOptions{DisableNameSuffixHash: true},
+			ConfigMaps:       []configMapGenerator{generator},
+		}
+		raw, err := yaml.Marshal(k)
+		if err != nil {
+			return fmt.Errorf("failed to encode kustomization of site %q: %w", site, err)
+		}
+		files[path.Join("sites", site, "kustomization.yaml")] = string(raw)
+	}
+	raw, err := yaml.Marshal(root)
+	if err != nil {
+		return fmt.Errorf("failed to encode kustomization: %w", err)
+	}
+	files["kustomization.yaml"] = string(raw)
+
+	images := make([]string, len(result.OutputImages))
+	ignore := "# Images built by Edge Image Builder.\n"
+	for i, image := range result.OutputImages {
+		images[i] = path.Join("sites", image)
+		ignore += "/" + images[i] + "\n"
+	}
+	files[".gitignore"] = ignore
+
+	for i, file := range result.SecretFiles {
+		result.SecretFiles[i] = path.Join("sites", file)
+	}
+	result.Files = files
+	result.OutputImages = images
+	result.Checksums = FileChecksums(files)
+	return nil
+}
@@ -0,0 +1,196 @@
+package tool
+
+import (
+	stderrors "errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/errors"
+)
+
+// gpgKeysDir is the image configuration directory holding the GPG keys EIB
+// imports to verify side-loaded RPMs and additional repositories.
+const gpgKeysDir = "rpms/gpg-keys"
+
+// gpgKeyNamePattern matches valid GPG key file names.
+var gpgKeyNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// GPGKey is an armored OpenPGP public key used to verify packages.
+type GPGKey struct {
+	// Name is the file name under rpms/gpg-keys/. If empty, it is derived
+	// from the key ID.
+	Name string `json:"name,omitempty"`
+	// Content is the ASCII armored public key block.
+	Content string `json:"content"`
+}
+
+// PrepareGPGKeys validates GPG keys, turns them into rpms/gpg-keys/
+// artifacts and checks that package verification is configured coherently.
+//
+// Every key must be an ASCII armored OpenPGP public key. Expired or revoked
+// keys are reported as warnings, and so are keys supplied while
+// packages.noGPGCheck disables verification, and signed additional
+// repositories without any key while verification is enabled.
+//
+// Parameters:
+//   - keys: The GPG keys.
+//   - input: The configuration, used to check the packages section.
+//   - now: The time against which key expiry is checked.
+//
+// Returns:
+//   - []Artifact: The artifacts, ordered by path.
+//   - []string: The warnings.
+//   - error: An error listing every invalid key.
+func PrepareGPGKeys(keys []GPGKey, input map[string]interface{}, now time.Time) ([]Artifact, []string, error) {
+	var artifacts []Artifact
+	var warnings, errs []string
+	seen := map[string]bool{}
+
+	for i, k := range keys {
+		label := k.Name
+		if label == "" {
+			label = fmt.Sprintf("GPG key %d", i)
+		}
+
+		entities, err := readGPGKey(k.Content)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+
+		name := k.Name
+		if name == "" {
+			if len(entities) == 0 {
+				errs = append(errs, fmt.Sprintf("%s: a name is required for keys using algorithms this server cannot parse", label))
+				continue
+			}
+			name = entities[0].PrimaryKey.KeyIdString() + ".asc"
+		}
+		if !gpgKeyNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Sprintf("%s: invalid file name %q (use letters, digits, '.', '_' and '-')", label, name))
+			continue
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Sprintf("%s: duplicate file name %q", label, name))
+			continue
+		}
+		seen[name] = true
+
+		for _, e := range entities {
+			if e.PrivateKey != nil {
+				errs = append(errs, fmt.Sprintf("%s: contains a private key; only public keys are needed", label))
+				break
+			}
+			warnings = append(warnings, gpgKeyWarnings(name, e, now)...)
+		}
+
+		artifacts = append(artifacts, Artifact{
+			Path:    gpgKeysDir + "/" + name,
+			Content: k.Content,
+			Mode:    defaultFileMode,
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid GPG keys:\n- %s", strings.Join(errs, "\n- "))
+	}
+	warnings = append(warnings, gpgCoherenceWarnings(len(keys) > 0, input)...)
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Path < artifacts[j].Path })
+	return artifacts, warnings, nil
+}
+
+// readGPGKey decodes an ASCII armored OpenPGP public key.
+//
+// The armor and its checksum are always verified. The key packets are then
+// parsed for further checks; keys using algorithms the parser does not know
+// are accepted without them.
+//
+// Parameters:
+//   - content: The armored key.
+//
+// Returns:
+//   - openpgp.EntityList: The parsed keys, or nil if their algorithm is not supported by the parser.
+//   - error: An error if the content is not a valid armored public key.
+func readGPGKey(content string) (openpgp.EntityList, error) {
+	block, err := armor.Decode(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid ASCII armored block: %v", err)
+	}
+	if block.Type != openpgp.PublicKeyType {
+		return nil, fmt.Errorf("unexpected armored block %q, expected %q", block.Type, openpgp.PublicKeyType)
+	}
+	entities, err := openpgp.ReadKeyRing(block.Body)
+	if err != nil {
+		var unsupported errors.UnsupportedError
+		if stderrors.As(err, &unsupported) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("not a valid OpenPGP public key: %v", err)
+	}
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("no key found")
+	}
+	return entities, nil
+}
+
+// gpgKeyWarnings reports revoked or expired keys.
+//
+// Parameters:
+//   - name: The key file name, used in messages.
+//   - e: The key.
+//   - now: The reference time.
+//
+// Returns:
+//   - []string: The warnings.
+func gpgKeyWarnings(name string, e *openpgp.Entity, now time.Time) []string {
+	id := e.PrimaryKey.KeyIdString()
+	if len(e.Revocations) > 0 {
+		return []string{fmt.Sprintf("GPG key %s (%s) is revoked", name, id)}
+	}
+	for _, ident := range e.Identities {
+		if ident.SelfSignature == nil || ident.SelfSignature.KeyLifetimeSecs == nil {
+			continue
+		}
+		expiry := e.PrimaryKey.CreationTime.Add(time.Duration(*ident.SelfSignature.KeyLifetimeSecs) * time.Second)
+		if now.After(expiry) {
+			return []string{fmt.Sprintf("GPG key %s (%s) expired on %s", name, id, expiry.Format(time.DateOnly))}
+		}
+	}
+	return nil
+}
+
+// gpgCoherenceWarnings checks that GPG keys and packages.noGPGCheck agree.
+//
+// Parameters:
+//   - haveKeys: Whether GPG keys are supplied.
+//   - input: The configuration.
+//
+// Returns:
+//   - []string: The warnings.
+func gpgCoherenceWarnings(haveKeys bool, input map[string]interface{}) []string {
+	packages := lookupMap(input, "operatingSystem", "packages")
+	noGPGCheck, _ := packages["noGPGCheck"].(bool)
+	if noGPGCheck {
+		if haveKeys {
+			return []string{"GPG keys are supplied but operatingSystem.packages.noGPGCheck disables package verification, so they are not used"}
+		}
+		return nil
+	}
+	if haveKeys {
+		return nil
+	}
+
+	var warnings []string
+	for _, repo := range lookupMaps(input, "operatingSystem", "packages", "additionalRepos") {
+		if unsigned, _ := repo["unsigned"].(bool); unsigned {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("additional repository %v is signed but no GPG key is supplied; add its key with gpgKeys unless the base image already trusts it, or mark it unsigned", repo["url"]))
+	}
+	return warnings
+}
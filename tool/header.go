@@ -0,0 +1,49 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InputHash returns the SHA-256 of a configuration as given to Generate,
+// before variables and secret references are resolved, so it identifies the
+// input without depending on secret values.
+//
+// Parameters:
+//   - input: The configuration.
+//
+// Returns:
+//   - string: The hexadecimal digest.
+//   - error: An error if the configuration cannot be encoded.
+func InputHash(input map[string]interface{}) (string, error) {
+	// encoding/json sorts map keys, so equal configurations hash equally.
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash the input: %w", err)
+	}
+	return sha256Hex(raw), nil
+}
+
+// metadataHeader renders the comment header that traces a definition back to
+// how it was produced.
+//
+// Parameters:
+//   - generator: The generator name and version, e.g. "eib-mcp 0.1.0".
+//   - apiVersion: The apiVersion, which selects the schema the definition was validated against.
+//   - inputHash: The SHA-256 of the input, see InputHash.
+//   - generated: The generation time, or the zero time to omit it.
+//
+// Returns:
+//   - string: The YAML comment lines, ending with a newline.
+func metadataHeader(generator, apiVersion, inputHash string, generated time.Time) string {
+	var b strings.Builder
+	b.WriteString("# Generated by " + generator + "\n")
+	fmt.Fprintf(&b, "# Schema: EIB definition apiVersion %s\n", apiVersion)
+	if !generated.IsZero() {
+		fmt.Fprintf(&b, "# Generated at: %s\n", generated.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "# Input SHA-256: %s\n", inputHash)
+	return b.String()
+}
@@ -0,0 +1,348 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultHelmTimeout bounds a single `helm template` run, including the
+// download of the chart.
+const defaultHelmTimeout = 2 * time.Minute
+
+// HelmRenderer renders the Helm charts of a configuration with
+// `helm template`, the way the cluster will install them, so users can
+// review what will actually be deployed before baking a chart into an image.
+//
+// The helm binary is run rather than the Helm SDK: the SDK would bring in
+// the Kubernetes client libraries, several times the size of the server,
+// and pin the Helm version to the one the server was built with, while the
+// binary can be updated to match the Helm of the cluster.
+type HelmRenderer struct {
+	// Binary is the path of the helm binary. Defaults to "helm".
+	Binary string
+	// Timeout bounds each run. Defaults to two minutes.
+	Timeout time.Duration
+}
+
+// ChartRender selects a chart of a configuration and its values.
+type ChartRender struct {
+	// Chart is the name of an entry of kubernetes.helm.charts. It can be
+	// empty if the configuration has a single chart.
+	Chart string
+	// Values is the content of the chart's values file, as YAML. EIB reads
+	// it from the file named by valuesFile.
+	Values string
+	// IncludeCRDs also renders the CRDs of the chart's crds/ directory,
+	// which Helm installs before the templates.
+	IncludeCRDs bool
+}
+
+// RenderedChart is a chart rendered by HelmRenderer.
+type RenderedChart struct {
+	// Chart is the chart name.
+	Chart string `json:"chart"`
+	// Version is the chart version.
+	Version string `json:"version"`
+	// Repository is the URL of the chart repository.
+	Repository string `json:"repository"`
+	// Release is the release name the chart was rendered with.
+	Release string `json:"release"`
+	// Namespace is the namespace the chart was rendered for.
+	Namespace string `json:"namespace"`
+	// Resources lists the rendered Kubernetes objects, in rendering order.
+	Resources []RenderedResource `json:"resources"`
+	// Images lists the container images the rendered objects reference.
+	Images []string `json:"images"`
+	// Notes lists what the rendering could not reproduce, such as settings
+	// of the repository that are not applied.
+	Notes []string `json:"notes,omitempty"`
+	// Manifests is the rendered YAML stream.
+	Manifests string `json:"-"`
+}
+
+// RenderedResource identifies a rendered Kubernetes object.
+type RenderedResource struct {
+	// APIVersion is the object's apiVersion, e.g. "apps/v1".
+	APIVersion string `json:"apiVersion"`
+	// Kind is the object's kind, e.g. "Deployment".
+	Kind string `json:"kind"`
+	// Name is the object's name.
+	Name string `json:"name"`
+	// Namespace is the object's namespace, if set in the manifest.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RenderChart renders a Helm chart of a configuration with the given values.
+//
+// The chart is downloaded from its repository in kubernetes.helm.repositories
+// and rendered with the release name, namespace and API versions of its
+// entry, for the Kubernetes version of kubernetes.version.
+//
+// Parameters:
+//   - ctx: The context bounding the rendering.
+//   - config: The configuration.
+//   - req: The chart and its values.
+//   - opts: The options; Helm, Offline and Trace are used.
+//
+// Returns:
+//   - *RenderedChart: The rendered manifests and what they contain.
+//   - error: An error if the chart is not in the configuration, the values
+//     are malformed, or helm fails; a *Error of KindNetwork in offline mode.
+func RenderChart(ctx context.Context, config map[string]interface{}, req ChartRender, opts Options) (*RenderedChart, error) {
+	chart, err := findChart(config, req.Chart)
+	if err != nil {
+		return nil, err
+	}
+	rendered := &RenderedChart{}
+	rendered.Chart, _ = chart["name"].(string)
+	rendered.Version, _ = chart["version"].(string)
+	rendered.Release, _ = chart["releaseName"].(string)
+	if rendered.Release == "" {
+		rendered.Release = rendered.Chart
+	}
+	rendered.Namespace, _ = chart["targetNamespace"].(string)
+	if rendered.Namespace == "" {
+		rendered.Namespace = "default"
+	}
+	repoName, _ := chart["repositoryName"].(string)
+	var repo map[string]interface{}
+	for _, r := range lookupMaps(config, "kubernetes", "helm", "repositories") {
+		if name, _ := r["name"].(string); name == repoName {
+			repo = r
+		}
+	}
+	if repo == nil {
+		return nil, fmt.Errorf("chart %q uses repository %q, which is not in kubernetes.helm.repositories", rendered.Chart, repoName)
+	}
+	rendered.Repository, _ = repo["url"].(string)
+	if err := checkValues(req.Values); err != nil {
+		return nil, err
+	}
+	if req.Values == "" {
+		if valuesFile, _ := chart["valuesFile"].(string); valuesFile != "" {
+			rendered.Notes = append(rendered.Notes, fmt.Sprintf("the chart's values file %s was not given, so the chart was rendered with its default values", valuesFile))
+		}
+	}
+	if opts.Offline {
+		return nil, classify(KindNetwork, errors.New("charts cannot be rendered in offline mode, which prevents downloading them"))
+	}
+
+	dir, err := os.MkdirTemp("", "eib-helm-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	args, notes := helmTemplateArgs(config, chart, repo, rendered)
+	rendered.Notes = append(rendered.Notes, notes...)
+	if req.Values != "" {
+		valuesPath := filepath.Join(dir, "values.yaml")
+		if err := os.WriteFile(valuesPath, []byte(req.Values), 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write values: %w", err)
+		}
+		args = append(args, "--values", valuesPath)
+	}
+	if req.IncludeCRDs {
+		args = append(args, "--include-crds")
+	}
+
+	renderer := opts.Helm
+	if renderer == nil {
+		renderer = &HelmRenderer{}
+	}
+	endStep := opts.Trace.Step("helm template " + rendered.Chart)
+	rendered.Manifests, err = renderer.template(ctx, dir, args)
+	endStep()
+	if err != nil {
+		return nil, err
+	}
+	if err := rendered.inventory(); err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// checkValues checks that chart values are a YAML mapping.
+//
+// Parameters:
+//   - values: The values, or empty.
+//
+// Returns:
+//   - error: An error if the values are malformed.
+func checkValues(values string) error {
+	var parsed interface{}
+	if err := yaml.Unmarshal([]byte(values), &parsed); err != nil {
+		return fmt.Errorf("invalid values: %w", err)
+	}
+	if _, ok := parsed.(map[string]interface{}); !ok && parsed != nil {
+		return errors.New("invalid values: expected a YAML mapping")
+	}
+	return nil
+}
+
+// findChart returns an entry of kubernetes.helm.charts.
+//
+// Parameters:
+//   - config: The configuration.
+//   - name: The chart name, or empty to select the only chart.
+//
+// Returns:
+//   - map[string]interface{}: The chart entry.
+//   - error: An error naming the available charts if none matches.
+func findChart(config map[string]interface{}, name string) (map[string]interface{}, error) {
+	charts := lookupMaps(config, "kubernetes", "helm", "charts")
+	var names []string
+	for _, chart := range charts {
+		n, _ := chart["name"].(string)
+		if n == name || (name == "" && len(charts) == 1) {
+			return chart, nil
+		}
+		names = append(names, n)
+	}
+	switch {
+	case len(charts) == 0:
+		return nil, errors.New("the configuration has no kubernetes.helm.charts")
+	case name == "":
+		return nil, fmt.Errorf("the configuration has several charts; name one of %s", strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("chart %q is not in kubernetes.helm.charts (available: %s)", name, strings.Join(names, ", "))
+}
+
+// helmTemplateArgs builds the `helm template` arguments rendering a chart.
+//
+// Parameters:
+//   - config: The configuration, for the Kubernetes version.
+//   - chart: The chart entry.
+//   - repo: The repository entry of the chart.
+//   - rendered: The chart name, version, release and namespace.
+//
+// Returns:
+//   - []string: The arguments, without the values file.
+//   - []string: Notes about repository settings that are not applied.
+func helmTemplateArgs(config, chart, repo map[string]interface{}, rendered *RenderedChart) ([]string, []string) {
+	args := []string{"template", rendered.Release}
+	if strings.HasPrefix(rendered.Repository, "oci://") {
+		args = append(args, strings.TrimRight(rendered.Repository, "/")+"/"+rendered.Chart)
+	} else {
+		args = append(args, rendered.Chart, "--repo", rendered.Repository)
+	}
+	args = append(args, "--version", rendered.Version, "--namespace", rendered.Namespace)
+	if auth := lookupMap(repo, "authentication"); auth != nil {
+		if username, _ := auth["username"].(string); username != "" {
+			args = append(args, "--username", username)
+		}
+		if password, _ := auth["password"].(string); password != "" {
+			args = append(args, "--password", password)
+		}
+	}
+	if skip, _ := repo["skipTLSVerify"].(bool); skip {
+		args = append(args, "--insecure-skip-tls-verify")
+	}
+	if plain, _ := repo["plainHTTP"].(bool); plain {
+		args = append(args, "--plain-http")
+	}
+	var notes []string
+	if caFile, _ := repo["caFile"].(string); caFile != "" {
+		notes = append(notes, fmt.Sprintf("the repository CA file %s is in the image configuration directory and was not used; the system CAs were", caFile))
+	}
+	if version, _ := lookupMap(config, "kubernetes")["version"].(string); version != "" {
+		// RKE2 and K3s versions carry a distribution suffix Helm rejects.
+		version, _, _ = strings.Cut(version, "+")
+		args = append(args, "--kube-version", version)
+	}
+	for _, apiVersion := range stringList(chart["apiVersions"]) {
+		args = append(args, "--api-versions", apiVersion)
+	}
+	return args, notes
+}
+
+// template runs helm with the given arguments.
+//
+// Parameters:
+//   - ctx: The context bounding the run.
+//   - dir: A private working directory; helm keeps its cache and
+//     configuration there, so the server's own Helm setup is not used.
+//   - args: The arguments.
+//
+// Returns:
+//   - string: The rendered manifests.
+//   - error: An error with helm's message if it fails.
+func (r *HelmRenderer) template(ctx context.Context, dir string, args []string) (string, error) {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = defaultHelmTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	binary := r.Binary
+	if binary == "" {
+		binary = "helm"
+	}
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Env = append(os.Environ(),
+		"HELM_CACHE_HOME="+filepath.Join(dir, "cache"),
+		"HELM_CONFIG_HOME="+filepath.Join(dir, "config"),
+		"HELM_DATA_HOME="+filepath.Join(dir, "data"),
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("helm template timed out after %s", timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("helm template failed: %s", msg)
+	}
+	return stdout.String(), nil
+}
+
+// inventory lists the objects and images of the rendered manifests.
+//
+// Returns:
+//   - error: An error if helm returned malformed YAML.
+func (c *RenderedChart) inventory() error {
+	c.Resources = []RenderedResource{}
+	seen := map[string]bool{}
+	dec := yaml.NewDecoder(strings.NewReader(c.Manifests))
+	for {
+		var doc map[string]interface{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("helm returned invalid manifests: %w", err)
+		}
+		if doc == nil {
+			continue
+		}
+		res := RenderedResource{}
+		res.APIVersion, _ = doc["apiVersion"].(string)
+		res.Kind, _ = doc["kind"].(string)
+		res.Name, _ = lookupMap(doc, "metadata")["name"].(string)
+		res.Namespace, _ = lookupMap(doc, "metadata")["namespace"].(string)
+		c.Resources = append(c.Resources, res)
+		collectImages(doc, seen)
+	}
+	c.Images = make([]string, 0, len(seen))
+	for name := range seen {
+		c.Images = append(c.Images, name)
+	}
+	sort.Strings(c.Images)
+	return nil
+}
@@ -0,0 +1,296 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// builtinAPIGroups are the API groups served by Kubernetes itself, and by
+// the helm-controller and addon manager of RKE2 and K3s, so their objects do
+// not need a CRD. Groups such as gateway.networking.k8s.io or
+// snapshot.storage.k8s.io are CRDs and are not listed.
+var builtinAPIGroups = map[string]bool{
+	"":                             true,
+	"admissionregistration.k8s.io": true,
+	"apiextensions.k8s.io":         true,
+	"apiregistration.k8s.io":       true,
+	"apps":                         true,
+	"authentication.k8s.io":        true,
+	"authorization.k8s.io":         true,
+	"autoscaling":                  true,
+	"batch":                        true,
+	"certificates.k8s.io":          true,
+	"coordination.k8s.io":          true,
+	"discovery.k8s.io":             true,
+	"events.k8s.io":                true,
+	"flowcontrol.apiserver.k8s.io": true,
+	"internal.apiserver.k8s.io":    true,
+	"networking.k8s.io":            true,
+	"node.k8s.io":                  true,
+	"policy":                       true,
+	"rbac.authorization.k8s.io":    true,
+	"resource.k8s.io":              true,
+	"scheduling.k8s.io":            true,
+	"storage.k8s.io":               true,
+	"storagemigration.k8s.io":      true,
+	"helm.cattle.io":               true,
+	"k3s.cattle.io":                true,
+}
+
+// InstallOrderRequest holds what CheckInstallOrder cannot read from the
+// configuration.
+type InstallOrderRequest struct {
+	// Values maps chart names to the content of their values file, as YAML.
+	Values map[string]string
+	// Manifests maps the names of the files of the kubernetes/manifests
+	// directory to their content.
+	Manifests map[string]string
+}
+
+// InstallOrderReport is the result of CheckInstallOrder.
+type InstallOrderReport struct {
+	// Sources lists the analyzed charts and manifests.
+	Sources []InstallSource `json:"sources"`
+	// Findings lists the custom resources whose CRD is missing or installed
+	// by another chart or manifest, and the namespaces nothing creates.
+	Findings []Finding `json:"findings"`
+}
+
+// InstallSource is a chart or manifest deployed on the cluster.
+type InstallSource struct {
+	// Type is "chart" or "manifest".
+	Type string `json:"type"`
+	// Name is the chart name, the manifest URL or the manifest file name.
+	Name string `json:"name"`
+	// Path locates the source in the configuration, e.g.
+	// "kubernetes.helm.charts.0", or in the image configuration directory.
+	Path string `json:"path"`
+	// CRDs lists the custom resource kinds whose CRD the source installs, as
+	// "Kind.group".
+	CRDs []string `json:"crds,omitempty"`
+	// Uses lists the custom resource kinds the source creates, as
+	// "Kind.group".
+	Uses []string `json:"uses,omitempty"`
+	// Namespaces lists the namespaces the source creates.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Targets lists the namespaces the source installs resources into.
+	Targets []string `json:"targets,omitempty"`
+	// Error explains why the source could not be analyzed.
+	Error string `json:"error,omitempty"`
+}
+
+// CheckInstallOrder finds custom resources whose CRD will not be there when
+// they are created on first boot, and resources installed into a namespace
+// that nothing creates.
+//
+// Charts are rendered with their CRDs (see RenderChart) and manifest URLs
+// are downloaded through opts.HTTP. On first boot, charts and manifests are
+// installed concurrently, so a custom resource whose CRD comes from another
+// chart or manifest fails and is retried until the CRD exists, and one whose
+// CRD nothing installs never reconciles. Sources that cannot be rendered or
+// downloaded, or in offline mode, are reported and skipped.
+//
+// Parameters:
+//   - ctx: The context bounding the downloads and renderings.
+//   - config: The configuration.
+//   - req: The chart values and local manifests.
+//   - opts: The options; Helm, HTTP, Offline and Trace are used.
+//
+// Returns:
+//   - *InstallOrderReport: The analyzed sources and findings.
+//   - error: An error if a values document or local manifest is malformed.
+func CheckInstallOrder(ctx context.Context, config map[string]interface{}, req InstallOrderRequest, opts Options) (*InstallOrderReport, error) {
+	report := &InstallOrderReport{Sources: []InstallSource{}, Findings: []Finding{}}
+	var unanalyzed []string
+
+	for i, chart := range lookupMaps(config, "kubernetes", "helm", "charts") {
+		src := InstallSource{Type: "chart", Path: fmt.Sprintf("kubernetes.helm.charts.%d", i)}
+		src.Name, _ = chart["name"].(string)
+		src.chartNamespaces(chart)
+		if err := checkValues(req.Values[src.Name]); err != nil {
+			return nil, fmt.Errorf("chart %q: %w", src.Name, err)
+		}
+		rendered, err := RenderChart(ctx, config, ChartRender{Chart: src.Name, Values: req.Values[src.Name], IncludeCRDs: true}, opts)
+		if err == nil {
+			err = src.inventory(rendered.Manifests, rendered.Namespace)
+		}
+		if err != nil {
+			src.Error = err.Error()
+			unanalyzed = append(unanalyzed, fmt.Sprintf("%s: chart %q could not be analyzed: %v", src.Path, src.Name, err))
+		}
+		report.Sources = append(report.Sources, src)
+	}
+
+	for i, url := range stringList(lookupMap(config, "kubernetes", "manifests")["urls"]) {
+		src := InstallSource{Type: "manifest", Name: url, Path: fmt.Sprintf("kubernetes.manifests.urls.%d", i)}
+		var body []byte
+		var err error
+		if opts.Offline {
+			err = errors.New("not downloaded in offline mode")
+		} else {
+			endStep := opts.Trace.Step("download " + url)
+			body, err = downloadManifest(ctx, opts.HTTP, url)
+			endStep()
+		}
+		if err == nil {
+			err = src.inventory(string(body), "")
+		}
+		if err != nil {
+			src.Error = err.Error()
+			unanalyzed = append(unanalyzed, fmt.Sprintf("%s: manifest %s could not be analyzed: %v", src.Path, url, err))
+		}
+		report.Sources = append(report.Sources, src)
+	}
+
+	names := make([]string, 0, len(req.Manifests))
+	for name := range req.Manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		src := InstallSource{Type: "manifest", Name: name, Path: "kubernetes/manifests/" + name}
+		if err := src.inventory(req.Manifests[name], ""); err != nil {
+			return nil, fmt.Errorf("manifest %s: %w", name, err)
+		}
+		report.Sources = append(report.Sources, src)
+	}
+
+	report.Findings = append(report.Findings, installOrderFindings(report.Sources, len(unanalyzed) > 0)...)
+	hedge := ""
+	if len(unanalyzed) > 0 {
+		hedge = "; it may come from a chart or manifest that could not be analyzed"
+	}
+	report.Findings = append(report.Findings, namespaceFindings(report.Sources, hedge)...)
+	report.Findings = append(report.Findings, NewFindings("install-order", SeverityInfo, unanalyzed)...)
+	return report, nil
+}
+
+// installOrderFindings reports the custom resources of each source whose
+// CRD is installed by another source, or by none.
+//
+// Parameters:
+//   - sources: The analyzed sources.
+//   - incomplete: Whether some sources could not be analyzed, which may
+//     hold the CRDs that look missing.
+//
+// Returns:
+//   - []Finding: The findings, in source order.
+func installOrderFindings(sources []InstallSource, incomplete bool) []Finding {
+	definedBy := map[string][]string{}
+	for _, src := range sources {
+		for _, crd := range src.CRDs {
+			definedBy[crd] = append(definedBy[crd], src.describe())
+		}
+	}
+	var findings []Finding
+	for _, src := range sources {
+		own := map[string]bool{}
+		for _, crd := range src.CRDs {
+			own[crd] = true
+		}
+		for _, kind := range src.Uses {
+			switch {
+			case own[kind]:
+			case len(definedBy[kind]) > 0:
+				findings = append(findings, Finding{
+					RuleID:     "install-order",
+					Severity:   SeverityWarning,
+					Path:       src.Path,
+					Message:    fmt.Sprintf("%s creates %s resources, whose CRD is installed by %s; charts and manifests are installed concurrently on first boot, so they fail and are retried until the CRD exists", src.describe(), kind, strings.Join(definedBy[kind], " and ")),
+					Suggestion: "use a chart that installs the CRD in its crds/ directory, or accept a slower first boot",
+				})
+			default:
+				msg := fmt.Sprintf("%s creates %s resources, but no chart or manifest installs their CRD, so they never reconcile unless an operator installs it", src.describe(), kind)
+				if incomplete {
+					msg += "; it may come from a chart or manifest that could not be analyzed"
+				}
+				findings = append(findings, Finding{
+					RuleID:     "missing-crd",
+					Severity:   SeverityWarning,
+					Path:       src.Path,
+					Message:    msg,
+					Suggestion: "add the chart or manifest installing the CRD",
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// describe names a source in messages, e.g. `chart "cert-manager"`.
+func (src InstallSource) describe() string {
+	return fmt.Sprintf("%s %q", src.Type, src.Name)
+}
+
+// inventory records the CRDs and namespaces a YAML stream installs, the
+// custom resources it creates and the namespaces it installs them into.
+//
+// Parameters:
+//   - manifests: The YAML stream.
+//   - namespace: The namespace of objects that do not set one, e.g. the
+//     release namespace of a chart, or empty to ignore them.
+//
+// Returns:
+//   - error: An error if the stream is malformed.
+func (src *InstallSource) inventory(manifests, namespace string) error {
+	crds := map[string]bool{}
+	uses := map[string]bool{}
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(manifests)))
+	for {
+		var doc map[string]interface{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid manifest: %w", err)
+		}
+		apiVersion, _ := doc["apiVersion"].(string)
+		kind, _ := doc["kind"].(string)
+		if kind == "" {
+			continue
+		}
+		group, _, found := strings.Cut(apiVersion, "/")
+		if !found {
+			group = ""
+		}
+		if kind == "Namespace" && group == "" {
+			if name, _ := lookupMap(doc, "metadata")["name"].(string); name != "" {
+				src.Namespaces = mergeSorted(src.Namespaces, name)
+			}
+		}
+		if ns := objectNamespace(doc, kind, namespace); ns != "" {
+			src.Targets = mergeSorted(src.Targets, ns)
+		}
+		if kind == "CustomResourceDefinition" && group == "apiextensions.k8s.io" {
+			spec := lookupMap(doc, "spec")
+			crdGroup, _ := spec["group"].(string)
+			crdKind, _ := lookupMap(spec, "names")["kind"].(string)
+			crds[crdKind+"."+crdGroup] = true
+			continue
+		}
+		if !builtinAPIGroups[group] {
+			uses[kind+"."+group] = true
+		}
+	}
+	src.CRDs = sortedKeys(crds)
+	src.Uses = sortedKeys(uses)
+	return nil
+}
+
+// sortedKeys returns the keys of a set, sorted.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
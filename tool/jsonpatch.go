@@ -0,0 +1,401 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchType selects how a patch is applied to an existing configuration.
+type PatchType string
+
+const (
+	// PatchDeepMerge applies a deep merge that matches list items by name (see MergeConfig).
+	PatchDeepMerge PatchType = "merge"
+	// PatchMergePatch applies an RFC 7386 JSON Merge Patch, where lists are replaced as a whole.
+	PatchMergePatch PatchType = "merge-patch"
+	// PatchJSONPatch applies an RFC 6902 JSON Patch, a list of add/remove/replace/move/copy/test operations.
+	PatchJSONPatch PatchType = "json-patch"
+)
+
+// ApplyPatch applies a patch of the given type to a configuration.
+//
+// Parameters:
+//   - config: The existing configuration. It may be modified in place.
+//   - patch: The patch: an object for the merge types, a list of operations for json-patch.
+//   - patchType: The patch type; empty selects PatchDeepMerge.
+//
+// Returns:
+//   - map[string]interface{}: The patched configuration.
+//   - error: An error if the patch is malformed or cannot be applied.
+func ApplyPatch(config map[string]interface{}, patch interface{}, patchType PatchType) (map[string]interface{}, error) {
+	switch patchType {
+	case "", PatchDeepMerge:
+		obj, ok := patch.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("a %q patch must be an object", PatchDeepMerge)
+		}
+		return MergeConfig(config, obj), nil
+	case PatchMergePatch:
+		merged, ok := ApplyMergePatch(config, patch).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("merge patch must produce an object")
+		}
+		return merged, nil
+	case PatchJSONPatch:
+		ops, ok := patch.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("a %q patch must be a list of operations", PatchJSONPatch)
+		}
+		patched, err := ApplyJSONPatch(config, ops)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := patched.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("JSON patch must produce an object")
+		}
+		return obj, nil
+	default:
+		return nil, fmt.Errorf("unknown patch type %q (expected %q, %q or %q)", patchType, PatchDeepMerge, PatchMergePatch, PatchJSONPatch)
+	}
+}
+
+// ApplyMergePatch applies an RFC 7386 JSON Merge Patch to a document.
+//
+// If the patch is an object, its members are merged recursively into the
+// target and null members remove the corresponding keys; any other patch
+// value (including lists) replaces the target.
+//
+// Parameters:
+//   - target: The document to patch. Objects are modified in place.
+//   - patch: The merge patch.
+//
+// Returns:
+//   - interface{}: The patched document.
+func ApplyMergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = ApplyMergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to a document.
+//
+// Operations are applied in order; if any operation fails, an error naming
+// the failing operation is returned.
+//
+// Parameters:
+//   - doc: The document to patch. It may be modified in place.
+//   - ops: The patch operations, each an object with "op", "path" and, depending on the op, "value" or "from".
+//
+// Returns:
+//   - interface{}: The patched document.
+//   - error: An error if an operation is malformed or cannot be applied.
+func ApplyJSONPatch(doc interface{}, ops []interface{}) (interface{}, error) {
+	for i, raw := range ops {
+		op, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operation %d: must be an object", i)
+		}
+		name, _ := op["op"].(string)
+		path, ok := op["path"].(string)
+		if !ok {
+			return nil, fmt.Errorf("operation %d (%s): missing \"path\"", i, name)
+		}
+
+		var err error
+		switch name {
+		case "add":
+			value, present := op["value"]
+			if !present {
+				return nil, fmt.Errorf("operation %d (add): missing \"value\"", i)
+			}
+			doc, err = pointerAdd(doc, path, value)
+		case "remove":
+			doc, _, err = pointerRemove(doc, path)
+		case "replace":
+			value, present := op["value"]
+			if !present {
+				return nil, fmt.Errorf("operation %d (replace): missing \"value\"", i)
+			}
+			if doc, _, err = pointerRemove(doc, path); err == nil {
+				doc, err = pointerAdd(doc, path, value)
+			}
+		case "move":
+			from, ok := op["from"].(string)
+			if !ok {
+				return nil, fmt.Errorf("operation %d (move): missing \"from\" or not a string", i)
+			}
+			if strings.HasPrefix(path, from+"/") {
+				return nil, fmt.Errorf("operation %d (move): cannot move %q into its own child %q", i, from, path)
+			}
+			var value interface{}
+			if doc, value, err = pointerRemove(doc, from); err == nil {
+				doc, err = pointerAdd(doc, path, value)
+			}
+		case "copy":
+			from, ok := op["from"].(string)
+			if !ok {
+				return nil, fmt.Errorf("operation %d (copy): missing \"from\" or not a string", i)
+			}
+			var value interface{}
+			if value, err = pointerGet(doc, from); err == nil {
+				doc, err = pointerAdd(doc, path, deepCopyValue(value))
+			}
+		case "test":
+			expected, present := op["value"]
+			if !present {
+				return nil, fmt.Errorf("operation %d (test): missing \"value\"", i)
+			}
+			var value interface{}
+			if value, err = pointerGet(doc, path); err == nil && !jsonEqual(value, expected) {
+				err = fmt.Errorf("value at %q does not match", path)
+			}
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op %q", i, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, name, err)
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+//
+// Parameters:
+//   - pointer: The pointer, e.g. "/operatingSystem/users/0".
+//
+// Returns:
+//   - []string: The reference tokens; empty for the whole document.
+//   - error: An error if the pointer does not start with "/".
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// pointerGet returns the value referenced by a JSON Pointer.
+func pointerGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			current = value
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, fmt.Errorf("path %q: %w", pointer, err)
+			}
+			current = node[idx]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	}
+	return current, nil
+}
+
+// pointerAdd adds a value at a JSON Pointer location.
+//
+// Adding to an object member sets it; adding to an array index inserts
+// before it, and "-" appends.
+func pointerAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[last] = value
+			return node, nil
+		case []interface{}:
+			idx, err := arrayIndex(last, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[idx+1:], node[idx:])
+			node[idx] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("path %q: parent is not a container", pointer)
+		}
+	})
+}
+
+// pointerRemove removes the value at a JSON Pointer location.
+//
+// Returns:
+//   - interface{}: The updated document.
+//   - interface{}: The removed value.
+//   - error: An error if the location does not exist.
+func pointerRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	updated, err := updateParent(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			value, ok := node[last]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			removed = value
+			delete(node, last)
+			return node, nil
+		case []interface{}:
+			idx, err := arrayIndex(last, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[idx]
+			return append(node[:idx], node[idx+1:]...), nil
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	})
+	return updated, removed, err
+}
+
+// updateParent walks to the parent of the location named by tokens, lets fn
+// update it, and writes the (possibly reallocated) parent back.
+func updateParent(doc interface{}, tokens []string, fn func(parent interface{}, last string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+
+	head := tokens[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[head]
+		if !ok {
+			return nil, fmt.Errorf("path segment %q does not exist", head)
+		}
+		updated, err := updateParent(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[head] = updated
+		return node, nil
+	case []interface{}:
+		idx, err := arrayIndex(head, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := updateParent(node[idx], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = updated
+		return node, nil
+	default:
+		return nil, fmt.Errorf("path segment %q does not exist", head)
+	}
+}
+
+// arrayIndex parses an array reference token.
+//
+// Parameters:
+//   - token: The token, a non-negative integer or "-".
+//   - length: The array length.
+//   - forAdd: Whether the index is used for insertion, which allows "-" and length.
+//
+// Returns:
+//   - int: The index.
+//   - error: An error if the token is not a valid index for the operation.
+func arrayIndex(token string, length int, forAdd bool) (int, error) {
+	if token == "-" {
+		if forAdd {
+			return length, nil
+		}
+		return 0, fmt.Errorf("index \"-\" can only be used to append")
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length - 1
+	if forAdd {
+		limit = length
+	}
+	if idx > limit {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
+
+// jsonEqual compares two values by their JSON encoding, so that numbers
+// decoded from YAML (int) and JSON (float64) compare equal.
+func jsonEqual(a, b interface{}) bool {
+	var na, nb interface{}
+	ra, errA := json.Marshal(a)
+	rb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	if json.Unmarshal(ra, &na) != nil || json.Unmarshal(rb, &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+// deepCopyValue returns a deep copy of a decoded JSON/YAML value.
+func deepCopyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = deepCopyValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = deepCopyValue(item)
+		}
+		return out
+	default:
+		return val
+	}
+}
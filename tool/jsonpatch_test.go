@@ -0,0 +1,163 @@
+package tool
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// patchDocument returns the document the JSON Patch tests apply to.
+func patchDocument() map[string]interface{} {
+	return map[string]interface{}{
+		"image": map[string]interface{}{"arch": "x86_64"},
+		"operatingSystem": map[string]interface{}{
+			"packages": map[string]interface{}{
+				"packageList": []interface{}{"vim", "curl"},
+			},
+		},
+	}
+}
+
+// decodeJSON decodes the expected document of a test case.
+func decodeJSON(t *testing.T, doc string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// TestApplyJSONPatch checks each operation and each malformed operation.
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		op   map[string]interface{}
+		// want is the JSON encoding of the patched document; empty when an
+		// error is expected.
+		want string
+		// err is a substring of the expected error.
+		err string
+	}{
+		{
+			name: "add field",
+			op:   map[string]interface{}{"op": "add", "path": "/image/imageType", "value": "iso"},
+			want: `{"image":{"arch":"x86_64","imageType":"iso"},"operatingSystem":{"packages":{"packageList":["vim","curl"]}}}`,
+		},
+		{
+			name: "add appends to list",
+			op:   map[string]interface{}{"op": "add", "path": "/operatingSystem/packages/packageList/-", "value": "git"},
+			want: `{"image":{"arch":"x86_64"},"operatingSystem":{"packages":{"packageList":["vim","curl","git"]}}}`,
+		},
+		{
+			name: "add without value",
+			op:   map[string]interface{}{"op": "add", "path": "/image/imageType"},
+			err:  `operation 0 (add): missing "value"`,
+		},
+		{
+			name: "remove",
+			op:   map[string]interface{}{"op": "remove", "path": "/operatingSystem/packages/packageList/0"},
+			want: `{"image":{"arch":"x86_64"},"operatingSystem":{"packages":{"packageList":["curl"]}}}`,
+		},
+		{
+			name: "remove missing field",
+			op:   map[string]interface{}{"op": "remove", "path": "/image/imageType"},
+			err:  "operation 0 (remove)",
+		},
+		{
+			name: "replace",
+			op:   map[string]interface{}{"op": "replace", "path": "/image/arch", "value": "aarch64"},
+			want: `{"image":{"arch":"aarch64"},"operatingSystem":{"packages":{"packageList":["vim","curl"]}}}`,
+		},
+		{
+			name: "replace without value",
+			op:   map[string]interface{}{"op": "replace", "path": "/image/arch"},
+			err:  `operation 0 (replace): missing "value"`,
+		},
+		{
+			name: "move",
+			op:   map[string]interface{}{"op": "move", "from": "/image/arch", "path": "/image/platform"},
+			want: `{"image":{"platform":"x86_64"},"operatingSystem":{"packages":{"packageList":["vim","curl"]}}}`,
+		},
+		{
+			name: "move without from",
+			op:   map[string]interface{}{"op": "move", "path": "/image/platform"},
+			err:  `operation 0 (move): missing "from"`,
+		},
+		{
+			name: "move with non-string from",
+			op:   map[string]interface{}{"op": "move", "from": 1, "path": "/image/platform"},
+			err:  `operation 0 (move): missing "from"`,
+		},
+		{
+			name: "move into own child",
+			op:   map[string]interface{}{"op": "move", "from": "/image", "path": "/image/copy"},
+			err:  "cannot move",
+		},
+		{
+			name: "copy",
+			op:   map[string]interface{}{"op": "copy", "from": "/image/arch", "path": "/image/platform"},
+			want: `{"image":{"arch":"x86_64","platform":"x86_64"},"operatingSystem":{"packages":{"packageList":["vim","curl"]}}}`,
+		},
+		{
+			name: "copy without from",
+			op:   map[string]interface{}{"op": "copy", "path": "/image/platform"},
+			err:  `operation 0 (copy): missing "from"`,
+		},
+		{
+			name: "copy with non-string from",
+			op:   map[string]interface{}{"op": "copy", "from": []interface{}{"image"}, "path": "/image/platform"},
+			err:  `operation 0 (copy): missing "from"`,
+		},
+		{
+			name: "test matches",
+			op:   map[string]interface{}{"op": "test", "path": "/image/arch", "value": "x86_64"},
+			want: `{"image":{"arch":"x86_64"},"operatingSystem":{"packages":{"packageList":["vim","curl"]}}}`,
+		},
+		{
+			name: "test does not match",
+			op:   map[string]interface{}{"op": "test", "path": "/image/arch", "value": "aarch64"},
+			err:  "does not match",
+		},
+		{
+			name: "test without value",
+			op:   map[string]interface{}{"op": "test", "path": "/image/missing"},
+			err:  `operation 0 (test): missing "value"`,
+		},
+		{
+			name: "missing path",
+			op:   map[string]interface{}{"op": "add", "value": "iso"},
+			err:  `operation 0 (add): missing "path"`,
+		},
+		{
+			name: "unknown op",
+			op:   map[string]interface{}{"op": "append", "path": "/image/arch"},
+			err:  `unknown op "append"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyJSONPatch(patchDocument(), []interface{}{tt.op})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(got, decodeJSON(t, tt.want)) {
+				t.Errorf("got %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyJSONPatchNotObject checks that an operation that is not an object
+// is rejected.
+func TestApplyJSONPatchNotObject(t *testing.T) {
+	if _, err := ApplyJSONPatch(patchDocument(), []interface{}{"add"}); err == nil || !strings.Contains(err.Error(), "must be an object") {
+		t.Fatalf("error %v, want one about the operation not being an object", err)
+	}
+}
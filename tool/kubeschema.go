@@ -0,0 +1,396 @@
+package tool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeKind describes what the API server requires of the objects of a
+// built-in kind.
+type kubeKind struct {
+	// Required lists the fields objects must set, as dotted paths.
+	Required []string
+	// PodSpec is the path of the pod spec of the objects, if they run pods.
+	PodSpec string
+	// Selector is whether spec.selector must match the labels of the pod
+	// template, as for Deployments.
+	Selector bool
+}
+
+// builtinKinds lists the kinds of the built-in API versions served by every
+// supported Kubernetes release, and by the helm-controller and addon manager
+// of RKE2 and K3s. Objects of other API versions, such as custom resources,
+// are only checked for a name.
+var builtinKinds = map[string]map[string]kubeKind{
+	"v1": {
+		"Binding":               {Required: []string{"target"}},
+		"ComponentStatus":       {},
+		"ConfigMap":             {},
+		"Endpoints":             {},
+		"Event":                 {Required: []string{"involvedObject"}},
+		"LimitRange":            {Required: []string{"spec.limits"}},
+		"List":                  {},
+		"Namespace":             {},
+		"Node":                  {},
+		"PersistentVolume":      {Required: []string{"spec.capacity", "spec.accessModes"}},
+		"PersistentVolumeClaim": {Required: []string{"spec.accessModes"}},
+		"Pod":                   {Required: []string{"spec"}, PodSpec: "spec"},
+		"PodTemplate":           {Required: []string{"template"}, PodSpec: "template.spec"},
+		"ReplicationController": {Required: []string{"spec.template"}, PodSpec: "spec.template.spec"},
+		"ResourceQuota":         {},
+		"Secret":                {},
+		"Service":               {},
+		"ServiceAccount":        {},
+	},
+	"apps/v1": {
+		"ControllerRevision": {Required: []string{"revision"}},
+		"DaemonSet":          {Required: []string{"spec.selector", "spec.template"}, PodSpec: "spec.template.spec", Selector: true},
+		"Deployment":         {Required: []string{"spec.selector", "spec.template"}, PodSpec: "spec.template.spec", Selector: true},
+		"ReplicaSet":         {Required: []string{"spec.selector", "spec.template"}, PodSpec: "spec.template.spec", Selector: true},
+		"StatefulSet":        {Required: []string{"spec.selector", "spec.template"}, PodSpec: "spec.template.spec", Selector: true},
+	},
+	"batch/v1": {
+		"CronJob": {Required: []string{"spec.schedule", "spec.jobTemplate.spec.template"}, PodSpec: "spec.jobTemplate.spec.template.spec"},
+		"Job":     {Required: []string{"spec.template"}, PodSpec: "spec.template.spec"},
+	},
+	"autoscaling/v1": {
+		"HorizontalPodAutoscaler": {Required: []string{"spec.scaleTargetRef", "spec.maxReplicas"}},
+	},
+	"autoscaling/v2": {
+		"HorizontalPodAutoscaler": {Required: []string{"spec.scaleTargetRef", "spec.maxReplicas"}},
+	},
+	"networking.k8s.io/v1": {
+		"Ingress":       {},
+		"IngressClass":  {},
+		"NetworkPolicy": {Required: []string{"spec.podSelector"}},
+	},
+	"policy/v1": {
+		"PodDisruptionBudget": {},
+	},
+	"rbac.authorization.k8s.io/v1": {
+		"ClusterRole":        {},
+		"ClusterRoleBinding": {Required: []string{"roleRef.kind", "roleRef.name"}},
+		"Role":               {},
+		"RoleBinding":        {Required: []string{"roleRef.kind", "roleRef.name"}},
+	},
+	"storage.k8s.io/v1": {
+		"CSIDriver":          {Required: []string{"spec"}},
+		"CSINode":            {Required: []string{"spec"}},
+		"CSIStorageCapacity": {Required: []string{"storageClassName"}},
+		"StorageClass":       {Required: []string{"provisioner"}},
+		"VolumeAttachment":   {Required: []string{"spec.attacher", "spec.nodeName", "spec.source"}},
+	},
+	"apiextensions.k8s.io/v1": {
+		"CustomResourceDefinition": {Required: []string{"spec.group", "spec.names.kind", "spec.names.plural", "spec.scope", "spec.versions"}},
+	},
+	"admissionregistration.k8s.io/v1": {
+		"MutatingWebhookConfiguration":     {},
+		"ValidatingAdmissionPolicy":        {},
+		"ValidatingAdmissionPolicyBinding": {},
+		"ValidatingWebhookConfiguration":   {},
+	},
+	"apiregistration.k8s.io/v1": {
+		"APIService": {},
+	},
+	"certificates.k8s.io/v1": {
+		"CertificateSigningRequest": {Required: []string{"spec.request", "spec.signerName"}},
+	},
+	"coordination.k8s.io/v1": {
+		"Lease": {},
+	},
+	"discovery.k8s.io/v1": {
+		"EndpointSlice": {Required: []string{"addressType", "endpoints"}},
+	},
+	"events.k8s.io/v1": {
+		"Event": {Required: []string{"eventTime"}},
+	},
+	"flowcontrol.apiserver.k8s.io/v1": {
+		"FlowSchema":                 {},
+		"PriorityLevelConfiguration": {},
+	},
+	"node.k8s.io/v1": {
+		"RuntimeClass": {Required: []string{"handler"}},
+	},
+	"scheduling.k8s.io/v1": {
+		"PriorityClass": {Required: []string{"value"}},
+	},
+	"helm.cattle.io/v1": {
+		"HelmChart":       {},
+		"HelmChartConfig": {},
+	},
+	"k3s.cattle.io/v1": {
+		"Addon":            {},
+		"ETCDSnapshotFile": {},
+	},
+}
+
+// checkKubernetesSchema checks the objects of a YAML stream against the API
+// of their built-in kind, so that a mistyped kind or a missing required
+// field fails before the manifest is baked into an image rather than when
+// the booted cluster rejects it.
+//
+// Objects of API versions that are not in builtinKinds are only checked for
+// a name; the API server is the authority on everything else.
+//
+// Parameters:
+//   - body: The YAML stream, already checked by checkKubernetesYAML.
+//
+// Returns:
+//   - []string: One message per problem, e.g. `document 2 (Deployment
+//     "web"): spec.selector is required`.
+func checkKubernetesSchema(body []byte) []string {
+	var problems []string
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+	for n := 1; ; n++ {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			if !errors.Is(err, io.EOF) {
+				problems = append(problems, fmt.Sprintf("document %d: %v", n, err))
+			}
+			break
+		}
+		if doc == nil {
+			continue
+		}
+		problems = append(problems, checkKubernetesObject(fmt.Sprintf("document %d", n), doc)...)
+	}
+	return problems
+}
+
+// checkKubernetesObject checks an object against the API of its kind.
+//
+// Parameters:
+//   - where: Locates the object in messages, e.g. "document 2".
+//   - obj: The object.
+//
+// Returns:
+//   - []string: One message per problem.
+func checkKubernetesObject(where string, obj map[string]interface{}) []string {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	name, _ := lookupMap(obj, "metadata")["name"].(string)
+	prefix := fmt.Sprintf("%s (%s %q): ", where, kind, name)
+	if name == "" {
+		prefix = fmt.Sprintf("%s (%s): ", where, kind)
+	}
+
+	kinds, builtin := builtinKinds[apiVersion]
+	spec, known := kinds[kind]
+	if builtin && !known {
+		return []string{prefix + unknownKindMessage(apiVersion, kind)}
+	}
+	if apiVersion == "v1" && kind == "List" {
+		var problems []string
+		items, _ := obj["items"].([]interface{})
+		for i, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				problems = append(problems, checkKubernetesObject(fmt.Sprintf("%s item %d", where, i), m)...)
+			} else {
+				problems = append(problems, fmt.Sprintf("%sitems.%d is not an object", prefix, i))
+			}
+		}
+		return problems
+	}
+
+	var problems []string
+	if name == "" {
+		if generated, _ := lookupMap(obj, "metadata")["generateName"].(string); generated == "" {
+			problems = append(problems, prefix+"metadata.name is required")
+		}
+	}
+	for _, field := range []string{"labels", "annotations"} {
+		problems = append(problems, checkStringMap(prefix+"metadata."+field, lookupMap(obj, "metadata")[field])...)
+	}
+	for _, field := range spec.Required {
+		if lookupPath(obj, field) == nil {
+			problems = append(problems, prefix+field+" is required")
+		}
+	}
+	if spec.PodSpec != "" {
+		if podSpec, ok := lookupPath(obj, spec.PodSpec).(map[string]interface{}); ok {
+			problems = append(problems, checkPodSpec(prefix+spec.PodSpec, podSpec)...)
+		} else if lookupPath(obj, strings.TrimSuffix(spec.PodSpec, ".spec")) != nil {
+			problems = append(problems, prefix+spec.PodSpec+" is required")
+		}
+	}
+	if spec.Selector {
+		problems = append(problems, checkSelector(prefix, obj)...)
+	}
+	return problems
+}
+
+// unknownKindMessage explains that a built-in API version has no such kind,
+// suggesting the kind the name was probably meant to be.
+//
+// Parameters:
+//   - apiVersion: The built-in API version, e.g. "apps/v1".
+//   - kind: The kind that it does not serve, e.g. "Deploymnet".
+//
+// Returns:
+//   - string: The message, ending in a suggestion when there is one.
+func unknownKindMessage(apiVersion, kind string) string {
+	msg := fmt.Sprintf("kind %q is not served by %s", kind, apiVersion)
+	var elsewhere []string
+	for groupVersion, kinds := range builtinKinds {
+		if _, ok := kinds[kind]; ok {
+			elsewhere = append(elsewhere, groupVersion)
+		}
+	}
+	if len(elsewhere) > 0 {
+		sort.Strings(elsewhere)
+		return fmt.Sprintf("%s; use apiVersion %s", msg, strings.Join(elsewhere, " or "))
+	}
+	best, bestDistance := "", 4
+	for candidate := range builtinKinds[apiVersion] {
+		if d := editDistance(strings.ToLower(kind), strings.ToLower(candidate)); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("%s; use kind %s", msg, best)
+	}
+	return msg
+}
+
+// checkPodSpec checks the containers of a pod spec.
+//
+// Parameters:
+//   - prefix: The message prefix locating the pod spec, ending in its path.
+//   - spec: The pod spec.
+//
+// Returns:
+//   - []string: One message per problem.
+func checkPodSpec(prefix string, spec map[string]interface{}) []string {
+	var problems []string
+	containers, _ := spec["containers"].([]interface{})
+	if len(containers) == 0 {
+		problems = append(problems, prefix+".containers must list at least one container")
+	}
+	for _, field := range []string{"initContainers", "containers"} {
+		list, _ := spec[field].([]interface{})
+		for i, item := range list {
+			path := fmt.Sprintf("%s.%s.%d", prefix, field, i)
+			container, ok := item.(map[string]interface{})
+			if !ok {
+				problems = append(problems, path+" is not an object")
+				continue
+			}
+			for _, key := range []string{"name", "image"} {
+				if s, _ := container[key].(string); s == "" {
+					problems = append(problems, fmt.Sprintf("%s.%s is required", path, key))
+				}
+			}
+			ports, _ := container["ports"].([]interface{})
+			for j, p := range ports {
+				port, _ := p.(map[string]interface{})
+				if _, ok := port["containerPort"].(int); !ok {
+					problems = append(problems, fmt.Sprintf("%s.ports.%d.containerPort must be an integer", path, j))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// checkSelector checks that spec.selector.matchLabels selects the pods of
+// spec.template, which the API server requires of workload controllers.
+//
+// Parameters:
+//   - prefix: The message prefix locating the object.
+//   - obj: The object.
+//
+// Returns:
+//   - []string: A message if the selector does not match the template labels.
+func checkSelector(prefix string, obj map[string]interface{}) []string {
+	matchLabels := lookupMap(obj, "spec", "selector", "matchLabels")
+	labels := lookupMap(obj, "spec", "template", "metadata", "labels")
+	for _, key := range sortedKeys(stringKeys(matchLabels)) {
+		if fmt.Sprint(labels[key]) != fmt.Sprint(matchLabels[key]) || labels[key] == nil {
+			return []string{fmt.Sprintf("%sspec.selector.matchLabels %s=%v does not match spec.template.metadata.labels; set the label on the pod template", prefix, key, matchLabels[key])}
+		}
+	}
+	return nil
+}
+
+// checkStringMap checks that a map of labels or annotations has string
+// values, as the API requires; an unquoted number or boolean is a common
+// mistake.
+//
+// Parameters:
+//   - where: The message prefix, ending in the path of the map.
+//   - value: The map, or nil.
+//
+// Returns:
+//   - []string: One message per value that is not a string.
+func checkStringMap(where string, value interface{}) []string {
+	if value == nil {
+		return nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return []string{where + " must be a map of strings"}
+	}
+	var problems []string
+	for _, key := range sortedKeys(stringKeys(m)) {
+		if _, ok := m[key].(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s.%s must be a string; quote the value %v", where, key, m[key]))
+		}
+	}
+	return problems
+}
+
+// stringKeys returns the key set of a map.
+func stringKeys(m map[string]interface{}) map[string]bool {
+	keys := make(map[string]bool, len(m))
+	for key := range m {
+		keys[key] = true
+	}
+	return keys
+}
+
+// lookupPath returns the value at a dotted path of nested maps.
+//
+// Parameters:
+//   - obj: The object.
+//   - path: The path, e.g. "spec.template.spec".
+//
+// Returns:
+//   - interface{}: The value, or nil if any part of the path is missing.
+func lookupPath(obj map[string]interface{}, path string) interface{} {
+	var value interface{} = obj
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,84 @@
+package tool
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LayerNames are the layers LayerConfig merges, lowest precedence first.
+var LayerNames = []string{"base", "environment", "site"}
+
+// Layering is the result of LayerConfig.
+type Layering struct {
+	// Config is the merged configuration.
+	Config map[string]interface{} `json:"-"`
+	// Origins maps the dotted path of each field to the layer whose value it
+	// has, e.g. "kubernetes.version": "environment". A list without
+	// identifying fields is attributed to the last layer adding items to it.
+	Origins map[string]string `json:"origins"`
+	// Findings lists the overridden and redundant values.
+	Findings []Finding `json:"findings"`
+}
+
+// LayerConfig merges the three layers fleet operators organize their
+// definitions in: a base template shared by every image, an environment
+// overlay, e.g. for production, and a site overlay for one location.
+//
+// Each overlay is merged onto the layers below it as MergeConfig merges a
+// patch: its values replace theirs, users, nodes, charts and the other keyed
+// list items are merged by their identifying field, lists of strings gain
+// its values and null removes a field. Each value an overlay replaces or
+// removes is reported: as a layer-override note when it comes from the base,
+// and as a layer-conflict warning when the site replaces the value of the
+// environment, since the site then departs from its environment. An overlay
+// setting a field to the value it already has is a layer-redundant warning.
+//
+// Parameters:
+//   - base: The base template.
+//   - environment: The environment overlay, or nil.
+//   - site: The site overlay, or nil.
+//
+// Returns:
+//   - *Layering: The merged configuration, the layer of each field and the
+//     findings.
+func LayerConfig(base, environment, site map[string]interface{}) *Layering {
+	c := &composer{
+		setters:  map[string]string{},
+		sources:  map[string][]string{},
+		reported: map[string]bool{},
+	}
+	config := map[string]interface{}{}
+	for i, layer := range []map[string]interface{}{base, environment, site} {
+		if layer == nil {
+			continue
+		}
+		layer = deepCopyValue(layer).(map[string]interface{})
+		c.walk(config, layer, "", "", LayerNames[i])
+		config = MergeConfig(config, layer)
+	}
+
+	var overrides, conflicts, redundant []string
+	for _, o := range c.overrides {
+		verb := "overrides"
+		if o.Removed {
+			verb = "removes"
+		}
+		switch {
+		case o.Same:
+			redundant = append(redundant, fmt.Sprintf("%s: %s sets the value %s already sets; remove it from %s", o.Path, o.Name, o.Previous, o.Name))
+		case o.Previous == "environment":
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s %s the value set by %s; remove it from %s, or move it to %s if every site of the environment needs it", o.Path, o.Name, verb, o.Previous, o.Name, o.Previous))
+		default:
+			overrides = append(overrides, fmt.Sprintf("%s: %s %s the value set by %s", o.Path, o.Name, verb, o.Previous))
+		}
+	}
+	sort.Strings(overrides)
+	sort.Strings(conflicts)
+	sort.Strings(redundant)
+
+	l := &Layering{Config: config, Origins: c.setters, Findings: []Finding{}}
+	l.Findings = append(l.Findings, NewFindings("layer-conflict", SeverityWarning, conflicts)...)
+	l.Findings = append(l.Findings, NewFindings("layer-redundant", SeverityWarning, redundant)...)
+	l.Findings = append(l.Findings, NewFindings("layer-override", SeverityInfo, overrides)...)
+	return l
+}
@@ -0,0 +1,271 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestsDir is the directory of the image configuration directory whose
+// manifests EIB applies once the cluster is up, like those of
+// kubernetes.manifests.urls.
+const ManifestsDir = "kubernetes/manifests"
+
+// unsafeFileNameChars matches the characters replaced in the file names of
+// manifest snapshots.
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Manifest is a local Kubernetes manifest EIB applies once the cluster is up.
+type Manifest struct {
+	// Name is the file name under kubernetes/manifests/, ending in .yaml or
+	// .yml, e.g. "web.yaml".
+	Name string `json:"name"`
+	// Content is the YAML stream of Kubernetes objects.
+	Content string `json:"content"`
+}
+
+// PrepareManifests checks local manifests against the Kubernetes API (see
+// checkKubernetesSchema) and turns them into ManifestsDir artifacts.
+//
+// Parameters:
+//   - manifests: The manifests.
+//
+// Returns:
+//   - []Artifact: One artifact per manifest.
+//   - error: A *FindingsError listing every invalid name and every object
+//     that does not match the API of its kind.
+func PrepareManifests(manifests []Manifest) ([]Artifact, error) {
+	var artifacts []Artifact
+	var findings []Finding
+	used := map[string]bool{}
+	for i, m := range manifests {
+		artifact := Artifact{Path: ManifestsDir + "/" + m.Name, Content: m.Content, Mode: "0644"}
+		var problems []string
+		switch {
+		case m.Name == "" || strings.ContainsAny(m.Name, "/\\") || strings.HasPrefix(m.Name, "."):
+			problems = append(problems, fmt.Sprintf("manifests.%d: %q is not a file name", i, m.Name))
+		case !strings.HasSuffix(m.Name, ".yaml") && !strings.HasSuffix(m.Name, ".yml"):
+			problems = append(problems, fmt.Sprintf("manifests.%d: %s does not end in .yaml or .yml, so EIB ignores it; use a .yaml name", i, m.Name))
+		case used[m.Name]:
+			problems = append(problems, fmt.Sprintf("manifests.%d: %s is listed twice", i, m.Name))
+		default:
+			used[m.Name] = true
+			if err := checkKubernetesYAML([]byte(m.Content)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: not Kubernetes YAML: %v", artifact.Path, err))
+				break
+			}
+			for _, problem := range checkKubernetesSchema([]byte(m.Content)) {
+				problems = append(problems, artifact.Path+": "+problem)
+			}
+		}
+		findings = append(findings, NewFindings("manifest-schema", SeverityError, problems)...)
+		artifacts = append(artifacts, artifact)
+	}
+	if len(findings) > 0 {
+		return nil, &FindingsError{Summary: "manifests are invalid", Findings: findings}
+	}
+	return artifacts, nil
+}
+
+// CheckManifestURLs downloads the manifests of kubernetes.manifests.urls
+// through opts.HTTP and checks they parse as Kubernetes objects matching the
+// API of their kind, so that a moved or mistyped URL, or a broken manifest,
+// is caught before EIB fails to download it at build time or the cluster
+// rejects it.
+//
+// Parameters:
+//   - ctx: The context bounding the downloads.
+//   - config: The configuration.
+//   - opts: The options; HTTP, Offline and Trace are used.
+//
+// Returns:
+//   - []string: One warning per manifest that cannot be downloaded or is not
+//     Kubernetes YAML, one per object that does not match the API of its
+//     kind, or a single warning in offline mode.
+func CheckManifestURLs(ctx context.Context, config map[string]interface{}, opts Options) []string {
+	urls := stringList(lookupMap(config, "kubernetes", "manifests")["urls"])
+	if len(urls) == 0 {
+		return nil
+	}
+	if opts.Offline {
+		return []string{"kubernetes.manifests.urls: the manifests were not downloaded in offline mode"}
+	}
+	var warnings []string
+	for i, u := range urls {
+		_, problems, err := fetchManifest(ctx, u, opts)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("kubernetes.manifests.urls.%d: %v", i, err))
+		}
+		for _, problem := range problems {
+			warnings = append(warnings, fmt.Sprintf("kubernetes.manifests.urls.%d: %s", i, problem))
+		}
+	}
+	return warnings
+}
+
+// SnapshotManifests downloads the manifests of kubernetes.manifests.urls and
+// returns them as artifacts of ManifestsDir, removing the URLs from the
+// configuration, so that the image can be rebuilt on an air-gapped host and
+// always deploys the same manifests.
+//
+// The snapshots keep the downloaded bytes unchanged, so their checksums can
+// be compared with the published files. A snapshot whose name is taken by a
+// local manifest or an earlier snapshot is prefixed with the index of its
+// URL, e.g. "1-cert-manager.yaml".
+//
+// Parameters:
+//   - ctx: The context bounding the downloads.
+//   - config: The configuration; kubernetes.manifests.urls is removed.
+//   - local: The local manifests, whose names are taken in ManifestsDir.
+//   - opts: The options; HTTP, Offline and Trace are used.
+//
+// Returns:
+//   - []Artifact: One artifact per manifest.
+//   - []string: One note per snapshot, naming its URL and file.
+//   - error: A *Error of KindNetwork if a manifest cannot be downloaded or is
+//     not Kubernetes YAML, or in offline mode; a *Error of KindArtifact
+//     holding a *FindingsError if objects do not match the API of their kind.
+func SnapshotManifests(ctx context.Context, config map[string]interface{}, local []Manifest, opts Options) ([]Artifact, []string, error) {
+	manifests := lookupMap(config, "kubernetes", "manifests")
+	urls := stringList(manifests["urls"])
+	if len(urls) == 0 {
+		return nil, nil, nil
+	}
+	if opts.Offline {
+		return nil, nil, classify(KindNetwork, errors.New("manifests cannot be snapshotted in offline mode"))
+	}
+
+	var artifacts []Artifact
+	var notes []string
+	var findings []Finding
+	used := map[string]bool{}
+	for _, m := range local {
+		used[m.Name] = true
+	}
+	for i, u := range urls {
+		body, problems, err := fetchManifest(ctx, u, opts)
+		if err != nil {
+			return nil, nil, classify(KindNetwork, fmt.Errorf("kubernetes.manifests.urls.%d: %w", i, err))
+		}
+		for _, problem := range problems {
+			findings = append(findings, NewFindings("manifest-schema", SeverityError, []string{fmt.Sprintf("kubernetes.manifests.urls.%d: %s", i, problem)})...)
+		}
+		base := snapshotName(u, i)
+		name := base
+		for n := i; used[name]; n++ {
+			name = fmt.Sprintf("%d-%s", n, base)
+		}
+		used[name] = true
+		artifact := Artifact{Path: ManifestsDir + "/" + name, Content: string(body), Mode: "0644"}
+		artifacts = append(artifacts, artifact)
+		notes = append(notes, fmt.Sprintf("kubernetes.manifests.urls.%d: %s was snapshotted to %s", i, u, artifact.Path))
+	}
+	if len(findings) > 0 {
+		return nil, nil, classify(KindArtifact, &FindingsError{Summary: "manifests are invalid", Findings: findings})
+	}
+	delete(manifests, "urls")
+	if len(manifests) == 0 {
+		delete(lookupMap(config, "kubernetes"), "manifests")
+	}
+	return artifacts, notes, nil
+}
+
+// fetchManifest downloads a manifest and checks it holds Kubernetes objects
+// matching the API of their kind.
+//
+// Parameters:
+//   - ctx: The context bounding the download.
+//   - u: The manifest URL.
+//   - opts: The options; HTTP and Trace are used.
+//
+// Returns:
+//   - []byte: The manifest.
+//   - []string: The objects that do not match the API of their kind (see
+//     checkKubernetesSchema).
+//   - error: An error naming the URL if it cannot be downloaded or is not
+//     Kubernetes YAML.
+func fetchManifest(ctx context.Context, u string, opts Options) ([]byte, []string, error) {
+	endStep := opts.Trace.Step("download " + u)
+	body, err := downloadManifest(ctx, opts.HTTP, u)
+	endStep()
+	if err != nil {
+		return nil, nil, fmt.Errorf("manifest %s could not be downloaded: %v", u, err)
+	}
+	if err := checkKubernetesYAML(body); err != nil {
+		return nil, nil, fmt.Errorf("manifest %s is not Kubernetes YAML: %v", u, err)
+	}
+	return body, checkKubernetesSchema(body), nil
+}
+
+// checkKubernetesYAML checks that a YAML stream holds Kubernetes objects.
+//
+// Parameters:
+//   - body: The YAML stream.
+//
+// Returns:
+//   - error: An error naming the first document that is not an object with
+//     an apiVersion and kind, or if there is no object at all.
+func checkKubernetesYAML(body []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+	objects := 0
+	for n := 1; ; n++ {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("document %d: %v", n, err)
+		}
+		if doc == nil {
+			continue
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("document %d is not an object", n)
+		}
+		for _, key := range []string{"apiVersion", "kind"} {
+			if s, _ := obj[key].(string); s == "" {
+				return fmt.Errorf("document %d has no %s", n, key)
+			}
+		}
+		objects++
+	}
+	if objects == 0 {
+		return errors.New("it holds no objects")
+	}
+	return nil
+}
+
+// snapshotName derives the file name of a manifest snapshot from its URL,
+// e.g. "cert-manager.yaml" from
+// https://github.com/cert-manager/cert-manager/releases/download/v1.16.1/cert-manager.yaml.
+//
+// Parameters:
+//   - u: The manifest URL.
+//   - i: The index of the URL, naming manifests whose URL has no file name.
+//
+// Returns:
+//   - string: The file name, ending in .yaml or .yml.
+func snapshotName(u string, i int) string {
+	name := ""
+	if parsed, err := url.Parse(u); err == nil {
+		name = unsafeFileNameChars.ReplaceAllString(path.Base(parsed.Path), "-")
+	}
+	name = strings.Trim(name, ".-")
+	if name == "" {
+		name = fmt.Sprintf("manifest-%d", i)
+	}
+	if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") {
+		name += ".yaml"
+	}
+	return name
+}
@@ -0,0 +1,42 @@
+package tool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestSnapshotManifestsNames checks that snapshots are not named after a
+// local manifest or an earlier snapshot, whose file they would replace.
+func TestSnapshotManifestsNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: " + r.URL.Path[1:4] + "\n"))
+	}))
+	defer server.Close()
+	config := map[string]interface{}{
+		"kubernetes": map[string]interface{}{
+			"manifests": map[string]interface{}{
+				"urls": []interface{}{
+					server.URL + "/one/app.yaml",
+					server.URL + "/two/app.yaml",
+					server.URL + "/six/web.yaml",
+				},
+			},
+		},
+	}
+	local := []Manifest{{Name: "web.yaml"}, {Name: "1-app.yaml"}}
+	artifacts, _, err := SnapshotManifests(context.Background(), config, local, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{ManifestsDir + "/app.yaml", ManifestsDir + "/2-app.yaml", ManifestsDir + "/2-web.yaml"}
+	if len(artifacts) != len(want) {
+		t.Fatalf("got %d snapshots, want %d", len(artifacts), len(want))
+	}
+	for i, artifact := range artifacts {
+		if artifact.Path != want[i] {
+			t.Errorf("snapshot %d: path %s, want %s", i, artifact.Path, want[i])
+		}
+	}
+}
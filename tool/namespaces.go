@@ -0,0 +1,175 @@
+package tool
+
+import (
+	"fmt"
+	"sort"
+)
+
+// builtinNamespaces are the namespaces every Kubernetes cluster has.
+var builtinNamespaces = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// clusterScopedKinds are the built-in kinds whose objects belong to no
+// namespace, so a namespace in their metadata is ignored.
+var clusterScopedKinds = map[string]bool{
+	"APIService":                       true,
+	"CSIDriver":                        true,
+	"CSINode":                          true,
+	"CertificateSigningRequest":        true,
+	"ClusterRole":                      true,
+	"ClusterRoleBinding":               true,
+	"ComponentStatus":                  true,
+	"CustomResourceDefinition":         true,
+	"FlowSchema":                       true,
+	"IngressClass":                     true,
+	"MutatingWebhookConfiguration":     true,
+	"Namespace":                        true,
+	"Node":                             true,
+	"PersistentVolume":                 true,
+	"PriorityClass":                    true,
+	"PriorityLevelConfiguration":       true,
+	"RuntimeClass":                     true,
+	"StorageClass":                     true,
+	"ValidatingAdmissionPolicy":        true,
+	"ValidatingAdmissionPolicyBinding": true,
+	"ValidatingWebhookConfiguration":   true,
+	"VolumeAttachment":                 true,
+}
+
+// CheckNamespaces finds the charts of a configuration and the objects of
+// its local manifests that are installed into a namespace nothing creates,
+// without rendering the charts or downloading manifest URLs.
+//
+// A chart creates its targetNamespace if createNamespace is set, and a
+// manifest creates the namespaces of its Namespace objects. Resources bound
+// for another namespace fail to install, which leaves the cluster half
+// deployed. CheckInstallOrder also renders the charts and downloads the
+// manifest URLs, which can create namespaces too.
+//
+// Parameters:
+//   - config: The configuration.
+//   - manifests: The local manifests, already checked by PrepareManifests.
+//
+// Returns:
+//   - []Finding: One missing-namespace warning per source and namespace.
+func CheckNamespaces(config map[string]interface{}, manifests []Manifest) []Finding {
+	var sources []InstallSource
+	charts := lookupMaps(config, "kubernetes", "helm", "charts")
+	for i, chart := range charts {
+		src := InstallSource{Type: "chart", Path: fmt.Sprintf("kubernetes.helm.charts.%d", i)}
+		src.Name, _ = chart["name"].(string)
+		src.chartNamespaces(chart)
+		sources = append(sources, src)
+	}
+	for _, m := range manifests {
+		src := InstallSource{Type: "manifest", Name: m.Name, Path: ManifestsDir + "/" + m.Name}
+		if err := src.inventory(m.Content, ""); err != nil {
+			continue
+		}
+		sources = append(sources, src)
+	}
+	hedge := ""
+	if len(charts) > 0 || len(stringList(lookupMap(config, "kubernetes", "manifests")["urls"])) > 0 {
+		hedge = "; it may be created by the templates of a chart or by a manifest URL, which check_install_order analyzes"
+	}
+	return namespaceFindings(sources, hedge)
+}
+
+// chartNamespaces records the namespaces a chart entry creates and installs
+// into: the helm-controller creates its HelmChart resource in
+// installationNamespace and the release in targetNamespace.
+//
+// Parameters:
+//   - chart: The chart entry.
+func (src *InstallSource) chartNamespaces(chart map[string]interface{}) {
+	target, _ := chart["targetNamespace"].(string)
+	if target == "" {
+		target = "default"
+	}
+	installation, _ := chart["installationNamespace"].(string)
+	if installation == "" {
+		installation = "kube-system"
+	}
+	if create, _ := chart["createNamespace"].(bool); create {
+		src.Namespaces = mergeSorted(src.Namespaces, target)
+	}
+	src.Targets = mergeSorted(src.Targets, target, installation)
+}
+
+// namespaceFindings reports the namespaces each source installs into that
+// are neither built in nor created by a source.
+//
+// Parameters:
+//   - sources: The analyzed sources.
+//   - hedge: Appended to each message when some sources could not be
+//     analyzed, e.g. "; it may come from a chart or manifest that could not
+//     be analyzed".
+//
+// Returns:
+//   - []Finding: The findings, in source order.
+func namespaceFindings(sources []InstallSource, hedge string) []Finding {
+	created := map[string]bool{}
+	for _, src := range sources {
+		for _, ns := range src.Namespaces {
+			created[ns] = true
+		}
+	}
+	var findings []Finding
+	for _, src := range sources {
+		for _, ns := range src.Targets {
+			if builtinNamespaces[ns] || created[ns] {
+				continue
+			}
+			suggestion := "add a Namespace object to a manifest, or set createNamespace on a chart installing into it"
+			if src.Type == "chart" {
+				suggestion = "set createNamespace to true, or add a Namespace object to a manifest"
+			}
+			findings = append(findings, Finding{
+				RuleID:     "missing-namespace",
+				Severity:   SeverityWarning,
+				Path:       src.Path,
+				Message:    fmt.Sprintf("%s installs resources into namespace %q, but no chart or manifest creates it, so they fail to install%s", src.describe(), ns, hedge),
+				Suggestion: suggestion,
+			})
+		}
+	}
+	return findings
+}
+
+// objectNamespace returns the namespace an object is created in.
+//
+// Parameters:
+//   - doc: The object.
+//   - kind: The object's kind.
+//   - namespace: The namespace of objects that do not set one, e.g. the
+//     release namespace of a chart, or empty to ignore them.
+//
+// Returns:
+//   - string: The namespace, or empty for cluster-scoped objects.
+func objectNamespace(doc map[string]interface{}, kind, namespace string) string {
+	if clusterScopedKinds[kind] {
+		return ""
+	}
+	if ns, _ := lookupMap(doc, "metadata")["namespace"].(string); ns != "" {
+		return ns
+	}
+	return namespace
+}
+
+// mergeSorted adds values to a sorted list of unique strings.
+func mergeSorted(list []string, values ...string) []string {
+	for _, v := range values {
+		i := sort.SearchStrings(list, v)
+		if i < len(list) && list[i] == v {
+			continue
+		}
+		list = append(list, "")
+		copy(list[i+1:], list[i:])
+		list[i] = v
+	}
+	return list
+}
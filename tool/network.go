@@ -0,0 +1,519 @@
+package tool
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// networkDir is the image configuration directory holding one nmstate file
+// per node, named after the node's hostname.
+const networkDir = "network"
+
+// maxInterfaceName is the longest Linux interface name (IFNAMSIZ - 1).
+const maxInterfaceName = 15
+
+// NetworkConfig is the nmstate network configuration of one node.
+type NetworkConfig struct {
+	// Hostname is the node the configuration applies to; EIB matches the
+	// file network/<hostname>.yaml to the node by this name.
+	Hostname string `json:"hostname"`
+	// Content is the nmstate YAML document.
+	Content string `json:"content"`
+}
+
+// nmstateFile is a parsed nmstate document, as seen by the network checks.
+type nmstateFile struct {
+	// doc is the whole document.
+	doc map[string]interface{}
+	// interfaces lists the objects of the "interfaces" list, in order.
+	interfaces []map[string]interface{}
+	// byName holds the interfaces keyed by name.
+	byName map[string]map[string]interface{}
+}
+
+// networkCheck inspects one nmstate file.
+//
+// Parameters:
+//   - f: The parsed file.
+//
+// Returns:
+//   - []string: The errors found.
+//   - []string: The warnings.
+type networkCheck func(f *nmstateFile) ([]string, []string)
+
+// networkChecks are run on every network file, in order.
+var networkChecks = []networkCheck{
+	checkInterfaces,
+	checkTopology,
+	checkDNS,
+	checkRoutes,
+}
+
+// PrepareNetworkConfigs validates nmstate network configurations and turns
+// them into network/ artifacts.
+//
+// Each file must be an nmstate document whose interfaces have valid names and
+// MAC addresses. The files must be named after distinct, valid hostnames
+// that, when the configuration lists kubernetes.nodes, belong to one of the
+// nodes, and a MAC address must not appear in two files.
+//
+// Parameters:
+//   - configs: The network configurations.
+//   - input: The configuration whose kubernetes.nodes the hostnames must match.
+//
+// Returns:
+//   - []Artifact: The artifacts, ordered by hostname.
+//   - []string: The warnings, prefixed with the file name.
+//   - error: An error listing every problem found.
+func PrepareNetworkConfigs(configs []NetworkConfig, input map[string]interface{}) ([]Artifact, []string, error) {
+	nodes := map[string]bool{}
+	for _, node := range lookupMaps(input, "kubernetes", "nodes") {
+		if hostname, _ := node["hostname"].(string); hostname != "" {
+			nodes[hostname] = true
+		}
+	}
+
+	var artifacts []Artifact
+	var warnings, errs []string
+	seen := map[string]bool{}
+	macOwners := map[string]string{}
+	for _, c := range configs {
+		file := networkDir + "/" + c.Hostname + ".yaml"
+		switch {
+		case !hostnamePattern.MatchString(c.Hostname):
+			errs = append(errs, fmt.Sprintf("%q: invalid hostname", c.Hostname))
+			continue
+		case seen[c.Hostname]:
+			errs = append(errs, fmt.Sprintf("%s: duplicate hostname", file))
+			continue
+		case len(nodes) > 0 && !nodes[c.Hostname]:
+			errs = append(errs, fmt.Sprintf("%s: %q is not a node of kubernetes.nodes", file, c.Hostname))
+		}
+		seen[c.Hostname] = true
+
+		fileErrs, fileWarnings, macs := CheckNetworkConfig(c.Content)
+		for _, e := range fileErrs {
+			errs = append(errs, file+": "+e)
+		}
+		for _, w := range fileWarnings {
+			warnings = append(warnings, file+": "+w)
+		}
+		for _, mac := range macs {
+			if owner, ok := macOwners[mac]; ok && owner != c.Hostname {
+				errs = append(errs, fmt.Sprintf("%s: MAC address %s is also used by %s", file, mac, owner))
+				continue
+			}
+			macOwners[mac] = c.Hostname
+		}
+		artifacts = append(artifacts, Artifact{Path: file, Content: c.Content, Mode: defaultFileMode})
+	}
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid network configurations:\n- %s", strings.Join(errs, "\n- "))
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Path < artifacts[j].Path })
+	return artifacts, warnings, nil
+}
+
+// CheckNetworkConfig validates one nmstate network file.
+//
+// Parameters:
+//   - content: The nmstate YAML document.
+//
+// Returns:
+//   - []string: The errors found.
+//   - []string: The warnings.
+//   - []string: The MAC addresses of the ethernet interfaces, normalized to
+//     upper case, for cross-file checks.
+func CheckNetworkConfig(content string) ([]string, []string, []string) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return []string{fmt.Sprintf("invalid YAML: %v", err)}, nil, nil
+	}
+	if doc == nil {
+		return []string{"empty document"}, nil, nil
+	}
+	list, ok := doc["interfaces"].([]interface{})
+	if !ok {
+		return []string{"the document has no \"interfaces\" list"}, nil, nil
+	}
+
+	f := &nmstateFile{doc: doc, byName: map[string]map[string]interface{}{}}
+	var errs, warnings, macs []string
+	for i, item := range list {
+		iface, ok := item.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("interfaces[%d]: must be an object", i))
+			continue
+		}
+		f.interfaces = append(f.interfaces, iface)
+		name, _ := iface["name"].(string)
+		if _, dup := f.byName[name]; dup && name != "" {
+			errs = append(errs, fmt.Sprintf("interface %s: defined twice", name))
+			continue
+		}
+		f.byName[name] = iface
+		if mac, _ := iface["mac-address"].(string); mac != "" && interfaceType(iface) == "ethernet" {
+			if hw, err := net.ParseMAC(mac); err == nil {
+				macs = append(macs, strings.ToUpper(hw.String()))
+			}
+		}
+	}
+	for _, check := range networkChecks {
+		e, w := check(f)
+		errs = append(errs, e...)
+		warnings = append(warnings, w...)
+	}
+	return errs, warnings, macs
+}
+
+// checkInterfaces checks interface names and MAC addresses, and that
+// ethernet interfaces can be matched to the NICs of the machine.
+func checkInterfaces(f *nmstateFile) ([]string, []string) {
+	var errs, warnings []string
+	macs := map[string]string{}
+	for i, iface := range f.interfaces {
+		name, _ := iface["name"].(string)
+		if msg := checkInterfaceName(name); msg != "" {
+			errs = append(errs, fmt.Sprintf("interfaces[%d]: %s", i, msg))
+			continue
+		}
+		mac, hasMAC := iface["mac-address"].(string)
+		if hasMAC {
+			hw, err := net.ParseMAC(mac)
+			if err != nil || len(hw) != 6 {
+				errs = append(errs, fmt.Sprintf("interface %s: invalid mac-address %q, expected six hexadecimal octets such as 52:54:00:12:34:56", name, mac))
+				continue
+			}
+		}
+		if id, _ := iface["identifier"].(string); id == "mac-address" && !hasMAC {
+			errs = append(errs, fmt.Sprintf("interface %s: identifier is mac-address but no mac-address is set", name))
+		}
+		if interfaceType(iface) != "ethernet" {
+			continue
+		}
+		if !hasMAC {
+			warnings = append(warnings, fmt.Sprintf("interface %s has no mac-address; EIB identifies the NICs of a node by MAC address, so the interface may not be configured", name))
+			continue
+		}
+		hw, _ := net.ParseMAC(mac)
+		upper := strings.ToUpper(hw.String())
+		if other, ok := macs[upper]; ok {
+			errs = append(errs, fmt.Sprintf("interface %s: mac-address %s is also used by interface %s", name, upper, other))
+		}
+		macs[upper] = name
+	}
+	return errs, warnings
+}
+
+// checkInterfaceName checks an interface name against the Linux rules.
+//
+// Parameters:
+//   - name: The interface name.
+//
+// Returns:
+//   - string: The problem, or empty if the name is valid.
+func checkInterfaceName(name string) string {
+	switch {
+	case name == "":
+		return "interface has no name"
+	case len(name) > maxInterfaceName:
+		return fmt.Sprintf("interface name %q is longer than %d characters", name, maxInterfaceName)
+	case name == "." || name == "..":
+		return fmt.Sprintf("interface name %q is reserved", name)
+	case strings.ContainsAny(name, "/: \t\n"):
+		return fmt.Sprintf("interface name %q contains '/', ':' or whitespace", name)
+	}
+	return ""
+}
+
+// interfaceType returns the nmstate type of an interface, "ethernet" if unset.
+func interfaceType(iface map[string]interface{}) string {
+	if t, _ := iface["type"].(string); t != "" {
+		return t
+	}
+	return "ethernet"
+}
+
+// checkTopology checks layered interfaces: the parent of a VLAN and the ports
+// of bonds and bridges must be interfaces of the file, and an interface can
+// be the port of only one bond or bridge. nmstate only rejects such files
+// when the device applies them, at first boot.
+func checkTopology(f *nmstateFile) ([]string, []string) {
+	var errs, warnings []string
+	controllers := map[string]string{}
+	for _, iface := range f.interfaces {
+		name, _ := iface["name"].(string)
+		if name == "" {
+			continue
+		}
+		switch interfaceType(iface) {
+		case "vlan":
+			vlan, _ := iface["vlan"].(map[string]interface{})
+			base, _ := vlan["base-iface"].(string)
+			switch {
+			case base == "":
+				errs = append(errs, fmt.Sprintf("VLAN %s: vlan.base-iface is not set", name))
+			case base == name:
+				errs = append(errs, fmt.Sprintf("VLAN %s: vlan.base-iface is the VLAN itself", name))
+			case f.byName[base] == nil:
+				errs = append(errs, fmt.Sprintf("VLAN %s: parent interface %s is not defined in the file", name, base))
+			}
+			if id, ok := vlan["id"].(int); !ok || id < 1 || id > 4094 {
+				errs = append(errs, fmt.Sprintf("VLAN %s: vlan.id must be an integer from 1 to 4094", name))
+			}
+		case "bond":
+			aggregation, _ := iface["link-aggregation"].(map[string]interface{})
+			if mode, _ := aggregation["mode"].(string); mode == "" {
+				errs = append(errs, fmt.Sprintf("bond %s: link-aggregation.mode is not set", name))
+			}
+			// nmstate 2 lists the members as "port", nmstate 1 as "slaves".
+			ports := stringList(aggregation["port"])
+			if ports == nil {
+				ports = stringList(aggregation["slaves"])
+			}
+			if len(ports) == 0 {
+				warnings = append(warnings, fmt.Sprintf("bond %s has no ports", name))
+			}
+			errs = append(errs, claimPorts(f, "bond", name, ports, controllers)...)
+		case "linux-bridge", "ovs-bridge":
+			bridge, _ := iface["bridge"].(map[string]interface{})
+			var ports []string
+			for _, p := range lookupList(bridge, "port") {
+				if port, ok := p.(map[string]interface{}); ok {
+					portName, _ := port["name"].(string)
+					ports = append(ports, portName)
+				}
+			}
+			errs = append(errs, claimPorts(f, "bridge", name, ports, controllers)...)
+		}
+	}
+	for port, controller := range controllers {
+		iface := f.byName[port]
+		if iface == nil {
+			continue
+		}
+		for _, family := range []string{"ipv4", "ipv6"} {
+			if ip, _ := iface[family].(map[string]interface{}); ip["enabled"] == true {
+				warnings = append(warnings, fmt.Sprintf("interface %s is a port of %s but has %s enabled; configure addresses on %s instead", port, controller, family, controller))
+			}
+		}
+	}
+	sort.Strings(warnings)
+	return errs, warnings
+}
+
+// claimPorts records the ports of a bond or bridge, reporting ports that are
+// not defined, that are the controller itself or that another controller
+// already uses.
+//
+// Parameters:
+//   - f: The parsed file.
+//   - kind: "bond" or "bridge", for messages.
+//   - controller: The name of the bond or bridge.
+//   - ports: The names of its ports.
+//   - controllers: The controller of each port claimed so far; updated.
+//
+// Returns:
+//   - []string: The errors found.
+func claimPorts(f *nmstateFile, kind, controller string, ports []string, controllers map[string]string) []string {
+	var errs []string
+	for _, port := range ports {
+		switch {
+		case port == "":
+			errs = append(errs, fmt.Sprintf("%s %s: a port has no name", kind, controller))
+		case port == controller:
+			errs = append(errs, fmt.Sprintf("%s %s: the %s is listed as its own port", kind, controller, kind))
+		case controllers[port] != "":
+			errs = append(errs, fmt.Sprintf("%s %s: port %s is already a port of %s", kind, controller, port, controllers[port]))
+		case f.byName[port] == nil:
+			errs = append(errs, fmt.Sprintf("%s %s: port %s is not defined in the file", kind, controller, port))
+		default:
+			controllers[port] = controller
+		}
+	}
+	return errs
+}
+
+// stringList converts a YAML list of strings, or returns nil if v is not a list.
+func stringList(v interface{}) []string {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		s, _ := item.(string)
+		result = append(result, s)
+	}
+	return result
+}
+
+// lookupList returns a list member of an object, or nil if it is missing.
+func lookupList(obj map[string]interface{}, key string) []interface{} {
+	list, _ := obj[key].([]interface{})
+	return list
+}
+
+// checkDNS checks that the DNS servers of the dns-resolver section are IP
+// addresses; nmstate does not resolve host names there.
+func checkDNS(f *nmstateFile) ([]string, []string) {
+	var errs []string
+	resolver, _ := f.doc["dns-resolver"].(map[string]interface{})
+	config, _ := resolver["config"].(map[string]interface{})
+	for i, server := range lookupList(config, "server") {
+		s, _ := server.(string)
+		if net.ParseIP(s) == nil {
+			errs = append(errs, fmt.Sprintf("dns-resolver.config.server[%d]: %v is not an IP address", i, server))
+		}
+	}
+	for i, domain := range lookupList(config, "search") {
+		if s, _ := domain.(string); s == "" || strings.ContainsAny(s, " \t/") {
+			errs = append(errs, fmt.Sprintf("dns-resolver.config.search[%d]: %v is not a domain name", i, domain))
+		}
+	}
+	return errs, nil
+}
+
+// checkRoutes checks the static routes of the routes section: the
+// destination must be a CIDR, the next-hop interface must be defined in the
+// file, and the next-hop address must be in a subnet of that interface. A
+// node with static addresses of a family, no dynamic configuration of that
+// family and no default route of it is reported, as it cannot reach beyond
+// its own subnets.
+func checkRoutes(f *nmstateFile) ([]string, []string) {
+	var errs, warnings []string
+	routes, _ := f.doc["routes"].(map[string]interface{})
+	defaults := map[string]bool{}
+	for i, item := range lookupList(routes, "config") {
+		route, ok := item.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("routes.config[%d]: must be an object", i))
+			continue
+		}
+		if state, _ := route["state"].(string); state == "absent" {
+			continue
+		}
+		where := fmt.Sprintf("routes.config[%d]", i)
+		destination, _ := route["destination"].(string)
+		_, dest, err := net.ParseCIDR(destination)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: destination %q is not a CIDR such as 0.0.0.0/0 or 10.0.0.0/24", where, destination))
+			continue
+		}
+		family := ipFamily(dest.IP)
+		if ones, _ := dest.Mask.Size(); ones == 0 {
+			defaults[family] = true
+		}
+
+		ifaceName, _ := route["next-hop-interface"].(string)
+		iface := f.byName[ifaceName]
+		if ifaceName != "" && iface == nil {
+			errs = append(errs, fmt.Sprintf("%s: next-hop-interface %s is not defined in the file", where, ifaceName))
+		}
+		hop, hasHop := route["next-hop-address"].(string)
+		if !hasHop || hop == "" {
+			if ifaceName == "" {
+				errs = append(errs, fmt.Sprintf("%s: neither next-hop-address nor next-hop-interface is set", where))
+			}
+			continue
+		}
+		hopIP := net.ParseIP(hop)
+		switch {
+		case hopIP == nil:
+			errs = append(errs, fmt.Sprintf("%s: next-hop-address %q is not an IP address", where, hop))
+		case ipFamily(hopIP) != family:
+			errs = append(errs, fmt.Sprintf("%s: next-hop-address %s is not an %s address like destination %s", where, hop, family, destination))
+		case iface != nil:
+			subnets := interfaceSubnets(iface, family)
+			if len(subnets) > 0 && !subnetsContain(subnets, hopIP) {
+				errs = append(errs, fmt.Sprintf("%s: next-hop-address %s is outside the subnets of interface %s (%s)", where, hop, ifaceName, joinSubnets(subnets)))
+			}
+		}
+	}
+
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if defaults[family] {
+			continue
+		}
+		var static []string
+		dynamic := false
+		for _, iface := range f.interfaces {
+			ip, _ := iface[family].(map[string]interface{})
+			if ip["enabled"] != true {
+				continue
+			}
+			if ip["dhcp"] == true || ip["autoconf"] == true {
+				dynamic = true
+			}
+			if len(interfaceSubnets(iface, family)) > 0 {
+				name, _ := iface["name"].(string)
+				static = append(static, name)
+			}
+		}
+		if len(static) > 0 && !dynamic {
+			warnings = append(warnings, fmt.Sprintf("no default %s route and no dynamic %s configuration: the node has static addresses on %s only, so it cannot reach hosts outside their subnets", family, family, strings.Join(static, ", ")))
+		}
+	}
+	return errs, warnings
+}
+
+// ipFamily returns "ipv4" or "ipv6", the nmstate section name of the family
+// of an address.
+func ipFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+// interfaceSubnets returns the subnets of the static addresses of an
+// interface.
+//
+// Parameters:
+//   - iface: The interface.
+//   - family: "ipv4" or "ipv6".
+//
+// Returns:
+//   - []*net.IPNet: The subnets; addresses that do not parse are skipped.
+func interfaceSubnets(iface map[string]interface{}, family string) []*net.IPNet {
+	ip, _ := iface[family].(map[string]interface{})
+	if ip["enabled"] != true {
+		return nil
+	}
+	var subnets []*net.IPNet
+	for _, item := range lookupList(ip, "address") {
+		addr, _ := item.(map[string]interface{})
+		host, _ := addr["ip"].(string)
+		prefix, ok := addr["prefix-length"].(int)
+		if !ok {
+			continue
+		}
+		if _, subnet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", host, prefix)); err == nil {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
+
+// subnetsContain reports whether one of the subnets contains ip.
+func subnetsContain(subnets []*net.IPNet, ip net.IP) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinSubnets formats subnets for messages.
+func joinSubnets(subnets []*net.IPNet) string {
+	names := make([]string, len(subnets))
+	for i, subnet := range subnets {
+		names[i] = subnet.String()
+	}
+	return strings.Join(names, ", ")
+}
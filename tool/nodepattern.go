@@ -0,0 +1,126 @@
+package tool
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nodeRangePattern matches the numeric ranges of a hostname pattern, e.g.
+// "{01..03}" in "edge-{01..03}".
+var nodeRangePattern = regexp.MustCompile(`\{([0-9]+)\.\.([0-9]+)\}`)
+
+// maxExpandedNodes bounds the nodes a single hostname pattern expands to,
+// so that a mistyped range does not produce a huge configuration.
+const maxExpandedNodes = 1000
+
+// ExpandNodePatterns replaces the kubernetes.nodes entries whose hostname is
+// a pattern with one entry per hostname it stands for, so that large
+// clusters need not list every node by hand.
+//
+// A pattern holds numeric ranges as in shell brace expansion: "edge-{01..03}"
+// stands for edge-01, edge-02 and edge-03, zero-padded to the width of the
+// bounds when one has a leading zero. Several ranges expand to every
+// combination, e.g. "rack{1..2}-node{1..3}". The expanded entries copy the
+// other fields of the pattern entry, except initializer, which only the
+// first one keeps.
+//
+// Parameters:
+//   - input: The configuration; kubernetes.nodes is modified in place.
+//
+// Returns:
+//   - []string: One note per expanded pattern.
+//   - error: A *FindingsError listing every malformed pattern, range or
+//     resulting hostname.
+func ExpandNodePatterns(input map[string]interface{}) ([]string, error) {
+	kube := lookupMap(input, "kubernetes")
+	nodes, _ := kube["nodes"].([]interface{})
+	var expanded []interface{}
+	var notes, errs []string
+	changed := false
+	for i, raw := range nodes {
+		node, ok := raw.(map[string]interface{})
+		hostname, _ := node["hostname"].(string)
+		if !ok || !strings.ContainsAny(hostname, "{}") {
+			expanded = append(expanded, raw)
+			continue
+		}
+		hostnames, err := expandHostname(hostname)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("kubernetes.nodes.%d.hostname: %v", i, err))
+			continue
+		}
+		for j, name := range hostnames {
+			copied := make(map[string]interface{}, len(node))
+			for key, value := range node {
+				copied[key] = value
+			}
+			copied["hostname"] = name
+			if j > 0 {
+				delete(copied, "initializer")
+			}
+			expanded = append(expanded, copied)
+		}
+		changed = true
+		notes = append(notes, fmt.Sprintf("kubernetes.nodes.%d: hostname pattern %q was expanded to %d nodes, %s to %s", i, hostname, len(hostnames), hostnames[0], hostnames[len(hostnames)-1]))
+	}
+	if len(errs) > 0 {
+		return nil, &FindingsError{Summary: "node hostname patterns are invalid", Findings: NewFindings("node-pattern", SeverityError, errs)}
+	}
+	if changed {
+		kube["nodes"] = expanded
+	}
+	return notes, nil
+}
+
+// expandHostname expands the ranges of a hostname pattern.
+//
+// Parameters:
+//   - pattern: The pattern, e.g. "edge-{01..03}".
+//
+// Returns:
+//   - []string: The hostnames, in order.
+//   - error: An error if the pattern is malformed, a range is reversed, it
+//     expands to too many hostnames or a hostname is invalid.
+func expandHostname(pattern string) ([]string, error) {
+	if strings.ContainsAny(nodeRangePattern.ReplaceAllString(pattern, ""), "{}") {
+		return nil, fmt.Errorf("%q is not a valid pattern; use numeric ranges such as edge-{01..03}", pattern)
+	}
+	hostnames := []string{""}
+	rest := pattern
+	for _, m := range nodeRangePattern.FindAllStringSubmatchIndex(pattern, -1) {
+		prefix := pattern[len(pattern)-len(rest) : m[0]]
+		low, high := pattern[m[2]:m[3]], pattern[m[4]:m[5]]
+		start, err1 := strconv.Atoi(low)
+		end, err2 := strconv.Atoi(high)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("range {%s..%s} of %q is too large", low, high, pattern)
+		}
+		if start > end {
+			return nil, fmt.Errorf("range {%s..%s} of %q is reversed; use {%s..%s}", low, high, pattern, high, low)
+		}
+		if len(hostnames)*(end-start+1) > maxExpandedNodes {
+			return nil, fmt.Errorf("%q expands to more than %d nodes", pattern, maxExpandedNodes)
+		}
+		width := 0
+		if (len(low) > 1 && low[0] == '0') || (len(high) > 1 && high[0] == '0') {
+			width = max(len(low), len(high))
+		}
+		var next []string
+		for _, h := range hostnames {
+			for n := start; n <= end; n++ {
+				next = append(next, fmt.Sprintf("%s%s%0*d", h, prefix, width, n))
+			}
+		}
+		hostnames = next
+		rest = pattern[m[1]:]
+	}
+	for i := range hostnames {
+		hostnames[i] += rest
+		if !hostnamePattern.MatchString(hostnames[i]) {
+			return nil, fmt.Errorf("%q expands to %q, which is not a valid hostname", pattern, hostnames[i])
+		}
+	}
+	return hostnames, nil
+}
@@ -0,0 +1,368 @@
+package tool
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KubernetesConfigDir is the directory of the image configuration directory
+// holding the RKE2 or K3s configuration files of the nodes.
+const KubernetesConfigDir = "kubernetes/config"
+
+// KubernetesConfig holds the RKE2 or K3s configuration files EIB installs on
+// the nodes: server.yaml on server nodes and agent.yaml on agent nodes.
+type KubernetesConfig struct {
+	// Server is the content of server.yaml, as YAML.
+	Server string `json:"server,omitempty"`
+	// Agent is the content of agent.yaml, as YAML.
+	Agent string `json:"agent,omitempty"`
+}
+
+// serverOnlyOptions are the RKE2 and K3s options that only servers accept.
+// An agent started with one of them fails to start.
+var serverOnlyOptions = map[string]bool{
+	"advertise-address":                  true,
+	"advertise-port":                     true,
+	"agent-token":                        true,
+	"agent-token-file":                   true,
+	"apiserver-port":                     true,
+	"audit-policy-file":                  true,
+	"bind-address":                       true,
+	"cni":                                true,
+	"default-local-storage-path":         true,
+	"disable":                            true,
+	"disable-apiserver":                  true,
+	"disable-cloud-controller":           true,
+	"disable-controller-manager":         true,
+	"disable-etcd":                       true,
+	"disable-helm-controller":            true,
+	"disable-kube-proxy":                 true,
+	"disable-network-policy":             true,
+	"disable-scheduler":                  true,
+	"egress-selector-mode":               true,
+	"embedded-registry":                  true,
+	"enable-pprof":                       true,
+	"flannel-backend":                    true,
+	"flannel-external-ip":                true,
+	"flannel-ipv6-masq":                  true,
+	"helm-job-image":                     true,
+	"https-listen-port":                  true,
+	"ingress-controller":                 true,
+	"pod-security-admission-config-file": true,
+	"service-cidr":                       true,
+	"service-node-port-range":            true,
+	"servicelb-namespace":                true,
+	"supervisor-metrics":                 true,
+	"supervisor-port":                    true,
+	"system-default-registry":            true,
+}
+
+// serverOnlyPrefixes are the prefixes of further server-only options, e.g.
+// the etcd and control plane component options.
+var serverOnlyPrefixes = []string{
+	"cluster-",
+	"control-plane-",
+	"datastore-",
+	"etcd-",
+	"kube-apiserver-",
+	"kube-cloud-controller-manager-",
+	"kube-controller-manager-",
+	"kube-scheduler-",
+	"secrets-encryption",
+	"tls-san",
+	"write-kubeconfig",
+}
+
+// isServerOnlyOption reports whether an RKE2 or K3s option only applies to
+// servers.
+func isServerOnlyOption(key string) bool {
+	if serverOnlyOptions[key] {
+		return true
+	}
+	for _, prefix := range serverOnlyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeReport explains the settings EIB derives for each node of a cluster.
+type NodeReport struct {
+	// Distribution is "rke2" or "k3s", from kubernetes.version.
+	Distribution string `json:"distribution"`
+	// APIEndpoint is the address nodes join the cluster through, e.g.
+	// "https://192.168.100.10:9345", when the cluster has several nodes.
+	APIEndpoint string `json:"apiEndpoint,omitempty"`
+	// Nodes lists the nodes, in configuration order. A single-node cluster
+	// without kubernetes.nodes has one unnamed server.
+	Nodes []NodeSettings `json:"nodes"`
+	// Findings lists the problems of the configuration files.
+	Findings []Finding `json:"findings"`
+}
+
+// NodeSettings are the settings of a node.
+type NodeSettings struct {
+	// Hostname is the node's hostname, empty for a single-node cluster
+	// without kubernetes.nodes.
+	Hostname string `json:"hostname,omitempty"`
+	// Type is "server" or "agent".
+	Type string `json:"type"`
+	// Role is "initializer", "server" or "agent": the initializer creates
+	// the cluster, the other nodes join it.
+	Role string `json:"role"`
+	// ConfigFile is the configuration file installed on the node, e.g.
+	// "kubernetes/config/server.yaml".
+	ConfigFile string `json:"configFile"`
+	// Settings are the options the node starts with: those of its
+	// configuration file and those EIB derives, such as server and tls-san.
+	// Tokens are redacted.
+	Settings map[string]interface{} `json:"settings"`
+	// Derived lists the options of Settings that EIB derives, with why.
+	Derived []string `json:"derived,omitempty"`
+}
+
+// PrepareKubernetesConfig checks the server.yaml and agent.yaml of a
+// configuration and turns them into KubernetesConfigDir artifacts.
+//
+// When the cluster has agents, agent.yaml must not hold server-only options
+// such as etcd or control plane settings, and in a cluster with several
+// servers server.yaml must not set cluster-init, which would make every
+// server start its own cluster. Options EIB derives from the nodes and
+// kubernetes.network, such as server, are reported as warnings.
+//
+// Parameters:
+//   - kc: The configuration files.
+//   - config: The configuration, for its nodes and network.
+//
+// Returns:
+//   - []Artifact: One artifact per file given.
+//   - []string: The warnings.
+//   - error: A *FindingsError listing every malformed file and misplaced option.
+func PrepareKubernetesConfig(kc KubernetesConfig, config map[string]interface{}) ([]Artifact, []string, error) {
+	var artifacts []Artifact
+	var warnings, errs []string
+	servers, agents := nodeCounts(config)
+	multiNode := len(lookupMaps(config, "kubernetes", "nodes")) > 1
+	for _, file := range []struct{ name, content string }{{"server.yaml", kc.Server}, {"agent.yaml", kc.Agent}} {
+		if file.content == "" {
+			continue
+		}
+		path := KubernetesConfigDir + "/" + file.name
+		options, err := parseKubernetesConfig(file.content)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		artifacts = append(artifacts, Artifact{Path: path, Content: file.content, Mode: "0600"})
+		if lookupMap(config, "kubernetes") == nil {
+			warnings = append(warnings, fmt.Sprintf("%s: the configuration installs no Kubernetes, so the file is not used; remove it", path))
+			continue
+		}
+		if file.name == "agent.yaml" {
+			if agents == 0 {
+				warnings = append(warnings, fmt.Sprintf("%s: no node of kubernetes.nodes is an agent, so the file is not used; remove it", path))
+				continue
+			}
+			for _, key := range sortedKeys(stringKeys(options)) {
+				if isServerOnlyOption(key) {
+					errs = append(errs, fmt.Sprintf("%s: %s is a server-only option, so the agent nodes fail to start; remove it, or move it to server.yaml", path, key))
+				}
+			}
+		}
+		if _, ok := options["cluster-init"]; ok && file.name == "server.yaml" && servers > 1 {
+			errs = append(errs, fmt.Sprintf("%s: cluster-init makes each of the %d servers start its own cluster; remove it, EIB sets it on the initializer", path, servers))
+		}
+		if _, ok := options["server"]; ok && multiNode {
+			warnings = append(warnings, fmt.Sprintf("%s: server is replaced by the address EIB derives from kubernetes.network.apiVIP; remove it", path))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, &FindingsError{Summary: "kubernetes configuration files are invalid", Findings: NewFindings("node-config-split", SeverityError, errs)}
+	}
+	return artifacts, warnings, nil
+}
+
+// ExplainNodes reports the settings each node of a cluster starts with,
+// combining its configuration file with what EIB derives from
+// kubernetes.nodes and kubernetes.network: which server initializes the
+// cluster, the address the other nodes join through and the names of the
+// API server certificate. Hostname patterns in kubernetes.nodes are expanded
+// first, as generate_config does.
+//
+// Parameters:
+//   - config: The configuration; its hostname patterns are expanded in place.
+//   - kc: The configuration files.
+//
+// Returns:
+//   - *NodeReport: The per-node settings and the problems of the files.
+//   - error: An error if the configuration installs no Kubernetes or a
+//     hostname pattern is invalid.
+func ExplainNodes(config map[string]interface{}, kc KubernetesConfig) (*NodeReport, error) {
+	kube := lookupMap(config, "kubernetes")
+	if kube == nil {
+		return nil, fmt.Errorf("the configuration has no kubernetes section")
+	}
+	if _, err := ExpandNodePatterns(config); err != nil {
+		return nil, classify(KindSchema, err)
+	}
+	report := &NodeReport{Distribution: "rke2", Nodes: []NodeSettings{}, Findings: []Finding{}}
+	port := "9345"
+	if version, _ := kube["version"].(string); strings.Contains(version, "k3s") {
+		report.Distribution, port = "k3s", "6443"
+	}
+
+	_, warnings, err := PrepareKubernetesConfig(kc, config)
+	if fe, ok := err.(*FindingsError); ok {
+		report.Findings = append(report.Findings, fe.Findings...)
+	} else if err != nil {
+		return nil, err
+	}
+	report.Findings = append(report.Findings, NewFindings("node-config", SeverityWarning, warnings)...)
+	serverOptions, _ := parseKubernetesConfig(kc.Server)
+	agentOptions, _ := parseKubernetesConfig(kc.Agent)
+
+	network := lookupMap(kube, "network")
+	vip, _ := network["apiVIP"].(string)
+	if vip == "" {
+		vip, _ = network["apiVIP6"].(string)
+	}
+	nodes := lookupMaps(kube, "nodes")
+	multiNode := len(nodes) > 1
+	if multiNode && vip != "" {
+		report.APIEndpoint = "https://" + net.JoinHostPort(vip, port)
+	}
+	if len(nodes) == 0 {
+		nodes = []map[string]interface{}{{"type": "server"}}
+	}
+	initializer := initializerIndex(nodes)
+
+	var sans []interface{}
+	for _, key := range []string{"apiVIP", "apiVIP6", "apiHost"} {
+		if v, _ := network[key].(string); v != "" {
+			sans = append(sans, v)
+		}
+	}
+	for i, node := range nodes {
+		ns := NodeSettings{Settings: map[string]interface{}{}}
+		ns.Hostname, _ = node["hostname"].(string)
+		ns.Type, _ = node["type"].(string)
+		options := serverOptions
+		switch {
+		case ns.Type == "agent":
+			ns.Role, ns.ConfigFile, options = "agent", KubernetesConfigDir+"/agent.yaml", agentOptions
+		case i == initializer:
+			ns.Role, ns.ConfigFile = "initializer", KubernetesConfigDir+"/server.yaml"
+		default:
+			ns.Role, ns.ConfigFile = "server", KubernetesConfigDir+"/server.yaml"
+		}
+		for key, value := range options {
+			ns.Settings[key] = value
+		}
+		if ns.Type != "agent" && len(sans) > 0 {
+			var names []interface{}
+			switch existing := ns.Settings["tls-san"].(type) {
+			case []interface{}:
+				names = append(names, existing...)
+			case string:
+				names = append(names, existing)
+			}
+			ns.Settings["tls-san"] = append(names, sans...)
+			ns.Derived = append(ns.Derived, "tls-san: the API VIPs and apiHost are added to the API server certificate of the servers")
+		}
+		if multiNode && report.APIEndpoint != "" {
+			if ns.Role == "initializer" {
+				delete(ns.Settings, "server")
+				if report.Distribution == "k3s" {
+					ns.Settings["cluster-init"] = true
+					ns.Derived = append(ns.Derived, "cluster-init: the initializer creates the cluster with embedded etcd")
+				}
+			} else {
+				ns.Settings["server"] = report.APIEndpoint
+				ns.Derived = append(ns.Derived, "server: the node joins the cluster through the API VIP")
+			}
+		}
+		if ns.Role != "initializer" && multiNode {
+			if _, ok := ns.Settings["token"]; !ok {
+				if _, ok := serverOptions["token"]; ok {
+					ns.Settings["token"] = "(token of server.yaml)"
+				} else {
+					ns.Settings["token"] = "(generated by EIB)"
+				}
+				ns.Derived = append(ns.Derived, "token: the node joins with the cluster token")
+			}
+		}
+		for _, key := range []string{"token", "agent-token"} {
+			if v, ok := ns.Settings[key].(string); ok && !strings.HasPrefix(v, "(") {
+				ns.Settings[key] = "(redacted)"
+			}
+		}
+		sort.Strings(ns.Derived)
+		report.Nodes = append(report.Nodes, ns)
+	}
+	return report, nil
+}
+
+// initializerIndex returns the index of the node that creates the cluster:
+// the server marked initializer or, if none is, the first server.
+//
+// Parameters:
+//   - nodes: The nodes.
+//
+// Returns:
+//   - int: The index, or -1 if there is no server.
+func initializerIndex(nodes []map[string]interface{}) int {
+	first := -1
+	for i, node := range nodes {
+		if node["type"] != "server" {
+			continue
+		}
+		if init, _ := node["initializer"].(bool); init {
+			return i
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return first
+}
+
+// nodeCounts counts the server and agent nodes of a configuration; a
+// cluster without kubernetes.nodes is a single server.
+func nodeCounts(config map[string]interface{}) (servers, agents int) {
+	nodes := lookupMaps(config, "kubernetes", "nodes")
+	if len(nodes) == 0 {
+		return 1, 0
+	}
+	for _, node := range nodes {
+		if node["type"] == "agent" {
+			agents++
+		} else {
+			servers++
+		}
+	}
+	return servers, agents
+}
+
+// parseKubernetesConfig parses an RKE2 or K3s configuration file.
+//
+// Parameters:
+//   - content: The file content, or empty.
+//
+// Returns:
+//   - map[string]interface{}: The options.
+//   - error: An error if the content is not a YAML mapping.
+func parseKubernetesConfig(content string) (map[string]interface{}, error) {
+	var options map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &options); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %v", err)
+	}
+	if options == nil && strings.TrimSpace(content) != "" {
+		return nil, fmt.Errorf("expected a mapping of options")
+	}
+	return options, nil
+}
@@ -0,0 +1,96 @@
+package tool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefinitionFile is the name under which a configuration is written to an
+// image configuration directory.
+const DefinitionFile = "definition.yaml"
+
+// Conflict is an existing path that writing output would replace.
+type Conflict struct {
+	// Path is the conflicting path, relative to the output directory.
+	Path string `json:"path"`
+	// Reason explains what exists at the path.
+	Reason string `json:"reason"`
+}
+
+// ConflictError reports that output was not written because it would
+// replace existing files.
+type ConflictError struct {
+	// Root is the output directory.
+	Root string `json:"root"`
+	// Conflicts lists the existing paths.
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	paths := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		paths[i] = c.Path
+	}
+	return fmt.Sprintf("refusing to overwrite %d existing path(s) in %q, pass overwrite to replace them: %s", len(e.Conflicts), e.Root, strings.Join(paths, ", "))
+}
+
+// OutputImageName returns the name of the image EIB builds from a
+// configuration, which it writes next to the definition.
+//
+// Parameters:
+//   - config: The configuration.
+//
+// Returns:
+//   - string: The value of image.outputImageName, or empty if unset.
+func OutputImageName(config map[string]interface{}) string {
+	image, _ := config["image"].(map[string]interface{})
+	name, _ := image["outputImageName"].(string)
+	return name
+}
+
+// FindConflicts lists the paths below a root directory that already exist.
+//
+// Parameters:
+//   - root: The output directory.
+//   - paths: The paths about to be written, relative to root.
+//
+// Returns:
+//   - []Conflict: The existing paths, in the order given.
+func FindConflicts(root string, paths []string) []Conflict {
+	var conflicts []Conflict
+	for _, rel := range paths {
+		st, err := os.Lstat(filepath.Join(root, rel))
+		switch {
+		case err != nil:
+			continue
+		case st.IsDir():
+			conflicts = append(conflicts, Conflict{Path: rel, Reason: "a directory exists at this path"})
+		default:
+			conflicts = append(conflicts, Conflict{Path: rel, Reason: "file exists"})
+		}
+	}
+	return conflicts
+}
+
+// CheckOutput refuses to write output that would replace existing files,
+// unless overwriting is allowed.
+//
+// Parameters:
+//   - root: The output directory.
+//   - paths: The paths about to be written, or reserved for files EIB will create, relative to root.
+//   - overwrite: Whether existing files may be replaced.
+//
+// Returns:
+//   - error: A *ConflictError if overwrite is false and any path exists.
+func CheckOutput(root string, paths []string, overwrite bool) error {
+	if overwrite {
+		return nil
+	}
+	if conflicts := FindConflicts(root, paths); len(conflicts) > 0 {
+		return &ConflictError{Root: root, Conflicts: conflicts}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package tool
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicyMode selects how password policy violations are reported.
+type PasswordPolicyMode string
+
+const (
+	// PasswordPolicyOff disables the policy.
+	PasswordPolicyOff PasswordPolicyMode = "off"
+	// PasswordPolicyWarn reports violations as warnings.
+	PasswordPolicyWarn PasswordPolicyMode = "warn"
+	// PasswordPolicyEnforce rejects configurations with violations.
+	PasswordPolicyEnforce PasswordPolicyMode = "enforce"
+)
+
+// deniedPasswords are obvious defaults that plaintext passwords may not be,
+// compared case-insensitively. Generated configurations often fill in such
+// values when the user gives none.
+var deniedPasswords = []string{
+	"admin", "changeme", "default", "eib", "letmein", "linux", "password",
+	"password1", "passw0rd", "qwerty", "root", "secret", "suse", "test",
+	"toor", "welcome", "123456", "12345678", "123456789",
+}
+
+// PasswordPolicy constrains the plaintext passwords of users before they are
+// hashed; hashes given as encryptedPassword cannot be checked.
+type PasswordPolicy struct {
+	// Mode selects whether violations are errors or warnings.
+	Mode PasswordPolicyMode `json:"mode"`
+	// MinLength is the minimum number of characters.
+	MinLength int `json:"minLength"`
+	// MinClasses is the minimum number of character classes used, out of
+	// lower case letters, upper case letters, digits and other characters.
+	MinClasses int `json:"minClasses"`
+	// denied holds the lower case denied passwords.
+	denied map[string]bool
+}
+
+// NewPasswordPolicy creates a password policy denying the built-in obvious
+// defaults and the passwords listed in denyListFile.
+//
+// Parameters:
+//   - mode: PasswordPolicyWarn or PasswordPolicyEnforce.
+//   - minLength: The minimum number of characters.
+//   - minClasses: The minimum number of character classes, from 0 to 4.
+//   - denyListFile: A file of additional denied passwords, one per line, or
+//     empty. Blank lines and lines starting with # are ignored.
+//
+// Returns:
+//   - *PasswordPolicy: The policy.
+//   - error: An error if an argument is out of range or the file cannot be read.
+func NewPasswordPolicy(mode PasswordPolicyMode, minLength, minClasses int, denyListFile string) (*PasswordPolicy, error) {
+	if mode != PasswordPolicyWarn && mode != PasswordPolicyEnforce {
+		return nil, fmt.Errorf("unknown password policy mode %q (expected %q, %q or %q)", mode, PasswordPolicyOff, PasswordPolicyWarn, PasswordPolicyEnforce)
+	}
+	if minLength < 0 {
+		return nil, fmt.Errorf("invalid minimum password length %d", minLength)
+	}
+	if minClasses < 0 || minClasses > 4 {
+		return nil, fmt.Errorf("invalid minimum number of character classes %d (expected 0 to 4)", minClasses)
+	}
+	p := &PasswordPolicy{Mode: mode, MinLength: minLength, MinClasses: minClasses, denied: map[string]bool{}}
+	for _, password := range deniedPasswords {
+		p.denied[password] = true
+	}
+	if denyListFile == "" {
+		return p, nil
+	}
+	f, err := os.Open(denyListFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password deny list: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			p.denied[strings.ToLower(line)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password deny list: %w", err)
+	}
+	return p, nil
+}
+
+// Check returns the violations of a plaintext password. The messages never
+// include the password.
+//
+// Parameters:
+//   - username: The user the password belongs to.
+//   - password: The plaintext password.
+//
+// Returns:
+//   - []string: One message per violation.
+func (p *PasswordPolicy) Check(username, password string) []string {
+	var msgs []string
+	lower := strings.ToLower(password)
+	if p.denied[lower] || (username != "" && lower == strings.ToLower(username)) {
+		msgs = append(msgs, fmt.Sprintf("the password of user %q is a common default or equals the username", username))
+	}
+	if n := len([]rune(password)); n < p.MinLength {
+		msgs = append(msgs, fmt.Sprintf("the password of user %q has %d characters, fewer than the required %d", username, n, p.MinLength))
+	}
+	if n := characterClasses(password); n < p.MinClasses {
+		msgs = append(msgs, fmt.Sprintf("the password of user %q uses %d of the character classes lower case, upper case, digits and symbols, fewer than the required %d", username, n, p.MinClasses))
+	}
+	return msgs
+}
+
+// characterClasses counts the character classes used by a password.
+func characterClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
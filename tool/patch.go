@@ -0,0 +1,209 @@
+package tool
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// listKeys maps the dotted path of each configuration list whose items are
+// objects to the field identifying an item. Merging uses it to update
+// matching items in place instead of appending duplicates.
+var listKeys = map[string]string{
+	"operatingSystem.users":                    "username",
+	"operatingSystem.groups":                   "name",
+	"operatingSystem.packages.additionalRepos": "url",
+	"kubernetes.nodes":                         "hostname",
+	"kubernetes.helm.charts":                   "name",
+	"kubernetes.helm.repositories":             "name",
+	"embeddedArtifactRegistry.images":          "name",
+	"embeddedArtifactRegistry.registries":      "uri",
+}
+
+// ParseConfig converts a configuration given as an object or as a YAML/JSON
+// string into a configuration map.
+//
+// Parameters:
+//   - v: Either a map[string]interface{} or a string containing YAML or JSON.
+//
+// Returns:
+//   - map[string]interface{}: The configuration map.
+//   - error: An error if the value is neither an object nor a parseable document.
+func ParseConfig(v interface{}) (map[string]interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return val, nil
+	case string:
+		var doc interface{}
+		if err := yaml.Unmarshal([]byte(val), &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration: %w", err)
+		}
+		m, ok := normalizeYAML(doc).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("configuration must be a mapping at the top level")
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("configuration must be an object or a YAML string, got %T", v)
+	}
+}
+
+// normalizeYAML converts YAML-decoded values into the shapes produced by
+// encoding/json, so that maps always have string keys.
+//
+// Parameters:
+//   - v: The decoded YAML value.
+//
+// Returns:
+//   - interface{}: The normalized value.
+func normalizeYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeYAML(item)
+		}
+		return val
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return out
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeYAML(item)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+// MergeConfig deep-merges a partial patch into a configuration.
+//
+// Merge rules:
+//   - objects are merged key by key, recursively;
+//   - a null value in the patch removes the key;
+//   - lists of known objects (users, nodes, charts, ...) are merged by their
+//     identifying field: matching items are merged, new items are appended;
+//   - lists of scalars gain the patch values they do not already contain;
+//   - any other value in the patch replaces the existing one.
+//
+// The base configuration is modified in place and returned.
+//
+// Parameters:
+//   - base: The existing configuration.
+//   - patch: The partial configuration to apply.
+//
+// Returns:
+//   - map[string]interface{}: The merged configuration.
+func MergeConfig(base, patch map[string]interface{}) map[string]interface{} {
+	return mergeObject(base, patch, "")
+}
+
+// mergeObject merges patch into base at the given path.
+func mergeObject(base, patch map[string]interface{}, path string) map[string]interface{} {
+	if base == nil {
+		base = map[string]interface{}{}
+	}
+	for key, patchVal := range patch {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+
+		if patchVal == nil {
+			delete(base, key)
+			continue
+		}
+
+		switch pv := patchVal.(type) {
+		case map[string]interface{}:
+			existing, _ := base[key].(map[string]interface{})
+			base[key] = mergeObject(existing, pv, childPath)
+		case []interface{}:
+			existing, ok := base[key].([]interface{})
+			if !ok {
+				base[key] = pv
+				continue
+			}
+			base[key] = mergeList(existing, pv, childPath)
+		default:
+			base[key] = patchVal
+		}
+	}
+	return base
+}
+
+// mergeList merges patch items into a list at the given path.
+func mergeList(base, patch []interface{}, path string) []interface{} {
+	idKey, keyed := listKeys[path]
+	for _, item := range patch {
+		if obj, ok := item.(map[string]interface{}); ok && keyed {
+			if idx := indexByKey(base, idKey, obj[idKey]); idx >= 0 {
+				existing, _ := base[idx].(map[string]interface{})
+				base[idx] = mergeObject(existing, obj, path+"[]")
+				continue
+			}
+			base = append(base, obj)
+			continue
+		}
+		if !containsScalar(base, item) {
+			base = append(base, item)
+		}
+	}
+	return base
+}
+
+// indexByKey finds the first object in list whose key field equals value.
+//
+// Returns:
+//   - int: The index of the item, or -1 if none matches.
+func indexByKey(list []interface{}, key string, value interface{}) int {
+	id, ok := value.(string)
+	if !ok {
+		return -1
+	}
+	for i, item := range list {
+		if obj, ok := item.(map[string]interface{}); ok && obj[key] == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// containsScalar reports whether list contains the scalar value.
+func containsScalar(list []interface{}, value interface{}) bool {
+	if _, isMap := value.(map[string]interface{}); isMap {
+		return false
+	}
+	if _, isList := value.([]interface{}); isList {
+		return false
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PatchConfig applies a patch to an existing configuration, then validates
+// and renders the result.
+//
+// Parameters:
+//   - config: The existing configuration.
+//   - patch: The patch, whose shape depends on patchType (see ApplyPatch).
+//   - patchType: The patch type; empty selects PatchDeepMerge.
+//   - opts: The generation options.
+//
+// Returns:
+//   - *Result: The updated YAML configuration and any warnings.
+//   - error: An error if the patch cannot be applied or the patched configuration is invalid.
+func PatchConfig(config map[string]interface{}, patch interface{}, patchType PatchType, opts Options) (*Result, error) {
+	patched, err := ApplyPatch(config, patch, patchType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch: %w", err)
+	}
+	return Generate(patched, opts)
+}
@@ -0,0 +1,218 @@
+package tool
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pemMarker starts every PEM block.
+const pemMarker = "-----BEGIN "
+
+// CheckPEM validates every PEM block embedded in a configuration or in its
+// artifacts, before they are emitted.
+//
+// Certificates must parse, and expired, not yet valid or soon-expiring
+// certificates are reported as warnings. Keys must parse, and a private key
+// stored together with certificates must match one of them. OpenPGP armored
+// blocks, which only look like PEM, are ignored, and so are certificates/
+// artifacts, which PrepareCertificates already checks.
+//
+// Parameters:
+//   - input: The configuration.
+//   - artifacts: The artifacts accompanying the configuration.
+//   - now: The time against which expiry is checked.
+//
+// Returns:
+//   - []string: The warnings.
+//   - error: An error listing every invalid PEM value.
+func CheckPEM(input map[string]interface{}, artifacts []Artifact, now time.Time) ([]string, error) {
+	var warnings, errs []string
+	values := map[string]string{}
+	collectPEMStrings(input, "", values)
+	for _, a := range artifacts {
+		if strings.HasPrefix(a.Path, certificatesDir+"/") {
+			continue
+		}
+		content := a.Content
+		if a.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(content)
+			if err != nil {
+				continue
+			}
+			content = string(decoded)
+		}
+		if !strings.Contains(content, pemMarker) {
+			continue
+		}
+		values[a.Path] = content
+		if perm, err := strconv.ParseUint(a.Mode, 8, 32); err == nil && perm&0o044 != 0 && strings.Contains(content, "PRIVATE KEY-----") {
+			warnings = append(warnings, fmt.Sprintf("%s contains a private key but is readable by other users (mode %s)", a.Path, a.Mode))
+		}
+	}
+
+	locations := make([]string, 0, len(values))
+	for loc := range values {
+		locations = append(locations, loc)
+	}
+	sort.Strings(locations)
+
+	for _, loc := range locations {
+		w, err := checkPEMValue(loc, values[loc], now)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", loc, err))
+			continue
+		}
+		warnings = append(warnings, w...)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid PEM content:\n- %s", strings.Join(errs, "\n- "))
+	}
+	return warnings, nil
+}
+
+// collectPEMStrings records every string value of a configuration that
+// contains a PEM block, keyed by its path.
+//
+// Parameters:
+//   - v: The value to walk.
+//   - path: The path of v, e.g. "kubernetes.helm.repositories[0].caFile".
+//   - values: The map receiving the values.
+func collectPEMStrings(v interface{}, path string, values map[string]string) {
+	switch val := v.(type) {
+	case string:
+		if strings.Contains(val, pemMarker) {
+			values[path] = val
+		}
+	case map[string]interface{}:
+		for k, item := range val {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			collectPEMStrings(item, child, values)
+		}
+	case []interface{}:
+		for i, item := range val {
+			collectPEMStrings(item, fmt.Sprintf("%s[%d]", path, i), values)
+		}
+	}
+}
+
+// checkPEMValue validates the PEM blocks of one value.
+//
+// Parameters:
+//   - loc: The location of the value, used in messages.
+//   - content: The value.
+//   - now: The reference time for certificate expiry.
+//
+// Returns:
+//   - []string: The warnings.
+//   - error: An error if a block cannot be decoded or a private key matches none of the certificates.
+func checkPEMValue(loc, content string, now time.Time) ([]string, error) {
+	if strings.Contains(content, pemMarker+"PGP ") {
+		return nil, nil
+	}
+
+	var certs []*x509.Certificate
+	var keys []crypto.Signer
+	rest := []byte(content[strings.Index(content, pemMarker):])
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+			key, err := parsePrivateKey(block)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		case "PUBLIC KEY":
+			if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+				return nil, fmt.Errorf("invalid public key: %w", err)
+			}
+		case "CERTIFICATE REQUEST":
+			if _, err := x509.ParseCertificateRequest(block.Bytes); err != nil {
+				return nil, fmt.Errorf("invalid certificate request: %w", err)
+			}
+		}
+	}
+	if bytes.Contains(rest, []byte(pemMarker)) {
+		return nil, fmt.Errorf("malformed PEM block")
+	}
+
+	var warnings []string
+	for _, cert := range certs {
+		warnings = append(warnings, certificateWarnings(loc, cert, now)...)
+	}
+	if len(certs) > 0 {
+		for _, key := range keys {
+			if !keyMatchesAny(key, certs) {
+				return nil, fmt.Errorf("private key does not match any of the certificates")
+			}
+		}
+	}
+	return warnings, nil
+}
+
+// parsePrivateKey decodes a PKCS #8, PKCS #1 or SEC 1 private key block.
+//
+// Parameters:
+//   - block: The PEM block.
+//
+// Returns:
+//   - crypto.Signer: The private key.
+//   - error: An error if the key cannot be parsed or is encrypted.
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// keyMatchesAny reports whether a private key belongs to one of the certificates.
+func keyMatchesAny(key crypto.Signer, certs []*x509.Certificate) bool {
+	type equaler interface {
+		Equal(crypto.PublicKey) bool
+	}
+	pub, ok := key.Public().(equaler)
+	if !ok {
+		return false
+	}
+	for _, cert := range certs {
+		if pub.Equal(cert.PublicKey) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,277 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plan describes what applying a change to a configuration would do,
+// without producing the final files.
+type Plan struct {
+	// ID identifies the configuration and change the plan was made for;
+	// ApplyPlan refuses to apply a different change under the same ID.
+	ID string `json:"id"`
+	// Changes lists the changed fields of the configuration.
+	Changes []PlanChange `json:"changes"`
+	// Diff is the unified diff of the generated definition.
+	Diff string `json:"diff,omitempty"`
+	// Rebuild is true if the image must be rebuilt for the change to take
+	// effect. EIB bakes the whole definition into the image, so any change
+	// requires a rebuild.
+	Rebuild bool `json:"rebuild"`
+	// RebuildReasons lists the changed configuration sections that cause the rebuild.
+	RebuildReasons []string `json:"rebuildReasons,omitempty"`
+	// Warnings lists non-fatal findings about the changed configuration.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PlanChange is one changed field of a Plan.
+type PlanChange struct {
+	// Path is the dotted path of the field, e.g. "kubernetes.version".
+	Path string `json:"path"`
+	// Action is "add", "remove" or "change".
+	Action string `json:"action"`
+	// From is the current value, if any. Secrets are redacted.
+	From interface{} `json:"from,omitempty"`
+	// To is the new value, if any. Secrets are redacted.
+	To interface{} `json:"to,omitempty"`
+}
+
+// redactedFields are the fields whose values plans never show.
+var redactedFields = map[string]bool{
+	"password":            true,
+	"encryptedPassword":   true,
+	"sccRegistrationCode": true,
+	"activationKey":       true,
+	"luksKey":             true,
+}
+
+// PlanID returns the identifier of a change to a configuration.
+//
+// Parameters:
+//   - config: The current configuration.
+//   - patch: The patch.
+//   - patchType: The patch type; empty selects PatchDeepMerge.
+//
+// Returns:
+//   - string: The SHA-256 of the canonical JSON of the three values.
+//   - error: An error if the values cannot be encoded.
+func PlanID(config map[string]interface{}, patch interface{}, patchType PatchType) (string, error) {
+	if patchType == "" {
+		patchType = PatchDeepMerge
+	}
+	raw, err := json.Marshal([]interface{}{config, patch, patchType})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plan: %w", err)
+	}
+	return sha256Hex(raw), nil
+}
+
+// PlanPatch reports what applying a patch to a configuration would change:
+// the changed fields, the diff of the generated definition and whether the
+// image must be rebuilt.
+//
+// The changed configuration is fully validated, but passwords are not hashed
+// (see Options.ValidateOnly), so plans are cheap and their diffs stable.
+//
+// Parameters:
+//   - config: The current configuration; it is not modified.
+//   - patch: The patch, whose shape depends on patchType (see ApplyPatch).
+//   - patchType: The patch type; empty selects PatchDeepMerge.
+//   - opts: The generation options.
+//
+// Returns:
+//   - *Plan: The plan.
+//   - error: An error if the patch cannot be applied or the changed configuration is invalid.
+func PlanPatch(config map[string]interface{}, patch interface{}, patchType PatchType, opts Options) (*Plan, error) {
+	id, err := PlanID(config, patch, patchType)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := ApplyPatch(deepCopyValue(config).(map[string]interface{}), deepCopyValue(patch), patchType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch: %w", err)
+	}
+
+	plan := &Plan{ID: id, Changes: []PlanChange{}}
+	diffValues(config, desired, "", &plan.Changes)
+	sections := map[string]bool{}
+	for _, c := range plan.Changes {
+		sections[planSection(c.Path)] = true
+	}
+	for section := range sections {
+		plan.RebuildReasons = append(plan.RebuildReasons, section+" changed")
+	}
+	sort.Strings(plan.RebuildReasons)
+	plan.Rebuild = len(plan.Changes) > 0
+
+	opts.ValidateOnly = true
+	after, err := Generate(deepCopyValue(desired).(map[string]interface{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	plan.Warnings = after.Warnings
+
+	var currentYAML string
+	if before, err := Generate(deepCopyValue(config).(map[string]interface{}), opts); err == nil {
+		currentYAML = before.YAML
+	} else if raw, merr := yaml.Marshal(config); merr == nil {
+		currentYAML = string(raw)
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("the current configuration is invalid: %v", err))
+	}
+	plan.Diff = UnifiedDiff(redactYAML(currentYAML), redactYAML(after.YAML), "current/"+DefinitionFile, "planned/"+DefinitionFile)
+	return plan, nil
+}
+
+// redactYAML hides the values of secret fields of a YAML definition.
+//
+// Parameters:
+//   - text: The YAML definition.
+//
+// Returns:
+//   - string: The redacted YAML, keeping a leading comment header, or text
+//     unchanged if it cannot be parsed.
+func redactYAML(text string) string {
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		return text
+	}
+	raw, err := yaml.Marshal(redact("", normalizeYAML(doc)))
+	if err != nil {
+		return text
+	}
+	var header strings.Builder
+	for rest := text; strings.HasPrefix(rest, "#"); {
+		line, next, _ := strings.Cut(rest, "\n")
+		header.WriteString(line + "\n")
+		rest = next
+	}
+	return header.String() + string(raw)
+}
+
+// ApplyPlan applies a planned patch and renders the final configuration.
+//
+// Parameters:
+//   - config: The current configuration.
+//   - patch: The patch that was planned.
+//   - patchType: The patch type that was planned.
+//   - planID: The ID of the reviewed plan.
+//   - opts: The generation options.
+//
+// Returns:
+//   - *Result: The updated YAML configuration and any warnings.
+//   - error: An error if the configuration or patch differ from the plan, or
+//     the patched configuration is invalid.
+func ApplyPlan(config map[string]interface{}, patch interface{}, patchType PatchType, planID string, opts Options) (*Result, error) {
+	id, err := PlanID(config, patch, patchType)
+	if err != nil {
+		return nil, err
+	}
+	if id != planID {
+		return nil, fmt.Errorf("the configuration or patch differ from plan %q; run plan_config again and review the new plan", planID)
+	}
+	return PatchConfig(config, patch, patchType, opts)
+}
+
+// diffValues records the differences between two configuration values.
+//
+// Objects are compared field by field; lists and scalars are compared as a
+// whole.
+//
+// Parameters:
+//   - from: The current value.
+//   - to: The new value.
+//   - path: The dotted path of the values.
+//   - changes: The list the differences are appended to.
+func diffValues(from, to interface{}, path string, changes *[]PlanChange) {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		keys := map[string]bool{}
+		for k := range fromMap {
+			keys[k] = true
+		}
+		for k := range toMap {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			f, inFrom := fromMap[k]
+			t, inTo := toMap[k]
+			switch {
+			case !inFrom:
+				*changes = append(*changes, PlanChange{Path: child, Action: "add", To: redact(k, t)})
+			case !inTo:
+				*changes = append(*changes, PlanChange{Path: child, Action: "remove", From: redact(k, f)})
+			default:
+				diffValues(f, t, child, changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		name := path[strings.LastIndex(path, ".")+1:]
+		*changes = append(*changes, PlanChange{Path: path, Action: "change", From: redact(name, from), To: redact(name, to)})
+	}
+}
+
+// redact hides the value of secret fields, including inside lists and
+// objects.
+//
+// Parameters:
+//   - name: The field name.
+//   - v: The value.
+//
+// Returns:
+//   - interface{}: The value, or "(redacted)" for secrets.
+func redact(name string, v interface{}) interface{} {
+	if redactedFields[name] {
+		return "(redacted)"
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			out[k] = redact(k, child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = redact("", child)
+		}
+		return out
+	}
+	return v
+}
+
+// planSection returns the configuration section of a changed field: its
+// first two path components, e.g. "operatingSystem.users".
+//
+// Parameters:
+//   - path: The dotted path.
+//
+// Returns:
+//   - string: The section.
+func planSection(path string) string {
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, ".")
+}
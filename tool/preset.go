@@ -0,0 +1,44 @@
+package tool
+
+import (
+	"fmt"
+
+	"github.com/e-minguez/eib-mcp/preset"
+)
+
+// FromPreset starts from a named preset and deep-merges the caller's
+// overrides on top of it (see MergeConfig).
+//
+// Parameters:
+//   - name: The preset name, e.g. "minimal-iso".
+//   - overrides: The partial configuration to apply on top of the preset.
+//
+// Returns:
+//   - map[string]interface{}: The merged configuration.
+//   - error: An error if the preset does not exist.
+func FromPreset(name string, overrides map[string]interface{}) (map[string]interface{}, error) {
+	p, err := preset.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return MergeConfig(p.Config, overrides), nil
+}
+
+// GenerateFromPreset merges overrides onto a preset, then validates and
+// renders the result.
+//
+// Parameters:
+//   - name: The preset name.
+//   - overrides: The partial configuration to apply on top of the preset.
+//   - opts: The generation options.
+//
+// Returns:
+//   - *Result: The generated YAML configuration and any warnings.
+//   - error: An error if the preset does not exist or the result is invalid.
+func GenerateFromPreset(name string, overrides map[string]interface{}, opts Options) (*Result, error) {
+	config, err := FromPreset(name, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply preset: %w", err)
+	}
+	return Generate(config, opts)
+}
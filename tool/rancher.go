@@ -0,0 +1,151 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/e-minguez/eib-mcp/httpcache"
+	"gopkg.in/yaml.v3"
+)
+
+// RancherManifestPath is where the Rancher registration manifest is placed
+// in the image configuration directory. EIB applies the manifests of
+// kubernetes/manifests once the cluster is up.
+const RancherManifestPath = "kubernetes/manifests/rancher-registration.yaml"
+
+var (
+	// rancherTokenPattern matches Rancher cluster registration tokens.
+	rancherTokenPattern = regexp.MustCompile(`^[a-z0-9]{20,128}$`)
+	// rancherClusterPattern matches Rancher cluster IDs, e.g. c-m-abc12def.
+	rancherClusterPattern = regexp.MustCompile(`^c-[a-z0-9-]+$`)
+)
+
+// RancherRegistration is a request for the manifest that imports a cluster
+// into Rancher.
+type RancherRegistration struct {
+	// URL is the Rancher server URL, e.g. https://rancher.example.com.
+	URL string
+	// Token is the cluster registration token.
+	Token string
+	// ClusterID is the ID of the imported cluster in Rancher, e.g. c-m-abc12def,
+	// which Rancher 2.6 and newer include in the import URL.
+	ClusterID string
+}
+
+// ImportURL returns the URL Rancher serves the registration manifest at.
+//
+// Returns:
+//   - string: The import URL.
+//   - error: An error if the server URL, token or cluster ID is malformed.
+func (r RancherRegistration) ImportURL() (string, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("rancher URL %q must be an https:// URL", r.URL)
+	}
+	if !rancherTokenPattern.MatchString(r.Token) {
+		return "", fmt.Errorf("registration token must be the lowercase alphanumeric token shown by Rancher")
+	}
+	name := r.Token
+	if r.ClusterID != "" {
+		if !rancherClusterPattern.MatchString(r.ClusterID) {
+			return "", fmt.Errorf("cluster ID %q must look like c-m-abc12def", r.ClusterID)
+		}
+		name += "_" + r.ClusterID
+	}
+	return strings.TrimSuffix(u.String(), "/") + "/v3/import/" + name + ".yaml", nil
+}
+
+// RancherManifest downloads the registration manifest of a cluster from
+// Rancher and returns it as an artifact of the image configuration
+// directory, so the cluster imports itself into Rancher on first boot.
+//
+// The manifest embeds the registration token, so it is never cached.
+//
+// Parameters:
+//   - ctx: The context controlling the download.
+//   - reg: The Rancher server and registration token.
+//   - opts: The options; the HTTP client of opts.HTTP is used, and offline
+//     mode makes the download fail.
+//
+// Returns:
+//   - Artifact: The manifest, at RancherManifestPath.
+//   - error: An error if the request is malformed, the download fails or the
+//     document is not a Rancher registration manifest.
+func RancherManifest(ctx context.Context, reg RancherRegistration, opts Options) (Artifact, error) {
+	importURL, err := reg.ImportURL()
+	if err != nil {
+		return Artifact{}, err
+	}
+	if opts.Offline {
+		return Artifact{}, classify(KindNetwork, errors.New("the Rancher registration manifest cannot be downloaded in offline mode"))
+	}
+
+	client := httpcache.New("", 0)
+	if opts.HTTP != nil {
+		client.Client = opts.HTTP.Client
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	// The import URL holds the token, so the step names the server only.
+	endStep := opts.Trace.Step("download registration manifest from " + reg.URL)
+	body, err := client.Get(ctx, importURL)
+	endStep()
+	if err != nil {
+		// The URL holds the token, so it is not repeated in the error.
+		return Artifact{}, classify(KindNetwork, fmt.Errorf("failed to download the registration manifest from %s: %w", reg.URL, redactToken(err, reg.Token)))
+	}
+	if err := checkRancherManifest(body); err != nil {
+		return Artifact{}, err
+	}
+	return Artifact{Path: RancherManifestPath, Content: string(body), Mode: "0600"}, nil
+}
+
+// redactToken removes a token from an error message.
+//
+// Parameters:
+//   - err: The error.
+//   - token: The token to remove.
+//
+// Returns:
+//   - error: The error, with the token replaced by "<token>".
+func redactToken(err error, token string) error {
+	return errors.New(strings.ReplaceAll(err.Error(), token, "<token>"))
+}
+
+// checkRancherManifest verifies that a document is a Rancher registration
+// manifest, i.e. Kubernetes objects including the cattle-cluster-agent.
+//
+// Parameters:
+//   - body: The downloaded document.
+//
+// Returns:
+//   - error: An error if the document is not such a manifest.
+func checkRancherManifest(body []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+	for {
+		var doc struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("the downloaded registration manifest is not valid YAML: %w", err)
+		}
+		if doc.Kind == "Deployment" && doc.Metadata.Name == "cattle-cluster-agent" {
+			return nil
+		}
+	}
+	return errors.New("the downloaded document is not a Rancher registration manifest: it has no cattle-cluster-agent deployment")
+}
@@ -0,0 +1,147 @@
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SeverityOff disables a rule in a RulePolicy.
+const SeverityOff Severity = "off"
+
+// fixedRules cannot be changed by a rule policy: their errors mean that EIB
+// would reject the definition.
+var fixedRules = map[string]bool{"schema": true, "compatibility": true}
+
+// RulePolicy changes the severity of rules, so that operators can make a
+// warning an error, an error a warning, or turn a rule off.
+type RulePolicy struct {
+	// Severities maps rule IDs to their severity: SeverityError,
+	// SeverityWarning, SeverityInfo or SeverityOff.
+	Severities map[string]Severity `yaml:"rules" json:"rules"`
+}
+
+// LoadRulePolicy reads a rule policy from a YAML file of the form:
+//
+//	rules:
+//	  image-output-extension: error
+//	  user-uid-system: off
+//
+// Parameters:
+//   - path: The path of the file.
+//   - rules: The cross-field rules in use; nil selects DefaultRules.
+//
+// Returns:
+//   - *RulePolicy: The policy.
+//   - error: An error if the file cannot be read, names an unknown or fixed
+//     rule, or uses an unknown severity.
+func LoadRulePolicy(path string, rules []Rule) (*RulePolicy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule policy: %w", err)
+	}
+	p := &RulePolicy{}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("invalid rule policy %s: %w", path, err)
+	}
+
+	known := map[string]bool{}
+	for _, r := range RuleCatalog(rules, nil) {
+		known[r.ID] = true
+	}
+	ids := make([]string, 0, len(p.Severities))
+	for id := range p.Severities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		switch {
+		case !known[id]:
+			return nil, fmt.Errorf("invalid rule policy %s: unknown rule %q", path, id)
+		case fixedRules[id]:
+			return nil, fmt.Errorf("invalid rule policy %s: rule %q cannot be changed", path, id)
+		}
+		switch p.Severities[id] {
+		case SeverityError, SeverityWarning, SeverityInfo, SeverityOff:
+		default:
+			return nil, fmt.Errorf("invalid rule policy %s: rule %q: unknown severity %q (expected %s, %s, %s or %s)", path, id, p.Severities[id], SeverityError, SeverityWarning, SeverityInfo, SeverityOff)
+		}
+	}
+	return p, nil
+}
+
+// severity returns the severity of a rule under the policy.
+//
+// Parameters:
+//   - id: The rule ID.
+//   - severity: The built-in severity of the rule.
+//
+// Returns:
+//   - Severity: The severity set by the policy, or severity if it sets none.
+func (p *RulePolicy) severity(id string, severity Severity) Severity {
+	if p == nil {
+		return severity
+	}
+	if s, ok := p.Severities[id]; ok {
+		return s
+	}
+	return severity
+}
+
+// applyRules applies the policy to cross-field rules.
+//
+// Parameters:
+//   - rules: The rules.
+//
+// Returns:
+//   - []Rule: The rules with their policy severity, without disabled rules.
+func (p *RulePolicy) applyRules(rules []Rule) []Rule {
+	if p == nil {
+		return rules
+	}
+	var applied []Rule
+	for _, r := range rules {
+		r.Severity = p.severity(r.ID, r.Severity)
+		if r.Severity != SeverityOff {
+			applied = append(applied, r)
+		}
+	}
+	return applied
+}
+
+// Check applies the policy to findings and fails if it made any of them an
+// error. A nil policy returns the findings unchanged.
+//
+// Parameters:
+//   - findings: The warnings and notes of a configuration.
+//
+// Returns:
+//   - []Finding: The findings with their policy severity, without those of
+//     disabled rules.
+//   - error: A *FindingsError listing the findings the policy made errors.
+func (p *RulePolicy) Check(findings []Finding) ([]Finding, error) {
+	if p == nil {
+		return findings, nil
+	}
+	var kept []Finding
+	policyErr := &FindingsError{Summary: "configuration violates rules the rule policy makes errors"}
+	for _, f := range findings {
+		f.Severity = p.severity(f.RuleID, f.Severity)
+		switch f.Severity {
+		case SeverityOff:
+		case SeverityError:
+			policyErr.Findings = append(policyErr.Findings, f)
+		default:
+			kept = append(kept, f)
+		}
+	}
+	if len(policyErr.Findings) > 0 {
+		return nil, classify(KindRule, policyErr)
+	}
+	return kept, nil
+}
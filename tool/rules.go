@@ -0,0 +1,608 @@
+package tool
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+)
+
+// Severity indicates how a finding is reported.
+type Severity string
+
+const (
+	// SeverityError makes the configuration invalid.
+	SeverityError Severity = "error"
+	// SeverityWarning is reported but does not block generation.
+	SeverityWarning Severity = "warning"
+	// SeverityInfo is a note about what the server did, e.g. a repair.
+	SeverityInfo Severity = "info"
+)
+
+// Rule is a declarative cross-field constraint evaluated after schema validation.
+//
+// JSON schema cannot express relations between distant fields (e.g. a chart
+// referencing a repository by name), so such constraints are expressed as rules.
+type Rule struct {
+	// ID uniquely identifies the rule, e.g. "helm-chart-repository".
+	ID string
+	// Description explains what the rule enforces.
+	Description string
+	// Severity is the severity of the rule's violations.
+	Severity Severity
+	// Check returns one message per violation found in the configuration.
+	Check func(cfg map[string]interface{}) []string
+}
+
+// RuleError reports every error-severity violation of a configuration at
+// once, so that all conflicts can be fixed in one round.
+type RuleError struct {
+	// Violations lists the violations, in rule order.
+	Violations []Violation `json:"violations"`
+}
+
+// Error implements the error interface, listing one violation per line.
+func (e *RuleError) Error() string {
+	var b strings.Builder
+	b.WriteString("configuration violates cross-field rules:\n")
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "- %s\n", v)
+	}
+	return b.String()
+}
+
+// DefaultRules returns the built-in cross-field rules, followed by the time
+// rules (see timeRules).
+//
+// Returns:
+//   - []Rule: The built-in rules, in evaluation order.
+func DefaultRules() []Rule {
+	rules := []Rule{
+		{
+			ID:          "helm-chart-repository",
+			Description: "Every helm chart's repositoryName must match the name of a repository in kubernetes.helm.repositories.",
+			Severity:    SeverityError,
+			Check:       checkChartRepositories,
+		},
+		{
+			ID:          "helm-repository-unique",
+			Description: "Helm repository names must be unique.",
+			Severity:    SeverityError,
+			Check:       checkUniqueRepositories,
+		},
+		{
+			ID:          "helm-chart-api-versions",
+			Description: "The apiVersions of helm charts must be API versions, as \"group/version\" or \"group/version/Kind\".",
+			Severity:    SeverityError,
+			Check:       checkChartAPIVersions,
+		},
+		{
+			ID:          "helm-chart-api-versions-kubernetes",
+			Description: "The apiVersions of helm charts should be served by the Kubernetes version of kubernetes.version.",
+			Severity:    SeverityWarning,
+			Check:       checkChartAPIVersionsKubernetes,
+		},
+		{
+			ID:          "kubernetes-node-hostname-unique",
+			Description: "Kubernetes node hostnames must be unique.",
+			Severity:    SeverityError,
+			Check:       checkUniqueHostnames,
+		},
+		{
+			ID:          "kubernetes-single-initializer",
+			Description: "At most one kubernetes node can be the initializer.",
+			Severity:    SeverityError,
+			Check:       checkSingleInitializer,
+		},
+		{
+			ID:          "kubernetes-server-node",
+			Description: "A multi-node cluster must contain at least one server node.",
+			Severity:    SeverityError,
+			Check:       checkServerNode,
+		},
+		{
+			ID:          "kubernetes-multinode-vip",
+			Description: "A multi-node cluster requires kubernetes.network.apiVIP or apiVIP6.",
+			Severity:    SeverityError,
+			Check:       checkMultiNodeVIP,
+		},
+		{
+			ID:          "kubernetes-api-host",
+			Description: "kubernetes.network.apiHost must be a DNS name, not an IP address.",
+			Severity:    SeverityError,
+			Check:       checkAPIHost,
+		},
+		{
+			ID:          "kubernetes-api-host-vip",
+			Description: "kubernetes.network.apiHost should come with the apiVIP or apiVIP6 it resolves to.",
+			Severity:    SeverityWarning,
+			Check:       checkAPIHostVIP,
+		},
+		{
+			ID:          "user-username-unique",
+			Description: "Usernames in operatingSystem.users must be unique; conflicting definitions of the same user are listed.",
+			Severity:    SeverityError,
+			Check:       checkUniqueUsernames,
+		},
+		{
+			ID:          "user-uid-unique",
+			Description: "Explicit uid values in operatingSystem.users must be unique.",
+			Severity:    SeverityError,
+			Check:       checkUniqueUIDs,
+		},
+		{
+			ID:          "user-uid-range",
+			Description: "Explicit uid values of regular users must not be 0 (root), 65534 (nobody) or outside 0 to 4294967294.",
+			Severity:    SeverityError,
+			Check:       checkUIDRange,
+		},
+		{
+			ID:          "user-uid-system",
+			Description: "Explicit uid values of regular users should lie in the regular range, 1000 to 60000.",
+			Severity:    SeverityWarning,
+			Check:       checkUIDSystemRange,
+		},
+		{
+			ID:          "group-unique",
+			Description: "Group names and explicit gid values in operatingSystem.groups must be unique.",
+			Severity:    SeverityError,
+			Check:       checkUniqueGroups,
+		},
+		{
+			ID:          "group-gid-range",
+			Description: "Explicit gid values of groups other than root must not be 0, 65534 (nobody) or outside 0 to 4294967294.",
+			Severity:    SeverityError,
+			Check:       checkGIDRange,
+		},
+		{
+			ID:          "group-gid-system",
+			Description: "Explicit gid values should lie in the regular range, 1000 to 60000.",
+			Severity:    SeverityWarning,
+			Check:       checkGIDSystemRange,
+		},
+		{
+			ID:          "user-root",
+			Description: "The root user keeps uid 0 and the primary group root.",
+			Severity:    SeverityError,
+			Check:       checkRootUser,
+		},
+		{
+			ID:          "user-root-home",
+			Description: "createHomeDir has no effect on the root user, whose home directory always exists.",
+			Severity:    SeverityWarning,
+			Check:       checkRootHome,
+		},
+		{
+			ID:          "image-output-extension",
+			Description: "image.outputImageName should end with the extension matching image.imageType.",
+			Severity:    SeverityWarning,
+			Check:       checkOutputExtension,
+		},
+	}
+	return append(rules, timeRules()...)
+}
+
+// RunRules evaluates rules against a configuration.
+//
+// Parameters:
+//   - cfg: The configuration map, already validated against the schema.
+//   - rules: The rules to evaluate.
+//
+// Returns:
+//   - []Violation: Every violation found, in rule order.
+func RunRules(cfg map[string]interface{}, rules []Rule) []Violation {
+	var violations []Violation
+	for _, rule := range rules {
+		violations = append(violations, NewFindings(rule.ID, rule.Severity, rule.Check(cfg))...)
+	}
+	return violations
+}
+
+// checkChartRepositories implements the helm-chart-repository rule.
+func checkChartRepositories(cfg map[string]interface{}) []string {
+	repos := map[string]bool{}
+	for _, repo := range lookupMaps(cfg, "kubernetes", "helm", "repositories") {
+		if name, ok := repo["name"].(string); ok {
+			repos[name] = true
+		}
+	}
+
+	var msgs []string
+	for i, chart := range lookupMaps(cfg, "kubernetes", "helm", "charts") {
+		repoName, _ := chart["repositoryName"].(string)
+		if !repos[repoName] {
+			msgs = append(msgs, fmt.Sprintf("kubernetes.helm.charts.%d: repositoryName %q does not match any repository name", i, repoName))
+		}
+	}
+	return msgs
+}
+
+// checkUniqueRepositories implements the helm-repository-unique rule.
+func checkUniqueRepositories(cfg map[string]interface{}) []string {
+	return duplicates(lookupMaps(cfg, "kubernetes", "helm", "repositories"), "name", "kubernetes.helm.repositories")
+}
+
+// checkUniqueHostnames implements the kubernetes-node-hostname-unique rule.
+func checkUniqueHostnames(cfg map[string]interface{}) []string {
+	return duplicates(lookupMaps(cfg, "kubernetes", "nodes"), "hostname", "kubernetes.nodes")
+}
+
+// checkSingleInitializer implements the kubernetes-single-initializer rule.
+func checkSingleInitializer(cfg map[string]interface{}) []string {
+	var initializers []string
+	for _, node := range lookupMaps(cfg, "kubernetes", "nodes") {
+		if init, _ := node["initializer"].(bool); init {
+			hostname, _ := node["hostname"].(string)
+			initializers = append(initializers, hostname)
+		}
+	}
+	if len(initializers) > 1 {
+		return []string{fmt.Sprintf("kubernetes.nodes: %d nodes are marked as initializer (%s)", len(initializers), strings.Join(initializers, ", "))}
+	}
+	return nil
+}
+
+// checkServerNode implements the kubernetes-server-node rule.
+func checkServerNode(cfg map[string]interface{}) []string {
+	nodes := lookupMaps(cfg, "kubernetes", "nodes")
+	if len(nodes) < 2 {
+		return nil
+	}
+	for _, node := range nodes {
+		if node["type"] == "server" {
+			return nil
+		}
+	}
+	return []string{"kubernetes.nodes: no node has type \"server\""}
+}
+
+// checkMultiNodeVIP implements the kubernetes-multinode-vip rule.
+func checkMultiNodeVIP(cfg map[string]interface{}) []string {
+	if len(lookupMaps(cfg, "kubernetes", "nodes")) < 2 {
+		return nil
+	}
+	network := lookupMap(cfg, "kubernetes", "network")
+	if vip, _ := network["apiVIP"].(string); vip != "" {
+		return nil
+	}
+	if vip6, _ := network["apiVIP6"].(string); vip6 != "" {
+		return nil
+	}
+	return []string{"kubernetes.network: apiVIP (or apiVIP6) is required when more than one node is defined"}
+}
+
+// checkAPIHost implements the kubernetes-api-host rule. The schema checks
+// the hostname syntax, which dotted IPv4 addresses satisfy.
+func checkAPIHost(cfg map[string]interface{}) []string {
+	host, _ := lookupMap(cfg, "kubernetes", "network")["apiHost"].(string)
+	if _, err := netip.ParseAddr(host); err != nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("kubernetes.network.apiHost: %q is an IP address, not a DNS name; use apiVIP for the address, and set apiHost to a name resolving to it", host)}
+}
+
+// checkAPIHostVIP implements the kubernetes-api-host-vip rule.
+func checkAPIHostVIP(cfg map[string]interface{}) []string {
+	network := lookupMap(cfg, "kubernetes", "network")
+	host, _ := network["apiHost"].(string)
+	if host == "" {
+		return nil
+	}
+	if vip, _ := network["apiVIP"].(string); vip != "" {
+		return nil
+	}
+	if vip6, _ := network["apiVIP6"].(string); vip6 != "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("kubernetes.network.apiHost: %q is set without apiVIP or apiVIP6, so it names no address serving the Kubernetes API; set apiVIP to the address it resolves to", host)}
+}
+
+// userFields are the fields compared between duplicate definitions of a
+// user, to tell a repeated entry from conflicting ones.
+var userFields = []string{"uid", "primaryGroup", "secondaryGroups", "createHomeDir", "encryptedPassword", "sshKeys"}
+
+// checkUniqueUsernames implements the user-username-unique rule.
+//
+// EIB creates each user once, so a second definition is silently lost; the
+// message names the fields it disagrees on, e.g. a different createHomeDir,
+// which decides whether the user gets a home directory.
+func checkUniqueUsernames(cfg map[string]interface{}) []string {
+	users := lookupMaps(cfg, "operatingSystem", "users")
+	seen := map[string]int{}
+	var msgs []string
+	for i, user := range users {
+		name, ok := user["username"].(string)
+		if !ok {
+			continue
+		}
+		first, dup := seen[name]
+		if !dup {
+			seen[name] = i
+			continue
+		}
+		var conflicts []string
+		for _, field := range userFields {
+			if !reflect.DeepEqual(users[first][field], user[field]) {
+				conflicts = append(conflicts, field)
+			}
+		}
+		msg := fmt.Sprintf("operatingSystem.users.%d: username %q is already used by operatingSystem.users.%d", i, name, first)
+		if len(conflicts) > 0 {
+			msg += fmt.Sprintf("; the definitions differ in %s", strings.Join(conflicts, ", "))
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// checkUniqueUIDs implements the user-uid-unique rule.
+func checkUniqueUIDs(cfg map[string]interface{}) []string {
+	seen := map[int]int{}
+	var msgs []string
+	for i, user := range lookupMaps(cfg, "operatingSystem", "users") {
+		uid, ok := intValue(user["uid"])
+		if !ok {
+			continue
+		}
+		if first, dup := seen[uid]; dup {
+			name, _ := user["username"].(string)
+			msgs = append(msgs, fmt.Sprintf("operatingSystem.users.%d: uid %d of user %q is already used by operatingSystem.users.%d", i, uid, name, first))
+			continue
+		}
+		seen[uid] = i
+	}
+	return msgs
+}
+
+// Ranges of user and group IDs, as set by /etc/login.defs on SLE Micro.
+const (
+	// minRegularID is the first ID of regular users and groups; IDs below
+	// it belong to system accounts created by packages.
+	minRegularID = 1000
+	// maxRegularID is the last ID useradd and groupadd assign.
+	maxRegularID = 60000
+	// nobodyID is the ID of the nobody user and group.
+	nobodyID = 65534
+	// maxID is the largest valid ID; 4294967295 means "no ID".
+	maxID = 4294967294
+)
+
+// idRangeError describes an ID no user or group other than root may use.
+//
+// Parameters:
+//   - id: The uid or gid.
+//
+// Returns:
+//   - string: The reason, or empty if the ID is usable.
+func idRangeError(id int) string {
+	switch {
+	case id < 0 || id > maxID:
+		return fmt.Sprintf("is outside 0 to %d", maxID)
+	case id == 0:
+		return "is reserved for root"
+	case id == nobodyID:
+		return "is reserved for nobody"
+	}
+	return ""
+}
+
+// idRangeWarning describes an ID outside the regular range.
+//
+// Generated configurations often invent small IDs such as 1; they collide
+// with the system accounts packages create, e.g. bin, at first boot.
+//
+// Parameters:
+//   - id: The uid or gid, already accepted by idRangeError.
+//
+// Returns:
+//   - string: The reason, or empty if the ID is in the regular range.
+func idRangeWarning(id int) string {
+	switch {
+	case id > 0 && id < minRegularID:
+		return fmt.Sprintf("is in the system range (1 to %d) and may collide with an account created by a package; use %d to %d, or omit it", minRegularID-1, minRegularID, maxRegularID)
+	case id > maxRegularID && id <= maxID && id != nobodyID:
+		return fmt.Sprintf("is above the regular range (%d to %d)", minRegularID, maxRegularID)
+	}
+	return ""
+}
+
+// checkIDs applies an ID check to the uid of every user but root, or to
+// the gid of every group but root.
+//
+// Parameters:
+//   - cfg: The configuration.
+//   - list: "users" or "groups".
+//   - check: idRangeError or idRangeWarning.
+//
+// Returns:
+//   - []string: One message per ID the check rejects.
+func checkIDs(cfg map[string]interface{}, list string, check func(int) string) []string {
+	kind, nameKey, idKey := "user", "username", "uid"
+	if list == "groups" {
+		kind, nameKey, idKey = "group", "name", "gid"
+	}
+	var msgs []string
+	for i, item := range lookupMaps(cfg, "operatingSystem", list) {
+		id, ok := intValue(item[idKey])
+		if !ok || item[nameKey] == "root" {
+			continue
+		}
+		if reason := check(id); reason != "" {
+			msgs = append(msgs, fmt.Sprintf("operatingSystem.%s.%d: %s %d of %s %q %s", list, i, idKey, id, kind, item[nameKey], reason))
+		}
+	}
+	return msgs
+}
+
+// checkUIDRange implements the user-uid-range rule.
+func checkUIDRange(cfg map[string]interface{}) []string {
+	return checkIDs(cfg, "users", idRangeError)
+}
+
+// checkUIDSystemRange implements the user-uid-system rule.
+func checkUIDSystemRange(cfg map[string]interface{}) []string {
+	return checkIDs(cfg, "users", idRangeWarning)
+}
+
+// checkGIDRange implements the group-gid-range rule.
+func checkGIDRange(cfg map[string]interface{}) []string {
+	return checkIDs(cfg, "groups", idRangeError)
+}
+
+// checkGIDSystemRange implements the group-gid-system rule.
+func checkGIDSystemRange(cfg map[string]interface{}) []string {
+	return checkIDs(cfg, "groups", idRangeWarning)
+}
+
+// checkUniqueGroups implements the group-unique rule.
+func checkUniqueGroups(cfg map[string]interface{}) []string {
+	groups := lookupMaps(cfg, "operatingSystem", "groups")
+	msgs := duplicates(groups, "name", "operatingSystem.groups")
+	seen := map[int]int{}
+	for i, group := range groups {
+		gid, ok := intValue(group["gid"])
+		if !ok {
+			continue
+		}
+		if first, dup := seen[gid]; dup {
+			msgs = append(msgs, fmt.Sprintf("operatingSystem.groups.%d: gid %d of group %q is already used by operatingSystem.groups.%d", i, gid, group["name"], first))
+			continue
+		}
+		seen[gid] = i
+	}
+	return msgs
+}
+
+// checkRootUser implements the user-root rule.
+//
+// EIB configures root by changing its credentials; a different uid or
+// primary group would be applied to the existing account and break it.
+func checkRootUser(cfg map[string]interface{}) []string {
+	var msgs []string
+	for i, user := range lookupMaps(cfg, "operatingSystem", "users") {
+		if user["username"] != "root" {
+			continue
+		}
+		if uid, ok := intValue(user["uid"]); ok && uid != 0 {
+			msgs = append(msgs, fmt.Sprintf("operatingSystem.users.%d: the root user must have uid 0, not %d; remove the uid field", i, uid))
+		}
+		if group, ok := user["primaryGroup"].(string); ok && group != "root" {
+			msgs = append(msgs, fmt.Sprintf("operatingSystem.users.%d: the primary group of the root user must be root, not %q; remove the primaryGroup field", i, group))
+		}
+	}
+	return msgs
+}
+
+// checkRootHome implements the user-root-home rule.
+func checkRootHome(cfg map[string]interface{}) []string {
+	var msgs []string
+	for i, user := range lookupMaps(cfg, "operatingSystem", "users") {
+		if _, set := user["createHomeDir"]; set && user["username"] == "root" {
+			msgs = append(msgs, fmt.Sprintf("operatingSystem.users.%d: createHomeDir is ignored for the root user, whose home directory /root always exists", i))
+		}
+	}
+	return msgs
+}
+
+// intValue converts a whole number decoded from JSON or YAML.
+//
+// Parameters:
+//   - v: The value.
+//
+// Returns:
+//   - int: The number.
+//   - bool: False if v is not a whole number.
+func intValue(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+// checkOutputExtension implements the image-output-extension rule.
+func checkOutputExtension(cfg map[string]interface{}) []string {
+	image := lookupMap(cfg, "image")
+	imageType, _ := image["imageType"].(string)
+	name, _ := image["outputImageName"].(string)
+	if imageType == "" || name == "" || strings.HasSuffix(name, "."+imageType) {
+		return nil
+	}
+	return []string{fmt.Sprintf("image.outputImageName: %q does not end with \".%s\" for imageType %q", name, imageType, imageType)}
+}
+
+// duplicates reports values of key that appear more than once in items.
+//
+// Parameters:
+//   - items: The list of objects to inspect.
+//   - key: The field that must be unique.
+//   - path: The dotted path of the list, used in messages.
+//
+// Returns:
+//   - []string: One message per duplicated value.
+func duplicates(items []map[string]interface{}, key, path string) []string {
+	seen := map[string]int{}
+	var msgs []string
+	for i, item := range items {
+		value, ok := item[key].(string)
+		if !ok {
+			continue
+		}
+		if first, dup := seen[value]; dup {
+			msgs = append(msgs, fmt.Sprintf("%s.%d: %s %q is already used by %s.%d", path, i, key, value, path, first))
+			continue
+		}
+		seen[value] = i
+	}
+	return msgs
+}
+
+// lookupMap walks nested objects following keys.
+//
+// Parameters:
+//   - cfg: The root object.
+//   - keys: The keys to follow.
+//
+// Returns:
+//   - map[string]interface{}: The object found, or nil if any step is missing or not an object.
+func lookupMap(cfg map[string]interface{}, keys ...string) map[string]interface{} {
+	current := cfg
+	for _, key := range keys {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// lookupMaps walks nested objects following keys and returns the objects of the final list.
+//
+// Parameters:
+//   - cfg: The root object.
+//   - keys: The keys to follow; the last one must name a list.
+//
+// Returns:
+//   - []map[string]interface{}: The objects in the list, skipping non-object items.
+func lookupMaps(cfg map[string]interface{}, keys ...string) []map[string]interface{} {
+	if len(keys) == 0 {
+		return nil
+	}
+	parent := lookupMap(cfg, keys[:len(keys)-1]...)
+	list, _ := parent[keys[len(keys)-1]].([]interface{})
+
+	var result []map[string]interface{}
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
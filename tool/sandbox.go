@@ -0,0 +1,128 @@
+package tool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox restricts the server paths that tools may read or write to a set of
+// allowed directories.
+//
+// Paths are made absolute and their symbolic links resolved before being
+// checked, so neither ".." components nor links can escape the allowed
+// directories. A nil *Sandbox allows every path.
+type Sandbox struct {
+	roots []string
+}
+
+// SandboxError reports a path outside the allowed directories.
+type SandboxError struct {
+	// Path is the rejected path, as given.
+	Path string `json:"path"`
+	// Allowed lists the allowed directories.
+	Allowed []string `json:"allowed"`
+}
+
+// Error implements the error interface.
+func (e *SandboxError) Error() string {
+	return fmt.Sprintf("access to %q denied: it is outside the allowed directories (%s)", e.Path, strings.Join(e.Allowed, ", "))
+}
+
+// NewSandbox creates a sandbox allowing the given directories.
+//
+// Parameters:
+//   - dirs: The allowed directories. They must exist.
+//
+// Returns:
+//   - *Sandbox: The sandbox.
+//   - error: An error if a directory cannot be resolved or is not a directory.
+func NewSandbox(dirs []string) (*Sandbox, error) {
+	s := &Sandbox{}
+	for _, dir := range dirs {
+		resolved, err := filepath.Abs(dir)
+		if err == nil {
+			resolved, err = filepath.EvalSymlinks(resolved)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("allowed directory %q: %w", dir, err)
+		}
+		if st, err := os.Stat(resolved); err != nil || !st.IsDir() {
+			return nil, fmt.Errorf("allowed directory %q is not a directory", dir)
+		}
+		s.roots = append(s.roots, resolved)
+	}
+	return s, nil
+}
+
+// Roots returns the allowed directories, resolved.
+//
+// Returns:
+//   - []string: The directories, or nil for a nil sandbox.
+func (s *Sandbox) Roots() []string {
+	if s == nil {
+		return nil
+	}
+	return s.roots
+}
+
+// Check verifies that a path lies within an allowed directory.
+//
+// The path need not exist: the symbolic links of its longest existing
+// ancestor are resolved and the remaining components appended.
+//
+// Parameters:
+//   - path: The path to read or write.
+//
+// Returns:
+//   - string: The resolved absolute path.
+//   - error: A *SandboxError if the path is outside the allowed directories, or an error if it cannot be resolved.
+func (s *Sandbox) Check(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := resolveExisting(abs)
+	if err != nil {
+		return "", err
+	}
+	if s == nil {
+		return resolved, nil
+	}
+	for _, root := range s.roots {
+		if resolved == root || strings.HasPrefix(resolved, root+string(filepath.Separator)) || root == string(filepath.Separator) {
+			return resolved, nil
+		}
+	}
+	return "", &SandboxError{Path: path, Allowed: s.roots}
+}
+
+// resolveExisting resolves the symbolic links of the longest existing
+// ancestor of an absolute path.
+//
+// Parameters:
+//   - abs: The cleaned absolute path.
+//
+// Returns:
+//   - string: The resolved path.
+//   - error: An error if an existing ancestor cannot be resolved.
+func resolveExisting(abs string) (string, error) {
+	var rest []string
+	current := abs
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, nil
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+		current = parent
+	}
+}
@@ -0,0 +1,161 @@
+package tool
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scriptsDir is the image configuration directory whose scripts EIB runs
+// during combustion, in lexical order of their file names.
+const scriptsDir = "custom/scripts"
+
+// scriptMode is the file mode of custom scripts.
+const scriptMode = "0755"
+
+// scriptNamePattern matches valid script file names.
+var scriptNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// scriptPrefixPattern splits a script name into its numeric prefix and the
+// rest, e.g. "10-network.sh" into "10" and "network.sh".
+var scriptPrefixPattern = regexp.MustCompile(`^([0-9]+)[-_]?(.*)$`)
+
+// Script is a custom script run at first boot by combustion.
+type Script struct {
+	// Name is the file name under custom/scripts/, e.g. "10-network.sh".
+	// Its numeric prefix orders the scripts.
+	Name string `json:"name"`
+	// Content is the script content.
+	Content string `json:"content"`
+}
+
+// PrepareScripts validates the execution order of custom scripts and turns
+// them into custom/scripts/ artifacts.
+//
+// Combustion runs scripts in lexical order of their names, so the list
+// order is taken as the intended order: names whose lexical order differs
+// from it (e.g. "9-a.sh" listed before "10-b.sh") and names sharing a
+// numeric prefix are rejected. With renumber, the scripts are instead renamed
+// to zero-padded prefixes in steps of 10 following the list order, and the
+// renames are reported as warnings.
+//
+// Parameters:
+//   - scripts: The scripts in their intended execution order.
+//   - renumber: Whether to rename the scripts to match the list order.
+//
+// Returns:
+//   - []Artifact: The artifacts, in execution order.
+//   - []string: The warnings.
+//   - error: An error describing every naming or ordering problem.
+func PrepareScripts(scripts []Script, renumber bool) ([]Artifact, []string, error) {
+	var warnings, errs []string
+	for _, s := range scripts {
+		if !scriptNamePattern.MatchString(s.Name) {
+			errs = append(errs, fmt.Sprintf("%q: invalid file name (use letters, digits, '.', '_' and '-')", s.Name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid scripts:\n- %s", strings.Join(errs, "\n- "))
+	}
+
+	names := make([]string, len(scripts))
+	for i, s := range scripts {
+		names[i] = s.Name
+	}
+	if renumber {
+		renamed := renumberScripts(names)
+		for i := range names {
+			if renamed[i] != names[i] {
+				warnings = append(warnings, fmt.Sprintf("script %s renamed to %s", names[i], renamed[i]))
+			}
+		}
+		names = renamed
+	} else {
+		errs = scriptOrderErrors(names)
+		for _, name := range names {
+			if !scriptPrefixPattern.MatchString(name) {
+				warnings = append(warnings, fmt.Sprintf("script %s has no numeric prefix, so its position depends on its name", name))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid script order (set renumberScripts to fix it automatically):\n- %s", strings.Join(errs, "\n- "))
+	}
+
+	artifacts := make([]Artifact, len(scripts))
+	for i, s := range scripts {
+		if !strings.HasPrefix(s.Content, "#!") {
+			warnings = append(warnings, fmt.Sprintf("script %s has no shebang line, e.g. #!/bin/bash", names[i]))
+		}
+		artifacts[i] = Artifact{Path: scriptsDir + "/" + names[i], Content: s.Content, Mode: scriptMode}
+	}
+	return artifacts, warnings, nil
+}
+
+// scriptOrderErrors reports duplicate names, shared numeric prefixes and
+// names whose lexical order differs from the list order.
+//
+// Parameters:
+//   - names: The script names in their intended order.
+//
+// Returns:
+//   - []string: The problems found.
+func scriptOrderErrors(names []string) []string {
+	var errs []string
+	byPrefix := map[string][]string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			errs = append(errs, fmt.Sprintf("duplicate script %s", name))
+		}
+		seen[name] = true
+		if m := scriptPrefixPattern.FindStringSubmatch(name); m != nil {
+			prefix := strings.TrimLeft(m[1], "0")
+			byPrefix[prefix] = append(byPrefix[prefix], name)
+		}
+	}
+
+	prefixes := make([]string, 0, len(byPrefix))
+	for p := range byPrefix {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	for _, p := range prefixes {
+		if len(byPrefix[p]) > 1 {
+			errs = append(errs, fmt.Sprintf("scripts %s share the same numeric prefix", strings.Join(byPrefix[p], ", ")))
+		}
+	}
+
+	for i := 1; i < len(names); i++ {
+		if names[i-1] > names[i] {
+			errs = append(errs, fmt.Sprintf("%s is listed before %s but runs after it, since scripts run in lexical order", names[i-1], names[i]))
+		}
+	}
+	return errs
+}
+
+// renumberScripts renames scripts so that their lexical order matches the
+// list order, replacing any numeric prefix with a zero-padded one in steps
+// of 10.
+//
+// Parameters:
+//   - names: The script names in their intended order.
+//
+// Returns:
+//   - []string: The new names.
+func renumberScripts(names []string) []string {
+	width := len(fmt.Sprint(len(names) * 10))
+	if width < 2 {
+		width = 2
+	}
+	renamed := make([]string, len(names))
+	for i, name := range names {
+		base := name
+		if m := scriptPrefixPattern.FindStringSubmatch(name); m != nil && m[2] != "" {
+			base = m[2]
+		}
+		renamed[i] = fmt.Sprintf("%0*d-%s", width, (i+1)*10, base)
+	}
+	return renamed
+}
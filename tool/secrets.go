@@ -0,0 +1,292 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSecretEnvPrefix is the default prefix of environment variables
+// that env:// secret references can read: env://REGISTRY_PASSWORD resolves
+// to EIB_SECRET_REGISTRY_PASSWORD.
+const DefaultSecretEnvPrefix = "EIB_SECRET_"
+
+// VaultClient reads secrets from HashiCorp Vault over its HTTP API.
+type VaultClient struct {
+	// Addr is the Vault address, e.g. https://vault.example.com:8200.
+	Addr string
+	// Token is the Vault token.
+	Token string
+	// Namespace is the Vault Enterprise namespace, if any.
+	Namespace string
+	// Client performs the requests. If nil, a client with a 30 second
+	// timeout is used.
+	Client *http.Client
+}
+
+// Read returns a key of a Vault secret.
+//
+// Both KV version 1 and version 2 secrets are supported; for version 2, the
+// path includes the "data/" segment, e.g. "secret/data/edge/site1".
+//
+// Parameters:
+//   - ctx: The context controlling the request.
+//   - path: The secret path.
+//   - key: The key within the secret.
+//
+// Returns:
+//   - string: The value.
+//   - error: An error if the secret cannot be read or has no such string key.
+func (v *VaultClient) Read(ctx context.Context, path, key string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(v.Addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&secret); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string key %q", key)
+	}
+	return value, nil
+}
+
+// ResolveSecrets replaces secret references in the secret-bearing fields of
+// a configuration (passwords, registration codes, activation keys, LUKS
+// keys) with the values they point to.
+//
+// A reference is either "vault://<path>#<key>", read with opts.Vault, or
+// "env://<NAME>", read from the environment variable
+// opts.SecretEnvPrefix+NAME. Only prefixed variables are visible, so a
+// configuration cannot read arbitrary secrets from the server's environment.
+//
+// Parameters:
+//   - config: The configuration; it is modified in place.
+//   - opts: The options; Vault and SecretEnvPrefix are used.
+//
+// Returns:
+//   - []string: The dotted paths of the resolved fields, sorted.
+//   - error: An error listing every reference that could not be resolved.
+func ResolveSecrets(config map[string]interface{}, opts Options) ([]string, error) {
+	var resolved, failures []string
+	var walk func(v interface{}, path string)
+	walk = func(v interface{}, path string) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for k, child := range val {
+				childPath := joinPath(path, k)
+				ref, isString := child.(string)
+				if !isString || !redactedFields[k] || !isSecretReference(ref) {
+					walk(child, childPath)
+					continue
+				}
+				secret, err := resolveSecret(ref, opts)
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("%s: %v", childPath, err))
+					continue
+				}
+				val[k] = secret
+				resolved = append(resolved, childPath)
+			}
+		case []interface{}:
+			for i, child := range val {
+				walk(child, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+	walk(config, "")
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return nil, fmt.Errorf("failed to resolve secret references:\n- %s", strings.Join(failures, "\n- "))
+	}
+	sort.Strings(resolved)
+	return resolved, nil
+}
+
+// joinPath appends a field name to a dotted path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// tokenPrefixes are the prefixes of well-known API tokens, which are secrets
+// whatever field holds them.
+var tokenPrefixes = []string{"ghp_", "gho_", "github_pat_", "glpat-", "xoxb-", "xoxp-", "AKIA", "hvs."}
+
+// CheckPlaintextSecrets looks for secrets written as literal values in a
+// processed configuration, which end up readable in the definition and have
+// passed through the conversation.
+//
+// User passwords are hashed before this check, so it finds the other
+// secrets: literal values of secret fields such as Helm repository and
+// registry passwords, values of unknown fields named like secrets, URLs
+// embedding a password and well-known API tokens.
+//
+// Parameters:
+//   - config: The processed configuration.
+//   - resolved: The paths of the fields resolved from secret references, as
+//     returned by ResolveSecrets; they are not reported.
+//
+// Returns:
+//   - []string: One warning per literal secret, naming the field but never the value.
+func CheckPlaintextSecrets(config map[string]interface{}, resolved []string) []string {
+	fromReference := map[string]bool{}
+	for _, path := range resolved {
+		fromReference[path] = true
+	}
+	var warnings []string
+	var walk func(v interface{}, name, path string)
+	walk = func(v interface{}, name, path string) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for k, child := range val {
+				walk(child, k, joinPath(path, k))
+			}
+		case []interface{}:
+			for i, child := range val {
+				walk(child, name, fmt.Sprintf("%s[%d]", path, i))
+			}
+		case string:
+			if val == "" || fromReference[path] {
+				return
+			}
+			if finding := plaintextSecret(name, val); finding != "" {
+				warnings = append(warnings, path+" "+finding)
+			}
+		}
+	}
+	walk(config, "", "")
+	sort.Strings(warnings)
+	return warnings
+}
+
+// plaintextSecret tells whether a field value looks like a secret.
+//
+// Parameters:
+//   - name: The field name.
+//   - value: The field value.
+//
+// Returns:
+//   - string: Why the value is a secret and what to do about it, or empty if
+//     it does not look like one.
+func plaintextSecret(name, value string) string {
+	if name == "encryptedPassword" {
+		// Plaintext values were hashed by processPasswords.
+		return ""
+	}
+	if redactedFields[name] {
+		return "holds a plaintext secret; use a vault:// or env:// secret reference instead, so the secret stays out of the conversation"
+	}
+	const readable = "; it will be readable by anyone with access to the definition"
+	lower := strings.ToLower(name)
+	for _, word := range []string{"password", "secret", "token"} {
+		if strings.Contains(lower, word) {
+			return "is named like a secret and holds a plaintext value" + readable
+		}
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			return "is a URL embedding a password" + readable
+		}
+	}
+	for _, prefix := range tokenPrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return "holds what looks like an API token" + readable
+		}
+	}
+	return ""
+}
+
+// isSecretReference reports whether a value is a secret reference.
+func isSecretReference(v string) bool {
+	return strings.HasPrefix(v, "vault://") || strings.HasPrefix(v, "env://")
+}
+
+// resolveSecret returns the value a secret reference points to.
+//
+// Parameters:
+//   - ref: The reference.
+//   - opts: The options; Vault and SecretEnvPrefix are used.
+//
+// Returns:
+//   - string: The secret.
+//   - error: An error if the reference is malformed or cannot be resolved.
+//     The secret is never part of the message.
+func resolveSecret(ref string, opts Options) (string, error) {
+	if name, ok := strings.CutPrefix(ref, "env://"); ok {
+		if opts.SecretEnvPrefix == "" {
+			return "", fmt.Errorf("env:// references are disabled on this server")
+		}
+		if name == "" {
+			return "", fmt.Errorf("env:// reference has no variable name")
+		}
+		value, found := os.LookupEnv(opts.SecretEnvPrefix + name)
+		if !found {
+			return "", fmt.Errorf("environment variable %s is not set", opts.SecretEnvPrefix+name)
+		}
+		return value, nil
+	}
+
+	location := strings.TrimPrefix(ref, "vault://")
+	path, key, ok := strings.Cut(location, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("vault reference %q must look like vault://<path>#<key>", ref)
+	}
+	if opts.Vault == nil || opts.Vault.Addr == "" {
+		return "", fmt.Errorf("vault:// references need VAULT_ADDR and VAULT_TOKEN on the server")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	value, err := opts.Vault.Read(ctx, path, key)
+	if err != nil {
+		return "", fmt.Errorf("vault secret %s#%s: %w", path, key, err)
+	}
+	return value, nil
+}
+
+// RedactSecrets hides the values of the secret-bearing fields of a YAML
+// definition, so it can be shown without the secrets resolved into it.
+//
+// Parameters:
+//   - definition: The YAML definition.
+//
+// Returns:
+//   - string: The definition with secrets replaced by "(redacted)".
+func RedactSecrets(definition string) string {
+	return redactYAML(definition)
+}
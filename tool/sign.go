@@ -0,0 +1,79 @@
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// SignatureFile is the name under which the detached signature of the
+// definition is written next to it.
+const SignatureFile = DefinitionFile + ".asc"
+
+// Signer signs generated definitions with an OpenPGP private key, so
+// provisioning pipelines can verify they come from this generator.
+type Signer struct {
+	entity *openpgp.Entity
+}
+
+// NewSigner loads the signing key from an armored OpenPGP private key.
+//
+// Parameters:
+//   - armoredKey: The ASCII-armored private key, e.g. from `gpg --armor --export-secret-keys`.
+//   - passphrase: The passphrase of the key, or empty if it is not encrypted.
+//
+// Returns:
+//   - *Signer: The signer.
+//   - error: An error if the key cannot be read or decrypted, or cannot sign.
+func NewSigner(armoredKey []byte, passphrase string) (*Signer, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("signing key file must hold exactly one key, found %d", len(entities))
+	}
+	entity := entities[0]
+	if entity.PrivateKey == nil {
+		return nil, fmt.Errorf("signing key file holds a public key, a private key is required")
+	}
+	if entity.PrivateKey.Encrypted {
+		if passphrase == "" {
+			return nil, fmt.Errorf("signing key is encrypted and no passphrase was given")
+		}
+		// The subkeys are decrypted too, since one of them may be the key
+		// that signs.
+		if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt signing key: %w", err)
+		}
+	}
+	return &Signer{entity: entity}, nil
+}
+
+// Fingerprint returns the fingerprint of the signing key, for verifiers to
+// pin.
+//
+// Returns:
+//   - string: The uppercase hexadecimal fingerprint.
+func (s *Signer) Fingerprint() string {
+	return strings.ToUpper(fmt.Sprintf("%x", s.entity.PrimaryKey.Fingerprint))
+}
+
+// Sign creates a detached signature of a definition.
+//
+// Parameters:
+//   - definition: The YAML definition.
+//
+// Returns:
+//   - string: The ASCII-armored signature, verifiable with `gpg --verify`.
+//   - error: An error if signing fails.
+func (s *Signer) Sign(definition string) (string, error) {
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, s.entity, strings.NewReader(definition), nil); err != nil {
+		return "", fmt.Errorf("failed to sign definition: %w", err)
+	}
+	sig.WriteString("\n")
+	return sig.String(), nil
+}
@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// armoredTestKey returns a new private key, armored and encrypted with the
+// passphrase if it is not empty.
+func armoredTestKey(t *testing.T, passphrase string) (*openpgp.Entity, []byte) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("eib-mcp test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return entity, buf.Bytes()
+}
+
+// TestSignerSign checks that signatures verify against the public key, with
+// plain and passphrase-protected keys.
+func TestSignerSign(t *testing.T) {
+	for _, passphrase := range []string{"", "secret"} {
+		entity, key := armoredTestKey(t, passphrase)
+		signer, err := NewSigner(key, passphrase)
+		if err != nil {
+			t.Fatalf("passphrase %q: %v", passphrase, err)
+		}
+		definition := "apiVersion: \"1.2\"\n"
+		sig, err := signer.Sign(definition)
+		if err != nil {
+			t.Fatalf("passphrase %q: %v", passphrase, err)
+		}
+		keyring := openpgp.EntityList{entity}
+		if _, err := openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(definition), strings.NewReader(sig), nil); err != nil {
+			t.Errorf("passphrase %q: signature does not verify: %v", passphrase, err)
+		}
+		if _, err := openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(definition+"#"), strings.NewReader(sig), nil); err == nil {
+			t.Errorf("passphrase %q: signature verifies a modified definition", passphrase)
+		}
+	}
+}
+
+// TestNewSignerWrongPassphrase checks that an encrypted key needs its
+// passphrase.
+func TestNewSignerWrongPassphrase(t *testing.T) {
+	_, key := armoredTestKey(t, "secret")
+	if _, err := NewSigner(key, ""); err == nil {
+		t.Error("an encrypted key was loaded without a passphrase")
+	}
+	if _, err := NewSigner(key, "wrong"); err == nil {
+		t.Error("an encrypted key was loaded with a wrong passphrase")
+	}
+}
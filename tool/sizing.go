@@ -0,0 +1,144 @@
+package tool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxEtcdMembers is the number of servers beyond which etcd, which
+// replicates every write to each member, gets slower rather than more
+// available.
+const maxEtcdMembers = 7
+
+// agentsPerServer is the number of agents a server is sized to manage; larger
+// clusters need more servers for the API server and etcd to keep up.
+const agentsPerServer = 50
+
+// workloadNeeds records the nodes a well-known chart needs to run as
+// intended.
+type workloadNeeds struct {
+	// Nodes is the minimum number of nodes.
+	Nodes int
+	// Reason explains why, e.g. "3 replicas on different nodes".
+	Reason string
+}
+
+// knownWorkloads maps the names of well-known charts to the nodes they need.
+var knownWorkloads = map[string]workloadNeeds{
+	"rancher":        {Nodes: 3, Reason: "3 replicas on different nodes for high availability"},
+	"longhorn":       {Nodes: 3, Reason: "3 replicas of each volume on different nodes"},
+	"suse-storage":   {Nodes: 3, Reason: "3 replicas of each volume on different nodes"},
+	"neuvector":      {Nodes: 3, Reason: "3 controller replicas that need a quorum"},
+	"neuvector-core": {Nodes: 3, Reason: "3 controller replicas that need a quorum"},
+	"metallb":        {Nodes: 2, Reason: "a second node to move service addresses to when one fails"},
+	"kube-vip":       {Nodes: 2, Reason: "a second node to move the virtual IP to when one fails"},
+}
+
+// SizingRequest describes a planned cluster for AdviseSizing, overriding
+// what the configuration defines.
+type SizingRequest struct {
+	// Servers is the number of server nodes, or nil to count those of
+	// kubernetes.nodes.
+	Servers *int `json:"servers,omitempty"`
+	// Agents is the number of agent nodes, or nil to count those of
+	// kubernetes.nodes.
+	Agents *int `json:"agents,omitempty"`
+	// Workloads lists the names of the charts the cluster runs, or nil for
+	// those of kubernetes.helm.charts.
+	Workloads []string `json:"workloads,omitempty"`
+}
+
+// SizingReport is the result of AdviseSizing.
+type SizingReport struct {
+	// Servers is the number of server nodes.
+	Servers int `json:"servers"`
+	// Agents is the number of agent nodes.
+	Agents int `json:"agents"`
+	// FaultTolerance is the number of servers that can fail while etcd keeps
+	// its quorum.
+	FaultTolerance int `json:"faultTolerance"`
+	// Workloads lists the analyzed charts.
+	Workloads []string `json:"workloads"`
+	// Findings lists the sizing problems, with recommendations.
+	Findings []Finding `json:"findings"`
+}
+
+// AdviseSizing checks the node counts and roles of a cluster against its
+// workloads: an even number of servers, which adds no fault tolerance, a
+// single server managing agents, which is a single point of failure, too few
+// servers for the agents, and too few nodes for the replicas of well-known
+// charts such as Rancher or Longhorn.
+//
+// Parameters:
+//   - config: The configuration, or nil to plan from req alone; its hostname
+//     patterns are expanded in place.
+//   - req: The planned node counts and workloads, overriding config.
+//
+// Returns:
+//   - *SizingReport: The cluster size and its findings.
+//   - error: An error if a hostname pattern is invalid, a count is
+//     negative or the cluster has no server.
+func AdviseSizing(config map[string]interface{}, req SizingRequest) (*SizingReport, error) {
+	if _, err := ExpandNodePatterns(config); err != nil {
+		return nil, classify(KindSchema, err)
+	}
+	report := &SizingReport{Workloads: req.Workloads, Findings: []Finding{}}
+	report.Servers, report.Agents = nodeCounts(config)
+	if req.Servers != nil {
+		report.Servers = *req.Servers
+	}
+	if req.Agents != nil {
+		report.Agents = *req.Agents
+	}
+	if report.Servers < 0 || report.Agents < 0 {
+		return nil, fmt.Errorf("node counts must not be negative")
+	}
+	if report.Servers == 0 {
+		return nil, fmt.Errorf("the cluster has no server node; use at least one")
+	}
+	workloadPath := "workloads"
+	if req.Workloads == nil {
+		workloadPath = "kubernetes.helm.charts"
+		for _, chart := range lookupMaps(config, "kubernetes", "helm", "charts") {
+			name, _ := chart["name"].(string)
+			report.Workloads = append(report.Workloads, name)
+		}
+	}
+	if report.Workloads == nil {
+		report.Workloads = []string{}
+	}
+	servers, agents := report.Servers, report.Agents
+	report.FaultTolerance = (servers - 1) / 2
+
+	var quorum, spof, single, control, workload []string
+	switch {
+	case servers%2 == 0:
+		quorum = append(quorum, fmt.Sprintf("kubernetes.nodes: %d servers tolerate the failure of %d, as %d would, since etcd needs %d of them for quorum; use %d servers", servers, report.FaultTolerance, servers-1, servers/2+1, servers+1))
+	case servers > maxEtcdMembers:
+		quorum = append(quorum, fmt.Sprintf("kubernetes.nodes: %d servers slow etcd down, which replicates every write to each of them; use %d servers and add agents instead", servers, maxEtcdMembers))
+	}
+	if servers == 1 && agents > 0 {
+		spof = append(spof, "kubernetes.nodes: the single server is a single point of failure; if it fails, the agents keep their pods running, but nothing can schedule, heal or update them; use 3 servers")
+	}
+	if servers+agents == 1 {
+		single = append(single, "kubernetes.nodes: a single-node cluster has no redundancy, so a failure of the node stops every workload; add nodes, with 3 servers, if the site must tolerate a failure")
+	}
+	if agents > servers*agentsPerServer && servers < maxEtcdMembers {
+		needed := min(maxEtcdMembers, max(3, (agents+agentsPerServer-1)/agentsPerServer)|1)
+		control = append(control, fmt.Sprintf("kubernetes.nodes: %d servers manage %d agents, more than %d each, so the API server and etcd may fall behind; use %d servers", servers, agents, agentsPerServer, needed))
+	}
+	for i, name := range report.Workloads {
+		needs, ok := knownWorkloads[strings.ToLower(name)]
+		if !ok || servers+agents >= needs.Nodes {
+			continue
+		}
+		workload = append(workload, fmt.Sprintf("%s.%d: %s needs at least %d nodes for %s, but the cluster has %d; add nodes, or lower its replica count", workloadPath, i, name, needs.Nodes, needs.Reason, servers+agents))
+	}
+
+	report.Findings = append(report.Findings, NewFindings("sizing-quorum", SeverityWarning, quorum)...)
+	report.Findings = append(report.Findings, NewFindings("sizing-single-point-of-failure", SeverityWarning, spof)...)
+	report.Findings = append(report.Findings, NewFindings("sizing-single-node", SeverityInfo, single)...)
+	report.Findings = append(report.Findings, NewFindings("sizing-control-plane", SeverityWarning, control)...)
+	report.Findings = append(report.Findings, NewFindings("sizing-workload", SeverityWarning, workload)...)
+	return report, nil
+}
@@ -0,0 +1,328 @@
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourcePolicy restricts where the software of an image may come from and
+// what it may be.
+//
+// Regulated fleets must only pull Helm charts, container images and RPM
+// packages from vetted sources, and never install forbidden packages or
+// known-vulnerable images; configurations that do fail validation.
+type SourcePolicy struct {
+	// Allow lists the approved sources. An empty list leaves its kind of
+	// source unrestricted.
+	Allow SourceAllowList `yaml:"allow" json:"allow"`
+	// Deny lists forbidden packages and images.
+	Deny SourceDenyList `yaml:"deny" json:"deny"`
+}
+
+// SourceAllowList lists approved sources. Each entry is a URL or image
+// reference prefix, matched at a path boundary: "https://charts.example.com"
+// approves "https://charts.example.com/stable" but not
+// "https://charts.example.com.evil.org".
+type SourceAllowList struct {
+	// HelmRepositories are the approved URLs of kubernetes.helm.repositories.
+	HelmRepositories []string `yaml:"helmRepositories" json:"helmRepositories,omitempty"`
+	// Registries are the approved registries of embeddedArtifactRegistry
+	// images, e.g. "registry.suse.com" or "registry.example.com/edge".
+	Registries []string `yaml:"registries" json:"registries,omitempty"`
+	// RPMRepositories are the approved URLs of
+	// operatingSystem.packages.additionalRepos.
+	RPMRepositories []string `yaml:"rpmRepositories" json:"rpmRepositories,omitempty"`
+}
+
+// SourceDenyList lists forbidden packages and images.
+type SourceDenyList struct {
+	// Packages are forbidden entries of operatingSystem.packages.packageList.
+	Packages []DeniedSource `yaml:"packages" json:"packages,omitempty"`
+	// Images are forbidden images of embeddedArtifactRegistry.images.
+	Images []DeniedSource `yaml:"images" json:"images,omitempty"`
+}
+
+// DeniedSource is a forbidden package or image.
+type DeniedSource struct {
+	// Name is the package name or image reference. A "*" matches any text,
+	// e.g. "*:latest", and is matched against fully qualified image
+	// references. An image without a tag or digest denies every tag.
+	Name string `yaml:"name" json:"name"`
+	// Reason explains why it is forbidden, e.g. a CVE.
+	Reason string `yaml:"reason" json:"reason"`
+}
+
+// LoadSourcePolicy reads a source policy from a YAML file of the form:
+//
+//	allow:
+//	  helmRepositories: [https://charts.example.com]
+//	  registries: [registry.suse.com]
+//	  rpmRepositories: [https://rpm.example.com/sle-micro]
+//	deny:
+//	  packages:
+//	    - name: telnet
+//	      reason: unencrypted remote login
+//
+// Parameters:
+//   - path: The path of the file.
+//
+// Returns:
+//   - *SourcePolicy: The policy.
+//   - error: An error if the file cannot be read or has unknown fields.
+func LoadSourcePolicy(path string) (*SourcePolicy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source policy: %w", err)
+	}
+	p := &SourcePolicy{}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("invalid source policy %s: %w", path, err)
+	}
+	for _, list := range [][]string{p.Allow.HelmRepositories, p.Allow.Registries, p.Allow.RPMRepositories} {
+		for i, entry := range list {
+			list[i] = strings.TrimRight(strings.TrimSpace(entry), "/")
+			if list[i] == "" {
+				return nil, fmt.Errorf("invalid source policy %s: empty allow-list entry", path)
+			}
+		}
+	}
+	for _, list := range [][]DeniedSource{p.Deny.Packages, p.Deny.Images} {
+		for _, d := range list {
+			if strings.TrimSpace(d.Name) == "" {
+				return nil, fmt.Errorf("invalid source policy %s: deny-list entry without a name", path)
+			}
+			if strings.TrimSpace(d.Reason) == "" {
+				return nil, fmt.Errorf("invalid source policy %s: deny-list entry %q has no reason", path, d.Name)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Rules returns the cross-field rules enforcing the policy, one per
+// restricted kind of source and deny-list.
+//
+// Returns:
+//   - []Rule: The rules.
+func (p *SourcePolicy) Rules() []Rule {
+	var rules []Rule
+	if allowed := p.Allow.HelmRepositories; len(allowed) > 0 {
+		rules = append(rules, Rule{
+			ID:          "source-helm-repository",
+			Description: "Helm repositories must be on the approved list: " + strings.Join(allowed, ", ") + ".",
+			Severity:    SeverityError,
+			Check: func(cfg map[string]interface{}) []string {
+				return checkAllowed(lookupMaps(cfg, "kubernetes", "helm", "repositories"), "url", "kubernetes.helm.repositories", "Helm repository", allowed, repositoryURL)
+			},
+		})
+	}
+	if allowed := p.Allow.Registries; len(allowed) > 0 {
+		rules = append(rules, Rule{
+			ID:          "source-registry",
+			Description: "Embedded container images must come from approved registries: " + strings.Join(allowed, ", ") + ".",
+			Severity:    SeverityError,
+			Check: func(cfg map[string]interface{}) []string {
+				return checkAllowed(lookupMaps(cfg, "embeddedArtifactRegistry", "images"), "name", "embeddedArtifactRegistry.images", "image", allowed, imageReference)
+			},
+		})
+	}
+	if allowed := p.Allow.RPMRepositories; len(allowed) > 0 {
+		rules = append(rules, Rule{
+			ID:          "source-rpm-repository",
+			Description: "Additional RPM repositories must be on the approved list: " + strings.Join(allowed, ", ") + ".",
+			Severity:    SeverityError,
+			Check: func(cfg map[string]interface{}) []string {
+				return checkAllowed(lookupMaps(cfg, "operatingSystem", "packages", "additionalRepos"), "url", "operatingSystem.packages.additionalRepos", "RPM repository", allowed, repositoryURL)
+			},
+		})
+	}
+	if denied := p.Deny.Packages; len(denied) > 0 {
+		rules = append(rules, Rule{
+			ID:          "denied-package",
+			Description: "Packages must not be on the deny-list: " + deniedNames(denied) + ".",
+			Severity:    SeverityError,
+			Check: func(cfg map[string]interface{}) []string {
+				return checkDenied(stringList(lookupMap(cfg, "operatingSystem", "packages")["packageList"]), "operatingSystem.packages.packageList", "package", denied, func(s string) string { return s })
+			},
+		})
+	}
+	if denied := p.Deny.Images; len(denied) > 0 {
+		rules = append(rules, Rule{
+			ID:          "denied-image",
+			Description: "Embedded container images must not be on the deny-list: " + deniedNames(denied) + ".",
+			Severity:    SeverityError,
+			Check: func(cfg map[string]interface{}) []string {
+				var names []string
+				for _, image := range lookupMaps(cfg, "embeddedArtifactRegistry", "images") {
+					name, _ := image["name"].(string)
+					names = append(names, name)
+				}
+				return checkDenied(names, "embeddedArtifactRegistry.images", "image", denied, imageReference)
+			},
+		})
+	}
+	return rules
+}
+
+// checkDenied reports the entries of a list that are on a deny-list, with
+// the reason.
+//
+// Parameters:
+//   - names: The entries; empty entries are skipped.
+//   - path: The path of the list, for messages.
+//   - what: What the entries are, for messages, e.g. "package".
+//   - denied: The deny-list.
+//   - normalize: Converts an entry, and a deny-list name without wildcards,
+//     to the form they are compared in.
+//
+// Returns:
+//   - []string: One message per denied entry.
+func checkDenied(names []string, path, what string, denied []DeniedSource, normalize func(string) string) []string {
+	var msgs []string
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		for _, d := range denied {
+			pattern := d.Name
+			if !strings.Contains(pattern, "*") {
+				pattern = normalize(pattern)
+			}
+			if matchesDenied(normalize(name), pattern) {
+				msgs = append(msgs, fmt.Sprintf("%s.%d: %s %q is denied: %s", path, i, what, name, d.Reason))
+				break
+			}
+		}
+	}
+	return msgs
+}
+
+// matchesDenied reports whether a name matches a deny-list name: a pattern
+// with "*" wildcards, or a name also matching every tag, digest and
+// subpath.
+//
+// Parameters:
+//   - name: The normalized name.
+//   - pattern: The normalized deny-list name.
+//
+// Returns:
+//   - bool: true if the name is denied.
+func matchesDenied(name, pattern string) bool {
+	if !strings.Contains(pattern, "*") {
+		return matchesPrefix(name, []string{pattern})
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	rest := name[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return strings.HasSuffix(rest, parts[len(parts)-1])
+}
+
+// deniedNames lists the names of a deny-list.
+//
+// Parameters:
+//   - denied: The deny-list.
+//
+// Returns:
+//   - string: The comma separated names.
+func deniedNames(denied []DeniedSource) string {
+	names := make([]string, len(denied))
+	for i, d := range denied {
+		names[i] = d.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkAllowed reports the items of a list whose source is not approved.
+//
+// Parameters:
+//   - items: The list items.
+//   - key: The field of an item holding the source.
+//   - path: The path of the list, for messages.
+//   - what: What the source is, for messages, e.g. "Helm repository".
+//   - allowed: The approved prefixes.
+//   - normalize: Converts a source to the form the prefixes are matched
+//     against.
+//
+// Returns:
+//   - []string: One message per source that is not approved.
+func checkAllowed(items []map[string]interface{}, key, path, what string, allowed []string, normalize func(string) string) []string {
+	var msgs []string
+	for i, item := range items {
+		source, ok := item[key].(string)
+		if !ok {
+			continue
+		}
+		if !matchesPrefix(normalize(source), allowed) {
+			msgs = append(msgs, fmt.Sprintf("%s.%d: %s %q is not from an approved source; use one of %s", path, i, what, source, strings.Join(allowed, ", ")))
+		}
+	}
+	return msgs
+}
+
+// matchesPrefix reports whether a source starts with one of the prefixes, at
+// a path boundary.
+//
+// Parameters:
+//   - source: The normalized source.
+//   - prefixes: The prefixes, without a trailing slash.
+//
+// Returns:
+//   - bool: true if a prefix matches.
+func matchesPrefix(source string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if source == prefix || strings.HasPrefix(source, prefix+"/") {
+			return true
+		}
+		// An image reference continues with a tag or digest.
+		if strings.HasPrefix(source, prefix+":") || strings.HasPrefix(source, prefix+"@") {
+			return true
+		}
+	}
+	return false
+}
+
+// repositoryURL normalizes a repository URL: the trailing slash is removed.
+//
+// Parameters:
+//   - s: The URL.
+//
+// Returns:
+//   - string: The normalized URL.
+func repositoryURL(s string) string {
+	return strings.TrimRight(s, "/")
+}
+
+// imageReference normalizes a container image reference to name its
+// registry, the way container runtimes resolve short names: "nginx:1.27"
+// is "docker.io/library/nginx:1.27".
+//
+// Parameters:
+//   - ref: The image reference.
+//
+// Returns:
+//   - string: The fully qualified reference.
+func imageReference(ref string) string {
+	first, rest, found := strings.Cut(ref, "/")
+	if !found {
+		return "docker.io/library/" + ref
+	}
+	if !strings.ContainsAny(first, ".:") && first != "localhost" {
+		return "docker.io/" + first + "/" + rest
+	}
+	return ref
+}
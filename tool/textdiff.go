@@ -0,0 +1,136 @@
+package tool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// UnifiedDiff renders the line differences between two texts in unified
+// diff format.
+//
+// Parameters:
+//   - from: The original text.
+//   - to: The new text.
+//   - fromName: The label of the original text.
+//   - toName: The label of the new text.
+//
+// Returns:
+//   - string: The diff, or empty if the texts are equal.
+func UnifiedDiff(from, to, fromName, toName string) string {
+	a := splitLines(from)
+	b := splitLines(to)
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change and the end of its hunk.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		end := first
+		for end < len(ops) {
+			next := end
+			for next < len(ops) && ops[next].kind != ' ' {
+				next++
+			}
+			gap := next
+			for gap < len(ops) && ops[gap].kind == ' ' {
+				gap++
+			}
+			end = next
+			if gap == len(ops) || gap-next > 2*diffContext {
+				break
+			}
+			end = gap
+		}
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := min(end+diffContext, len(ops))
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		aStart, bStart := ops[hunkStart].aLine, ops[hunkStart].bLine
+		var aCount, bCount int
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart+1, aCount, bStart+1, bCount)
+		for _, op := range ops[hunkStart:hunkEnd] {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.text)
+		}
+		start = hunkEnd
+	}
+	return out.String()
+}
+
+// diffOp is one line of an edit script.
+type diffOp struct {
+	// kind is ' ' for an unchanged line, '-' for a removed one and '+' for an added one.
+	kind byte
+	// text is the line, without its newline.
+	text string
+	// aLine and bLine are the zero-based positions in the original and new texts.
+	aLine, bLine int
+}
+
+// diffLines computes a minimal line edit script from the longest common subsequence.
+//
+// Parameters:
+//   - a: The original lines.
+//   - b: The new lines.
+//
+// Returns:
+//   - []diffOp: The edit script.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// splitLines splits a text into lines, ignoring a final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,110 @@
+package tool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keymapTimezones suggests a time zone for the keymaps of a country, keyed
+// by the keymap up to its first "-", e.g. "de" for "de-nodeadkeys".
+var keymapTimezones = map[string]string{
+	"at":    "Europe/Vienna",
+	"be":    "Europe/Brussels",
+	"br":    "America/Sao_Paulo",
+	"ch":    "Europe/Zurich",
+	"cz":    "Europe/Prague",
+	"de":    "Europe/Berlin",
+	"dk":    "Europe/Copenhagen",
+	"es":    "Europe/Madrid",
+	"fi":    "Europe/Helsinki",
+	"fr":    "Europe/Paris",
+	"gr":    "Europe/Athens",
+	"hu":    "Europe/Budapest",
+	"it":    "Europe/Rome",
+	"jp":    "Asia/Tokyo",
+	"jp106": "Asia/Tokyo",
+	"no":    "Europe/Oslo",
+	"pl":    "Europe/Warsaw",
+	"pt":    "Europe/Lisbon",
+	"ru":    "Europe/Moscow",
+	"se":    "Europe/Stockholm",
+	"uk":    "Europe/London",
+}
+
+// timeRules returns the rules tying the time settings to the rest of the
+// configuration: Kubernetes needs synchronized clocks, an air-gapped cluster
+// must not start before its clock is set, and a localized system is rarely
+// meant to run on UTC.
+//
+// Returns:
+//   - []Rule: The time rules, in evaluation order.
+func timeRules() []Rule {
+	return []Rule{
+		{
+			ID:          "time-kubernetes-ntp",
+			Description: "Configurations installing Kubernetes should synchronize the clock with operatingSystem.time.ntp.",
+			Severity:    SeverityWarning,
+			Check:       checkKubernetesNTP,
+		},
+		{
+			ID:          "time-airgap-force-wait",
+			Description: "Air-gapped clusters, which embed their container images, should set operatingSystem.time.ntp.forceWait.",
+			Severity:    SeverityWarning,
+			Check:       checkAirgapForceWait,
+		},
+		{
+			ID:          "time-timezone-localized",
+			Description: "Configurations with a localized keymap should set operatingSystem.time.timezone to a zone other than UTC.",
+			Severity:    SeverityWarning,
+			Check:       checkLocalizedTimezone,
+		},
+	}
+}
+
+// hasNTP reports whether a configuration sets NTP servers or pools.
+func hasNTP(cfg map[string]interface{}) bool {
+	ntp := lookupMap(cfg, "operatingSystem", "time", "ntp")
+	return len(stringList(ntp["servers"])) > 0 || len(stringList(ntp["pools"])) > 0
+}
+
+// checkKubernetesNTP implements the time-kubernetes-ntp rule.
+func checkKubernetesNTP(cfg map[string]interface{}) []string {
+	if lookupMap(cfg, "kubernetes") == nil || hasNTP(cfg) {
+		return nil
+	}
+	return []string{"operatingSystem.time.ntp: Kubernetes is installed without NTP, so the node clocks drift apart, which breaks certificate validity and etcd leases; add ntp.pools or ntp.servers"}
+}
+
+// checkAirgapForceWait implements the time-airgap-force-wait rule. A
+// cluster embedding its container images is taken to be air-gapped: its
+// nodes often lack a battery-backed clock that is right, and only reach a
+// local NTP server, so they must wait for it before Kubernetes starts.
+func checkAirgapForceWait(cfg map[string]interface{}) []string {
+	if lookupMap(cfg, "kubernetes") == nil || len(lookupMaps(cfg, "embeddedArtifactRegistry", "images")) == 0 || !hasNTP(cfg) {
+		return nil
+	}
+	if wait, _ := lookupMap(cfg, "operatingSystem", "time", "ntp")["forceWait"].(bool); wait {
+		return nil
+	}
+	return []string{"operatingSystem.time.ntp.forceWait: the cluster embeds its images, so it is likely air-gapped, but it starts without waiting for the clock to be synchronized, which can make its certificates not yet valid; set forceWait to true"}
+}
+
+// checkLocalizedTimezone implements the time-timezone-localized rule.
+func checkLocalizedTimezone(cfg map[string]interface{}) []string {
+	keymap, _ := lookupMap(cfg, "operatingSystem")["keymap"].(string)
+	if keymap == "" || keymap == "us" || strings.HasPrefix(keymap, "us-") {
+		return nil
+	}
+	timezone, _ := lookupMap(cfg, "operatingSystem", "time")["timezone"].(string)
+	if timezone != "" && timezone != "UTC" && timezone != "Etc/UTC" {
+		return nil
+	}
+	example := "e.g. Europe/Madrid"
+	if zone, ok := keymapTimezones[strings.SplitN(keymap, "-", 2)[0]]; ok {
+		example = "e.g. " + zone
+	}
+	if timezone == "" {
+		return []string{fmt.Sprintf("operatingSystem.time.timezone: the keymap %q is localized, but no time zone is set, so the system runs on UTC; set timezone to the local zone, %s", keymap, example)}
+	}
+	return []string{fmt.Sprintf("operatingSystem.time.timezone: the keymap %q is localized, but the time zone is %s; set timezone to the local zone, %s", keymap, timezone, example)}
+}
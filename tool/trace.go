@@ -0,0 +1,78 @@
+package tool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is a timed step of a tool call, such as schema validation or a
+// download.
+type Span struct {
+	// Name describes the step, e.g. "schema" or "download https://...".
+	Name string
+	// Start is when the step started.
+	Start time.Time
+	// Duration is how long the step took.
+	Duration time.Duration
+}
+
+// Trace records the steps of a tool call, so that slow calls can be broken
+// down. A nil *Trace records nothing, so callers need not check for one. A
+// Trace is safe for concurrent use.
+type Trace struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+// Step starts timing a step.
+//
+// Parameters:
+//   - name: The step name.
+//
+// Returns:
+//   - func(): Ends the step; typically deferred.
+func (t *Trace) Step(name string) func() {
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.spans = append(t.spans, Span{Name: name, Start: start, Duration: time.Since(start)})
+	}
+}
+
+// Spans returns the steps recorded so far, in the order they ended.
+//
+// Returns:
+//   - []Span: The steps.
+func (t *Trace) Spans() []Span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Span(nil), t.spans...)
+}
+
+// String lists the steps with their durations, slowest first, e.g.
+// "download https://example.com/index.yaml 4.2s, schema 12ms".
+//
+// Returns:
+//   - string: The summary, or "no steps recorded".
+func (t *Trace) String() string {
+	spans := t.Spans()
+	if len(spans) == 0 {
+		return "no steps recorded"
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].Duration > spans[j].Duration })
+	parts := make([]string, len(spans))
+	for i, s := range spans {
+		parts[i] = fmt.Sprintf("%s %s", s.Name, s.Duration.Round(time.Millisecond))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,96 @@
+package tool
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// variablePattern matches ${NAME} references, and $${NAME} escapes.
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// DefaultEnvPrefix is the default prefix of environment variables that can
+// be referenced from configurations: ${CLUSTER_NAME} resolves to
+// EIB_VAR_CLUSTER_NAME when no explicit value is given.
+const DefaultEnvPrefix = "EIB_VAR_"
+
+// SubstituteVariables replaces ${NAME} references in every string value of
+// a configuration.
+//
+// Values are looked up in vars first, then in the environment variable
+// envPrefix+NAME if envPrefix is not empty. Only prefixed environment
+// variables are visible, so a configuration cannot read arbitrary secrets
+// from the server's environment. A literal "${" is written as "$${".
+//
+// Parameters:
+//   - input: The configuration value to process; maps and lists are modified in place.
+//   - vars: The explicitly provided variable values.
+//   - envPrefix: The environment variable prefix, or empty to disable environment lookups.
+//
+// Returns:
+//   - interface{}: The value with all references substituted.
+//   - error: An error listing every referenced variable that has no value.
+func SubstituteVariables(input interface{}, vars map[string]string, envPrefix string) (interface{}, error) {
+	missing := map[string]bool{}
+	lookup := func(name string) (string, bool) {
+		if v, ok := vars[name]; ok {
+			return v, true
+		}
+		if envPrefix != "" {
+			return os.LookupEnv(envPrefix + name)
+		}
+		return "", false
+	}
+
+	result := substitute(input, lookup, missing)
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(names, ", "))
+	}
+	return result, nil
+}
+
+// substitute walks a value and replaces variable references in strings.
+//
+// Parameters:
+//   - v: The value to process.
+//   - lookup: Resolves a variable name to its value.
+//   - missing: Collects the names of unresolved variables.
+//
+// Returns:
+//   - interface{}: The processed value.
+func substitute(v interface{}, lookup func(string) (string, bool), missing map[string]bool) interface{} {
+	switch val := v.(type) {
+	case string:
+		return variablePattern.ReplaceAllStringFunc(val, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			name := ref[2 : len(ref)-1]
+			value, ok := lookup(name)
+			if !ok {
+				missing[name] = true
+				return ref
+			}
+			return value
+		})
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = substitute(item, lookup, missing)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = substitute(item, lookup, missing)
+		}
+		return val
+	default:
+		return val
+	}
+}
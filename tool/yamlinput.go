@@ -0,0 +1,93 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseYAMLConfig parses a configuration written as a YAML document, the way
+// LLMs commonly produce it, and normalizes it for validation.
+//
+// A surrounding Markdown code fence is removed, and an apiVersion written as
+// a number (apiVersion: 1.0, which YAML reads as the float 1) is turned back
+// into the string it was meant to be. Each normalization is reported as a
+// warning. Only a single YAML document is accepted.
+//
+// Parameters:
+//   - text: The YAML document.
+//
+// Returns:
+//   - map[string]interface{}: The configuration map.
+//   - []string: The normalizations applied.
+//   - error: An error if the document cannot be parsed or is not a mapping.
+func ParseYAMLConfig(text string) (map[string]interface{}, []string, error) {
+	var warnings []string
+	if stripped, ok := stripCodeFence(text); ok {
+		text = stripped
+		warnings = append(warnings, "removed the Markdown code fence around the YAML document")
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(text))
+	var root yaml.Node
+	if err := dec.Decode(&root); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("the YAML document is empty")
+		}
+		return nil, nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	var extra yaml.Node
+	if err := dec.Decode(&extra); !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("expected a single YAML document, found several")
+	}
+
+	doc := &root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("configuration must be a mapping at the top level")
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, value := doc.Content[i], doc.Content[i+1]
+		if key.Value == "apiVersion" && value.Kind == yaml.ScalarNode && (value.Tag == "!!float" || value.Tag == "!!int") {
+			value.Tag = "!!str"
+			value.Style = yaml.DoubleQuotedStyle
+			warnings = append(warnings, fmt.Sprintf("apiVersion %s was written as a number; it was read as the string %q", value.Value, value.Value))
+		}
+	}
+
+	var decoded interface{}
+	if err := doc.Decode(&decoded); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	config, ok := normalizeYAML(decoded).(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("configuration must be a mapping at the top level")
+	}
+	return config, warnings, nil
+}
+
+// stripCodeFence removes a Markdown code fence (```yaml ... ```) around a document.
+//
+// Parameters:
+//   - text: The document.
+//
+// Returns:
+//   - string: The document without the fence.
+//   - bool: True if a fence was removed.
+func stripCodeFence(text string) (string, bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return text, false
+	}
+	body := strings.TrimSuffix(trimmed, "```")
+	newline := strings.IndexByte(body, '\n')
+	if newline < 0 {
+		return text, false
+	}
+	return strings.TrimRight(body[newline+1:], " \t"), true
+}